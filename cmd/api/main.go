@@ -24,12 +24,28 @@ import (
 
 	_ "github.com/nneji123/ecommerce-golang/docs"
 	"github.com/nneji123/ecommerce-golang/internal/common/email"
+	"github.com/nneji123/ecommerce-golang/internal/common/geo"
+	"github.com/nneji123/ecommerce-golang/internal/common/money"
+	"github.com/nneji123/ecommerce-golang/internal/common/revocation"
+	"github.com/nneji123/ecommerce-golang/internal/common/storage"
+	"github.com/nneji123/ecommerce-golang/internal/domain/cart"
+	"github.com/nneji123/ecommerce-golang/internal/domain/category"
+	"github.com/nneji123/ecommerce-golang/internal/domain/inventory"
 	"github.com/nneji123/ecommerce-golang/internal/domain/order"
+	"github.com/nneji123/ecommerce-golang/internal/domain/outbox"
+	"github.com/nneji123/ecommerce-golang/internal/domain/payment"
 	"github.com/nneji123/ecommerce-golang/internal/domain/product"
+	"github.com/nneji123/ecommerce-golang/internal/domain/promotion"
+	"github.com/nneji123/ecommerce-golang/internal/domain/rbac"
+	"github.com/nneji123/ecommerce-golang/internal/domain/review"
+	"github.com/nneji123/ecommerce-golang/internal/domain/shipping"
+	"github.com/nneji123/ecommerce-golang/internal/domain/tax"
 	"github.com/nneji123/ecommerce-golang/internal/domain/user"
+	appmiddleware "github.com/nneji123/ecommerce-golang/internal/middleware"
 
 	"github.com/nneji123/ecommerce-golang/internal/config"
 	"github.com/nneji123/ecommerce-golang/internal/db"
+	"github.com/nneji123/ecommerce-golang/internal/db/migrations"
 )
 
 //	@title			GoCommerce API
@@ -71,7 +87,21 @@ func main() {
 	}
 	defer sqlDB.Close()
 
+	migrator, err := migrations.New(sqlDB)
+	if err != nil {
+		logger.Fatal("Failed to load migrations", zap.Error(err))
+	}
+	if err := migrator.Check(context.Background()); err != nil {
+		logger.Fatal("Refusing to start; run `go run ./cmd/migrate up` first", zap.Error(err))
+	}
+
+	if err := money.SetDefaultCurrency(cfg.Currency); err != nil {
+		logger.Fatal("Invalid currency", zap.Error(err))
+	}
 	validate := validator.New()
+	if err := money.RegisterValidation(validate); err != nil {
+		logger.Fatal("Failed to register money validation", zap.Error(err))
+	}
 
 	e := echo.New()
 
@@ -84,27 +114,143 @@ func main() {
 	e.GET("/swagger/*any", echoSwagger.WrapHandler)
 
 	userRepo := user.NewRepository(database)
-	emailNotificationService, err := email.NewEmailNotificationService("smtp", &cfg)
+	emailNotificationService, err := email.NewEmailNotificationService(cfg.EmailProvider, &cfg)
 	if err != nil {
 		logger.Fatal("Failed to initialize email service", zap.Error(err))
 	}
-	emailService := user.NewEmailService(emailNotificationService, &cfg)
-	authService := user.NewJWTService(cfg.JWTSecret)
+	emailService := user.NewEmailService(&cfg)
+	outboxRepo := outbox.NewRepository(database)
+	emailDispatcher := outbox.NewDispatcher(
+		outboxRepo,
+		emailNotificationService,
+		outbox.DispatcherConfig{
+			Workers:     cfg.EmailWorkers,
+			MaxAttempts: cfg.EmailMaxAttempts,
+		},
+		logger,
+	)
+	authService := user.NewJWTService(cfg.JWTSecret, cfg.AccessTokenTTL)
+	revocations := revocation.NewPostgresStore(database)
+	cursorSigner, err := appmiddleware.NewCursorSigner(cfg.CursorSecret)
+	if err != nil {
+		logger.Fatal("Invalid cursor configuration", zap.Error(err))
+	}
+	sessionService := user.NewSessionService(
+		authService,
+		userRepo,
+		user.NewRefreshTokenRepository(database),
+		revocations,
+		cfg.RefreshTokenTTL,
+	)
+	cartRepo := cart.NewRepository(database)
+	addressRepo := user.NewAddressRepository(database)
 
 	// Initialize handlers
 	userHandler := user.NewHandler(
 		userRepo,
 		validate,
-		authService,
+		sessionService,
 		emailService,
+		cartRepo,
+		addressRepo,
 		logger,
 	)
 
-	user.RegisterRoutes(e, userHandler)
+	user.RegisterRoutes(e, userHandler, revocations)
+
+	rbacRepo := rbac.NewRepository(database)
+	permissionResolver := rbac.NewResolver(rbacRepo, 30*time.Second)
+	rbacHandler := rbac.NewHandler(rbacRepo, permissionResolver, revocations, validate, logger)
+	rbac.RegisterRoutes(e, rbacHandler, revocations, permissionResolver)
+
+	outboxHandler := outbox.NewHandler(outboxRepo, logger)
+	outbox.RegisterRoutes(e, outboxHandler, revocations, permissionResolver)
+
+	productRepo := product.NewRepository(database, cursorSigner)
+	productFacets, err := product.ParseFacetConfig(cfg.ProductFacets, cfg.ProductPriceBuckets)
+	if err != nil {
+		logger.Fatal("Invalid product facet configuration", zap.Error(err))
+	}
+	mediaStorage, err := storage.NewStorage(&cfg)
+	if err != nil {
+		logger.Fatal("Failed to initialize media storage", zap.Error(err))
+	}
+	if local, ok := mediaStorage.(*storage.LocalStorage); ok {
+		e.Static("/media", local.Root())
+	}
+	thumbnailSizes, err := product.ParseThumbnailSizes(cfg.MediaThumbnailSizes)
+	if err != nil {
+		logger.Fatal("Invalid media thumbnail sizes", zap.Error(err))
+	}
+	productMedia := product.MediaConfig{MaxBytes: cfg.MediaMaxBytes, Thumbnails: thumbnailSizes}
+	productImporter := product.NewImporter(productRepo, validate, product.ImporterConfig{}, logger)
+	productHandler := product.NewHandler(productRepo, productImporter, mediaStorage, productMedia, productFacets, validate, logger)
+	product.RegisterRoutes(e, productHandler, revocations, permissionResolver)
+
+	reviewRepo := review.NewRepository(database)
+	reviewHandler := review.NewHandler(reviewRepo, validate, logger)
+	review.RegisterRoutes(e, reviewHandler, revocations, permissionResolver)
 
-	if err := database.AutoMigrate(&user.User{}, &product.Product{}, &order.Order{}, &order.OrderItem{}); err != nil {
-		logger.Fatal("Failed to auto-migrate user model", zap.Error(err))
+	categoryRepo := category.NewRepository(database)
+	categoryHandler := category.NewHandler(categoryRepo, validate, logger)
+	category.RegisterRoutes(e, categoryHandler, revocations, permissionResolver)
+
+	inventoryRepo := inventory.NewRepository(database)
+	inventoryHandler := inventory.NewHandler(inventoryRepo, validate, logger)
+	inventory.RegisterRoutes(e, inventoryHandler, revocations, permissionResolver)
+
+	promotionRepo := promotion.NewRepository(database)
+	promotionHandler := promotion.NewHandler(promotionRepo, validate, logger)
+	promotion.RegisterRoutes(e, promotionHandler, revocations, permissionResolver)
+
+	taxRepo := tax.NewRepository(database)
+	taxLocation, err := geo.ParseLocation(cfg.TaxDefaultLocation)
+	if err != nil {
+		logger.Fatal("Invalid default tax location", zap.Error(err))
+	}
+	taxCalculator, err := tax.NewLocalCalculator(taxRepo, tax.Config{
+		PricesIncludeTax: cfg.TaxPricesIncludeTax,
+		Rounding:         tax.Rounding(cfg.TaxRounding),
+		DefaultLocation:  taxLocation,
+	})
+	if err != nil {
+		logger.Fatal("Invalid tax configuration", zap.Error(err))
+	}
+	taxHandler := tax.NewHandler(taxRepo, validate, logger)
+	tax.RegisterRoutes(e, taxHandler, revocations, permissionResolver)
+
+	shippingRepo := shipping.NewRepository(database)
+	shippingProviders := shipping.NewProviders(shipping.NewLocalProvider())
+	shippingQuoter := shipping.NewQuoter(shippingRepo, shippingProviders)
+	shippingHandler := shipping.NewHandler(shippingRepo, shippingProviders, validate, logger)
+	shipping.RegisterRoutes(e, shippingHandler, revocations, permissionResolver)
+
+	orderRepo := order.NewRepository(database, cfg.ReservationTTL, taxCalculator, shippingQuoter, cursorSigner)
+	reservationSweeper := order.NewReservationSweeper(
+		orderRepo,
+		inventoryRepo,
+		order.SweeperConfig{Interval: cfg.ReservationSweep},
+		logger,
+	)
+	orderHandler := order.NewHandler(orderRepo, permissionResolver, validate, logger)
+	order.RegisterRoutes(e, orderHandler, revocations, permissionResolver)
+
+	cartHandler := cart.NewHandler(cartRepo, orderRepo, shippingQuoter, validate, logger)
+	cart.RegisterRoutes(e, cartHandler, revocations)
+
+	paymentRepo := payment.NewRepository(database, orderRepo)
+	var gateways []payment.PaymentProvider
+	if cfg.PaymentFakeEnabled {
+		fake, err := payment.NewFakeProvider(cfg.PaymentWebhookSecret)
+		if err != nil {
+			logger.Fatal("Invalid fake payment provider configuration", zap.Error(err))
+		}
+		logger.Warn("The fake payment provider is enabled; do not use it in production")
+		gateways = append(gateways, fake)
 	}
+	paymentProviders := payment.NewProviders(gateways...)
+	paymentHandler := payment.NewHandler(paymentRepo, orderRepo, paymentProviders, permissionResolver, validate, logger)
+	payment.RegisterRoutes(e, paymentHandler, revocations, permissionResolver)
 
 	printServerDetails(cfg)
 
@@ -112,6 +258,10 @@ func main() {
 		Addr:    fmt.Sprintf(":%s", cfg.ServerPort),
 		Handler: e,
 	}
+	emailDispatcher.Start()
+	productImporter.Start()
+	reservationSweeper.Start()
+
 	go func() {
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Error starting server: %v", err)
@@ -119,7 +269,7 @@ func main() {
 	}()
 
 	// Graceful shutdown
-	shutdownServer(srv)
+	shutdownServer(srv, emailDispatcher, productImporter, reservationSweeper)
 }
 
 // HandlePing
@@ -166,7 +316,7 @@ func printServerDetails(cfg config.Config) {
 	fmt.Printf("  Used:  %d MB\n\n", memInfo.Used/1024/1024)
 }
 
-func shutdownServer(server *http.Server) {
+func shutdownServer(server *http.Server, dispatcher *outbox.Dispatcher, importer *product.Importer, sweeper *order.ReservationSweeper) {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
@@ -178,5 +328,17 @@ func shutdownServer(server *http.Server) {
 	if err := server.Shutdown(ctx); err != nil {
 		log.Fatalf("Error shutting down server: %v", err)
 	}
+
+	// Stop the email workers after the server so in-flight sends finish;
+	// anything still queued stays in the outbox for the next start.
+	if err := dispatcher.Stop(ctx); err != nil {
+		log.Printf("Error stopping email dispatcher: %v", err)
+	}
+	if err := importer.Stop(ctx); err != nil {
+		log.Printf("Error stopping product importer: %v", err)
+	}
+	if err := sweeper.Stop(ctx); err != nil {
+		log.Printf("Error stopping reservation sweeper: %v", err)
+	}
 	log.Println("Server gracefully stopped")
 }
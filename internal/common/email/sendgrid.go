@@ -0,0 +1,188 @@
+package email
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nneji123/ecommerce-golang/internal/config"
+)
+
+const sendGridSendPath = "/v3/mail/send"
+
+// SendOptions carries SendGrid-specific metadata for a message.
+type SendOptions struct {
+	Categories []string
+	CustomArgs map[string]string
+}
+
+// SendGridError is returned when the SendGrid API rejects a request.
+type SendGridError struct {
+	StatusCode int
+	Messages   []string
+	// RetryAfter is the delay requested by SendGrid on 429 responses.
+	RetryAfter time.Duration
+}
+
+func (e *SendGridError) Error() string {
+	if len(e.Messages) == 0 {
+		return fmt.Sprintf("sendgrid: request failed with status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("sendgrid: request failed with status %d: %s", e.StatusCode, strings.Join(e.Messages, "; "))
+}
+
+// Temporary reports whether the request may succeed if retried: rate limits
+// and server errors are temporary, other client errors are not.
+func (e *SendGridError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+type SendGridService struct {
+	config *config.Config
+	client *http.Client
+}
+
+func NewSendGridService(config *config.Config) *SendGridService {
+	return &SendGridService{
+		config: config,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// SendEmail sends a rendered template through the SendGrid v3 API. The
+// template's file name is used as the message category.
+func (s *SendGridService) SendEmail(subject, templatePath string, toEmail string, context map[string]interface{}, attachments []Attachment) error {
+	category := strings.TrimSuffix(filepath.Base(templatePath), filepath.Ext(templatePath))
+	return s.SendEmailWithOptions(subject, templatePath, toEmail, context, attachments, SendOptions{
+		Categories: []string{category},
+	})
+}
+
+func (s *SendGridService) SendEmailWithOptions(subject, templatePath string, toEmail string, context map[string]interface{}, attachments []Attachment, opts SendOptions) error {
+	html, text, err := RenderTemplate(templatePath, context)
+	if err != nil {
+		return fmt.Errorf("failed to render template: %w", err)
+	}
+
+	body, err := json.Marshal(s.buildMessage(subject, toEmail, html, text, attachments, opts))
+	if err != nil {
+		return fmt.Errorf("failed to encode sendgrid request: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimRight(s.config.SendGridBaseURL, "/")+sendGridSendPath, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build sendgrid request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+s.config.SendGridAPIKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	return parseSendGridError(resp)
+}
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+type sendGridPersonalization struct {
+	To []sendGridAddress `json:"to"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sendGridAttachment struct {
+	Content     string `json:"content"`
+	Type        string `json:"type,omitempty"`
+	Filename    string `json:"filename"`
+	Disposition string `json:"disposition"`
+}
+
+type sendGridMessage struct {
+	Personalizations []sendGridPersonalization `json:"personalizations"`
+	From             sendGridAddress           `json:"from"`
+	Subject          string                    `json:"subject"`
+	Content          []sendGridContent         `json:"content"`
+	Attachments      []sendGridAttachment      `json:"attachments,omitempty"`
+	Categories       []string                  `json:"categories,omitempty"`
+	CustomArgs       map[string]string         `json:"custom_args,omitempty"`
+}
+
+func (s *SendGridService) buildMessage(subject, toEmail, html, text string, attachments []Attachment, opts SendOptions) sendGridMessage {
+	message := sendGridMessage{
+		Personalizations: []sendGridPersonalization{{To: []sendGridAddress{{Email: toEmail}}}},
+		From:             sendGridAddress{Email: s.config.EmailFromAddress, Name: s.config.EmailFromName},
+		Subject:          subject,
+		// SendGrid requires text/plain to precede text/html.
+		Content: []sendGridContent{
+			{Type: "text/plain", Value: text},
+			{Type: "text/html", Value: html},
+		},
+		Categories: opts.Categories,
+		CustomArgs: opts.CustomArgs,
+	}
+
+	for _, att := range attachments {
+		contentType := att.ContentType
+		if contentType == "" {
+			contentType = GetMimeType(att.Filename)
+		}
+		message.Attachments = append(message.Attachments, sendGridAttachment{
+			Content:     base64.StdEncoding.EncodeToString(att.Content),
+			Type:        contentType,
+			Filename:    att.Filename,
+			Disposition: "attachment",
+		})
+	}
+
+	return message
+}
+
+func parseSendGridError(resp *http.Response) error {
+	sgErr := &SendGridError{StatusCode: resp.StatusCode}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			sgErr.RetryAfter = time.Duration(seconds) * time.Second
+		} else if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			sgErr.RetryAfter = time.Until(time.Unix(reset, 0))
+		}
+	}
+
+	var body struct {
+		Errors []struct {
+			Message string `json:"message"`
+			Field   string `json:"field"`
+		} `json:"errors"`
+	}
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if json.Unmarshal(raw, &body) == nil {
+		for _, e := range body.Errors {
+			if e.Field != "" {
+				sgErr.Messages = append(sgErr.Messages, e.Field+": "+e.Message)
+			} else {
+				sgErr.Messages = append(sgErr.Messages, e.Message)
+			}
+		}
+	}
+
+	return sgErr
+}
@@ -55,15 +55,3 @@ func (s *SMTPService) SendEmail(subject, templatePath string, toEmail string, co
 
 	return nil
 }
-
-type SendGridService struct {
-	config *config.Config
-}
-
-func NewSendGridService(config *config.Config) *SendGridService {
-	return &SendGridService{config: config}
-}
-
-func (s *SendGridService) SendEmail(subject, templatePath string, toEmail string, context map[string]interface{}, attachments []Attachment) error {
-	return nil
-}
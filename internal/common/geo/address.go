@@ -0,0 +1,103 @@
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var ErrInvalidAddress = errors.New("invalid address")
+
+// Address is a postal address. It is stored in the columns of its fields,
+// under a prefix when embedded in a GORM model, so orders can keep a copy of
+// the addresses they were placed with. Country is an ISO 3166-1 alpha-2
+// code; which of Region and Postcode are required, and the postcode format,
+// depend on the country and are checked by Validate.
+type Address struct {
+	Name     string `gorm:"size:255" json:"name" validate:"required,max=255"`
+	Company  string `gorm:"size:255" json:"company,omitempty" validate:"max=255"`
+	Line1    string `gorm:"column:line1;size:255" json:"line1" validate:"required,max=255"`
+	Line2    string `gorm:"column:line2;size:255" json:"line2,omitempty" validate:"max=255"`
+	City     string `gorm:"size:100" json:"city" validate:"required,max=100"`
+	Region   string `gorm:"size:100" json:"region,omitempty" validate:"max=100"`
+	Postcode string `gorm:"size:50" json:"postcode,omitempty" validate:"max=50"`
+	Country  string `gorm:"size:2" json:"country" validate:"required,iso3166_1_alpha2"`
+	Phone    string `gorm:"size:50" json:"phone,omitempty" validate:"max=50"`
+}
+
+// addressFormat lists what a country's addresses need beyond a name, a
+// street line and a city.
+type addressFormat struct {
+	region   bool
+	postcode *regexp.Regexp
+	// postcodeOptional accepts addresses without a postcode; one that is
+	// given must still match.
+	postcodeOptional bool
+}
+
+// addressFormats holds the countries whose addresses need a region or a
+// postcode of a known format. Addresses elsewhere only need the common
+// fields.
+var addressFormats = map[string]addressFormat{
+	"AU": {region: true, postcode: regexp.MustCompile(`^\d{4}$`)},
+	"BR": {region: true, postcode: regexp.MustCompile(`^\d{5}-?\d{3}$`)},
+	"CA": {region: true, postcode: regexp.MustCompile(`^[A-Z]\d[A-Z] ?\d[A-Z]\d$`)},
+	"DE": {postcode: regexp.MustCompile(`^\d{5}$`)},
+	"ES": {postcode: regexp.MustCompile(`^\d{5}$`)},
+	"FR": {postcode: regexp.MustCompile(`^\d{5}$`)},
+	"GB": {postcode: regexp.MustCompile(`^[A-Z]{1,2}\d[A-Z\d]? ?\d[A-Z]{2}$`)},
+	"IE": {postcode: regexp.MustCompile(`^[A-Z\d]{3} ?[A-Z\d]{4}$`), postcodeOptional: true},
+	"IN": {region: true, postcode: regexp.MustCompile(`^\d{6}$`)},
+	"IT": {region: true, postcode: regexp.MustCompile(`^\d{5}$`)},
+	"JP": {region: true, postcode: regexp.MustCompile(`^\d{3}-?\d{4}$`)},
+	"MX": {region: true, postcode: regexp.MustCompile(`^\d{5}$`)},
+	"NG": {region: true, postcode: regexp.MustCompile(`^\d{6}$`), postcodeOptional: true},
+	"NL": {postcode: regexp.MustCompile(`^\d{4} ?[A-Z]{2}$`)},
+	"US": {region: true, postcode: regexp.MustCompile(`^\d{5}(-\d{4})?$`)},
+}
+
+// Normalize returns the address with its fields trimmed and its country and
+// postcode in upper case.
+func (a Address) Normalize() Address {
+	return Address{
+		Name:     strings.TrimSpace(a.Name),
+		Company:  strings.TrimSpace(a.Company),
+		Line1:    strings.TrimSpace(a.Line1),
+		Line2:    strings.TrimSpace(a.Line2),
+		City:     strings.TrimSpace(a.City),
+		Region:   strings.TrimSpace(a.Region),
+		Postcode: strings.ToUpper(strings.TrimSpace(a.Postcode)),
+		Country:  strings.ToUpper(strings.TrimSpace(a.Country)),
+		Phone:    strings.TrimSpace(a.Phone),
+	}
+}
+
+// Validate checks a normalized address against the format of its country:
+// whether it needs a region and a postcode, and what the postcode looks
+// like.
+func (a Address) Validate() error {
+	format, ok := addressFormats[a.Country]
+	if !ok {
+		return nil
+	}
+	if format.region && a.Region == "" {
+		return fmt.Errorf("%w: region is required for %s addresses", ErrInvalidAddress, a.Country)
+	}
+	if a.Postcode == "" {
+		if format.postcode != nil && !format.postcodeOptional {
+			return fmt.Errorf("%w: postcode is required for %s addresses", ErrInvalidAddress, a.Country)
+		}
+		return nil
+	}
+	if format.postcode != nil && !format.postcode.MatchString(a.Postcode) {
+		return fmt.Errorf("%w: postcode %q is not valid for %s", ErrInvalidAddress, a.Postcode, a.Country)
+	}
+	return nil
+}
+
+// Location returns where the address is, for matching tax and shipping
+// zones.
+func (a Address) Location() Location {
+	return Location{Country: a.Country, Region: a.Region, Postcode: a.Postcode}.Normalize()
+}
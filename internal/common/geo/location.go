@@ -0,0 +1,83 @@
+// Package geo describes where orders are delivered and the areas, such as tax
+// and shipping zones, those places fall into.
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+var ErrInvalidLocation = errors.New("invalid location")
+
+// Location is a place an order is delivered to. Country is an ISO 3166-1
+// alpha-2 code.
+type Location struct {
+	Country  string `json:"country" validate:"omitempty,iso3166_1_alpha2"`
+	Region   string `json:"region,omitempty" validate:"max=100"`
+	Postcode string `json:"postcode,omitempty" validate:"max=50"`
+}
+
+// ParseLocation reads a location written as COUNTRY[/REGION[/POSTCODE]], e.g.
+// "US/CA/94103". An empty string is the zero Location.
+func ParseLocation(s string) (Location, error) {
+	if strings.TrimSpace(s) == "" {
+		return Location{}, nil
+	}
+	parts := strings.SplitN(s, "/", 3)
+	location := Location{Country: parts[0]}
+	if len(parts) > 1 {
+		location.Region = parts[1]
+	}
+	if len(parts) > 2 {
+		location.Postcode = parts[2]
+	}
+	location = location.Normalize()
+	if len(location.Country) != 2 {
+		return Location{}, fmt.Errorf("%w: %q must start with a two-letter country code", ErrInvalidLocation, s)
+	}
+	return location, nil
+}
+
+// Normalize returns the location in upper case, without spaces in the
+// postcode, as locations are compared.
+func (l Location) Normalize() Location {
+	return Location{
+		Country:  strings.ToUpper(strings.TrimSpace(l.Country)),
+		Region:   strings.ToUpper(strings.TrimSpace(l.Region)),
+		Postcode: strings.ToUpper(strings.ReplaceAll(l.Postcode, " ", "")),
+	}
+}
+
+// ValidPattern reports whether area is usable as a zone location: its
+// postcode, if any, must be a valid pattern in which * matches any
+// characters and ? a single one.
+func ValidPattern(area Location) bool {
+	_, err := path.Match(area.Normalize().Postcode, "")
+	return err == nil
+}
+
+// Specificity tells how closely area, a country optionally narrowed to a
+// region and a postcode pattern, covers the normalized location l: 0 when it
+// does not, 1 for the whole country, plus 2 for a region and 4 for a postcode
+// pattern. Zones use it to pick the most specific match.
+func Specificity(area, l Location) int {
+	if area.Country != l.Country {
+		return 0
+	}
+	score := 1
+	if area.Region != "" {
+		if area.Region != l.Region {
+			return 0
+		}
+		score += 2
+	}
+	if area.Postcode != "" {
+		if ok, _ := path.Match(area.Postcode, l.Postcode); !ok || l.Postcode == "" {
+			return 0
+		}
+		score += 4
+	}
+	return score
+}
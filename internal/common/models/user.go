@@ -9,4 +9,8 @@ type Claims struct {
 	Email  string `json:"email"`
 	Role   string `json:"role"`
 	jwt.RegisteredClaims
+
+	// TokenVersion is the user's token version when the token was issued.
+	// Revoking all of a user's tokens moves them on to a later version.
+	TokenVersion uint `json:"ver"`
 }
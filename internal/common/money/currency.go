@@ -0,0 +1,72 @@
+package money
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// exponents holds the number of minor unit digits of the active ISO 4217
+// currencies that do not use two. Every other currency in currencies uses two.
+var exponents = map[string]int{
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0,
+	"KRW": 0, "PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0,
+	"XAF": 0, "XOF": 0, "XPF": 0,
+	"CLF": 4, "UYW": 4,
+}
+
+// currencies lists the active ISO 4217 currency codes.
+var currencies = map[string]bool{}
+
+func init() {
+	codes := "AED AFN ALL AMD ANG AOA ARS AUD AWG AZN BAM BBD BDT BGN BHD BIF BMD BND BOB BOV " +
+		"BRL BSD BTN BWP BYN BZD CAD CDF CHE CHF CHW CLF CLP CNY COP COU CRC CUP CVE CZK " +
+		"DJF DKK DOP DZD EGP ERN ETB EUR FJD FKP GBP GEL GHS GIP GMD GNF GTQ GYD HKD HNL " +
+		"HTG HUF IDR ILS INR IQD IRR ISK JMD JOD JPY KES KGS KHR KMF KPW KRW KWD KYD KZT " +
+		"LAK LBP LKR LRD LSL LYD MAD MDL MGA MKD MMK MNT MOP MRU MUR MVR MWK MXN MXV MYR " +
+		"MZN NAD NGN NIO NOK NPR NZD OMR PAB PEN PGK PHP PKR PLN PYG QAR RON RSD RUB RWF " +
+		"SAR SBD SCR SDG SEK SGD SHP SLE SOS SRD SSP STN SVC SYP SZL THB TJS TMT TND TOP " +
+		"TRY TTD TWD TZS UAH UGX USD USN UYI UYU UYW UZS VED VES VND VUV WST XAF XCD XOF " +
+		"XPF YER ZAR ZMW ZWG"
+	for _, code := range strings.Fields(codes) {
+		currencies[code] = true
+	}
+}
+
+// IsCurrency reports whether code is an active ISO 4217 currency code.
+func IsCurrency(code string) bool {
+	return currencies[code]
+}
+
+// Exponent returns the number of minor unit digits of a currency, e.g. 2 for
+// USD and 0 for JPY.
+func Exponent(currency string) int {
+	if exponent, ok := exponents[currency]; ok {
+		return exponent
+	}
+	return 2
+}
+
+var defaultCurrency atomic.Value
+
+func init() {
+	defaultCurrency.Store("USD")
+}
+
+// SetDefaultCurrency sets the currency of amounts given without one, such as
+// a bare JSON number or a price filter.
+func SetDefaultCurrency(code string) error {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if !IsCurrency(code) {
+		return fmt.Errorf("%w: %q", ErrUnknownCurrency, code)
+	}
+	defaultCurrency.Store(code)
+	return nil
+}
+
+// DefaultCurrency returns the currency set by SetDefaultCurrency, USD unless
+// configured otherwise.
+func DefaultCurrency() string {
+	return defaultCurrency.Load().(string)
+}
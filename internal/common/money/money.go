@@ -0,0 +1,320 @@
+// Package money represents monetary amounts exactly, as a whole number of
+// minor units (e.g. cents) of an ISO 4217 currency.
+package money
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var (
+	ErrUnknownCurrency  = errors.New("unknown currency")
+	ErrInvalidAmount    = errors.New("invalid amount")
+	ErrTooPrecise       = errors.New("amount has more decimal places than the currency allows")
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+)
+
+// Money is an amount in the minor units of Currency: {1999, "USD"} is
+// $19.99. It is stored in two columns, <prefix>amount and <prefix>currency,
+// when embedded in a GORM model, and is written to JSON as
+// {"amount": "19.99", "currency": "USD"}.
+type Money struct {
+	Amount   int64  `gorm:"column:amount"`
+	Currency string `gorm:"column:currency;size:3"`
+}
+
+// New returns amount minor units of currency.
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: strings.ToUpper(currency)}
+}
+
+// Zero returns no money in currency.
+func Zero(currency string) Money {
+	return New(0, currency)
+}
+
+// Parse reads a decimal amount such as "19.99" or "-5" in currency. It fails
+// rather than round when s has more decimal places than the currency has
+// minor unit digits, unless the extra digits are zeros.
+func Parse(s, currency string) (Money, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if !IsCurrency(currency) {
+		return Money{}, fmt.Errorf("%w: %q", ErrUnknownCurrency, currency)
+	}
+
+	digits := strings.TrimSpace(s)
+	negative := false
+	if strings.HasPrefix(digits, "-") || strings.HasPrefix(digits, "+") {
+		negative = digits[0] == '-'
+		digits = digits[1:]
+	}
+	whole, frac, _ := strings.Cut(digits, ".")
+	if whole == "" && frac == "" || !isDigits(whole) || !isDigits(frac) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+
+	exponent := Exponent(currency)
+	if len(frac) > exponent {
+		if strings.Trim(frac[exponent:], "0") != "" {
+			return Money{}, fmt.Errorf("%w: %q in %s", ErrTooPrecise, s, currency)
+		}
+		frac = frac[:exponent]
+	}
+	frac += strings.Repeat("0", exponent-len(frac))
+
+	amount, err := strconv.ParseInt("0"+whole+frac, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	if negative {
+		amount = -amount
+	}
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Decimal formats the amount in major units with the currency's minor unit
+// digits, e.g. "19.99" or "-0.50".
+func (m Money) Decimal() string {
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+	}
+	digits := new(big.Int).Abs(big.NewInt(amount)).String()
+
+	exponent := Exponent(m.Currency)
+	if exponent == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+}
+
+// String formats m as e.g. "19.99 USD".
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+func (m Money) IsZero() bool     { return m.Amount == 0 }
+func (m Money) IsPositive() bool { return m.Amount > 0 }
+func (m Money) IsNegative() bool { return m.Amount < 0 }
+
+// Add returns m + o. Both must be in the same currency.
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, mismatch(m, o)
+	}
+	return Money{Amount: m.Amount + o.Amount, Currency: m.Currency}, nil
+}
+
+// Sub returns m - o. Both must be in the same currency.
+func (m Money) Sub(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, mismatch(m, o)
+	}
+	return Money{Amount: m.Amount - o.Amount, Currency: m.Currency}, nil
+}
+
+// Cmp compares m with o, returning -1, 0 or +1. Both must be in the same
+// currency.
+func (m Money) Cmp(o Money) (int, error) {
+	if m.Currency != o.Currency {
+		return 0, mismatch(m, o)
+	}
+	switch {
+	case m.Amount < o.Amount:
+		return -1, nil
+	case m.Amount > o.Amount:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func mismatch(m, o Money) error {
+	return fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, o.Currency)
+}
+
+// Mul returns m multiplied by a whole quantity.
+func (m Money) Mul(quantity int64) Money {
+	return Money{Amount: m.Amount * quantity, Currency: m.Currency}
+}
+
+// Neg returns -m.
+func (m Money) Neg() Money {
+	return Money{Amount: -m.Amount, Currency: m.Currency}
+}
+
+// Sum adds amounts in currency; it returns zero in currency for none.
+func Sum(currency string, amounts ...Money) (Money, error) {
+	total := Zero(currency)
+	for _, amount := range amounts {
+		var err error
+		if total, err = total.Add(amount); err != nil {
+			return Money{}, err
+		}
+	}
+	return total, nil
+}
+
+// RoundingMode selects how a fractional number of minor units is rounded.
+type RoundingMode int
+
+const (
+	// HalfUp rounds to the nearest minor unit, ties away from zero.
+	HalfUp RoundingMode = iota
+	// HalfEven rounds to the nearest minor unit, ties to the even one.
+	HalfEven
+	// Down truncates towards zero.
+	Down
+	// Up rounds away from zero.
+	Up
+)
+
+// MulRat returns m multiplied by num/den, rounded to a whole minor unit with
+// mode. For example m.MulRat(15, 100, HalfUp) is 15% of m.
+func (m Money) MulRat(num, den int64, mode RoundingMode) Money {
+	if den == 0 {
+		panic("money: division by zero")
+	}
+	n := new(big.Int).Mul(big.NewInt(m.Amount), big.NewInt(num))
+	return Money{Amount: divRound(n, big.NewInt(den), mode).Int64(), Currency: m.Currency}
+}
+
+// divRound returns n/d rounded with mode.
+func divRound(n, d *big.Int, mode RoundingMode) *big.Int {
+	q, r := new(big.Int).QuoRem(n, d, new(big.Int))
+	if r.Sign() == 0 {
+		return q
+	}
+
+	away := false
+	switch mode {
+	case Up:
+		away = true
+	case HalfUp, HalfEven:
+		half := new(big.Int).Abs(r)
+		switch half.Lsh(half, 1).Cmp(new(big.Int).Abs(d)) {
+		case 1:
+			away = true
+		case 0:
+			away = mode == HalfUp || q.Bit(0) == 1
+		}
+	}
+	if away {
+		q.Add(q, big.NewInt(int64(n.Sign()*d.Sign())))
+	}
+	return q
+}
+
+// Allocate splits m into parts proportional to weights without losing or
+// creating a minor unit: the remainder left by rounding each part down is
+// handed out one unit at a time from the first part. Equal weights are used
+// when they sum to zero.
+func (m Money) Allocate(weights []int64) []Money {
+	parts := make([]Money, len(weights))
+	if len(weights) == 0 {
+		return parts
+	}
+
+	var total int64
+	for _, w := range weights {
+		total += w
+	}
+	remainder := m.Amount
+	for i, w := range weights {
+		if total == 0 {
+			parts[i] = m.MulRat(1, int64(len(weights)), Down)
+		} else {
+			parts[i] = m.MulRat(w, total, Down)
+		}
+		remainder -= parts[i].Amount
+	}
+
+	unit := int64(1)
+	if remainder < 0 {
+		unit = -1
+	}
+	for i := 0; remainder != 0; i = (i + 1) % len(parts) {
+		parts[i].Amount += unit
+		remainder -= unit
+	}
+	return parts
+}
+
+type jsonMoney struct {
+	Amount   json.RawMessage `json:"amount"`
+	Currency string          `json:"currency"`
+}
+
+// MarshalJSON writes m as {"amount": "19.99", "currency": "USD"}. The amount
+// is a string so that clients do not read it into a float.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   string `json:"amount"`
+		Currency string `json:"currency"`
+	}{m.Decimal(), m.Currency})
+}
+
+// UnmarshalJSON reads the format written by MarshalJSON, with the amount as
+// either a string or a number. A bare number or string such as 19.99 or
+// "19.99" is read in the default currency.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	value := jsonMoney{Amount: data}
+	if len(data) > 0 && data[0] == '{' {
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+	}
+	if value.Currency == "" {
+		value.Currency = DefaultCurrency()
+	}
+
+	amount := string(value.Amount)
+	if len(value.Amount) > 0 && value.Amount[0] == '"' {
+		if err := json.Unmarshal(value.Amount, &amount); err != nil {
+			return err
+		}
+	}
+	parsed, err := Parse(amount, value.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// RegisterValidation lets validate check Money fields by their amount, so
+// tags such as "gt=0" apply to the number of minor units, and adds a
+// "currency" tag for ISO 4217 codes.
+func RegisterValidation(validate *validator.Validate) error {
+	validate.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+		return field.Interface().(Money).Amount
+	}, Money{})
+	return validate.RegisterValidation("currency", func(fl validator.FieldLevel) bool {
+		return IsCurrency(fl.Field().String())
+	})
+}
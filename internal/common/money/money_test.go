@@ -0,0 +1,196 @@
+package money
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input    string
+		currency string
+		want     Money
+		wantErr  error
+	}{
+		{"19.99", "USD", Money{1999, "USD"}, nil},
+		{"19.9", "USD", Money{1990, "USD"}, nil},
+		{"19", "usd", Money{1900, "USD"}, nil},
+		{" 0.05 ", "USD", Money{5, "USD"}, nil},
+		{".5", "USD", Money{50, "USD"}, nil},
+		{"7.", "USD", Money{700, "USD"}, nil},
+		{"-5", "USD", Money{-500, "USD"}, nil},
+		{"+5.25", "EUR", Money{525, "EUR"}, nil},
+		{"-0.01", "USD", Money{-1, "USD"}, nil},
+		{"19.990", "USD", Money{1999, "USD"}, nil},
+		{"1500", "JPY", Money{1500, "JPY"}, nil},
+		{"1500.00", "JPY", Money{1500, "JPY"}, nil},
+		{"1.234", "BHD", Money{1234, "BHD"}, nil},
+		{"92233720368547758.07", "USD", Money{9223372036854775807, "USD"}, nil},
+
+		{"19.999", "USD", Money{}, ErrTooPrecise},
+		{"1500.5", "JPY", Money{}, ErrTooPrecise},
+		{"1.2345", "BHD", Money{}, ErrTooPrecise},
+		{"", "USD", Money{}, ErrInvalidAmount},
+		{".", "USD", Money{}, ErrInvalidAmount},
+		{"-", "USD", Money{}, ErrInvalidAmount},
+		{"abc", "USD", Money{}, ErrInvalidAmount},
+		{"1.2.3", "USD", Money{}, ErrInvalidAmount},
+		{"1e3", "USD", Money{}, ErrInvalidAmount},
+		{"--1", "USD", Money{}, ErrInvalidAmount},
+		{"92233720368547758.08", "USD", Money{}, ErrInvalidAmount},
+		{"1.00", "XYZ", Money{}, ErrUnknownCurrency},
+		{"1.00", "", Money{}, ErrUnknownCurrency},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.input, tt.currency)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("Parse(%q, %q) error = %v, want %v", tt.input, tt.currency, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q, %q) = %+v, want %+v", tt.input, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{Money{1999, "USD"}, "19.99"},
+		{Money{5, "USD"}, "0.05"},
+		{Money{-50, "USD"}, "-0.50"},
+		{Money{0, "USD"}, "0.00"},
+		{Money{1500, "JPY"}, "1500"},
+		{Money{1234, "BHD"}, "1.234"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.money.Decimal(); got != tt.want {
+			t.Errorf("%+v.Decimal() = %q, want %q", tt.money, got, tt.want)
+		}
+	}
+}
+
+func TestAddSub(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    Money
+		sum     Money
+		diff    Money
+		wantErr error
+	}{
+		{"same currency", Money{1999, "USD"}, Money{1, "USD"}, Money{2000, "USD"}, Money{1998, "USD"}, nil},
+		{"negative result", Money{100, "EUR"}, Money{250, "EUR"}, Money{350, "EUR"}, Money{-150, "EUR"}, nil},
+		{"zero", Money{0, "JPY"}, Money{0, "JPY"}, Money{0, "JPY"}, Money{0, "JPY"}, nil},
+		{"currency mismatch", Money{100, "USD"}, Money{100, "EUR"}, Money{}, Money{}, ErrCurrencyMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sum, err := tt.a.Add(tt.b)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Add() error = %v, want %v", err, tt.wantErr)
+			}
+			if sum != tt.sum {
+				t.Errorf("Add() = %+v, want %+v", sum, tt.sum)
+			}
+
+			diff, err := tt.a.Sub(tt.b)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Sub() error = %v, want %v", err, tt.wantErr)
+			}
+			if diff != tt.diff {
+				t.Errorf("Sub() = %+v, want %+v", diff, tt.diff)
+			}
+		})
+	}
+}
+
+func TestSum(t *testing.T) {
+	got, err := Sum("USD", Money{100, "USD"}, Money{250, "USD"}, Money{-50, "USD"})
+	if err != nil || got != (Money{300, "USD"}) {
+		t.Errorf("Sum() = %+v, %v, want 3.00 USD", got, err)
+	}
+
+	got, err = Sum("EUR")
+	if err != nil || got != (Money{0, "EUR"}) {
+		t.Errorf("Sum() of nothing = %+v, %v, want 0.00 EUR", got, err)
+	}
+
+	if _, err := Sum("USD", Money{100, "EUR"}); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Sum() in another currency error = %v, want %v", err, ErrCurrencyMismatch)
+	}
+}
+
+func TestMulRat(t *testing.T) {
+	tests := []struct {
+		amount   int64
+		num, den int64
+		mode     RoundingMode
+		want     int64
+	}{
+		{1000, 15, 100, HalfUp, 150},
+		{1005, 1, 2, HalfUp, 503},
+		{1005, 1, 2, HalfEven, 502},
+		{1015, 1, 2, HalfEven, 508},
+		{1005, 1, 2, Down, 502},
+		{1005, 1, 2, Up, 503},
+		{-1005, 1, 2, HalfUp, -503},
+		{-1005, 1, 2, Down, -502},
+		{1001, 1, 3, HalfUp, 334},
+		{1001, 1, 3, Up, 334},
+		{1000, 1, 3, Up, 334},
+	}
+
+	for _, tt := range tests {
+		got := Money{tt.amount, "USD"}.MulRat(tt.num, tt.den, tt.mode)
+		if got.Amount != tt.want || got.Currency != "USD" {
+			t.Errorf("MulRat(%d × %d/%d, mode %d) = %+v, want %d USD", tt.amount, tt.num, tt.den, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  int64
+		weights []int64
+		want    []int64
+	}{
+		{"even split", 900, []int64{1, 1, 1}, []int64{300, 300, 300}},
+		{"remainder to the first parts", 1000, []int64{1, 1, 1}, []int64{334, 333, 333}},
+		{"proportional", 1000, []int64{1, 3}, []int64{250, 750}},
+		{"proportional with remainder", 100, []int64{2, 1}, []int64{67, 33}},
+		{"zero weights split evenly", 10, []int64{0, 0, 0}, []int64{4, 3, 3}},
+		{"zero weight gets nothing", 500, []int64{0, 5}, []int64{0, 500}},
+		{"negative amount", -1000, []int64{1, 1, 1}, []int64{-334, -333, -333}},
+		{"single part", 1999, []int64{7}, []int64{1999}},
+		{"no parts", 1000, nil, []int64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts := Money{tt.amount, "USD"}.Allocate(tt.weights)
+
+			got := make([]int64, len(parts))
+			var total int64
+			for i, part := range parts {
+				if part.Currency != "USD" {
+					t.Errorf("part %d currency = %q, want USD", i, part.Currency)
+				}
+				got[i] = part.Amount
+				total += part.Amount
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Allocate(%v) = %v, want %v", tt.weights, got, tt.want)
+			}
+			if len(parts) > 0 && total != tt.amount {
+				t.Errorf("Allocate(%v) parts sum to %d, want %d", tt.weights, total, tt.amount)
+			}
+		})
+	}
+}
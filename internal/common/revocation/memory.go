@@ -0,0 +1,62 @@
+package revocation
+
+import (
+	"sync"
+	"time"
+
+	"github.com/nneji123/ecommerce-golang/internal/common/models"
+)
+
+// MemoryStore keeps revocations in process memory. It is suitable for a
+// single instance or for tests; revocations are lost on restart.
+type MemoryStore struct {
+	mu     sync.RWMutex
+	tokens map[string]time.Time
+	users  map[uint]uint
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		tokens: make(map[string]time.Time),
+		users:  make(map[uint]uint),
+	}
+}
+
+func (s *MemoryStore) RevokeToken(jti string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for id, expiry := range s.tokens {
+		if expiry.Before(now) {
+			delete(s.tokens, id)
+		}
+	}
+	s.tokens[jti] = expiresAt
+	return nil
+}
+
+func (s *MemoryStore) RevokeUserTokens(userID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.users[userID]++
+	return nil
+}
+
+func (s *MemoryStore) TokenVersion(userID uint) (uint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.users[userID], nil
+}
+
+func (s *MemoryStore) IsRevoked(claims *models.Claims) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.tokens[claims.ID]; ok && claims.ID != "" {
+		return true, nil
+	}
+	return claims.TokenVersion < s.users[claims.UserID], nil
+}
@@ -0,0 +1,80 @@
+package revocation
+
+import (
+	"errors"
+	"time"
+
+	"github.com/nneji123/ecommerce-golang/internal/common/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RevokedToken is a single access token revoked before its expiry.
+type RevokedToken struct {
+	JTI       string    `gorm:"primaryKey;size:64"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
+
+// UserTokenVersion is the version of the access tokens issued to a user.
+// Tokens of an earlier version have been revoked.
+type UserTokenVersion struct {
+	UserID  uint `gorm:"primaryKey;autoIncrement:false"`
+	Version uint `gorm:"not null"`
+}
+
+// PostgresStore keeps revocations in the database so they are shared by every
+// instance of the API.
+type PostgresStore struct {
+	db *gorm.DB
+}
+
+func NewPostgresStore(db *gorm.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) RevokeToken(jti string, expiresAt time.Time) error {
+	if err := s.db.Where("expires_at < ?", time.Now()).Delete(&RevokedToken{}).Error; err != nil {
+		return err
+	}
+	return s.db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&RevokedToken{JTI: jti, ExpiresAt: expiresAt}).Error
+}
+
+func (s *PostgresStore) RevokeUserTokens(userID uint) error {
+	return s.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"version": gorm.Expr("user_token_versions.version + 1"),
+		}),
+	}).Create(&UserTokenVersion{UserID: userID, Version: 1}).Error
+}
+
+func (s *PostgresStore) TokenVersion(userID uint) (uint, error) {
+	var version UserTokenVersion
+	err := s.db.Where("user_id = ?", userID).First(&version).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return version.Version, nil
+}
+
+func (s *PostgresStore) IsRevoked(claims *models.Claims) (bool, error) {
+	if claims.ID != "" {
+		var count int64
+		if err := s.db.Model(&RevokedToken{}).Where("jti = ?", claims.ID).Count(&count).Error; err != nil {
+			return false, err
+		}
+		if count > 0 {
+			return true, nil
+		}
+	}
+
+	version, err := s.TokenVersion(claims.UserID)
+	if err != nil {
+		return false, err
+	}
+	return claims.TokenVersion < version, nil
+}
@@ -0,0 +1,20 @@
+package revocation
+
+import (
+	"time"
+
+	"github.com/nneji123/ecommerce-golang/internal/common/models"
+)
+
+// Store tracks access tokens that must be rejected before they expire.
+type Store interface {
+	// RevokeToken revokes a single access token by its jti until expiresAt.
+	RevokeToken(jti string, expiresAt time.Time) error
+	// RevokeUserTokens revokes every access token issued to a user so far by
+	// moving the user on to a new token version.
+	RevokeUserTokens(userID uint) error
+	// TokenVersion returns the version a user's new access tokens carry.
+	TokenVersion(userID uint) (uint, error)
+	// IsRevoked reports whether the token described by claims has been revoked.
+	IsRevoked(claims *models.Claims) (bool, error)
+}
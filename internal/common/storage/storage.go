@@ -0,0 +1,59 @@
+// Package storage saves uploaded files and serves them from a public URL.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nneji123/ecommerce-golang/internal/config"
+)
+
+var ErrInvalidKey = errors.New("invalid storage key")
+
+// Storage is a flat store of public files addressed by slash-separated keys.
+type Storage interface {
+	// Put stores data under key, replacing any existing file.
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Delete removes the file under key. Missing files are not an error.
+	Delete(ctx context.Context, key string) error
+	// URL returns the public URL the file under key is served from.
+	URL(key string) string
+}
+
+// NewStorage returns the storage backend selected by config.StorageDriver.
+func NewStorage(config *config.Config) (Storage, error) {
+	switch config.StorageDriver {
+	case "local":
+		return NewLocalStorage(config.StorageLocalPath, config.StoragePublicURL)
+	case "s3":
+		return NewS3Storage(S3Config{
+			Endpoint:  config.S3Endpoint,
+			Region:    config.S3Region,
+			Bucket:    config.S3Bucket,
+			AccessKey: config.S3AccessKey,
+			SecretKey: config.S3SecretKey,
+			PublicURL: config.StoragePublicURL,
+		})
+	default:
+		return nil, fmt.Errorf("unsupported storage driver: %s", config.StorageDriver)
+	}
+}
+
+// checkKey rejects keys that are empty, absolute or escape the store.
+func checkKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return ErrInvalidKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
+
+func joinURL(base, key string) string {
+	return strings.TrimRight(base, "/") + "/" + key
+}
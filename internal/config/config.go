@@ -1,7 +1,9 @@
 package config
 
 import (
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -19,6 +21,41 @@ type Config struct {
 	SMTPHost         string   `mapstructure:"SMTP_HOST"`
 	AppURL           string   `mapstructure:"APP_URL"`
 	JWTSecret        string   `mapstructure:"JWT_SECRET"`
+
+	EmailProvider    string `mapstructure:"EMAIL_PROVIDER"`
+	SendGridAPIKey   string `mapstructure:"SENDGRID_API_KEY"`
+	SendGridBaseURL  string `mapstructure:"SENDGRID_BASE_URL"`
+	EmailWorkers     int    `mapstructure:"EMAIL_WORKERS"`
+	EmailMaxAttempts int    `mapstructure:"EMAIL_MAX_ATTEMPTS"`
+
+	CursorSecret    string        `mapstructure:"CURSOR_SECRET"`
+	AccessTokenTTL  time.Duration `mapstructure:"ACCESS_TOKEN_TTL"`
+	RefreshTokenTTL time.Duration `mapstructure:"REFRESH_TOKEN_TTL"`
+
+	PaymentWebhookSecret string `mapstructure:"PAYMENT_WEBHOOK_SECRET"`
+	PaymentFakeEnabled   bool   `mapstructure:"PAYMENT_FAKE_ENABLED"`
+
+	ProductFacets       string `mapstructure:"PRODUCT_FACETS"`
+	ProductPriceBuckets string `mapstructure:"PRODUCT_PRICE_BUCKETS"`
+
+	StorageDriver       string `mapstructure:"STORAGE_DRIVER"`
+	StorageLocalPath    string `mapstructure:"STORAGE_LOCAL_PATH"`
+	StoragePublicURL    string `mapstructure:"STORAGE_PUBLIC_URL"`
+	S3Endpoint          string `mapstructure:"S3_ENDPOINT"`
+	S3Region            string `mapstructure:"S3_REGION"`
+	S3Bucket            string `mapstructure:"S3_BUCKET"`
+	S3AccessKey         string `mapstructure:"S3_ACCESS_KEY"`
+	S3SecretKey         string `mapstructure:"S3_SECRET_KEY"`
+	MediaMaxBytes       int64  `mapstructure:"MEDIA_MAX_BYTES"`
+	MediaThumbnailSizes string `mapstructure:"MEDIA_THUMBNAIL_SIZES"`
+
+	ReservationTTL   time.Duration `mapstructure:"RESERVATION_TTL"`
+	ReservationSweep time.Duration `mapstructure:"RESERVATION_SWEEP_INTERVAL"`
+
+	Currency            string `mapstructure:"CURRENCY"`
+	TaxPricesIncludeTax bool   `mapstructure:"TAX_PRICES_INCLUDE_TAX"`
+	TaxRounding         string `mapstructure:"TAX_ROUNDING"`
+	TaxDefaultLocation  string `mapstructure:"TAX_DEFAULT_LOCATION"`
 }
 
 func LoadConfig() (Config, error) {
@@ -38,6 +75,59 @@ func LoadConfig() (Config, error) {
 		return config, err
 	}
 
+	if config.EmailProvider == "" {
+		config.EmailProvider = "smtp"
+	}
+	if config.SendGridBaseURL == "" {
+		config.SendGridBaseURL = "https://api.sendgrid.com"
+	}
+	if config.EmailWorkers <= 0 {
+		config.EmailWorkers = 2
+	}
+	if config.EmailMaxAttempts <= 0 {
+		config.EmailMaxAttempts = 5
+	}
+	if config.AccessTokenTTL <= 0 {
+		config.AccessTokenTTL = 15 * time.Minute
+	}
+	if config.RefreshTokenTTL <= 0 {
+		config.RefreshTokenTTL = 30 * 24 * time.Hour
+	}
+	if config.ProductFacets == "" {
+		config.ProductFacets = "price,availability,category"
+	}
+	if config.ProductPriceBuckets == "" {
+		config.ProductPriceBuckets = "0-25,25-50,50-100,100-250,250-"
+	}
+
+	if config.StorageDriver == "" {
+		config.StorageDriver = "local"
+	}
+	if config.StorageLocalPath == "" {
+		config.StorageLocalPath = "./uploads"
+	}
+	if config.StoragePublicURL == "" && config.StorageDriver == "local" {
+		config.StoragePublicURL = fmt.Sprintf("http://localhost:%s/media", config.ServerPort)
+	}
+	if config.MediaMaxBytes <= 0 {
+		config.MediaMaxBytes = 10 << 20
+	}
+	if config.MediaThumbnailSizes == "" {
+		config.MediaThumbnailSizes = "small=160,medium=480,large=1024"
+	}
+	if config.ReservationTTL <= 0 {
+		config.ReservationTTL = 15 * time.Minute
+	}
+	if config.ReservationSweep <= 0 {
+		config.ReservationSweep = time.Minute
+	}
+	if config.Currency == "" {
+		config.Currency = "USD"
+	}
+	if config.TaxRounding == "" {
+		config.TaxRounding = "line"
+	}
+
 	origins := viper.GetString("CORS_ALLOWED_ORIGINS")
 	if origins != "" {
 		config.AllowedOrigins = strings.Split(origins, ",")
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS payments (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL,
+    provider VARCHAR(50) NOT NULL,
+    provider_ref VARCHAR(255),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    amount DECIMAL NOT NULL,
+    refunded_amount DECIMAL NOT NULL DEFAULT 0,
+    currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    card_last4 VARCHAR(4),
+    next_action_url TEXT,
+    failure_reason TEXT,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_payments_order_id ON payments (order_id);
+CREATE INDEX IF NOT EXISTS idx_payments_provider_ref ON payments (provider_ref);
+
+CREATE TABLE IF NOT EXISTS payment_webhook_events (
+    id BIGSERIAL PRIMARY KEY,
+    provider VARCHAR(50) NOT NULL,
+    event_id VARCHAR(255) NOT NULL,
+    type VARCHAR(100) NOT NULL,
+    provider_ref VARCHAR(255),
+    created_at TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_provider_event ON payment_webhook_events (provider, event_id);
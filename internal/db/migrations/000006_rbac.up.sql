@@ -0,0 +1,63 @@
+CREATE TABLE IF NOT EXISTS roles (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    system BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_roles_name ON roles (name);
+
+CREATE TABLE IF NOT EXISTS permissions (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    created_at TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_permissions_name ON permissions (name);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id BIGINT NOT NULL,
+    permission_id BIGINT NOT NULL,
+    PRIMARY KEY (role_id, permission_id),
+    CONSTRAINT fk_role_permissions_role FOREIGN KEY (role_id) REFERENCES roles (id),
+    CONSTRAINT fk_role_permissions_permission FOREIGN KEY (permission_id) REFERENCES permissions (id)
+);
+
+-- Default permissions and roles. Existing rows are left untouched so edits
+-- made through the API are preserved.
+INSERT INTO permissions (name, description, created_at) VALUES
+    ('*', 'Every permission', now()),
+    ('products:write', 'Create, update and delete products', now()),
+    ('orders:read', 'View any customer''s orders', now()),
+    ('orders:write', 'Change the status of any order', now()),
+    ('payments:read', 'View any payment', now()),
+    ('payments:write', 'Capture, void and refund payments', now()),
+    ('roles:manage', 'Manage roles and assign them to users', now())
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO roles (name, description, system, created_at, updated_at) VALUES
+    ('admin', 'Full access', true, now(), now()),
+    ('user', 'Customer', true, now(), now()),
+    ('catalog-manager', 'Maintains the product catalog', false, now(), now()),
+    ('fulfilment', 'Processes and ships orders', false, now(), now()),
+    ('support', 'Assists customers with their orders', false, now(), now()),
+    ('finance', 'Handles payments and refunds', false, now(), now())
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM (VALUES
+    ('admin', '*'),
+    ('catalog-manager', 'products:write'),
+    ('fulfilment', 'orders:read'),
+    ('fulfilment', 'orders:write'),
+    ('support', 'orders:read'),
+    ('support', 'payments:read'),
+    ('finance', 'orders:read'),
+    ('finance', 'payments:read'),
+    ('finance', 'payments:write')
+) AS defaults (role_name, permission_name)
+JOIN roles r ON r.name = defaults.role_name
+JOIN permissions p ON p.name = defaults.permission_name
+ON CONFLICT DO NOTHING;
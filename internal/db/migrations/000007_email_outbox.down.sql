@@ -0,0 +1,5 @@
+DELETE FROM role_permissions
+WHERE permission_id IN (SELECT id FROM permissions WHERE name = 'emails:manage');
+DELETE FROM permissions WHERE name = 'emails:manage';
+
+DROP TABLE IF EXISTS email_outbox;
@@ -0,0 +1,20 @@
+CREATE TABLE email_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    subject VARCHAR(255) NOT NULL,
+    template VARCHAR(255) NOT NULL,
+    recipient VARCHAR(255) NOT NULL,
+    data JSONB NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts BIGINT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL,
+    locked_at TIMESTAMPTZ,
+    last_error TEXT,
+    sent_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+CREATE INDEX idx_email_outbox_due ON email_outbox (status, next_attempt_at);
+
+INSERT INTO permissions (name, description, created_at) VALUES
+    ('emails:manage', 'View the email outbox and requeue failed emails', now())
+ON CONFLICT (name) DO NOTHING;
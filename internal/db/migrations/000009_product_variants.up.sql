@@ -0,0 +1,56 @@
+CREATE TABLE option_types (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    presentation VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX idx_option_types_name ON option_types (name);
+
+CREATE TABLE option_values (
+    id BIGSERIAL PRIMARY KEY,
+    option_type_id BIGINT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    presentation VARCHAR(255) NOT NULL,
+    position BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    CONSTRAINT fk_option_types_values FOREIGN KEY (option_type_id) REFERENCES option_types (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX idx_option_values_type_name ON option_values (option_type_id, name);
+
+CREATE TABLE product_variants (
+    id BIGSERIAL PRIMARY KEY,
+    product_id BIGINT NOT NULL,
+    sku VARCHAR(100) NOT NULL,
+    barcode VARCHAR(100),
+    price DECIMAL,
+    stock BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    CONSTRAINT fk_products_variants FOREIGN KEY (product_id) REFERENCES products (id)
+);
+CREATE INDEX idx_product_variants_product_id ON product_variants (product_id);
+CREATE INDEX idx_product_variants_deleted_at ON product_variants (deleted_at);
+-- SKUs of deleted variants may be reused.
+CREATE UNIQUE INDEX idx_product_variants_sku ON product_variants (sku) WHERE deleted_at IS NULL;
+
+CREATE TABLE variant_option_values (
+    product_variant_id BIGINT NOT NULL,
+    option_value_id BIGINT NOT NULL,
+    PRIMARY KEY (product_variant_id, option_value_id),
+    CONSTRAINT fk_variant_option_values_variant FOREIGN KEY (product_variant_id) REFERENCES product_variants (id),
+    CONSTRAINT fk_variant_option_values_value FOREIGN KEY (option_value_id) REFERENCES option_values (id)
+);
+CREATE INDEX idx_variant_option_values_value ON variant_option_values (option_value_id);
+
+ALTER TABLE order_items ADD COLUMN variant_id BIGINT;
+ALTER TABLE order_items ADD COLUMN sku VARCHAR(100);
+CREATE INDEX idx_order_items_variant_id ON order_items (variant_id);
+
+-- A cart holds one line per product and variant; lines without a variant
+-- must also be unique, hence NULLS NOT DISTINCT.
+ALTER TABLE cart_items ADD COLUMN variant_id BIGINT;
+DROP INDEX IF EXISTS idx_cart_product;
+CREATE UNIQUE INDEX idx_cart_product ON cart_items (cart_id, product_id, variant_id) NULLS NOT DISTINCT;
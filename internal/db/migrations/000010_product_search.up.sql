@@ -0,0 +1,7 @@
+-- Names rank above descriptions. The vector is maintained by Postgres, so
+-- the application never writes it.
+ALTER TABLE products ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'B')
+) STORED;
+CREATE INDEX idx_products_search_vector ON products USING GIN (search_vector);
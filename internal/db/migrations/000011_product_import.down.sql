@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS product_import_jobs;
+
+DROP INDEX IF EXISTS idx_products_external_id;
+ALTER TABLE products DROP COLUMN IF EXISTS external_id;
@@ -0,0 +1,26 @@
+-- Products can carry the identifier of the spreadsheet or upstream system
+-- they are maintained in, which bulk imports match rows on.
+ALTER TABLE products ADD COLUMN external_id VARCHAR(100);
+CREATE UNIQUE INDEX idx_products_external_id ON products (external_id);
+
+CREATE TABLE product_import_jobs (
+    id BIGSERIAL PRIMARY KEY,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    format VARCHAR(20) NOT NULL,
+    filename VARCHAR(255),
+    payload BYTEA,
+    total_rows BIGINT NOT NULL DEFAULT 0,
+    processed_rows BIGINT NOT NULL DEFAULT 0,
+    created_count BIGINT NOT NULL DEFAULT 0,
+    updated_count BIGINT NOT NULL DEFAULT 0,
+    failed_count BIGINT NOT NULL DEFAULT 0,
+    row_errors JSONB NOT NULL DEFAULT '[]',
+    error TEXT,
+    created_by BIGINT,
+    locked_at TIMESTAMPTZ,
+    started_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+CREATE INDEX idx_product_import_jobs_status ON product_import_jobs (status, created_at);
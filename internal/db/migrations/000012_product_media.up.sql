@@ -0,0 +1,19 @@
+CREATE TABLE product_media (
+    id BIGSERIAL PRIMARY KEY,
+    product_id BIGINT NOT NULL,
+    storage_key VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size BIGINT NOT NULL,
+    width BIGINT NOT NULL,
+    height BIGINT NOT NULL,
+    alt_text VARCHAR(255),
+    position BIGINT NOT NULL DEFAULT 0,
+    is_primary BOOLEAN NOT NULL DEFAULT false,
+    thumbnails JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    CONSTRAINT fk_products_media FOREIGN KEY (product_id) REFERENCES products (id)
+);
+CREATE INDEX idx_product_media_product_id ON product_media (product_id, position);
+-- A product has at most one primary image.
+CREATE UNIQUE INDEX idx_product_media_primary ON product_media (product_id) WHERE is_primary;
@@ -0,0 +1,10 @@
+DELETE FROM role_permissions
+WHERE permission_id IN (SELECT id FROM permissions WHERE name = 'reviews:moderate');
+DELETE FROM permissions WHERE name = 'reviews:moderate';
+
+DROP TABLE IF EXISTS reviews;
+
+DROP INDEX IF EXISTS idx_products_rating_average;
+ALTER TABLE products DROP COLUMN IF EXISTS rating_average;
+ALTER TABLE products DROP COLUMN IF EXISTS review_count;
+ALTER TABLE products DROP COLUMN IF EXISTS rating_sum;
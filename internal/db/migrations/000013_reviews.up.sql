@@ -0,0 +1,41 @@
+-- Ratings are aggregated over approved reviews. The sum and count are
+-- adjusted whenever a review enters or leaves the approved state, and the
+-- average is derived from them by Postgres.
+ALTER TABLE products
+    ADD COLUMN rating_sum BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN review_count BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE products ADD COLUMN rating_average DOUBLE PRECISION GENERATED ALWAYS AS (
+    CASE WHEN review_count > 0 THEN rating_sum::double precision / review_count END
+) STORED;
+CREATE INDEX idx_products_rating_average ON products (rating_average);
+
+CREATE TABLE reviews (
+    id BIGSERIAL PRIMARY KEY,
+    product_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    title VARCHAR(255) NOT NULL,
+    body TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    verified_purchase BOOLEAN NOT NULL DEFAULT false,
+    moderation_note TEXT,
+    moderated_by BIGINT,
+    moderated_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    CONSTRAINT fk_products_reviews FOREIGN KEY (product_id) REFERENCES products (id)
+);
+CREATE UNIQUE INDEX idx_reviews_product_user ON reviews (product_id, user_id);
+CREATE INDEX idx_reviews_product_status ON reviews (product_id, status, created_at);
+CREATE INDEX idx_reviews_status ON reviews (status, created_at);
+
+INSERT INTO permissions (name, description, created_at) VALUES
+    ('reviews:moderate', 'Approve, reject and hide product reviews', now())
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON p.name = 'reviews:moderate'
+WHERE r.name IN ('catalog-manager', 'support')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,3 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS reserved_until;
+
+DROP TABLE IF EXISTS inventory_reservations;
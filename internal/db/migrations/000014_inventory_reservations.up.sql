@@ -0,0 +1,21 @@
+-- Reservations hold stock for pending orders until they expire. Stock is only
+-- decremented when an order is confirmed; until then available-to-sell is
+-- stock minus the active, unexpired reservations.
+CREATE TABLE inventory_reservations (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL,
+    product_id BIGINT NOT NULL,
+    variant_id BIGINT,
+    quantity BIGINT NOT NULL CHECK (quantity > 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'active',
+    expires_at TIMESTAMPTZ NOT NULL,
+    released_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    CONSTRAINT fk_orders_reservations FOREIGN KEY (order_id) REFERENCES orders (id) ON DELETE CASCADE
+);
+CREATE INDEX idx_inventory_reservations_order_id ON inventory_reservations (order_id);
+CREATE INDEX idx_inventory_reservations_active ON inventory_reservations (product_id, variant_id) WHERE status = 'active';
+CREATE INDEX idx_inventory_reservations_expiry ON inventory_reservations (expires_at) WHERE status = 'active';
+
+ALTER TABLE orders ADD COLUMN reserved_until TIMESTAMPTZ;
@@ -0,0 +1,7 @@
+DELETE FROM role_permissions
+WHERE permission_id IN (SELECT id FROM permissions WHERE name = 'inventory:manage');
+DELETE FROM permissions WHERE name = 'inventory:manage';
+
+DROP TABLE IF EXISTS stock_levels;
+DROP TABLE IF EXISTS stock_movements;
+DROP TABLE IF EXISTS warehouses;
@@ -0,0 +1,75 @@
+CREATE TABLE warehouses (
+    id BIGSERIAL PRIMARY KEY,
+    code VARCHAR(50) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    address TEXT,
+    priority BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX idx_warehouses_code ON warehouses (code);
+
+-- The ledger is append-only: every change of stock is a movement, and
+-- stock_levels holds the running sum of the movements per warehouse, updated
+-- in the same transaction. products.stock and product_variants.stock hold the
+-- total over all warehouses.
+CREATE TABLE stock_movements (
+    id BIGSERIAL PRIMARY KEY,
+    type VARCHAR(20) NOT NULL,
+    warehouse_id BIGINT NOT NULL,
+    product_id BIGINT NOT NULL,
+    variant_id BIGINT,
+    quantity BIGINT NOT NULL CHECK (quantity <> 0),
+    order_id BIGINT,
+    counterpart_warehouse_id BIGINT,
+    reason TEXT,
+    actor_id BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ,
+    CONSTRAINT fk_warehouses_movements FOREIGN KEY (warehouse_id) REFERENCES warehouses (id)
+);
+CREATE INDEX idx_stock_movements_item ON stock_movements (product_id, variant_id, created_at);
+CREATE INDEX idx_stock_movements_warehouse ON stock_movements (warehouse_id, created_at);
+CREATE INDEX idx_stock_movements_order ON stock_movements (order_id) WHERE order_id IS NOT NULL;
+
+CREATE TABLE stock_levels (
+    id BIGSERIAL PRIMARY KEY,
+    warehouse_id BIGINT NOT NULL,
+    product_id BIGINT NOT NULL,
+    variant_id BIGINT,
+    quantity BIGINT NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+    updated_at TIMESTAMPTZ,
+    CONSTRAINT fk_warehouses_levels FOREIGN KEY (warehouse_id) REFERENCES warehouses (id)
+);
+CREATE UNIQUE INDEX idx_stock_levels_item ON stock_levels (warehouse_id, product_id, variant_id) NULLS NOT DISTINCT;
+CREATE INDEX idx_stock_levels_product ON stock_levels (product_id, variant_id);
+
+-- Existing stock becomes the opening balance of a main warehouse.
+INSERT INTO warehouses (code, name, priority, created_at, updated_at)
+VALUES ('MAIN', 'Main warehouse', 0, now(), now());
+
+INSERT INTO stock_movements (type, warehouse_id, product_id, variant_id, quantity, reason, created_at)
+SELECT 'receipt', w.id, p.id, NULL, p.stock, 'opening balance', now()
+FROM products p
+JOIN warehouses w ON w.code = 'MAIN'
+WHERE p.stock > 0
+UNION ALL
+SELECT 'receipt', w.id, v.product_id, v.id, v.stock, 'opening balance', now()
+FROM product_variants v
+JOIN warehouses w ON w.code = 'MAIN'
+WHERE v.stock > 0;
+
+INSERT INTO stock_levels (warehouse_id, product_id, variant_id, quantity, updated_at)
+SELECT warehouse_id, product_id, variant_id, SUM(quantity), now()
+FROM stock_movements
+GROUP BY warehouse_id, product_id, variant_id;
+
+INSERT INTO permissions (name, description, created_at) VALUES
+    ('inventory:manage', 'Manage warehouses and receive, transfer and adjust stock', now())
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON p.name = 'inventory:manage'
+WHERE r.name IN ('catalog-manager', 'fulfilment')
+ON CONFLICT DO NOTHING;
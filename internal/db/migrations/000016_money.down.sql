@@ -0,0 +1,30 @@
+ALTER TABLE payments
+    DROP COLUMN refunded_currency,
+    ALTER COLUMN currency SET DEFAULT 'USD',
+    ALTER COLUMN refunded_amount TYPE DECIMAL USING refunded_amount / 100.0,
+    ALTER COLUMN amount TYPE DECIMAL USING amount / 100.0;
+
+ALTER TABLE orders
+    DROP COLUMN total_currency,
+    ALTER COLUMN total_amount TYPE DECIMAL USING total_amount / 100.0;
+
+ALTER TABLE order_items ADD COLUMN price DECIMAL;
+UPDATE order_items SET price = price_amount / 100.0;
+ALTER TABLE order_items
+    ALTER COLUMN price SET NOT NULL,
+    DROP COLUMN price_amount,
+    DROP COLUMN price_currency;
+
+ALTER TABLE product_variants ADD COLUMN price DECIMAL;
+UPDATE product_variants SET price = price_amount / 100.0;
+ALTER TABLE product_variants
+    DROP CONSTRAINT chk_product_variants_price,
+    DROP COLUMN price_amount,
+    DROP COLUMN price_currency;
+
+ALTER TABLE products ADD COLUMN price DECIMAL;
+UPDATE products SET price = price_amount / 100.0;
+ALTER TABLE products
+    ALTER COLUMN price SET NOT NULL,
+    DROP COLUMN price_amount,
+    DROP COLUMN price_currency;
@@ -0,0 +1,42 @@
+-- Amounts are stored as a whole number of minor units (e.g. cents) with an
+-- ISO 4217 currency code. Existing decimal amounts were in US dollars.
+ALTER TABLE products
+    ADD COLUMN price_amount BIGINT,
+    ADD COLUMN price_currency VARCHAR(3) NOT NULL DEFAULT 'USD';
+UPDATE products SET price_amount = ROUND(price * 100);
+ALTER TABLE products
+    ALTER COLUMN price_amount SET NOT NULL,
+    ALTER COLUMN price_currency DROP DEFAULT,
+    DROP COLUMN price;
+
+ALTER TABLE product_variants
+    ADD COLUMN price_amount BIGINT,
+    ADD COLUMN price_currency VARCHAR(3);
+UPDATE product_variants SET price_amount = ROUND(price * 100), price_currency = 'USD'
+WHERE price IS NOT NULL;
+ALTER TABLE product_variants
+    DROP COLUMN price,
+    ADD CONSTRAINT chk_product_variants_price
+        CHECK ((price_amount IS NULL) = (price_currency IS NULL));
+
+ALTER TABLE order_items
+    ADD COLUMN price_amount BIGINT,
+    ADD COLUMN price_currency VARCHAR(3) NOT NULL DEFAULT 'USD';
+UPDATE order_items SET price_amount = ROUND(price * 100);
+ALTER TABLE order_items
+    ALTER COLUMN price_amount SET NOT NULL,
+    ALTER COLUMN price_currency DROP DEFAULT,
+    DROP COLUMN price;
+
+ALTER TABLE orders
+    ALTER COLUMN total_amount TYPE BIGINT USING ROUND(total_amount * 100),
+    ADD COLUMN total_currency VARCHAR(3) NOT NULL DEFAULT 'USD';
+ALTER TABLE orders ALTER COLUMN total_currency DROP DEFAULT;
+
+ALTER TABLE payments
+    ALTER COLUMN amount TYPE BIGINT USING ROUND(amount * 100),
+    ALTER COLUMN refunded_amount TYPE BIGINT USING ROUND(refunded_amount * 100),
+    ALTER COLUMN currency DROP DEFAULT,
+    ADD COLUMN refunded_currency VARCHAR(3);
+UPDATE payments SET refunded_currency = currency;
+ALTER TABLE payments ALTER COLUMN refunded_currency SET NOT NULL;
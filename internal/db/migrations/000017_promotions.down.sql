@@ -0,0 +1,15 @@
+DELETE FROM role_permissions
+WHERE permission_id IN (SELECT id FROM permissions WHERE name = 'promotions:manage');
+DELETE FROM permissions WHERE name = 'promotions:manage';
+
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS discount_total_currency,
+    DROP COLUMN IF EXISTS discount_total_amount,
+    DROP COLUMN IF EXISTS subtotal_currency,
+    DROP COLUMN IF EXISTS subtotal_amount;
+
+DROP TABLE IF EXISTS order_discounts;
+DROP TABLE IF EXISTS promotion_redemptions;
+DROP TABLE IF EXISTS promotion_categories;
+DROP TABLE IF EXISTS promotion_products;
+DROP TABLE IF EXISTS promotions;
@@ -0,0 +1,110 @@
+-- A promotion with a code is a coupon; one without applies automatically.
+-- amount_off and min_subtotal are money columns and are either both NULL or
+-- both set.
+CREATE TABLE promotions (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    code VARCHAR(50),
+    type VARCHAR(20) NOT NULL,
+    percentage BIGINT NOT NULL DEFAULT 0 CHECK (percentage BETWEEN 0 AND 100),
+    amount_off_amount BIGINT,
+    amount_off_currency VARCHAR(3),
+    buy_quantity BIGINT NOT NULL DEFAULT 0,
+    get_quantity BIGINT NOT NULL DEFAULT 0,
+    min_subtotal_amount BIGINT,
+    min_subtotal_currency VARCHAR(3),
+    first_order_only BOOLEAN NOT NULL DEFAULT false,
+    usage_limit BIGINT,
+    per_user_limit BIGINT,
+    usage_count BIGINT NOT NULL DEFAULT 0 CHECK (usage_count >= 0),
+    starts_at TIMESTAMPTZ,
+    ends_at TIMESTAMPTZ,
+    stackable BOOLEAN NOT NULL DEFAULT false,
+    priority BIGINT NOT NULL DEFAULT 0,
+    active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    CONSTRAINT chk_promotions_amount_off
+        CHECK ((amount_off_amount IS NULL) = (amount_off_currency IS NULL)),
+    CONSTRAINT chk_promotions_min_subtotal
+        CHECK ((min_subtotal_amount IS NULL) = (min_subtotal_currency IS NULL))
+);
+CREATE UNIQUE INDEX idx_promotions_code ON promotions (code);
+CREATE INDEX idx_promotions_active ON promotions (active, priority);
+
+CREATE TABLE promotion_products (
+    promotion_id BIGINT NOT NULL,
+    product_id BIGINT NOT NULL,
+    PRIMARY KEY (promotion_id, product_id),
+    CONSTRAINT fk_promotion_products_promotion FOREIGN KEY (promotion_id) REFERENCES promotions (id) ON DELETE CASCADE,
+    CONSTRAINT fk_promotion_products_product FOREIGN KEY (product_id) REFERENCES products (id)
+);
+
+CREATE TABLE promotion_categories (
+    promotion_id BIGINT NOT NULL,
+    category_id BIGINT NOT NULL,
+    PRIMARY KEY (promotion_id, category_id),
+    CONSTRAINT fk_promotion_categories_promotion FOREIGN KEY (promotion_id) REFERENCES promotions (id) ON DELETE CASCADE,
+    CONSTRAINT fk_promotion_categories_category FOREIGN KEY (category_id) REFERENCES categories (id) ON DELETE CASCADE
+);
+
+-- A redemption is one use of a promotion by an order; they are counted for
+-- per-user limits and removed when the order is cancelled.
+CREATE TABLE promotion_redemptions (
+    id BIGSERIAL PRIMARY KEY,
+    promotion_id BIGINT NOT NULL,
+    order_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    amount BIGINT NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    created_at TIMESTAMPTZ,
+    CONSTRAINT fk_promotions_redemptions FOREIGN KEY (promotion_id) REFERENCES promotions (id) ON DELETE CASCADE,
+    CONSTRAINT fk_orders_redemptions FOREIGN KEY (order_id) REFERENCES orders (id)
+);
+CREATE UNIQUE INDEX idx_promotion_redemptions_order ON promotion_redemptions (order_id, promotion_id);
+CREATE INDEX idx_promotion_redemptions_user ON promotion_redemptions (promotion_id, user_id);
+
+-- Discount lines are a snapshot of the promotions applied to an order and
+-- outlive the promotion itself.
+CREATE TABLE order_discounts (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL,
+    promotion_id BIGINT,
+    code VARCHAR(50),
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    amount BIGINT NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    created_at TIMESTAMPTZ,
+    CONSTRAINT fk_orders_discounts FOREIGN KEY (order_id) REFERENCES orders (id),
+    CONSTRAINT fk_promotions_order_discounts FOREIGN KEY (promotion_id) REFERENCES promotions (id) ON DELETE SET NULL
+);
+CREATE INDEX idx_order_discounts_order_id ON order_discounts (order_id);
+
+-- total_amount is now the subtotal of the items less the discounts.
+ALTER TABLE orders
+    ADD COLUMN subtotal_amount BIGINT,
+    ADD COLUMN subtotal_currency VARCHAR(3),
+    ADD COLUMN discount_total_amount BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN discount_total_currency VARCHAR(3);
+UPDATE orders SET
+    subtotal_amount = total_amount,
+    subtotal_currency = total_currency,
+    discount_total_currency = total_currency;
+ALTER TABLE orders
+    ALTER COLUMN subtotal_amount SET NOT NULL,
+    ALTER COLUMN subtotal_currency SET NOT NULL,
+    ALTER COLUMN discount_total_amount DROP DEFAULT,
+    ALTER COLUMN discount_total_currency SET NOT NULL;
+
+INSERT INTO permissions (name, description, created_at) VALUES
+    ('promotions:manage', 'Create, edit and delete promotions and coupon codes', now())
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON p.name = 'promotions:manage'
+WHERE r.name IN ('catalog-manager')
+ON CONFLICT DO NOTHING;
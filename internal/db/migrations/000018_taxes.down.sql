@@ -0,0 +1,23 @@
+DELETE FROM role_permissions
+WHERE permission_id IN (SELECT id FROM permissions WHERE name = 'taxes:manage');
+DELETE FROM permissions WHERE name = 'taxes:manage';
+
+DROP TABLE IF EXISTS order_tax_lines;
+
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS prices_include_tax,
+    DROP COLUMN IF EXISTS tax_total_currency,
+    DROP COLUMN IF EXISTS tax_total_amount;
+
+ALTER TABLE order_items
+    DROP COLUMN IF EXISTS tax_currency,
+    DROP COLUMN IF EXISTS tax_amount,
+    DROP COLUMN IF EXISTS discount_currency,
+    DROP COLUMN IF EXISTS discount_amount;
+
+ALTER TABLE products DROP COLUMN IF EXISTS tax_class_id;
+
+DROP TABLE IF EXISTS tax_rates;
+DROP TABLE IF EXISTS tax_classes;
+DROP TABLE IF EXISTS tax_zone_locations;
+DROP TABLE IF EXISTS tax_zones;
@@ -0,0 +1,107 @@
+CREATE TABLE tax_zones (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    priority BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+
+-- A location is a country, optionally narrowed to a region and to postcodes
+-- matching a pattern. Values are stored upper case.
+CREATE TABLE tax_zone_locations (
+    id BIGSERIAL PRIMARY KEY,
+    zone_id BIGINT NOT NULL,
+    country VARCHAR(2) NOT NULL,
+    region VARCHAR(100) NOT NULL DEFAULT '',
+    postcode VARCHAR(50) NOT NULL DEFAULT '',
+    CONSTRAINT fk_tax_zones_locations FOREIGN KEY (zone_id) REFERENCES tax_zones (id) ON DELETE CASCADE
+);
+CREATE INDEX idx_tax_zone_locations_zone_id ON tax_zone_locations (zone_id);
+CREATE INDEX idx_tax_zone_locations_country ON tax_zone_locations (country, region);
+
+CREATE TABLE tax_classes (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    is_default BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX idx_tax_classes_name ON tax_classes (LOWER(name));
+CREATE UNIQUE INDEX idx_tax_classes_default ON tax_classes (is_default) WHERE is_default;
+
+INSERT INTO tax_classes (name, description, is_default, created_at, updated_at)
+VALUES ('Standard', 'Goods taxed at the standard rate', true, now(), now());
+
+-- Rates are in millionths of the taxed amount: 8.875% is 88750.
+CREATE TABLE tax_rates (
+    id BIGSERIAL PRIMARY KEY,
+    zone_id BIGINT NOT NULL,
+    class_id BIGINT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    rate BIGINT NOT NULL CHECK (rate BETWEEN 0 AND 1000000),
+    effective_from TIMESTAMPTZ NOT NULL,
+    effective_to TIMESTAMPTZ,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    CONSTRAINT fk_tax_zones_rates FOREIGN KEY (zone_id) REFERENCES tax_zones (id),
+    CONSTRAINT fk_tax_classes_rates FOREIGN KEY (class_id) REFERENCES tax_classes (id),
+    CONSTRAINT chk_tax_rates_effective CHECK (effective_to IS NULL OR effective_to > effective_from)
+);
+CREATE INDEX idx_tax_rates_zone_class ON tax_rates (zone_id, class_id, effective_from);
+
+ALTER TABLE products
+    ADD COLUMN tax_class_id BIGINT,
+    ADD CONSTRAINT fk_tax_classes_products FOREIGN KEY (tax_class_id) REFERENCES tax_classes (id) ON DELETE SET NULL;
+CREATE INDEX idx_products_tax_class_id ON products (tax_class_id);
+
+-- Orders placed so far were not taxed.
+ALTER TABLE order_items
+    ADD COLUMN discount_amount BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN discount_currency VARCHAR(3),
+    ADD COLUMN tax_amount BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN tax_currency VARCHAR(3);
+UPDATE order_items SET discount_currency = price_currency, tax_currency = price_currency;
+ALTER TABLE order_items
+    ALTER COLUMN discount_amount DROP DEFAULT,
+    ALTER COLUMN discount_currency SET NOT NULL,
+    ALTER COLUMN tax_amount DROP DEFAULT,
+    ALTER COLUMN tax_currency SET NOT NULL;
+
+-- Discounts of orders placed before they were tracked per item are left on
+-- the order only.
+ALTER TABLE orders
+    ADD COLUMN tax_total_amount BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN tax_total_currency VARCHAR(3),
+    ADD COLUMN prices_include_tax BOOLEAN NOT NULL DEFAULT false;
+UPDATE orders SET tax_total_currency = total_currency;
+ALTER TABLE orders
+    ALTER COLUMN tax_total_amount DROP DEFAULT,
+    ALTER COLUMN tax_total_currency SET NOT NULL;
+
+CREATE TABLE order_tax_lines (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL,
+    tax_rate_id BIGINT,
+    name VARCHAR(100) NOT NULL,
+    rate BIGINT NOT NULL,
+    taxable_amount BIGINT NOT NULL,
+    taxable_currency VARCHAR(3) NOT NULL,
+    amount BIGINT NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    created_at TIMESTAMPTZ,
+    CONSTRAINT fk_orders_tax_lines FOREIGN KEY (order_id) REFERENCES orders (id),
+    CONSTRAINT fk_tax_rates_order_tax_lines FOREIGN KEY (tax_rate_id) REFERENCES tax_rates (id) ON DELETE SET NULL
+);
+CREATE INDEX idx_order_tax_lines_order_id ON order_tax_lines (order_id);
+
+INSERT INTO permissions (name, description, created_at) VALUES
+    ('taxes:manage', 'Manage tax zones, classes and rates', now())
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON p.name = 'taxes:manage'
+WHERE r.name IN ('finance')
+ON CONFLICT DO NOTHING;
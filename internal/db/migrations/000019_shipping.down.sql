@@ -0,0 +1,20 @@
+DELETE FROM role_permissions
+WHERE permission_id IN (SELECT id FROM permissions WHERE name = 'shipping:manage');
+DELETE FROM permissions WHERE name = 'shipping:manage';
+
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS shipping_cost_currency,
+    DROP COLUMN IF EXISTS shipping_cost_amount,
+    DROP COLUMN IF EXISTS shipping_method,
+    DROP COLUMN IF EXISTS shipping_method_id;
+
+ALTER TABLE products
+    DROP COLUMN IF EXISTS height_mm,
+    DROP COLUMN IF EXISTS width_mm,
+    DROP COLUMN IF EXISTS length_mm,
+    DROP COLUMN IF EXISTS weight_grams;
+
+DROP TABLE IF EXISTS shipping_weight_rates;
+DROP TABLE IF EXISTS shipping_methods;
+DROP TABLE IF EXISTS shipping_zone_locations;
+DROP TABLE IF EXISTS shipping_zones;
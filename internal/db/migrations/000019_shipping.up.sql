@@ -0,0 +1,89 @@
+CREATE TABLE shipping_zones (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    priority BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+
+-- Same shape as tax_zone_locations: a country, optionally narrowed to a
+-- region and to postcodes matching a pattern, stored upper case.
+CREATE TABLE shipping_zone_locations (
+    id BIGSERIAL PRIMARY KEY,
+    zone_id BIGINT NOT NULL,
+    country VARCHAR(2) NOT NULL,
+    region VARCHAR(100) NOT NULL DEFAULT '',
+    postcode VARCHAR(50) NOT NULL DEFAULT '',
+    CONSTRAINT fk_shipping_zones_locations FOREIGN KEY (zone_id) REFERENCES shipping_zones (id) ON DELETE CASCADE
+);
+CREATE INDEX idx_shipping_zone_locations_zone_id ON shipping_zone_locations (zone_id);
+CREATE INDEX idx_shipping_zone_locations_country ON shipping_zone_locations (country, region);
+
+-- Methods without a zone are offered at every destination. price and
+-- threshold are money columns and are either both NULL or both set.
+CREATE TABLE shipping_methods (
+    id BIGSERIAL PRIMARY KEY,
+    zone_id BIGINT,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    type VARCHAR(30) NOT NULL,
+    provider VARCHAR(50) NOT NULL DEFAULT 'local',
+    service VARCHAR(100) NOT NULL DEFAULT '',
+    price_amount BIGINT,
+    price_currency VARCHAR(3),
+    threshold_amount BIGINT,
+    threshold_currency VARCHAR(3),
+    max_weight_grams BIGINT CHECK (max_weight_grams > 0),
+    pickup_location TEXT,
+    position BIGINT NOT NULL DEFAULT 0,
+    active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    CONSTRAINT fk_shipping_zones_methods FOREIGN KEY (zone_id) REFERENCES shipping_zones (id),
+    CONSTRAINT chk_shipping_methods_price
+        CHECK ((price_amount IS NULL) = (price_currency IS NULL)),
+    CONSTRAINT chk_shipping_methods_threshold
+        CHECK ((threshold_amount IS NULL) = (threshold_currency IS NULL))
+);
+CREATE INDEX idx_shipping_methods_zone_id ON shipping_methods (zone_id);
+
+-- A weight-based method costs the price of its lightest bracket the parcel
+-- fits in.
+CREATE TABLE shipping_weight_rates (
+    id BIGSERIAL PRIMARY KEY,
+    method_id BIGINT NOT NULL,
+    max_weight_grams BIGINT NOT NULL CHECK (max_weight_grams > 0),
+    price_amount BIGINT NOT NULL CHECK (price_amount >= 0),
+    price_currency VARCHAR(3) NOT NULL,
+    CONSTRAINT fk_shipping_methods_weight_rates FOREIGN KEY (method_id) REFERENCES shipping_methods (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX idx_shipping_weight_rates_method ON shipping_weight_rates (method_id, max_weight_grams);
+
+ALTER TABLE products
+    ADD COLUMN weight_grams BIGINT NOT NULL DEFAULT 0 CHECK (weight_grams >= 0),
+    ADD COLUMN length_mm BIGINT NOT NULL DEFAULT 0 CHECK (length_mm >= 0),
+    ADD COLUMN width_mm BIGINT NOT NULL DEFAULT 0 CHECK (width_mm >= 0),
+    ADD COLUMN height_mm BIGINT NOT NULL DEFAULT 0 CHECK (height_mm >= 0);
+
+-- Orders placed so far were not shipped through a method.
+ALTER TABLE orders
+    ADD COLUMN shipping_method_id BIGINT,
+    ADD COLUMN shipping_method VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN shipping_cost_amount BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN shipping_cost_currency VARCHAR(3),
+    ADD CONSTRAINT fk_shipping_methods_orders FOREIGN KEY (shipping_method_id) REFERENCES shipping_methods (id) ON DELETE SET NULL;
+UPDATE orders SET shipping_cost_currency = total_currency;
+ALTER TABLE orders
+    ALTER COLUMN shipping_cost_amount DROP DEFAULT,
+    ALTER COLUMN shipping_cost_currency SET NOT NULL;
+
+INSERT INTO permissions (name, description, created_at) VALUES
+    ('shipping:manage', 'Manage shipping zones and methods', now())
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON p.name = 'shipping:manage'
+WHERE r.name IN ('fulfilment')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,21 @@
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS billing_address_phone,
+    DROP COLUMN IF EXISTS billing_address_country,
+    DROP COLUMN IF EXISTS billing_address_postcode,
+    DROP COLUMN IF EXISTS billing_address_region,
+    DROP COLUMN IF EXISTS billing_address_city,
+    DROP COLUMN IF EXISTS billing_address_line2,
+    DROP COLUMN IF EXISTS billing_address_line1,
+    DROP COLUMN IF EXISTS billing_address_company,
+    DROP COLUMN IF EXISTS billing_address_name,
+    DROP COLUMN IF EXISTS shipping_address_phone,
+    DROP COLUMN IF EXISTS shipping_address_country,
+    DROP COLUMN IF EXISTS shipping_address_postcode,
+    DROP COLUMN IF EXISTS shipping_address_region,
+    DROP COLUMN IF EXISTS shipping_address_city,
+    DROP COLUMN IF EXISTS shipping_address_line2,
+    DROP COLUMN IF EXISTS shipping_address_line1,
+    DROP COLUMN IF EXISTS shipping_address_company,
+    DROP COLUMN IF EXISTS shipping_address_name;
+
+DROP TABLE IF EXISTS addresses;
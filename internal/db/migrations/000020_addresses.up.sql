@@ -0,0 +1,44 @@
+-- A user has at most one default shipping and one default billing address.
+CREATE TABLE addresses (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    label VARCHAR(100),
+    name VARCHAR(255) NOT NULL,
+    company VARCHAR(255),
+    line1 VARCHAR(255) NOT NULL,
+    line2 VARCHAR(255),
+    city VARCHAR(100) NOT NULL,
+    region VARCHAR(100),
+    postcode VARCHAR(50),
+    country VARCHAR(2) NOT NULL,
+    phone VARCHAR(50),
+    is_default_shipping BOOLEAN NOT NULL DEFAULT false,
+    is_default_billing BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+CREATE INDEX idx_addresses_user_id ON addresses (user_id);
+CREATE UNIQUE INDEX idx_addresses_default_shipping ON addresses (user_id) WHERE is_default_shipping;
+CREATE UNIQUE INDEX idx_addresses_default_billing ON addresses (user_id) WHERE is_default_billing;
+
+-- Copies of the addresses an order was placed with. Orders placed so far
+-- have none.
+ALTER TABLE orders
+    ADD COLUMN shipping_address_name VARCHAR(255),
+    ADD COLUMN shipping_address_company VARCHAR(255),
+    ADD COLUMN shipping_address_line1 VARCHAR(255),
+    ADD COLUMN shipping_address_line2 VARCHAR(255),
+    ADD COLUMN shipping_address_city VARCHAR(100),
+    ADD COLUMN shipping_address_region VARCHAR(100),
+    ADD COLUMN shipping_address_postcode VARCHAR(50),
+    ADD COLUMN shipping_address_country VARCHAR(2),
+    ADD COLUMN shipping_address_phone VARCHAR(50),
+    ADD COLUMN billing_address_name VARCHAR(255),
+    ADD COLUMN billing_address_company VARCHAR(255),
+    ADD COLUMN billing_address_line1 VARCHAR(255),
+    ADD COLUMN billing_address_line2 VARCHAR(255),
+    ADD COLUMN billing_address_city VARCHAR(100),
+    ADD COLUMN billing_address_region VARCHAR(100),
+    ADD COLUMN billing_address_postcode VARCHAR(50),
+    ADD COLUMN billing_address_country VARCHAR(2),
+    ADD COLUMN billing_address_phone VARCHAR(50);
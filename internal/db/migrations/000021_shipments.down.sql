@@ -0,0 +1,6 @@
+-- Without shipments an order is either shipped or not; those with items on
+-- their way can no longer be cancelled.
+UPDATE orders SET status = 'shipped' WHERE status = 'partially_shipped';
+
+DROP TABLE IF EXISTS shipment_items;
+DROP TABLE IF EXISTS shipments;
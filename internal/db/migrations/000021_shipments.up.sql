@@ -0,0 +1,28 @@
+-- Orders ship in one or more shipments, each carrying part or all of some
+-- order lines. Orders shipped so far have none and keep their status.
+CREATE TABLE shipments (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL,
+    carrier VARCHAR(100) NOT NULL,
+    tracking_number VARCHAR(100),
+    tracking_url VARCHAR(500),
+    status VARCHAR(20) NOT NULL DEFAULT 'shipped',
+    actor_id BIGINT NOT NULL,
+    shipped_at TIMESTAMPTZ NOT NULL,
+    delivered_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    CONSTRAINT fk_orders_shipments FOREIGN KEY (order_id) REFERENCES orders (id) ON DELETE CASCADE
+);
+CREATE INDEX idx_shipments_order_id ON shipments (order_id);
+
+CREATE TABLE shipment_items (
+    id BIGSERIAL PRIMARY KEY,
+    shipment_id BIGINT NOT NULL,
+    order_item_id BIGINT NOT NULL,
+    quantity BIGINT NOT NULL CHECK (quantity > 0),
+    CONSTRAINT fk_shipments_items FOREIGN KEY (shipment_id) REFERENCES shipments (id) ON DELETE CASCADE,
+    CONSTRAINT fk_order_items_shipment_items FOREIGN KEY (order_item_id) REFERENCES order_items (id) ON DELETE CASCADE
+);
+CREATE INDEX idx_shipment_items_shipment_id ON shipment_items (shipment_id);
+CREATE UNIQUE INDEX idx_shipment_items_order_item ON shipment_items (shipment_id, order_item_id);
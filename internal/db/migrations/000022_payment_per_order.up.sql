@@ -0,0 +1,4 @@
+-- An order has at most one payment that has not failed, declined or been
+-- voided, so it cannot be charged twice.
+CREATE UNIQUE INDEX idx_payments_active_order ON payments (order_id)
+    WHERE status IN ('pending', 'requires_action', 'authorized', 'captured', 'refunded');
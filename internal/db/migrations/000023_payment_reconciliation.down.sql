@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_payments_reconciliation;
+ALTER TABLE payments DROP COLUMN IF EXISTS reconciliation_reason;
@@ -0,0 +1,4 @@
+-- Payments whose money no longer matches their order, such as a capture for a
+-- cancelled order, are flagged for staff to settle.
+ALTER TABLE payments ADD COLUMN reconciliation_reason TEXT;
+CREATE INDEX idx_payments_reconciliation ON payments (created_at) WHERE reconciliation_reason <> '';
@@ -0,0 +1,9 @@
+-- Users who had revoked their tokens have every token issued so far revoked.
+CREATE TABLE user_revocations (
+    user_id BIGINT PRIMARY KEY,
+    revoked_before TIMESTAMPTZ NOT NULL
+);
+INSERT INTO user_revocations (user_id, revoked_before)
+    SELECT user_id, NOW() FROM user_token_versions;
+
+DROP TABLE user_token_versions;
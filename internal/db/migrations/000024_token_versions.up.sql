@@ -0,0 +1,12 @@
+-- Revoking all of a user's access tokens moves the user on to a new token
+-- version rather than recording a cutoff time, which a token's iat only
+-- gives to the second. Users who had revoked their tokens start at version 1,
+-- which rejects every token issued before this migration.
+CREATE TABLE user_token_versions (
+    user_id BIGINT PRIMARY KEY,
+    version BIGINT NOT NULL
+);
+INSERT INTO user_token_versions (user_id, version)
+    SELECT user_id, 1 FROM user_revocations;
+
+DROP TABLE user_revocations;
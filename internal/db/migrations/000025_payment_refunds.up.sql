@@ -0,0 +1,12 @@
+-- Each refund of a payment is recorded once under the provider's reference
+-- for it, so the provider's webhook for a refund made through the API is not
+-- counted a second time.
+CREATE TABLE IF NOT EXISTS payment_refunds (
+    id BIGSERIAL PRIMARY KEY,
+    payment_id BIGINT NOT NULL REFERENCES payments (id),
+    provider_ref VARCHAR(255) NOT NULL,
+    amount BIGINT NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    created_at TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payment_refund ON payment_refunds (payment_id, provider_ref);
@@ -0,0 +1,509 @@
+package cart
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/nneji123/ecommerce-golang/internal/common/models"
+	"github.com/nneji123/ecommerce-golang/internal/common/money"
+	"github.com/nneji123/ecommerce-golang/internal/domain/inventory"
+	"github.com/nneji123/ecommerce-golang/internal/domain/order"
+	"github.com/nneji123/ecommerce-golang/internal/domain/product"
+	"github.com/nneji123/ecommerce-golang/internal/domain/promotion"
+	"github.com/nneji123/ecommerce-golang/internal/domain/shipping"
+	"github.com/nneji123/ecommerce-golang/internal/domain/user"
+	"go.uber.org/zap"
+)
+
+type Handler struct {
+	repo      *Repository
+	orders    *order.Repository
+	shipping  *shipping.Quoter
+	validator *validator.Validate
+	logger    *zap.Logger
+}
+
+func NewHandler(repo *Repository, orders *order.Repository, quoter *shipping.Quoter, validator *validator.Validate, logger *zap.Logger) *Handler {
+	return &Handler{
+		repo:      repo,
+		orders:    orders,
+		shipping:  quoter,
+		validator: validator,
+		logger:    logger,
+	}
+}
+
+// generateCartToken creates a random token identifying an anonymous cart.
+func generateCartToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// resolveCart finds the cart for the current request: the user's cart when
+// authenticated, otherwise the anonymous cart named by the cart token header.
+// When create is true a missing cart is created.
+func (h *Handler) resolveCart(c echo.Context, create bool) (*Cart, error) {
+	if claims, ok := c.Get("userClaims").(*models.Claims); ok {
+		cart, err := h.repo.FindByUser(claims.UserID)
+		if err != nil || cart != nil || !create {
+			return cart, err
+		}
+		cart = &Cart{UserID: &claims.UserID}
+		if err := h.repo.Create(cart); err != nil {
+			return nil, err
+		}
+		return cart, nil
+	}
+
+	if token := c.Request().Header.Get(CartTokenHeader); token != "" {
+		cart, err := h.repo.FindByToken(token)
+		if err != nil || cart != nil || !create {
+			return cart, err
+		}
+	}
+
+	if !create {
+		return nil, nil
+	}
+
+	token, err := generateCartToken()
+	if err != nil {
+		return nil, err
+	}
+	cart := &Cart{Token: &token}
+	if err := h.repo.Create(cart); err != nil {
+		return nil, err
+	}
+	return cart, nil
+}
+
+// render re-validates every line against the catalog and returns the priced
+// view of the cart.
+func (h *Handler) render(cartID uint, token *string) (*CartResponse, error) {
+	cart, err := h.repo.FindByID(cartID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &CartResponse{ID: cartID, Lines: []CartLine{}, Subtotal: money.Zero(money.DefaultCurrency()), Valid: true}
+	if token != nil {
+		response.Token = *token
+	}
+	if cart == nil {
+		return response, nil
+	}
+
+	productIDs := make([]uint, 0, len(cart.Items))
+	for _, item := range cart.Items {
+		productIDs = append(productIDs, item.ProductID)
+	}
+	products, err := h.repo.Products(productIDs)
+	if err != nil {
+		return nil, err
+	}
+	variants, err := h.repo.Variants(productIDs)
+	if err != nil {
+		return nil, err
+	}
+	reserved, err := h.repo.Reserved(productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	priced := false
+	for _, item := range cart.Items {
+		line := CartLine{ProductID: item.ProductID, VariantID: item.VariantID, Quantity: item.Quantity}
+
+		p, ok := products[item.ProductID]
+		mixed := false
+		var v product.ProductVariant
+		if ok && item.VariantID != nil {
+			v, ok = variants[*item.VariantID]
+		}
+		if ok {
+			line.Name = p.Name
+			line.UnitPrice = p.Price
+			line.Available = p.Stock - reserved[inventory.Key{ProductID: p.ID}]
+			if item.VariantID != nil {
+				line.SKU = v.SKU
+				line.UnitPrice = v.UnitPrice(p.Price)
+				line.Available = v.Stock - reserved[inventory.Key{ProductID: p.ID, VariantID: v.ID}]
+			}
+			line.Available = max(line.Available, 0)
+			line.LineTotal = line.UnitPrice.Mul(int64(item.Quantity))
+			if !priced {
+				response.Subtotal = money.Zero(line.UnitPrice.Currency)
+				priced = true
+			}
+			subtotal, err := response.Subtotal.Add(line.LineTotal)
+			mixed = err != nil
+			if !mixed {
+				response.Subtotal = subtotal
+			}
+		}
+		switch {
+		case !ok:
+			line.Issue = "product is no longer available"
+		case mixed:
+			line.Issue = "priced in a different currency from the rest of the cart"
+		case line.Available < item.Quantity:
+			line.Issue = "insufficient stock"
+		}
+		if line.Issue != "" {
+			response.Valid = false
+		}
+
+		response.Lines = append(response.Lines, line)
+	}
+
+	return response, nil
+}
+
+// @Summary		Get cart
+// @Description	Get the current cart with prices and stock re-validated against the catalog
+// @Tags			cart
+// @Produce		json
+// @Param			X-Cart-Token	header		string	false	"Anonymous cart token"
+// @Success		200				{object}	CartResponse
+// @Router			/cart [get]
+func (h *Handler) Get(c echo.Context) error {
+	cart, err := h.resolveCart(c, false)
+	if err != nil {
+		h.logger.Error("Failed to load cart", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load cart")
+	}
+	if cart == nil {
+		return c.JSON(http.StatusOK, CartResponse{Lines: []CartLine{}, Valid: true})
+	}
+
+	return h.respond(c, http.StatusOK, cart)
+}
+
+// @Summary		Add item to cart
+// @Description	Add a product to the cart, naming the variant for products sold in variants. Anonymous callers receive a cart token in the response.
+// @Tags			cart
+// @Accept			json
+// @Produce		json
+// @Param			X-Cart-Token	header		string			false	"Anonymous cart token"
+// @Param			request			body		AddItemRequest	true	"Product and quantity"
+// @Success		200				{object}	CartResponse
+// @Failure		400				{object}	middleware.ErrorResponse
+// @Failure		404				{object}	middleware.ErrorResponse
+// @Router			/cart/items [post]
+func (h *Handler) AddItem(c echo.Context) error {
+	var req AddItemRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	products, err := h.repo.Products([]uint{req.ProductID})
+	if err != nil {
+		h.logger.Error("Failed to load product", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to add item")
+	}
+	if _, ok := products[req.ProductID]; !ok {
+		return echo.NewHTTPError(http.StatusNotFound, "Product not found")
+	}
+
+	variants, err := h.repo.Variants([]uint{req.ProductID})
+	if err != nil {
+		h.logger.Error("Failed to load variants", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to add item")
+	}
+	if req.VariantID != nil {
+		if _, ok := variants[*req.VariantID]; !ok {
+			return echo.NewHTTPError(http.StatusNotFound, "Variant not found")
+		}
+	} else if len(variants) > 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "A variant must be chosen for this product")
+	}
+
+	cart, err := h.resolveCart(c, true)
+	if err != nil {
+		h.logger.Error("Failed to load cart", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to add item")
+	}
+
+	if err := h.repo.AddItem(cart.ID, req.ProductID, req.VariantID, req.Quantity); err != nil {
+		h.logger.Error("Failed to add cart item", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to add item")
+	}
+
+	return h.respond(c, http.StatusOK, cart)
+}
+
+// @Summary		Update cart item
+// @Description	Set the quantity of a product already in the cart
+// @Tags			cart
+// @Accept			json
+// @Produce		json
+// @Param			X-Cart-Token	header		string				false	"Anonymous cart token"
+// @Param			product_id		path		int					true	"Product ID"
+// @Param			variant_id		query		int					false	"Variant ID"
+// @Param			request			body		UpdateItemRequest	true	"New quantity"
+// @Success		200				{object}	CartResponse
+// @Failure		400				{object}	middleware.ErrorResponse
+// @Failure		404				{object}	middleware.ErrorResponse
+// @Router			/cart/items/{product_id} [put]
+func (h *Handler) UpdateItem(c echo.Context) error {
+	productID, err := strconv.ParseUint(c.Param("product_id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
+	}
+	variantID, err := parseVariantID(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid variant ID")
+	}
+
+	var req UpdateItemRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	cart, err := h.resolveCart(c, false)
+	if err != nil {
+		h.logger.Error("Failed to load cart", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update item")
+	}
+	if cart == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Cart not found")
+	}
+
+	if err := h.repo.SetItemQuantity(cart.ID, uint(productID), variantID, req.Quantity); err != nil {
+		if errors.Is(err, ErrItemNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "Item not in cart")
+		}
+		h.logger.Error("Failed to update cart item", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update item")
+	}
+
+	return h.respond(c, http.StatusOK, cart)
+}
+
+// @Summary		Remove cart item
+// @Description	Remove a product from the cart
+// @Tags			cart
+// @Produce		json
+// @Param			X-Cart-Token	header		string	false	"Anonymous cart token"
+// @Param			product_id		path		int		true	"Product ID"
+// @Param			variant_id		query		int		false	"Variant ID"
+// @Success		200				{object}	CartResponse
+// @Failure		404				{object}	middleware.ErrorResponse
+// @Router			/cart/items/{product_id} [delete]
+func (h *Handler) RemoveItem(c echo.Context) error {
+	productID, err := strconv.ParseUint(c.Param("product_id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
+	}
+	variantID, err := parseVariantID(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid variant ID")
+	}
+
+	cart, err := h.resolveCart(c, false)
+	if err != nil {
+		h.logger.Error("Failed to load cart", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to remove item")
+	}
+	if cart == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Cart not found")
+	}
+
+	if err := h.repo.RemoveItem(cart.ID, uint(productID), variantID); err != nil {
+		if errors.Is(err, ErrItemNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "Item not in cart")
+		}
+		h.logger.Error("Failed to remove cart item", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to remove item")
+	}
+
+	return h.respond(c, http.StatusOK, cart)
+}
+
+// @Summary		Checkout cart
+// @Description	Convert the authenticated user's cart into an order and empty the cart. The coupons entered and any automatic promotions the order is eligible for are applied. The shipping and billing addresses chosen from the address book, or the defaults, are copied onto the order. The chosen shipping method is priced for the destination, or else the shipping address, and added to the total. The order is taxed there, or at the store's default tax location.
+// @Tags			cart
+// @Accept			json
+// @Produce		json
+// @Param			request	body		CheckoutRequest	false	"Coupon codes, addresses, destination and shipping method"
+// @Success		201		{object}	order.Order
+// @Failure		400		{object}	middleware.ErrorResponse
+// @Failure		409		{object}	order.OutOfStockResponse	"Out of stock, or a coupon's usage limit was reached"
+// @Router			/cart/checkout [post]
+func (h *Handler) Checkout(c echo.Context) error {
+	var req CheckoutRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := h.validator.Struct(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	claims := c.Get("userClaims").(*models.Claims)
+
+	cart, err := h.repo.FindByUser(claims.UserID)
+	if err != nil {
+		h.logger.Error("Failed to load cart", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to checkout")
+	}
+	if cart == nil || len(cart.Items) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Cart is empty")
+	}
+
+	items := make([]order.CheckoutItem, 0, len(cart.Items))
+	for _, item := range cart.Items {
+		items = append(items, order.CheckoutItem{ProductID: item.ProductID, VariantID: item.VariantID, Quantity: item.Quantity})
+	}
+
+	placed, err := h.orders.Checkout(c.Request().Context(), claims.UserID, order.CreateOrderRequest{
+		Items:             items,
+		CouponCodes:       req.CouponCodes,
+		ShippingAddressID: req.ShippingAddressID,
+		BillingAddressID:  req.BillingAddressID,
+		Destination:       req.Destination,
+		ShippingMethodID:  req.ShippingMethodID,
+	})
+	if err != nil {
+		var stockErr *order.OutOfStockError
+		switch {
+		case errors.As(err, &stockErr):
+			return c.JSON(http.StatusConflict, order.OutOfStockResponse{
+				Error: "Some items are out of stock",
+				Items: stockErr.Items,
+			})
+		case errors.Is(err, order.ErrProductNotFound), errors.Is(err, order.ErrVariantNotFound):
+			return echo.NewHTTPError(http.StatusBadRequest, "Cart contains products that no longer exist")
+		case errors.Is(err, order.ErrVariantRequired):
+			return echo.NewHTTPError(http.StatusBadRequest, "Cart contains products without a chosen variant")
+		case errors.Is(err, order.ErrMixedCurrencies):
+			return echo.NewHTTPError(http.StatusBadRequest, "Cart contains products priced in different currencies")
+		case errors.Is(err, promotion.ErrCouponInvalid),
+			errors.Is(err, promotion.ErrCouponNotApplicable),
+			errors.Is(err, promotion.ErrCouponNotCombinable):
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		case errors.Is(err, user.ErrAddressNotFound):
+			return echo.NewHTTPError(http.StatusBadRequest, "Address not found")
+		case errors.Is(err, shipping.ErrMethodUnavailable):
+			return echo.NewHTTPError(http.StatusBadRequest, "The shipping method is not available for this order")
+		case errors.Is(err, promotion.ErrUsageLimitReached):
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+		h.logger.Error("Failed to checkout cart", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to checkout")
+	}
+
+	if err := h.repo.Clear(cart.ID); err != nil {
+		h.logger.Error("Failed to clear cart after checkout",
+			zap.Error(err),
+			zap.Uint("cart_id", cart.ID),
+			zap.Uint("order_id", placed.ID))
+	}
+
+	return c.JSON(http.StatusCreated, placed)
+}
+
+// @Summary		Quote shipping for cart
+// @Description	List the shipping methods available for the current cart at a destination, with their prices. Checkout with the chosen method's ID.
+// @Tags			cart
+// @Accept			json
+// @Produce		json
+// @Param			X-Cart-Token	header		string					false	"Anonymous cart token"
+// @Param			request			body		ShippingQuoteRequest	true	"Destination"
+// @Success		200				{array}		shipping.Quote
+// @Failure		400				{object}	middleware.ErrorResponse
+// @Router			/cart/shipping-quote [post]
+func (h *Handler) ShippingQuote(c echo.Context) error {
+	var req ShippingQuoteRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := h.validator.Struct(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	cart, err := h.resolveCart(c, false)
+	if err != nil {
+		h.logger.Error("Failed to load cart", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to quote shipping")
+	}
+	if cart == nil || len(cart.Items) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Cart is empty")
+	}
+
+	response, err := h.render(cart.ID, cart.Token)
+	if err != nil {
+		h.logger.Error("Failed to render cart", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to quote shipping")
+	}
+	productIDs := make([]uint, 0, len(response.Lines))
+	for _, line := range response.Lines {
+		productIDs = append(productIDs, line.ProductID)
+	}
+	products, err := h.repo.Products(productIDs)
+	if err != nil {
+		h.logger.Error("Failed to load cart products", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to quote shipping")
+	}
+
+	parcel := shipping.Parcel{Destination: req.Destination, Subtotal: response.Subtotal}
+	for _, line := range response.Lines {
+		p, ok := products[line.ProductID]
+		if !ok {
+			continue
+		}
+		parcel.Items = append(parcel.Items, shipping.Item{
+			ProductID:   p.ID,
+			Quantity:    line.Quantity,
+			WeightGrams: p.WeightGrams,
+			LengthMM:    p.LengthMM,
+			WidthMM:     p.WidthMM,
+			HeightMM:    p.HeightMM,
+		})
+	}
+
+	quotes, err := h.shipping.Quote(c.Request().Context(), parcel)
+	if err != nil {
+		h.logger.Error("Failed to quote shipping", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to quote shipping")
+	}
+	return c.JSON(http.StatusOK, quotes)
+}
+
+// parseVariantID reads the optional variant_id query parameter.
+func parseVariantID(c echo.Context) (*uint, error) {
+	raw := c.QueryParam("variant_id")
+	if raw == "" {
+		return nil, nil
+	}
+	id, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	variantID := uint(id)
+	return &variantID, nil
+}
+
+func (h *Handler) respond(c echo.Context, status int, cart *Cart) error {
+	response, err := h.render(cart.ID, cart.Token)
+	if err != nil {
+		h.logger.Error("Failed to render cart", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load cart")
+	}
+	return c.JSON(status, response)
+}
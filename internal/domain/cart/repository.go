@@ -0,0 +1,195 @@
+package cart
+
+import (
+	"errors"
+
+	"github.com/nneji123/ecommerce-golang/internal/domain/inventory"
+	"github.com/nneji123/ecommerce-golang/internal/domain/product"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrItemNotFound = errors.New("cart item not found")
+
+// lineScope restricts a query to the line of a cart holding a product, or one
+// variant of it.
+func lineScope(db *gorm.DB, cartID, productID uint, variantID *uint) *gorm.DB {
+	db = db.Where("cart_id = ? AND product_id = ?", cartID, productID)
+	if variantID == nil {
+		return db.Where("variant_id IS NULL")
+	}
+	return db.Where("variant_id = ?", *variantID)
+}
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) Create(cart *Cart) error {
+	return r.db.Create(cart).Error
+}
+
+func (r *Repository) FindByID(id uint) (*Cart, error) {
+	return r.findOne(r.db.Where("id = ?", id))
+}
+
+// FindByUser returns the user's cart, or nil if the user has none yet.
+func (r *Repository) FindByUser(userID uint) (*Cart, error) {
+	return r.findOne(r.db.Where("user_id = ?", userID))
+}
+
+// FindByToken returns the anonymous cart identified by token, or nil.
+func (r *Repository) FindByToken(token string) (*Cart, error) {
+	return r.findOne(r.db.Where("token = ? AND user_id IS NULL", token))
+}
+
+func (r *Repository) findOne(query *gorm.DB) (*Cart, error) {
+	var cart Cart
+	err := query.Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).First(&cart).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &cart, nil
+}
+
+// AddItem adds quantity of a product or variant to the cart, increasing the
+// existing line if it is already present.
+func (r *Repository) AddItem(cartID, productID uint, variantID *uint, quantity int) error {
+	return addQuantity(r.db, &CartItem{CartID: cartID, ProductID: productID, VariantID: variantID, Quantity: quantity})
+}
+
+// addQuantity inserts item, or adds its quantity to the existing line for the
+// same cart, product and variant.
+func addQuantity(db *gorm.DB, item *CartItem) error {
+	return db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "cart_id"}, {Name: "product_id"}, {Name: "variant_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"quantity":   gorm.Expr("cart_items.quantity + excluded.quantity"),
+			"updated_at": gorm.Expr("excluded.updated_at"),
+		}),
+	}).Create(item).Error
+}
+
+// SetItemQuantity replaces the quantity of an existing cart line.
+func (r *Repository) SetItemQuantity(cartID, productID uint, variantID *uint, quantity int) error {
+	result := lineScope(r.db.Model(&CartItem{}), cartID, productID, variantID).
+		Update("quantity", quantity)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrItemNotFound
+	}
+	return nil
+}
+
+func (r *Repository) RemoveItem(cartID, productID uint, variantID *uint) error {
+	result := lineScope(r.db, cartID, productID, variantID).Delete(&CartItem{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrItemNotFound
+	}
+	return nil
+}
+
+// Clear removes every line from the cart.
+func (r *Repository) Clear(cartID uint) error {
+	return r.db.Where("cart_id = ?", cartID).Delete(&CartItem{}).Error
+}
+
+// Products loads the catalog entries referenced by a cart, keyed by ID.
+// Deleted products are absent from the result.
+func (r *Repository) Products(productIDs []uint) (map[uint]product.Product, error) {
+	result := make(map[uint]product.Product, len(productIDs))
+	if len(productIDs) == 0 {
+		return result, nil
+	}
+
+	var products []product.Product
+	if err := r.db.Where("id IN ?", productIDs).Find(&products).Error; err != nil {
+		return nil, err
+	}
+	for _, p := range products {
+		result[p.ID] = p
+	}
+	return result, nil
+}
+
+// Variants loads the live variants of the given products, keyed by variant ID.
+func (r *Repository) Variants(productIDs []uint) (map[uint]product.ProductVariant, error) {
+	result := make(map[uint]product.ProductVariant)
+	if len(productIDs) == 0 {
+		return result, nil
+	}
+
+	var variants []product.ProductVariant
+	if err := r.db.Where("product_id IN ?", productIDs).Find(&variants).Error; err != nil {
+		return nil, err
+	}
+	for _, v := range variants {
+		result[v.ID] = v
+	}
+	return result, nil
+}
+
+// Reserved returns the stock of the given products held by pending orders.
+func (r *Repository) Reserved(productIDs []uint) (map[inventory.Key]int, error) {
+	return inventory.NewRepository(r.db).Reserved(productIDs)
+}
+
+// MergeGuestCart moves the lines of the anonymous cart identified by token into
+// the user's cart, summing quantities of lines present in both, and deletes
+// the anonymous cart. If the user has no cart yet, the anonymous cart is
+// simply claimed.
+func (r *Repository) MergeGuestCart(token string, userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var guest Cart
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("Items").
+			Where("token = ? AND user_id IS NULL", token).
+			First(&guest).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		var owned Cart
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ?", userID).
+			First(&owned).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return tx.Model(&guest).Updates(map[string]interface{}{
+				"user_id": userID,
+				"token":   nil,
+			}).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, item := range guest.Items {
+			merged := CartItem{CartID: owned.ID, ProductID: item.ProductID, VariantID: item.VariantID, Quantity: item.Quantity}
+			if err := addQuantity(tx, &merged); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Where("cart_id = ?", guest.ID).Delete(&CartItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&guest).Error
+	})
+}
@@ -0,0 +1,30 @@
+package cart
+
+import (
+	"log"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nneji123/ecommerce-golang/internal/common/revocation"
+	"github.com/nneji123/ecommerce-golang/internal/config"
+	"github.com/nneji123/ecommerce-golang/internal/middleware"
+)
+
+func RegisterRoutes(e *echo.Echo, h *Handler, revocations revocation.Store) {
+	carts := e.Group("/cart")
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Error loading configuration: %s", err)
+	}
+
+	// Anonymous or authenticated routes
+	carts.Use(middleware.OptionalAuthMiddleware(cfg.JWTSecret, revocations))
+	carts.GET("", h.Get)
+	carts.POST("/items", h.AddItem)
+	carts.PUT("/items/:product_id", h.UpdateItem)
+	carts.DELETE("/items/:product_id", h.RemoveItem)
+	carts.POST("/shipping-quote", h.ShippingQuote)
+
+	// Authenticated routes
+	carts.POST("/checkout", h.Checkout, middleware.AuthMiddleware(cfg.JWTSecret, revocations))
+}
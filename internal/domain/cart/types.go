@@ -0,0 +1,87 @@
+package cart
+
+import (
+	"time"
+
+	"github.com/nneji123/ecommerce-golang/internal/common/geo"
+	"github.com/nneji123/ecommerce-golang/internal/common/money"
+)
+
+// CartTokenHeader carries the token identifying an anonymous cart.
+const CartTokenHeader = "X-Cart-Token"
+
+// Cart belongs either to a user or, for anonymous shoppers, to a cart token.
+type Cart struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    *uint      `gorm:"uniqueIndex" json:"user_id,omitempty"`
+	Token     *string    `gorm:"uniqueIndex;size:64" json:"-"`
+	Items     []CartItem `gorm:"constraint:OnDelete:CASCADE" json:"items"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// CartItem is a quantity of a product, or of one variant of it, in a cart.
+type CartItem struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CartID    uint      `gorm:"not null;uniqueIndex:idx_cart_product" json:"cart_id"`
+	ProductID uint      `gorm:"not null;uniqueIndex:idx_cart_product" json:"product_id"`
+	VariantID *uint     `gorm:"uniqueIndex:idx_cart_product" json:"variant_id,omitempty"`
+	Quantity  int       `gorm:"not null" json:"quantity"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type AddItemRequest struct {
+	ProductID uint  `json:"product_id" validate:"required"`
+	VariantID *uint `json:"variant_id,omitempty"`
+	Quantity  int   `json:"quantity" validate:"required,gt=0"`
+}
+
+type UpdateItemRequest struct {
+	Quantity int `json:"quantity" validate:"required,gt=0"`
+}
+
+// CheckoutRequest lists the coupon codes to apply when the cart is checked
+// out, the addresses from the user's address book to ship and bill to (the
+// defaults when omitted), where the order is shipped and taxed if not at the
+// shipping address, and the shipping method chosen from the cart's shipping
+// quotes. The body is optional.
+type CheckoutRequest struct {
+	CouponCodes       []string      `json:"coupon_codes" validate:"max=5,dive,required,max=50"`
+	ShippingAddressID *uint         `json:"shipping_address_id"`
+	BillingAddressID  *uint         `json:"billing_address_id"`
+	Destination       *geo.Location `json:"destination"`
+	ShippingMethodID  *uint         `json:"shipping_method_id"`
+}
+
+// ShippingQuoteRequest asks for the shipping methods available for the cart
+// at Destination.
+type ShippingQuoteRequest struct {
+	Destination geo.Location `json:"destination"`
+}
+
+// CartLine is a cart item re-validated against the current catalog.
+// Available is the stock left to sell once the reservations of pending
+// orders are taken out.
+type CartLine struct {
+	ProductID uint        `json:"product_id"`
+	VariantID *uint       `json:"variant_id,omitempty"`
+	SKU       string      `json:"sku,omitempty"`
+	Name      string      `json:"name"`
+	UnitPrice money.Money `json:"unit_price"`
+	Quantity  int         `json:"quantity"`
+	LineTotal money.Money `json:"line_total"`
+	Available int         `json:"available"`
+	Issue     string      `json:"issue,omitempty"`
+}
+
+// CartResponse is the priced view of a cart returned by every cart endpoint.
+// Subtotal is in the currency of the first priced line; lines priced in
+// another currency are reported as an issue and left out of it.
+type CartResponse struct {
+	ID       uint        `json:"id"`
+	Token    string      `json:"token,omitempty"`
+	Lines    []CartLine  `json:"lines"`
+	Subtotal money.Money `json:"subtotal"`
+	Valid    bool        `json:"valid"`
+}
@@ -0,0 +1,237 @@
+package category
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type Handler struct {
+	repo      *Repository
+	validator *validator.Validate
+	logger    *zap.Logger
+}
+
+func NewHandler(repo *Repository, validator *validator.Validate, logger *zap.Logger) *Handler {
+	return &Handler{
+		repo:      repo,
+		validator: validator,
+		logger:    logger,
+	}
+}
+
+// @Summary		Category tree
+// @Description	Get every category as a tree with product counts per node
+// @Tags			categories
+// @Produce		json
+// @Success		200	{array}	Node
+// @Router			/categories [get]
+func (h *Handler) Tree(c echo.Context) error {
+	tree, err := h.repo.Tree()
+	if err != nil {
+		h.logger.Error("Failed to load category tree", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load categories")
+	}
+	return c.JSON(http.StatusOK, tree)
+}
+
+// @Summary		Get category
+// @Description	Get category by ID
+// @Tags			categories
+// @Produce		json
+// @Param			id	path		int	true	"Category ID"
+// @Success		200	{object}	Category
+// @Failure		404	{object}	middleware.ErrorResponse
+// @Router			/categories/{id} [get]
+func (h *Handler) Get(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid category ID")
+	}
+
+	category, err := h.repo.GetByID(uint(id))
+	if err != nil {
+		return h.categoryError(err, "Failed to get category")
+	}
+	return c.JSON(http.StatusOK, category)
+}
+
+// @Summary		Create category
+// @Description	Create a category, optionally under a parent (requires products:write)
+// @Tags			categories
+// @Accept			json
+// @Produce		json
+// @Param			category	body		CreateCategoryRequest	true	"Category definition"
+// @Success		201			{object}	Category
+// @Failure		400			{object}	middleware.ErrorResponse
+// @Failure		409			{object}	middleware.ErrorResponse
+// @Router			/categories [post]
+func (h *Handler) Create(c echo.Context) error {
+	var req CreateCategoryRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	category := Category{
+		Name:        req.Name,
+		Slug:        req.Slug,
+		Description: req.Description,
+		ParentID:    req.ParentID,
+	}
+	if err := h.repo.Create(&category); err != nil {
+		return h.categoryError(err, "Failed to create category")
+	}
+
+	return c.JSON(http.StatusCreated, category)
+}
+
+// @Summary		Update category
+// @Description	Update a category's name, slug and description (requires products:write)
+// @Tags			categories
+// @Accept			json
+// @Produce		json
+// @Param			id			path		int						true	"Category ID"
+// @Param			category	body		UpdateCategoryRequest	true	"Category definition"
+// @Success		200			{object}	Category
+// @Failure		400			{object}	middleware.ErrorResponse
+// @Failure		404			{object}	middleware.ErrorResponse
+// @Failure		409			{object}	middleware.ErrorResponse
+// @Router			/categories/{id} [put]
+func (h *Handler) Update(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid category ID")
+	}
+
+	var req UpdateCategoryRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	category, err := h.repo.GetByID(uint(id))
+	if err != nil {
+		return h.categoryError(err, "Failed to update category")
+	}
+
+	category.Name = req.Name
+	category.Slug = req.Slug
+	category.Description = req.Description
+	if err := h.repo.Update(category); err != nil {
+		return h.categoryError(err, "Failed to update category")
+	}
+
+	return c.JSON(http.StatusOK, category)
+}
+
+// @Summary		Move category
+// @Description	Move a category and its subtree under a new parent, or to the root when parent_id is null (requires products:write)
+// @Tags			categories
+// @Accept			json
+// @Produce		json
+// @Param			id		path		int					true	"Category ID"
+// @Param			request	body		MoveCategoryRequest	true	"New parent and position"
+// @Success		200		{object}	Category
+// @Failure		400		{object}	middleware.ErrorResponse
+// @Failure		404		{object}	middleware.ErrorResponse
+// @Failure		409		{object}	middleware.ErrorResponse
+// @Router			/categories/{id}/move [post]
+func (h *Handler) Move(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid category ID")
+	}
+
+	var req MoveCategoryRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	category, err := h.repo.Move(uint(id), req.ParentID, req.Position)
+	if err != nil {
+		return h.categoryError(err, "Failed to move category")
+	}
+
+	return c.JSON(http.StatusOK, category)
+}
+
+// @Summary		Reorder categories
+// @Description	Set the order of the children of a category, or of the root categories when parent_id is null (requires products:write)
+// @Tags			categories
+// @Accept			json
+// @Param			request	body	ReorderCategoriesRequest	true	"Parent and ordered child IDs"
+// @Success		204		"No Content"
+// @Failure		400		{object}	middleware.ErrorResponse
+// @Failure		404		{object}	middleware.ErrorResponse
+// @Router			/categories/reorder [post]
+func (h *Handler) Reorder(c echo.Context) error {
+	var req ReorderCategoriesRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.repo.Reorder(req.ParentID, req.CategoryIDs); err != nil {
+		return h.categoryError(err, "Failed to reorder categories")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// @Summary		Delete category
+// @Description	Delete a category without child categories; its products are unassigned (requires products:write)
+// @Tags			categories
+// @Param			id	path	int	true	"Category ID"
+// @Success		204	"No Content"
+// @Failure		404	{object}	middleware.ErrorResponse
+// @Failure		409	{object}	middleware.ErrorResponse
+// @Router			/categories/{id} [delete]
+func (h *Handler) Delete(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid category ID")
+	}
+
+	if err := h.repo.Delete(uint(id)); err != nil {
+		return h.categoryError(err, "Failed to delete category")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Handler) categoryError(err error, message string) error {
+	switch {
+	case errors.Is(err, ErrCategoryNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Category not found")
+	case errors.Is(err, ErrParentNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Parent category not found")
+	case errors.Is(err, ErrSlugConflict):
+		return echo.NewHTTPError(http.StatusConflict, "Category slug already exists")
+	case errors.Is(err, ErrCycle):
+		return echo.NewHTTPError(http.StatusConflict, "Category cannot be moved into its own subtree")
+	case errors.Is(err, ErrHasChildren):
+		return echo.NewHTTPError(http.StatusConflict, "Category has child categories")
+	case errors.Is(err, ErrInvalidOrder):
+		return echo.NewHTTPError(http.StatusBadRequest, "Category IDs must list every child exactly once")
+	}
+	h.logger.Error(message, zap.Error(err))
+	return echo.NewHTTPError(http.StatusInternalServerError, message)
+}
@@ -0,0 +1,325 @@
+package category
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrCategoryNotFound = errors.New("category not found")
+	ErrParentNotFound   = errors.New("parent category not found")
+	ErrSlugConflict     = errors.New("category slug already exists")
+	ErrCycle            = errors.New("category cannot be moved into its own subtree")
+	ErrHasChildren      = errors.New("category has child categories")
+	ErrInvalidOrder     = errors.New("category ids must list every child exactly once")
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) GetByID(id uint) (*Category, error) {
+	return findCategory(r.db, id)
+}
+
+// Tree returns every category arranged as a forest of root nodes, ordered by
+// position, with direct and subtree product counts.
+func (r *Repository) Tree() ([]*Node, error) {
+	var categories []Category
+	if err := r.db.Order("depth, position, id").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+
+	type count struct {
+		CategoryID uint
+		Total      int64
+	}
+
+	var direct []count
+	err := r.db.Table("product_categories pc").
+		Select("pc.category_id, COUNT(*) AS total").
+		Joins("JOIN products p ON p.id = pc.product_id AND p.deleted_at IS NULL").
+		Group("pc.category_id").
+		Scan(&direct).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Products assigned to several categories of one subtree are counted
+	// once for that subtree.
+	var subtree []count
+	err = r.db.Table("categories c").
+		Select("c.id AS category_id, COUNT(DISTINCT pc.product_id) AS total").
+		Joins("JOIN categories d ON d.path LIKE c.path || '%'").
+		Joins("JOIN product_categories pc ON pc.category_id = d.id").
+		Joins("JOIN products p ON p.id = pc.product_id AND p.deleted_at IS NULL").
+		Group("c.id").
+		Scan(&subtree).Error
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[uint]*Node, len(categories))
+	roots := []*Node{}
+	for _, category := range categories {
+		node := &Node{Category: category, Children: []*Node{}}
+		nodes[category.ID] = node
+		if category.ParentID == nil {
+			roots = append(roots, node)
+		} else if parent, ok := nodes[*category.ParentID]; ok {
+			parent.Children = append(parent.Children, node)
+		}
+	}
+	for _, c := range direct {
+		if node, ok := nodes[c.CategoryID]; ok {
+			node.ProductCount = c.Total
+		}
+	}
+	for _, c := range subtree {
+		if node, ok := nodes[c.CategoryID]; ok {
+			node.TotalProductCount = c.Total
+		}
+	}
+
+	return roots, nil
+}
+
+// Create inserts a category as the last child of its parent.
+func (r *Repository) Create(category *Category) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkSlug(tx, category.Slug, 0); err != nil {
+			return err
+		}
+
+		parentPath := ""
+		category.Depth = 0
+		if category.ParentID != nil {
+			parent, err := lockCategory(tx, *category.ParentID)
+			if errors.Is(err, ErrCategoryNotFound) {
+				return ErrParentNotFound
+			}
+			if err != nil {
+				return err
+			}
+			parentPath = parent.Path
+			category.Depth = parent.Depth + 1
+		}
+
+		var siblings int64
+		if err := children(tx, category.ParentID).Count(&siblings).Error; err != nil {
+			return err
+		}
+		category.Position = int(siblings)
+
+		if err := tx.Create(category).Error; err != nil {
+			return err
+		}
+		category.Path = parentPath + strconv.FormatUint(uint64(category.ID), 10) + "/"
+		return tx.Model(category).Update("path", category.Path).Error
+	})
+}
+
+// Update changes a category's name, slug and description.
+func (r *Repository) Update(category *Category) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkSlug(tx, category.Slug, category.ID); err != nil {
+			return err
+		}
+		return tx.Model(category).Updates(map[string]interface{}{
+			"name":        category.Name,
+			"slug":        category.Slug,
+			"description": category.Description,
+		}).Error
+	})
+}
+
+// Move re-parents a category together with its whole subtree and places it
+// at position among its new siblings, or last when position is nil.
+func (r *Repository) Move(id uint, parentID *uint, position *int) (*Category, error) {
+	var category *Category
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		category, err = lockCategory(tx, id)
+		if err != nil {
+			return err
+		}
+
+		parentPath := ""
+		depth := 0
+		if parentID != nil {
+			parent, err := lockCategory(tx, *parentID)
+			if errors.Is(err, ErrCategoryNotFound) {
+				return ErrParentNotFound
+			}
+			if err != nil {
+				return err
+			}
+			if strings.HasPrefix(parent.Path, category.Path) {
+				return ErrCycle
+			}
+			parentPath = parent.Path
+			depth = parent.Depth + 1
+		}
+
+		// Close the gap left among the old siblings.
+		err = children(tx, category.ParentID).
+			Where("position > ?", category.Position).
+			Update("position", gorm.Expr("position - 1")).Error
+		if err != nil {
+			return err
+		}
+
+		var siblings int64
+		if err := children(tx, parentID).Where("id <> ?", category.ID).Count(&siblings).Error; err != nil {
+			return err
+		}
+		target := int(siblings)
+		if position != nil && *position < target {
+			target = *position
+		}
+		err = children(tx, parentID).
+			Where("id <> ? AND position >= ?", category.ID, target).
+			Update("position", gorm.Expr("position + 1")).Error
+		if err != nil {
+			return err
+		}
+
+		oldPath := category.Path
+		newPath := parentPath + strconv.FormatUint(uint64(category.ID), 10) + "/"
+		err = tx.Model(&Category{}).
+			Where("path LIKE ?", oldPath+"%").
+			Updates(map[string]interface{}{
+				"path":  gorm.Expr("? || substr(path, ?)", newPath, len(oldPath)+1),
+				"depth": gorm.Expr("depth + ?", depth-category.Depth),
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		category.ParentID = parentID
+		category.Position = target
+		category.Path = newPath
+		category.Depth = depth
+		return tx.Model(category).Updates(map[string]interface{}{
+			"parent_id": parentID,
+			"position":  target,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// Reorder sets the positions of the children of parentID to match ids, which
+// must list every child exactly once.
+func (r *Repository) Reorder(parentID *uint, ids []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if parentID != nil {
+			if _, err := lockCategory(tx, *parentID); err != nil {
+				if errors.Is(err, ErrCategoryNotFound) {
+					return ErrParentNotFound
+				}
+				return err
+			}
+		}
+
+		var existing []uint
+		if err := children(tx, parentID).Pluck("id", &existing).Error; err != nil {
+			return err
+		}
+		if len(existing) != len(ids) {
+			return ErrInvalidOrder
+		}
+		remaining := make(map[uint]bool, len(existing))
+		for _, id := range existing {
+			remaining[id] = true
+		}
+		for _, id := range ids {
+			if !remaining[id] {
+				return ErrInvalidOrder
+			}
+			delete(remaining, id)
+		}
+
+		for position, id := range ids {
+			if err := tx.Model(&Category{}).Where("id = ?", id).Update("position", position).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Delete removes a category that has no children. Product assignments are
+// removed with it.
+func (r *Repository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		category, err := lockCategory(tx, id)
+		if err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&Category{}).Where("parent_id = ?", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrHasChildren
+		}
+
+		if err := tx.Exec("DELETE FROM product_categories WHERE category_id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(category).Error; err != nil {
+			return err
+		}
+		return children(tx, category.ParentID).
+			Where("position > ?", category.Position).
+			Update("position", gorm.Expr("position - 1")).Error
+	})
+}
+
+func findCategory(tx *gorm.DB, id uint) (*Category, error) {
+	var category Category
+	if err := tx.First(&category, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCategoryNotFound
+		}
+		return nil, err
+	}
+	return &category, nil
+}
+
+func lockCategory(tx *gorm.DB, id uint) (*Category, error) {
+	return findCategory(tx.Clauses(clause.Locking{Strength: "UPDATE"}), id)
+}
+
+// children scopes a query to the direct children of parentID, or to the root
+// categories when parentID is nil.
+func children(tx *gorm.DB, parentID *uint) *gorm.DB {
+	query := tx.Model(&Category{})
+	if parentID == nil {
+		return query.Where("parent_id IS NULL")
+	}
+	return query.Where("parent_id = ?", *parentID)
+}
+
+func checkSlug(tx *gorm.DB, slug string, excludeID uint) error {
+	var count int64
+	if err := tx.Model(&Category{}).Where("slug = ? AND id <> ?", slug, excludeID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrSlugConflict
+	}
+	return nil
+}
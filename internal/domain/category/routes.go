@@ -0,0 +1,34 @@
+package category
+
+import (
+	"log"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nneji123/ecommerce-golang/internal/common/revocation"
+	"github.com/nneji123/ecommerce-golang/internal/config"
+	"github.com/nneji123/ecommerce-golang/internal/domain/rbac"
+	"github.com/nneji123/ecommerce-golang/internal/middleware"
+)
+
+func RegisterRoutes(e *echo.Echo, h *Handler, revocations revocation.Store, permissions middleware.PermissionResolver) {
+	categories := e.Group("/categories")
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Error loading configuration: %s", err)
+	}
+
+	categories.Use(middleware.AuthMiddleware(cfg.JWTSecret, revocations))
+
+	// Public routes (authenticated users)
+	categories.GET("", h.Tree)
+	categories.GET("/:id", h.Get)
+
+	// Catalog management routes
+	catalog := categories.Group("", middleware.RequirePermission(permissions, rbac.PermissionProductsWrite))
+	catalog.POST("", h.Create)
+	catalog.POST("/reorder", h.Reorder)
+	catalog.PUT("/:id", h.Update)
+	catalog.POST("/:id/move", h.Move)
+	catalog.DELETE("/:id", h.Delete)
+}
@@ -0,0 +1,58 @@
+package category
+
+import (
+	"time"
+)
+
+// Category is a node in the catalog tree. Path is a materialized path of the
+// IDs from the root down to and including the category itself, e.g. "1/4/9/",
+// so a subtree can be selected with a single prefix match.
+type Category struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	ParentID    *uint     `gorm:"index" json:"parent_id"`
+	Name        string    `gorm:"size:255;not null" json:"name"`
+	Slug        string    `gorm:"size:255;not null;uniqueIndex" json:"slug"`
+	Description string    `gorm:"type:text" json:"description"`
+	Path        string    `gorm:"size:1024;not null;index" json:"path"`
+	Depth       int       `gorm:"not null;default:0" json:"depth"`
+	Position    int       `gorm:"not null;default:0" json:"position"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Node is a category with its children, as returned by the tree endpoint.
+// ProductCount counts products assigned directly to the category and
+// TotalProductCount also includes products in every descendant.
+type Node struct {
+	Category
+	ProductCount      int64   `json:"product_count"`
+	TotalProductCount int64   `json:"total_product_count"`
+	Children          []*Node `json:"children"`
+}
+
+type CreateCategoryRequest struct {
+	Name        string `json:"name" validate:"required,max=255"`
+	Slug        string `json:"slug" validate:"required,max=255"`
+	Description string `json:"description"`
+	ParentID    *uint  `json:"parent_id"`
+}
+
+type UpdateCategoryRequest struct {
+	Name        string `json:"name" validate:"required,max=255"`
+	Slug        string `json:"slug" validate:"required,max=255"`
+	Description string `json:"description"`
+}
+
+// MoveCategoryRequest re-parents a category. A nil ParentID moves it to the
+// root; a nil Position appends it after its new siblings.
+type MoveCategoryRequest struct {
+	ParentID *uint `json:"parent_id"`
+	Position *int  `json:"position" validate:"omitempty,gte=0"`
+}
+
+// ReorderCategoriesRequest sets the order of every child of ParentID (or of
+// the root categories when ParentID is nil).
+type ReorderCategoriesRequest struct {
+	ParentID    *uint  `json:"parent_id"`
+	CategoryIDs []uint `json:"category_ids" validate:"required,min=1"`
+}
@@ -0,0 +1,280 @@
+package inventory
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/nneji123/ecommerce-golang/internal/common/models"
+	"github.com/nneji123/ecommerce-golang/internal/middleware"
+	"go.uber.org/zap"
+)
+
+type Handler struct {
+	repo      *Repository
+	validator *validator.Validate
+	logger    *zap.Logger
+}
+
+func NewHandler(repo *Repository, validator *validator.Validate, logger *zap.Logger) *Handler {
+	return &Handler{
+		repo:      repo,
+		validator: validator,
+		logger:    logger,
+	}
+}
+
+// @Summary		List warehouses
+// @Description	List every warehouse in fulfilment order (requires inventory:manage)
+// @Tags			inventory
+// @Produce		json
+// @Success		200	{array}	Warehouse
+// @Router			/inventory/warehouses [get]
+func (h *Handler) ListWarehouses(c echo.Context) error {
+	warehouses, err := h.repo.ListWarehouses()
+	if err != nil {
+		h.logger.Error("Failed to list warehouses", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list warehouses")
+	}
+	return c.JSON(http.StatusOK, warehouses)
+}
+
+// @Summary		Create warehouse
+// @Description	Create a warehouse (requires inventory:manage). Orders are fulfilled from warehouses in ascending priority.
+// @Tags			inventory
+// @Accept			json
+// @Produce		json
+// @Param			request	body		WarehouseRequest	true	"Warehouse"
+// @Success		201		{object}	Warehouse
+// @Failure		400		{object}	middleware.ErrorResponse
+// @Failure		409		{object}	middleware.ErrorResponse
+// @Router			/inventory/warehouses [post]
+func (h *Handler) CreateWarehouse(c echo.Context) error {
+	var req WarehouseRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := h.validator.Struct(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	warehouse := Warehouse{
+		Code:     req.Code,
+		Name:     req.Name,
+		Address:  req.Address,
+		Priority: req.Priority,
+	}
+	if err := h.repo.CreateWarehouse(&warehouse); err != nil {
+		return h.inventoryError(err, "Failed to create warehouse")
+	}
+
+	return c.JSON(http.StatusCreated, warehouse)
+}
+
+// @Summary		Update warehouse
+// @Description	Update a warehouse's code, name, address and fulfilment priority (requires inventory:manage)
+// @Tags			inventory
+// @Accept			json
+// @Produce		json
+// @Param			id		path		int					true	"Warehouse ID"
+// @Param			request	body		WarehouseRequest	true	"Warehouse"
+// @Success		200		{object}	Warehouse
+// @Failure		400		{object}	middleware.ErrorResponse
+// @Failure		404		{object}	middleware.ErrorResponse
+// @Failure		409		{object}	middleware.ErrorResponse
+// @Router			/inventory/warehouses/{id} [put]
+func (h *Handler) UpdateWarehouse(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid warehouse ID")
+	}
+
+	var req WarehouseRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := h.validator.Struct(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	warehouse, err := h.repo.GetWarehouse(uint(id))
+	if err != nil {
+		return h.inventoryError(err, "Failed to update warehouse")
+	}
+	warehouse.Code = req.Code
+	warehouse.Name = req.Name
+	warehouse.Address = req.Address
+	warehouse.Priority = req.Priority
+	if err := h.repo.UpdateWarehouse(warehouse); err != nil {
+		return h.inventoryError(err, "Failed to update warehouse")
+	}
+
+	return c.JSON(http.StatusOK, warehouse)
+}
+
+// @Summary		List stock levels
+// @Description	List the stock held in each warehouse, optionally for one warehouse, product or variant (requires inventory:manage)
+// @Tags			inventory
+// @Produce		json
+// @Param			warehouse_id	query	int	false	"Warehouse ID"
+// @Param			product_id		query	int	false	"Product ID"
+// @Param			variant_id		query	int	false	"Variant ID"
+// @Success		200				{array}	StockLevel
+// @Router			/inventory/levels [get]
+func (h *Handler) Levels(c echo.Context) error {
+	var query LevelQuery
+	if err := c.Bind(&query); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	levels, err := h.repo.Levels(query)
+	if err != nil {
+		h.logger.Error("Failed to list stock levels", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list stock levels")
+	}
+	return c.JSON(http.StatusOK, levels)
+}
+
+// @Summary		List stock movements
+// @Description	Get a paginated list of the stock ledger, newest first (requires inventory:manage)
+// @Tags			inventory
+// @Produce		json
+// @Param			warehouse_id	query		int		false	"Warehouse ID"
+// @Param			product_id		query		int		false	"Product ID"
+// @Param			variant_id		query		int		false	"Variant ID"
+// @Param			order_id		query		int		false	"Order ID"
+// @Param			type			query		string	false	"Movement type (receipt, sale, return, adjustment, transfer)"
+// @Param			page			query		int		false	"Page number"
+// @Param			limit			query		int		false	"Items per page"
+// @Success		200				{object}	middleware.PaginatedResponse
+// @Router			/inventory/movements [get]
+func (h *Handler) Movements(c echo.Context) error {
+	var query MovementQuery
+	if err := c.Bind(&query); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	query.Normalize(20, 100, "DESC")
+	switch query.Type {
+	case "", MovementReceipt, MovementSale, MovementReturn, MovementAdjustment, MovementTransfer:
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid movement type")
+	}
+
+	movements, page, err := h.repo.Movements(query)
+	if err != nil {
+		h.logger.Error("Failed to list stock movements", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list stock movements")
+	}
+
+	middleware.SetLinkHeader(c, page)
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"movements":  movements,
+		"pagination": page,
+	})
+}
+
+// @Summary		Receive stock
+// @Description	Record stock arriving at a warehouse, or at the first warehouse in fulfilment order if none is given (requires inventory:manage)
+// @Tags			inventory
+// @Accept			json
+// @Produce		json
+// @Param			request	body		ReceiveRequest	true	"Receipt"
+// @Success		201		{object}	Movement
+// @Failure		400		{object}	middleware.ErrorResponse
+// @Failure		404		{object}	middleware.ErrorResponse
+// @Router			/inventory/receipts [post]
+func (h *Handler) Receive(c echo.Context) error {
+	var req ReceiveRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := h.validator.Struct(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	claims := c.Get("userClaims").(*models.Claims)
+	movement, err := h.repo.Receive(req, claims.UserID)
+	if err != nil {
+		return h.inventoryError(err, "Failed to receive stock")
+	}
+
+	return c.JSON(http.StatusCreated, movement)
+}
+
+// @Summary		Transfer stock
+// @Description	Move stock from one warehouse to another (requires inventory:manage)
+// @Tags			inventory
+// @Accept			json
+// @Produce		json
+// @Param			request	body		TransferRequest	true	"Transfer"
+// @Success		201		{array}		Movement
+// @Failure		400		{object}	middleware.ErrorResponse
+// @Failure		404		{object}	middleware.ErrorResponse
+// @Failure		409		{object}	middleware.ErrorResponse
+// @Router			/inventory/transfers [post]
+func (h *Handler) Transfer(c echo.Context) error {
+	var req TransferRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := h.validator.Struct(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	claims := c.Get("userClaims").(*models.Claims)
+	movements, err := h.repo.Transfer(req, claims.UserID)
+	if err != nil {
+		return h.inventoryError(err, "Failed to transfer stock")
+	}
+
+	return c.JSON(http.StatusCreated, movements)
+}
+
+// @Summary		Adjust stock
+// @Description	Correct the stock of a warehouse by a signed quantity with a reason, e.g. after a stock count (requires inventory:manage)
+// @Tags			inventory
+// @Accept			json
+// @Produce		json
+// @Param			request	body		AdjustRequest	true	"Adjustment"
+// @Success		201		{object}	Movement
+// @Failure		400		{object}	middleware.ErrorResponse
+// @Failure		404		{object}	middleware.ErrorResponse
+// @Failure		409		{object}	middleware.ErrorResponse
+// @Router			/inventory/adjustments [post]
+func (h *Handler) Adjust(c echo.Context) error {
+	var req AdjustRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := h.validator.Struct(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	claims := c.Get("userClaims").(*models.Claims)
+	movement, err := h.repo.Adjust(req, claims.UserID)
+	if err != nil {
+		return h.inventoryError(err, "Failed to adjust stock")
+	}
+
+	return c.JSON(http.StatusCreated, movement)
+}
+
+// inventoryError maps repository errors to HTTP responses.
+func (h *Handler) inventoryError(err error, message string) error {
+	switch {
+	case errors.Is(err, ErrWarehouseNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Warehouse not found")
+	case errors.Is(err, ErrProductNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Product not found")
+	case errors.Is(err, ErrVariantNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Variant not found")
+	case errors.Is(err, ErrVariantRequired), errors.Is(err, ErrNoWarehouse):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrWarehouseConflict), errors.Is(err, ErrInsufficientStock):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	h.logger.Error(message, zap.Error(err))
+	return echo.NewHTTPError(http.StatusInternalServerError, message)
+}
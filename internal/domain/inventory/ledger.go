@@ -0,0 +1,494 @@
+package inventory
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/nneji123/ecommerce-golang/internal/middleware"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrWarehouseNotFound = errors.New("warehouse not found")
+	ErrWarehouseConflict = errors.New("warehouse code already exists")
+	ErrNoWarehouse       = errors.New("no warehouse has been set up")
+	ErrProductNotFound   = errors.New("product not found")
+	ErrVariantNotFound   = errors.New("variant not found")
+	ErrVariantRequired   = errors.New("a variant must be chosen for products sold in variants")
+	ErrInsufficientStock = errors.New("not enough stock in the warehouse")
+)
+
+// itemScope restricts a query to the rows of a product, or of one variant of
+// it.
+func itemScope(productID uint, variantID *uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Where("product_id = ?", productID)
+		if variantID == nil {
+			return db.Where("variant_id IS NULL")
+		}
+		return db.Where("variant_id = ?", *variantID)
+	}
+}
+
+// ListWarehouses returns every warehouse in fulfilment order.
+func (r *Repository) ListWarehouses() ([]Warehouse, error) {
+	warehouses := []Warehouse{}
+	err := r.db.Order("priority, id").Find(&warehouses).Error
+	return warehouses, err
+}
+
+func (r *Repository) GetWarehouse(id uint) (*Warehouse, error) {
+	var warehouse Warehouse
+	if err := r.db.First(&warehouse, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWarehouseNotFound
+		}
+		return nil, err
+	}
+	return &warehouse, nil
+}
+
+func (r *Repository) CreateWarehouse(warehouse *Warehouse) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkCode(tx, warehouse.Code, 0); err != nil {
+			return err
+		}
+		return tx.Create(warehouse).Error
+	})
+}
+
+// UpdateWarehouse saves a warehouse's code, name, address and priority.
+func (r *Repository) UpdateWarehouse(warehouse *Warehouse) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkCode(tx, warehouse.Code, warehouse.ID); err != nil {
+			return err
+		}
+		return tx.Save(warehouse).Error
+	})
+}
+
+// checkCode returns ErrWarehouseConflict if another warehouse uses code.
+func checkCode(tx *gorm.DB, code string, exceptID uint) error {
+	var count int64
+	if err := tx.Model(&Warehouse{}).Where("code = ? AND id <> ?", code, exceptID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrWarehouseConflict
+	}
+	return nil
+}
+
+// defaultWarehouse returns the warehouse that comes first in fulfilment
+// order.
+func defaultWarehouse(tx *gorm.DB) (*Warehouse, error) {
+	var warehouse Warehouse
+	if err := tx.Order("priority, id").First(&warehouse).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoWarehouse
+		}
+		return nil, err
+	}
+	return &warehouse, nil
+}
+
+// Levels returns the stock levels matching query, grouped by product and
+// variant, warehouses in fulfilment order.
+func (r *Repository) Levels(query LevelQuery) ([]StockLevel, error) {
+	levels := []StockLevel{}
+	db := r.db.Model(&StockLevel{}).
+		Joins("Warehouse").
+		Order("stock_levels.product_id, stock_levels.variant_id NULLS FIRST, \"Warehouse\".priority, \"Warehouse\".id")
+	if query.WarehouseID > 0 {
+		db = db.Where("stock_levels.warehouse_id = ?", query.WarehouseID)
+	}
+	if query.ProductID > 0 {
+		db = db.Where("stock_levels.product_id = ?", query.ProductID)
+	}
+	if query.VariantID != nil {
+		db = db.Where("stock_levels.variant_id = ?", *query.VariantID)
+	}
+	err := db.Find(&levels).Error
+	return levels, err
+}
+
+// Movements returns a page of the ledger matching query, newest first.
+func (r *Repository) Movements(query MovementQuery) ([]Movement, middleware.PaginatedResponse, error) {
+	movements := []Movement{}
+	db := r.db.Model(&Movement{})
+	if query.WarehouseID > 0 {
+		db = db.Where("warehouse_id = ?", query.WarehouseID)
+	}
+	if query.ProductID > 0 {
+		db = db.Where("product_id = ?", query.ProductID)
+	}
+	if query.VariantID != nil {
+		db = db.Where("variant_id = ?", *query.VariantID)
+	}
+	if query.OrderID > 0 {
+		db = db.Where("order_id = ?", query.OrderID)
+	}
+	if query.Type != "" {
+		db = db.Where("type = ?", query.Type)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, middleware.PaginatedResponse{}, err
+	}
+
+	err := db.Order("created_at DESC, id DESC").
+		Offset((query.Page - 1) * query.Limit).
+		Limit(query.Limit).
+		Find(&movements).Error
+	if err != nil {
+		return nil, middleware.PaginatedResponse{}, err
+	}
+	return movements, middleware.OffsetPage(query.Page, query.Limit, total), nil
+}
+
+// Receive records stock arriving at a warehouse, or at the default warehouse
+// when the request names none.
+func (r *Repository) Receive(req ReceiveRequest, actorID uint) (*Movement, error) {
+	movement := Movement{
+		Type:      MovementReceipt,
+		ProductID: req.ProductID,
+		VariantID: req.VariantID,
+		Quantity:  req.Quantity,
+		Reason:    req.Reason,
+		ActorID:   actorID,
+	}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if req.WarehouseID == 0 {
+			warehouse, err := defaultWarehouse(tx)
+			if err != nil {
+				return err
+			}
+			movement.WarehouseID = warehouse.ID
+		} else {
+			if err := findWarehouse(tx, req.WarehouseID); err != nil {
+				return err
+			}
+			movement.WarehouseID = req.WarehouseID
+		}
+		if err := lockItem(tx, req.ProductID, req.VariantID); err != nil {
+			return err
+		}
+		return record(tx, &movement)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &movement, nil
+}
+
+// Transfer moves stock from one warehouse to another. The total stock of
+// the product or variant is unchanged.
+func (r *Repository) Transfer(req TransferRequest, actorID uint) ([]Movement, error) {
+	from, to := req.FromWarehouseID, req.ToWarehouseID
+	movements := []Movement{
+		{
+			Type:                   MovementTransfer,
+			WarehouseID:            from,
+			ProductID:              req.ProductID,
+			VariantID:              req.VariantID,
+			Quantity:               -req.Quantity,
+			CounterpartWarehouseID: &to,
+			Reason:                 req.Reason,
+			ActorID:                actorID,
+		},
+		{
+			Type:                   MovementTransfer,
+			WarehouseID:            to,
+			ProductID:              req.ProductID,
+			VariantID:              req.VariantID,
+			Quantity:               req.Quantity,
+			CounterpartWarehouseID: &from,
+			Reason:                 req.Reason,
+			ActorID:                actorID,
+		},
+	}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for _, id := range []uint{from, to} {
+			if err := findWarehouse(tx, id); err != nil {
+				return err
+			}
+		}
+		if err := lockItem(tx, req.ProductID, req.VariantID); err != nil {
+			return err
+		}
+		for i := range movements {
+			if err := record(tx, &movements[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return movements, nil
+}
+
+// Adjust corrects the stock of a warehouse by a signed quantity.
+func (r *Repository) Adjust(req AdjustRequest, actorID uint) (*Movement, error) {
+	movement := Movement{
+		Type:        MovementAdjustment,
+		WarehouseID: req.WarehouseID,
+		ProductID:   req.ProductID,
+		VariantID:   req.VariantID,
+		Quantity:    req.Quantity,
+		Reason:      req.Reason,
+		ActorID:     actorID,
+	}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := findWarehouse(tx, req.WarehouseID); err != nil {
+			return err
+		}
+		if err := lockItem(tx, req.ProductID, req.VariantID); err != nil {
+			return err
+		}
+		return record(tx, &movement)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &movement, nil
+}
+
+// Pick records the sale of an order's lines, taking stock from warehouses in
+// fulfilment order. The caller must hold locks on the products and variants
+// involved.
+func (r *Repository) Pick(orderID uint, lines []Line, actorID uint) error {
+	for _, line := range lines {
+		var stock []warehouseStock
+		if err := r.db.Model(&StockLevel{}).
+			Select("stock_levels.warehouse_id, warehouses.priority, stock_levels.quantity").
+			Joins("JOIN warehouses ON warehouses.id = stock_levels.warehouse_id").
+			Scopes(itemScope(line.ProductID, line.VariantID)).
+			Where("stock_levels.quantity > 0").
+			Find(&stock).Error; err != nil {
+			return err
+		}
+
+		picks, err := allocate(stock, line.Quantity)
+		if err != nil {
+			return err
+		}
+		for _, pick := range picks {
+			if err := record(r.db, &Movement{
+				Type:        MovementSale,
+				WarehouseID: pick.WarehouseID,
+				ProductID:   line.ProductID,
+				VariantID:   line.VariantID,
+				Quantity:    -pick.Quantity,
+				OrderID:     &orderID,
+				ActorID:     actorID,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// warehouseStock is the stock of an item held in a warehouse, with the
+// warehouse's fulfilment priority.
+type warehouseStock struct {
+	WarehouseID uint
+	Priority    int
+	Quantity    int
+}
+
+// allocate takes quantity from stock in fulfilment order, by ascending
+// priority and then warehouse ID, and returns what is taken from each
+// warehouse in that order.
+func allocate(stock []warehouseStock, quantity int) ([]warehouseStock, error) {
+	ordered := make([]warehouseStock, len(stock))
+	copy(ordered, stock)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].Priority != ordered[j].Priority {
+			return ordered[i].Priority < ordered[j].Priority
+		}
+		return ordered[i].WarehouseID < ordered[j].WarehouseID
+	})
+
+	var picks []warehouseStock
+	remaining := quantity
+	for _, level := range ordered {
+		if remaining == 0 {
+			break
+		}
+		if level.Quantity <= 0 {
+			continue
+		}
+		take := min(level.Quantity, remaining)
+		picks = append(picks, warehouseStock{WarehouseID: level.WarehouseID, Priority: level.Priority, Quantity: take})
+		remaining -= take
+	}
+	if remaining > 0 {
+		return nil, ErrInsufficientStock
+	}
+	return picks, nil
+}
+
+// Return puts the stock sold to an order back into the warehouses it was
+// picked from. Orders sold before the ledger existed have no sales in it;
+// their lines are returned to the default warehouse. The caller must hold
+// locks on the products and variants involved.
+func (r *Repository) Return(orderID uint, lines []Line, actorID uint) error {
+	var sales []Movement
+	if err := r.db.Where("order_id = ? AND type = ?", orderID, MovementSale).
+		Order("id").
+		Find(&sales).Error; err != nil {
+		return err
+	}
+
+	var fallback uint
+	if len(sales) == 0 {
+		warehouse, err := defaultWarehouse(r.db)
+		if err != nil {
+			return err
+		}
+		fallback = warehouse.ID
+	}
+
+	returns := returnsFor(orderID, sales, lines, fallback, actorID)
+	for i := range returns {
+		if err := record(r.db, &returns[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// returnsFor builds the movements returning an order's stock: each sale is
+// reversed into the warehouse it was picked from or, when the order has no
+// sales, its lines go into the fallback warehouse.
+func returnsFor(orderID uint, sales []Movement, lines []Line, fallback, actorID uint) []Movement {
+	returns := make([]Movement, 0, max(len(sales), len(lines)))
+	for _, sale := range sales {
+		returns = append(returns, Movement{
+			WarehouseID: sale.WarehouseID,
+			ProductID:   sale.ProductID,
+			VariantID:   sale.VariantID,
+			Quantity:    -sale.Quantity,
+		})
+	}
+	if len(sales) == 0 {
+		for _, line := range lines {
+			returns = append(returns, Movement{
+				WarehouseID: fallback,
+				ProductID:   line.ProductID,
+				VariantID:   line.VariantID,
+				Quantity:    line.Quantity,
+			})
+		}
+	}
+
+	for i := range returns {
+		returns[i].Type = MovementReturn
+		returns[i].OrderID = &orderID
+		returns[i].ActorID = actorID
+	}
+	return returns
+}
+
+func findWarehouse(tx *gorm.DB, id uint) error {
+	var count int64
+	if err := tx.Model(&Warehouse{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrWarehouseNotFound
+	}
+	return nil
+}
+
+// lockItem locks the product, and the variant if given, that a movement
+// concerns, in the same order as checkout: product first, then variant.
+func lockItem(tx *gorm.DB, productID uint, variantID *uint) error {
+	var ids []uint
+	if err := tx.Table("products").
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND deleted_at IS NULL", productID).
+		Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return ErrProductNotFound
+	}
+
+	if variantID == nil {
+		var count int64
+		if err := tx.Table("product_variants").
+			Where("product_id = ? AND deleted_at IS NULL", productID).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrVariantRequired
+		}
+		return nil
+	}
+
+	if err := tx.Table("product_variants").
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND product_id = ? AND deleted_at IS NULL", *variantID, productID).
+		Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return ErrVariantNotFound
+	}
+	return nil
+}
+
+// record appends a movement to the ledger and applies it to the warehouse's
+// stock level and to the total stock of the product or variant. The caller
+// must hold locks on the product and variant.
+func record(tx *gorm.DB, movement *Movement) error {
+	now := time.Now()
+	if movement.Quantity < 0 {
+		// Stock leaving a warehouse must be there, so the level exists.
+		result := tx.Model(&StockLevel{}).
+			Where("warehouse_id = ? AND quantity >= ?", movement.WarehouseID, -movement.Quantity).
+			Scopes(itemScope(movement.ProductID, movement.VariantID)).
+			UpdateColumns(map[string]interface{}{
+				"quantity":   gorm.Expr("quantity + ?", movement.Quantity),
+				"updated_at": now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInsufficientStock
+		}
+	} else if err := tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "warehouse_id"}, {Name: "product_id"}, {Name: "variant_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"quantity":   gorm.Expr("stock_levels.quantity + excluded.quantity"),
+			"updated_at": now,
+		}),
+	}).Create(&StockLevel{
+		WarehouseID: movement.WarehouseID,
+		ProductID:   movement.ProductID,
+		VariantID:   movement.VariantID,
+		Quantity:    movement.Quantity,
+		UpdatedAt:   now,
+	}).Error; err != nil {
+		return err
+	}
+
+	total := tx.Table("products").Where("id = ?", movement.ProductID)
+	if movement.VariantID != nil {
+		total = tx.Table("product_variants").Where("id = ?", *movement.VariantID)
+	}
+	if err := total.UpdateColumn("stock", gorm.Expr("stock + ?", movement.Quantity)).Error; err != nil {
+		return err
+	}
+
+	return tx.Create(movement).Error
+}
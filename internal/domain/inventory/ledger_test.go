@@ -0,0 +1,171 @@
+package inventory
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name     string
+		stock    []warehouseStock
+		quantity int
+		want     []warehouseStock
+		wantErr  error
+	}{
+		{
+			name:     "single warehouse",
+			stock:    []warehouseStock{{WarehouseID: 1, Quantity: 10}},
+			quantity: 4,
+			want:     []warehouseStock{{WarehouseID: 1, Quantity: 4}},
+		},
+		{
+			name: "lowest priority first",
+			stock: []warehouseStock{
+				{WarehouseID: 1, Priority: 5, Quantity: 10},
+				{WarehouseID: 2, Priority: 1, Quantity: 10},
+			},
+			quantity: 3,
+			want:     []warehouseStock{{WarehouseID: 2, Priority: 1, Quantity: 3}},
+		},
+		{
+			name: "spills over in priority order",
+			stock: []warehouseStock{
+				{WarehouseID: 3, Priority: 2, Quantity: 5},
+				{WarehouseID: 1, Priority: 0, Quantity: 2},
+				{WarehouseID: 2, Priority: 1, Quantity: 4},
+			},
+			quantity: 8,
+			want: []warehouseStock{
+				{WarehouseID: 1, Priority: 0, Quantity: 2},
+				{WarehouseID: 2, Priority: 1, Quantity: 4},
+				{WarehouseID: 3, Priority: 2, Quantity: 2},
+			},
+		},
+		{
+			name: "equal priority by warehouse ID",
+			stock: []warehouseStock{
+				{WarehouseID: 9, Priority: 1, Quantity: 5},
+				{WarehouseID: 4, Priority: 1, Quantity: 5},
+			},
+			quantity: 7,
+			want: []warehouseStock{
+				{WarehouseID: 4, Priority: 1, Quantity: 5},
+				{WarehouseID: 9, Priority: 1, Quantity: 2},
+			},
+		},
+		{
+			name: "skips empty warehouses",
+			stock: []warehouseStock{
+				{WarehouseID: 1, Priority: 0, Quantity: 0},
+				{WarehouseID: 2, Priority: 1, Quantity: 3},
+			},
+			quantity: 3,
+			want:     []warehouseStock{{WarehouseID: 2, Priority: 1, Quantity: 3}},
+		},
+		{
+			name: "exactly all stock",
+			stock: []warehouseStock{
+				{WarehouseID: 1, Quantity: 2},
+				{WarehouseID: 2, Quantity: 3},
+			},
+			quantity: 5,
+			want: []warehouseStock{
+				{WarehouseID: 1, Quantity: 2},
+				{WarehouseID: 2, Quantity: 3},
+			},
+		},
+		{
+			name: "not enough stock",
+			stock: []warehouseStock{
+				{WarehouseID: 1, Quantity: 2},
+				{WarehouseID: 2, Quantity: 3},
+			},
+			quantity: 6,
+			wantErr:  ErrInsufficientStock,
+		},
+		{
+			name:     "no stock",
+			quantity: 1,
+			wantErr:  ErrInsufficientStock,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := allocate(tt.stock, tt.quantity)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("allocate() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("allocate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReturnsFor(t *testing.T) {
+	variant := uint(11)
+
+	tests := []struct {
+		name  string
+		sales []Movement
+		lines []Line
+		want  []Movement
+	}{
+		{
+			name: "reverses each sale into its warehouse",
+			sales: []Movement{
+				{Type: MovementSale, WarehouseID: 1, ProductID: 5, Quantity: -2},
+				{Type: MovementSale, WarehouseID: 2, ProductID: 5, Quantity: -1},
+				{Type: MovementSale, WarehouseID: 2, ProductID: 6, VariantID: &variant, Quantity: -3},
+			},
+			lines: []Line{{ProductID: 5, Quantity: 3}, {ProductID: 6, VariantID: &variant, Quantity: 3}},
+			want: []Movement{
+				{WarehouseID: 1, ProductID: 5, Quantity: 2},
+				{WarehouseID: 2, ProductID: 5, Quantity: 1},
+				{WarehouseID: 2, ProductID: 6, VariantID: &variant, Quantity: 3},
+			},
+		},
+		{
+			name:  "orders without sales go to the fallback warehouse",
+			lines: []Line{{ProductID: 5, Quantity: 3}, {ProductID: 6, VariantID: &variant, Quantity: 1}},
+			want: []Movement{
+				{WarehouseID: 99, ProductID: 5, Quantity: 3},
+				{WarehouseID: 99, ProductID: 6, VariantID: &variant, Quantity: 1},
+			},
+		},
+		{
+			name: "nothing to return",
+			want: []Movement{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := returnsFor(42, tt.sales, tt.lines, 99, 7)
+			if len(got) != len(tt.want) {
+				t.Fatalf("returnsFor() returned %d movements, want %d", len(got), len(tt.want))
+			}
+			for i, movement := range got {
+				want := tt.want[i]
+				if movement.Type != MovementReturn {
+					t.Errorf("movement %d type = %s, want %s", i, movement.Type, MovementReturn)
+				}
+				if movement.OrderID == nil || *movement.OrderID != 42 {
+					t.Errorf("movement %d order = %v, want 42", i, movement.OrderID)
+				}
+				if movement.ActorID != 7 {
+					t.Errorf("movement %d actor = %d, want 7", i, movement.ActorID)
+				}
+				if movement.WarehouseID != want.WarehouseID || movement.ProductID != want.ProductID ||
+					movement.VariantID != want.VariantID || movement.Quantity != want.Quantity {
+					t.Errorf("movement %d = warehouse %d product %d variant %v quantity %d, want warehouse %d product %d variant %v quantity %d",
+						i, movement.WarehouseID, movement.ProductID, movement.VariantID, movement.Quantity,
+						want.WarehouseID, want.ProductID, want.VariantID, want.Quantity)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package inventory
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// WithTx returns a repository that runs its queries in tx.
+func (r *Repository) WithTx(tx *gorm.DB) *Repository {
+	return &Repository{db: tx}
+}
+
+// Reserve stores active reservations for an order, holding their stock until
+// expiresAt. Callers check availability with Reserved in the same
+// transaction while holding locks on the products and variants involved.
+func (r *Repository) Reserve(orderID uint, reservations []Reservation, expiresAt time.Time) error {
+	if len(reservations) == 0 {
+		return nil
+	}
+	for i := range reservations {
+		reservations[i].OrderID = orderID
+		reservations[i].Status = ReservationActive
+		reservations[i].ExpiresAt = expiresAt
+	}
+	return r.db.Create(&reservations).Error
+}
+
+// Reserved returns the quantities held by active, unexpired reservations on
+// the given products, keyed by stock level.
+func (r *Repository) Reserved(productIDs []uint) (map[Key]int, error) {
+	result := make(map[Key]int)
+	if len(productIDs) == 0 {
+		return result, nil
+	}
+
+	var rows []struct {
+		ProductID uint
+		VariantID uint
+		Quantity  int
+	}
+	err := r.db.Model(&Reservation{}).
+		Select("product_id, COALESCE(variant_id, 0) AS variant_id, SUM(quantity) AS quantity").
+		Where("product_id IN ? AND status = ? AND expires_at > ?", productIDs, ReservationActive, time.Now()).
+		Group("product_id, COALESCE(variant_id, 0)").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		result[Key{ProductID: row.ProductID, VariantID: row.VariantID}] = row.Quantity
+	}
+	return result, nil
+}
+
+// ForOrder locks and returns every reservation of an order, whatever its
+// status. Orders placed before reservations existed have none.
+func (r *Repository) ForOrder(orderID uint) ([]Reservation, error) {
+	var reservations []Reservation
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id = ?", orderID).
+		Order("id").
+		Find(&reservations).Error
+	return reservations, err
+}
+
+// Convert marks the active reservations of an order as converted. The caller
+// decrements stock in the same transaction.
+func (r *Repository) Convert(orderID uint) error {
+	return r.db.Model(&Reservation{}).
+		Where("order_id = ? AND status = ?", orderID, ReservationActive).
+		Update("status", ReservationConverted).Error
+}
+
+// Release returns the stock held by the active reservations of an order.
+func (r *Repository) Release(orderID uint) error {
+	return r.db.Model(&Reservation{}).
+		Where("order_id = ? AND status = ?", orderID, ReservationActive).
+		Updates(map[string]interface{}{
+			"status":      ReservationReleased,
+			"released_at": time.Now(),
+		}).Error
+}
+
+// ExpiredOrders returns up to limit orders holding active reservations that
+// have expired, oldest expiry first.
+func (r *Repository) ExpiredOrders(limit int) ([]uint, error) {
+	var orderIDs []uint
+	err := r.db.Model(&Reservation{}).
+		Select("order_id").
+		Where("status = ? AND expires_at <= ?", ReservationActive, time.Now()).
+		Group("order_id").
+		Order("MIN(expires_at)").
+		Limit(limit).
+		Pluck("order_id", &orderIDs).Error
+	return orderIDs, err
+}
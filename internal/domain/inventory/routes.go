@@ -0,0 +1,31 @@
+package inventory
+
+import (
+	"log"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nneji123/ecommerce-golang/internal/common/revocation"
+	"github.com/nneji123/ecommerce-golang/internal/config"
+	"github.com/nneji123/ecommerce-golang/internal/domain/rbac"
+	"github.com/nneji123/ecommerce-golang/internal/middleware"
+)
+
+func RegisterRoutes(e *echo.Echo, h *Handler, revocations revocation.Store, permissions middleware.PermissionResolver) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Error loading configuration: %s", err)
+	}
+
+	inventory := e.Group("/inventory",
+		middleware.AuthMiddleware(cfg.JWTSecret, revocations),
+		middleware.RequirePermission(permissions, rbac.PermissionInventoryManage),
+	)
+	inventory.GET("/warehouses", h.ListWarehouses)
+	inventory.POST("/warehouses", h.CreateWarehouse)
+	inventory.PUT("/warehouses/:id", h.UpdateWarehouse)
+	inventory.GET("/levels", h.Levels)
+	inventory.GET("/movements", h.Movements)
+	inventory.POST("/receipts", h.Receive)
+	inventory.POST("/transfers", h.Transfer)
+	inventory.POST("/adjustments", h.Adjust)
+}
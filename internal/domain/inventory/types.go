@@ -0,0 +1,178 @@
+package inventory
+
+import (
+	"time"
+
+	"github.com/nneji123/ecommerce-golang/internal/middleware"
+)
+
+type ReservationStatus string
+
+const (
+	// ReservationActive holds stock until the reservation expires.
+	ReservationActive ReservationStatus = "active"
+	// ReservationConverted reservations have become permanent stock
+	// decrements because their order was confirmed.
+	ReservationConverted ReservationStatus = "converted"
+	// ReservationReleased reservations returned their stock to sale, because
+	// they expired or their order was cancelled.
+	ReservationReleased ReservationStatus = "released"
+)
+
+// Reservation holds a quantity of a product, or of one of its variants, for
+// a pending order. Active reservations stop counting once ExpiresAt passes,
+// even before the sweeper has released them.
+type Reservation struct {
+	ID         uint              `gorm:"primaryKey" json:"id"`
+	OrderID    uint              `gorm:"not null;index" json:"order_id"`
+	ProductID  uint              `gorm:"not null" json:"product_id"`
+	VariantID  *uint             `json:"variant_id,omitempty"`
+	Quantity   int               `gorm:"not null" json:"quantity"`
+	Status     ReservationStatus `gorm:"type:varchar(20);not null;default:'active'" json:"status"`
+	ExpiresAt  time.Time         `gorm:"not null" json:"expires_at"`
+	ReleasedAt *time.Time        `json:"released_at,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+}
+
+func (Reservation) TableName() string {
+	return "inventory_reservations"
+}
+
+// Expired reports whether the reservation no longer holds stock at now.
+func (r *Reservation) Expired(now time.Time) bool {
+	return !now.Before(r.ExpiresAt)
+}
+
+// Key identifies a stock level: a variant, or a product sold without
+// variants, in which case VariantID is zero.
+type Key struct {
+	ProductID uint
+	VariantID uint
+}
+
+// Key returns the stock level a reservation draws from.
+func (r *Reservation) Key() Key {
+	key := Key{ProductID: r.ProductID}
+	if r.VariantID != nil {
+		key.VariantID = *r.VariantID
+	}
+	return key
+}
+
+// Warehouse is a location holding stock. Orders are fulfilled from
+// warehouses in ascending Priority, ties broken by ID; the first one is the
+// default for stock entered without a warehouse.
+type Warehouse struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Code      string    `gorm:"size:50;not null;uniqueIndex" json:"code"`
+	Name      string    `gorm:"size:255;not null" json:"name"`
+	Address   string    `gorm:"type:text" json:"address"`
+	Priority  int       `gorm:"not null;default:0" json:"priority"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type MovementType string
+
+const (
+	MovementReceipt    MovementType = "receipt"
+	MovementSale       MovementType = "sale"
+	MovementReturn     MovementType = "return"
+	MovementAdjustment MovementType = "adjustment"
+	MovementTransfer   MovementType = "transfer"
+)
+
+// Movement is an entry of the append-only stock ledger. Quantity is signed:
+// positive movements add stock to the warehouse, negative ones remove it. A
+// transfer is recorded as a pair of movements, each naming the other
+// warehouse as its counterpart.
+type Movement struct {
+	ID                     uint         `gorm:"primaryKey" json:"id"`
+	Type                   MovementType `gorm:"type:varchar(20);not null" json:"type"`
+	WarehouseID            uint         `gorm:"not null" json:"warehouse_id"`
+	ProductID              uint         `gorm:"not null" json:"product_id"`
+	VariantID              *uint        `json:"variant_id,omitempty"`
+	Quantity               int          `gorm:"not null" json:"quantity"`
+	OrderID                *uint        `json:"order_id,omitempty"`
+	CounterpartWarehouseID *uint        `json:"counterpart_warehouse_id,omitempty"`
+	Reason                 string       `gorm:"type:text" json:"reason"`
+	ActorID                uint         `gorm:"not null;default:0" json:"actor_id"`
+	CreatedAt              time.Time    `json:"created_at"`
+}
+
+func (Movement) TableName() string {
+	return "stock_movements"
+}
+
+// StockLevel is the quantity of a product or variant held in a warehouse:
+// the sum of its movements there.
+type StockLevel struct {
+	ID          uint       `gorm:"primaryKey" json:"-"`
+	WarehouseID uint       `gorm:"not null" json:"warehouse_id"`
+	Warehouse   *Warehouse `json:"warehouse,omitempty"`
+	ProductID   uint       `gorm:"not null" json:"product_id"`
+	VariantID   *uint      `json:"variant_id,omitempty"`
+	Quantity    int        `gorm:"not null" json:"quantity"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Line is a quantity of a product, or of one of its variants.
+type Line struct {
+	ProductID uint
+	VariantID *uint
+	Quantity  int
+}
+
+type WarehouseRequest struct {
+	Code     string `json:"code" validate:"required,max=50"`
+	Name     string `json:"name" validate:"required,max=255"`
+	Address  string `json:"address"`
+	Priority int    `json:"priority"`
+}
+
+// ReceiveRequest records stock arriving at a warehouse. Without a
+// WarehouseID the default warehouse receives it.
+type ReceiveRequest struct {
+	WarehouseID uint   `json:"warehouse_id"`
+	ProductID   uint   `json:"product_id" validate:"required"`
+	VariantID   *uint  `json:"variant_id,omitempty"`
+	Quantity    int    `json:"quantity" validate:"required,gt=0"`
+	Reason      string `json:"reason"`
+}
+
+type TransferRequest struct {
+	FromWarehouseID uint   `json:"from_warehouse_id" validate:"required"`
+	ToWarehouseID   uint   `json:"to_warehouse_id" validate:"required,nefield=FromWarehouseID"`
+	ProductID       uint   `json:"product_id" validate:"required"`
+	VariantID       *uint  `json:"variant_id,omitempty"`
+	Quantity        int    `json:"quantity" validate:"required,gt=0"`
+	Reason          string `json:"reason"`
+}
+
+// AdjustRequest corrects the stock of a warehouse, e.g. after a count or for
+// damaged goods. Quantity is the signed change.
+type AdjustRequest struct {
+	WarehouseID uint   `json:"warehouse_id" validate:"required"`
+	ProductID   uint   `json:"product_id" validate:"required"`
+	VariantID   *uint  `json:"variant_id,omitempty"`
+	Quantity    int    `json:"quantity" validate:"required,ne=0"`
+	Reason      string `json:"reason" validate:"required"`
+}
+
+// LevelQuery selects stock levels; every field is optional.
+type LevelQuery struct {
+	WarehouseID uint  `query:"warehouse_id"`
+	ProductID   uint  `query:"product_id"`
+	VariantID   *uint `query:"variant_id"`
+}
+
+// MovementQuery selects a page of the ledger, newest first.
+type MovementQuery struct {
+	middleware.PaginationQuery
+	WarehouseID uint         `query:"warehouse_id"`
+	ProductID   uint         `query:"product_id"`
+	VariantID   *uint        `query:"variant_id"`
+	OrderID     uint         `query:"order_id"`
+	Type        MovementType `query:"type"`
+}
@@ -1,45 +1,88 @@
 package order
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 	"github.com/nneji123/ecommerce-golang/internal/common/models"
+	"github.com/nneji123/ecommerce-golang/internal/domain/inventory"
+	"github.com/nneji123/ecommerce-golang/internal/domain/promotion"
+	"github.com/nneji123/ecommerce-golang/internal/domain/rbac"
+	"github.com/nneji123/ecommerce-golang/internal/domain/shipping"
+	"github.com/nneji123/ecommerce-golang/internal/domain/user"
+	"github.com/nneji123/ecommerce-golang/internal/middleware"
 	"go.uber.org/zap"
 )
 
 type Handler struct {
-	repo   *Repository
-	logger *zap.Logger
+	repo      *Repository
+	resolver  middleware.PermissionResolver
+	validator *validator.Validate
+	logger    *zap.Logger
 }
 
-func NewHandler(repo *Repository, logger *zap.Logger) *Handler {
+func NewHandler(repo *Repository, resolver middleware.PermissionResolver, validator *validator.Validate, logger *zap.Logger) *Handler {
 	return &Handler{
-		repo:   repo,
-		logger: logger,
+		repo:      repo,
+		resolver:  resolver,
+		validator: validator,
+		logger:    logger,
 	}
 }
 
 // @Summary		Create order
-// @Description	Place a new order
+// @Description	Place a new order. Prices are taken from the catalog and stock is reserved until the order is confirmed or the reservation expires (reserved_until). The coupons entered and any automatic promotions the order is eligible for are applied and listed in discounts. The shipping and billing addresses chosen from the address book, or the customer's defaults, are copied onto the order. The shipping method chosen, if any, is priced for the destination, or else the shipping address, and added to the total; a free shipping promotion waives it. What remains of each item is taxed there, or at the store's default tax location.
 // @Tags			orders
 // @Accept			json
 // @Produce		json
-// @Param			order	body		Order	true	"Order object"
+// @Param			order	body		CreateOrderRequest	true	"Products and quantities"
 // @Success		201		{object}	Order
 // @Failure		400		{object}	middleware.ErrorResponse
+// @Failure		409		{object}	OutOfStockResponse	"Out of stock, or a coupon's usage limit was reached"
 // @Router			/orders [post]
 func (h *Handler) Create(c echo.Context) error {
-	var order Order
-	if err := c.Bind(&order); err != nil {
+	var req CreateOrderRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.validator.Struct(req); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
 	claims := c.Get("userClaims").(*models.Claims)
-	order.UserID = claims.UserID
 
-	if err := h.repo.Create(&order); err != nil {
+	order, err := h.repo.Checkout(c.Request().Context(), claims.UserID, req)
+	if err != nil {
+		var stockErr *OutOfStockError
+		switch {
+		case errors.As(err, &stockErr):
+			return c.JSON(http.StatusConflict, OutOfStockResponse{
+				Error: "Some items are out of stock",
+				Items: stockErr.Items,
+			})
+		case errors.Is(err, ErrProductNotFound):
+			return echo.NewHTTPError(http.StatusBadRequest, "One or more products do not exist")
+		case errors.Is(err, ErrVariantNotFound):
+			return echo.NewHTTPError(http.StatusBadRequest, "One or more variants do not exist")
+		case errors.Is(err, ErrVariantRequired):
+			return echo.NewHTTPError(http.StatusBadRequest, "A variant must be chosen for products sold in variants")
+		case errors.Is(err, ErrMixedCurrencies):
+			return echo.NewHTTPError(http.StatusBadRequest, "All items of an order must be priced in the same currency")
+		case errors.Is(err, promotion.ErrCouponInvalid),
+			errors.Is(err, promotion.ErrCouponNotApplicable),
+			errors.Is(err, promotion.ErrCouponNotCombinable):
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		case errors.Is(err, user.ErrAddressNotFound):
+			return echo.NewHTTPError(http.StatusBadRequest, "Address not found")
+		case errors.Is(err, shipping.ErrMethodUnavailable):
+			return echo.NewHTTPError(http.StatusBadRequest, "The shipping method is not available for this order")
+		case errors.Is(err, promotion.ErrUsageLimitReached):
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
 		h.logger.Error("Failed to create order", zap.Error(err))
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create order")
 	}
@@ -48,39 +91,62 @@ func (h *Handler) Create(c echo.Context) error {
 }
 
 // @Summary		List user orders
-// @Description	List all orders for the authenticated user
+// @Description	List the authenticated user's orders. Pages are numbered, or selected by cursor for stable paging; neighbouring pages are also advertised in the Link header.
 // @Tags			orders
 // @Produce		json
-// @Success		200	{array}	Order
+// @Param			page		query		int		false	"Page number"
+// @Param			limit		query		int		false	"Items per page"
+// @Param			sort_by		query		string	false	"Sort by field (created_at, total_amount)"
+// @Param			sort_dir	query		string	false	"Sort direction (asc, desc)"
+// @Param			cursor		query		string	false	"Cursor from a previous page's next_cursor or prev_cursor; overrides page and sort"
+// @Success		200			{object}	map[string]interface{}
+// @Failure		400			{object}	middleware.ErrorResponse
 // @Router			/orders [get]
 func (h *Handler) ListUserOrders(c echo.Context) error {
 	claims := c.Get("userClaims").(*models.Claims)
-	page, _ := strconv.Atoi(c.QueryParam("page"))
-	if page < 1 {
-		page = 1
+
+	var query middleware.PaginationQuery
+	if err := c.Bind(&query); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	query.Normalize(10, 100, "")
+	if query.SortBy != "" && !SortColumns[query.SortBy] {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid sort column")
 	}
-	limit := 10
 
-	orders, total, err := h.repo.ListByUser(claims.UserID, page, limit)
+	orders, page, err := h.repo.ListByUser(claims.UserID, query)
 	if err != nil {
+		if errors.Is(err, middleware.ErrInvalidCursor) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid cursor")
+		}
 		h.logger.Error("Failed to list orders", zap.Error(err))
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list orders")
 	}
 
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"orders": orders,
-		"total":  total,
-		"page":   page,
-		"limit":  limit,
-	})
+	response := map[string]interface{}{
+		"orders":     orders,
+		"limit":      page.Limit,
+		"pagination": page,
+	}
+	if page.TotalItems != nil {
+		response["total"] = *page.TotalItems
+		response["page"] = page.CurrentPage
+	}
+
+	middleware.SetLinkHeader(c, page)
+	return c.JSON(http.StatusOK, response)
 }
 
 // @Summary		Cancel order
-// @Description	Cancel an order (only if pending)
+// @Description	Cancel a pending order and release its stock. Orders that have been confirmed can only be cancelled by staff.
 // @Tags			orders
-// @Param			id	path		int	true	"Order ID"
-// @Success		200	{object}	Order
-// @Failure		400	{object}	middleware.ErrorResponse
+// @Accept			json
+// @Produce		json
+// @Param			id		path		int					true	"Order ID"
+// @Param			request	body		CancelOrderRequest	false	"Cancellation reason"
+// @Success		200		{object}	Order
+// @Failure		400		{object}	middleware.ErrorResponse
+// @Failure		409		{object}	middleware.ErrorResponse
 // @Router			/orders/{id}/cancel [post]
 func (h *Handler) CancelOrder(c echo.Context) error {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -88,6 +154,11 @@ func (h *Handler) CancelOrder(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid order ID")
 	}
 
+	var req CancelOrderRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
 	order, err := h.repo.GetByID(uint(id))
 	if err != nil {
 		return echo.NewHTTPError(http.StatusNotFound, "Order not found")
@@ -98,27 +169,28 @@ func (h *Handler) CancelOrder(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusForbidden, "Not authorized to cancel this order")
 	}
 
-	if order.Status != StatusPending {
-		return echo.NewHTTPError(http.StatusBadRequest, "Only pending orders can be cancelled")
-	}
-
-	if err := h.repo.UpdateStatus(order.ID, StatusCancelled); err != nil {
-		h.logger.Error("Failed to cancel order", zap.Error(err))
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to cancel order")
+	order, err = h.repo.CancelPending(order.ID, claims.UserID, req.Reason)
+	if err != nil {
+		if errors.Is(err, ErrNotPending) {
+			return echo.NewHTTPError(http.StatusConflict, "Only pending orders can be cancelled")
+		}
+		return h.transitionError(c, err, "Failed to cancel order")
 	}
 
-	return c.JSON(http.StatusOK, map[string]string{"message": "Order cancelled successfully"})
+	return c.JSON(http.StatusOK, order)
 }
 
 // @Summary		Update order status
-// @Description	Update order status (Admin only)
+// @Description	Move an order to a new status (requires orders:write). Only transitions allowed by the order lifecycle are accepted. partially_shipped, shipped and delivered follow the shipments of the order and cannot be set here.
 // @Tags			orders
 // @Accept			json
 // @Produce		json
-// @Param			id		path		int		true	"Order ID"
-// @Param			status	body		string	true	"New status"
+// @Param			id		path		int					true	"Order ID"
+// @Param			request	body		UpdateStatusRequest	true	"New status and reason"
 // @Success		200		{object}	Order
 // @Failure		400		{object}	middleware.ErrorResponse
+// @Failure		404		{object}	middleware.ErrorResponse
+// @Failure		409		{object}	IllegalTransitionResponse
 // @Router			/orders/{id}/status [put]
 func (h *Handler) UpdateStatus(c echo.Context) error {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -126,25 +198,224 @@ func (h *Handler) UpdateStatus(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid order ID")
 	}
 
-	var statusUpdate struct {
-		Status OrderStatus `json:"status"`
+	var req UpdateStatusRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if !IsValidOrderStatus(req.Status) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid order status")
+	}
+	if IsShipmentStatus(req.Status) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Shipping statuses are driven by shipments")
+	}
+
+	claims := c.Get("userClaims").(*models.Claims)
+
+	order, err := h.repo.Transition(uint(id), req.Status, claims.UserID, req.Reason)
+	if err != nil {
+		return h.transitionError(c, err, "Failed to update order status")
 	}
 
-	if err := c.Bind(&statusUpdate); err != nil {
+	return c.JSON(http.StatusOK, order)
+}
+
+// @Summary		Get order
+// @Description	Get an order with its items, discounts, taxes and the shipping and billing addresses it was placed with. Customers can view their own orders; staff need orders:read.
+// @Tags			orders
+// @Produce		json
+// @Param			id	path		int	true	"Order ID"
+// @Success		200	{object}	Order
+// @Failure		403	{object}	middleware.ErrorResponse
+// @Failure		404	{object}	middleware.ErrorResponse
+// @Router			/orders/{id} [get]
+func (h *Handler) Get(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid order ID")
+	}
+
+	order, err := h.repo.GetByID(uint(id))
+	if err != nil {
+		if errors.Is(err, ErrOrderNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "Order not found")
+		}
+		h.logger.Error("Failed to load order", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load order")
+	}
+
+	claims := c.Get("userClaims").(*models.Claims)
+	if order.UserID != claims.UserID && !middleware.HasPermission(c, h.resolver, rbac.PermissionOrdersRead) {
+		return echo.NewHTTPError(http.StatusForbidden, "Not authorized to view this order")
+	}
+
+	return c.JSON(http.StatusOK, order)
+}
+
+// @Summary		Get order status history
+// @Description	List every status change of an order, oldest first
+// @Tags			orders
+// @Produce		json
+// @Param			id	path	int	true	"Order ID"
+// @Success		200	{array}		OrderStatusHistory
+// @Failure		403	{object}	middleware.ErrorResponse
+// @Failure		404	{object}	middleware.ErrorResponse
+// @Router			/orders/{id}/history [get]
+func (h *Handler) History(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid order ID")
+	}
+
+	order, err := h.repo.GetByID(uint(id))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Order not found")
+	}
+
+	claims := c.Get("userClaims").(*models.Claims)
+	if order.UserID != claims.UserID && !middleware.HasPermission(c, h.resolver, rbac.PermissionOrdersRead) {
+		return echo.NewHTTPError(http.StatusForbidden, "Not authorized to view this order")
+	}
+
+	history, err := h.repo.History(order.ID)
+	if err != nil {
+		h.logger.Error("Failed to load order history", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load order history")
+	}
+
+	return c.JSON(http.StatusOK, history)
+}
+
+// @Summary		List order shipments
+// @Description	List the shipments of an order with their items and tracking details, oldest first. Customers can view their own orders' shipments; staff need orders:read.
+// @Tags			orders
+// @Produce		json
+// @Param			id	path		int	true	"Order ID"
+// @Success		200	{array}		Shipment
+// @Failure		403	{object}	middleware.ErrorResponse
+// @Failure		404	{object}	middleware.ErrorResponse
+// @Router			/orders/{id}/shipments [get]
+func (h *Handler) ListShipments(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid order ID")
+	}
+
+	order, err := h.repo.GetByID(uint(id))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Order not found")
+	}
+
+	claims := c.Get("userClaims").(*models.Claims)
+	if order.UserID != claims.UserID && !middleware.HasPermission(c, h.resolver, rbac.PermissionOrdersRead) {
+		return echo.NewHTTPError(http.StatusForbidden, "Not authorized to view this order")
+	}
+
+	shipments, err := h.repo.Shipments(order.ID)
+	if err != nil {
+		h.logger.Error("Failed to load shipments", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load shipments")
+	}
+
+	return c.JSON(http.StatusOK, shipments)
+}
+
+// @Summary		Create shipment
+// @Description	Ship some or all of a confirmed order's items (requires orders:write). Items may list part of an order line's quantity; without items everything not shipped yet is sent. The order becomes partially_shipped or shipped accordingly and the customer is emailed the tracking details.
+// @Tags			orders
+// @Accept			json
+// @Produce		json
+// @Param			id			path		int						true	"Order ID"
+// @Param			shipment	body		CreateShipmentRequest	true	"Carrier, tracking and items"
+// @Success		201			{object}	Shipment
+// @Failure		400			{object}	middleware.ErrorResponse
+// @Failure		404			{object}	middleware.ErrorResponse
+// @Failure		409			{object}	middleware.ErrorResponse
+// @Router			/orders/{id}/shipments [post]
+func (h *Handler) CreateShipment(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid order ID")
+	}
+
+	var req CreateShipmentRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := h.validator.Struct(req); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
-	if !IsValidOrderStatus(statusUpdate.Status) {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid order status")
+	claims := c.Get("userClaims").(*models.Claims)
+
+	shipment, err := h.repo.CreateShipment(uint(id), claims.UserID, req)
+	if err != nil {
+		return h.shipmentError(c, err, "Failed to create shipment")
+	}
+
+	return c.JSON(http.StatusCreated, shipment)
+}
+
+// @Summary		Mark shipment delivered
+// @Description	Record that a shipment has arrived (requires orders:write). The order is delivered once all of its items have shipped and every shipment has been delivered.
+// @Tags			orders
+// @Produce		json
+// @Param			id			path		int	true	"Order ID"
+// @Param			shipment_id	path		int	true	"Shipment ID"
+// @Success		200			{object}	Shipment
+// @Failure		404			{object}	middleware.ErrorResponse
+// @Failure		409			{object}	middleware.ErrorResponse
+// @Router			/orders/{id}/shipments/{shipment_id}/deliver [post]
+func (h *Handler) DeliverShipment(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid order ID")
+	}
+	shipmentID, err := strconv.ParseUint(c.Param("shipment_id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid shipment ID")
+	}
+
+	claims := c.Get("userClaims").(*models.Claims)
+
+	shipment, err := h.repo.DeliverShipment(uint(id), uint(shipmentID), claims.UserID)
+	if err != nil {
+		return h.shipmentError(c, err, "Failed to deliver shipment")
 	}
 
-	if err := h.repo.UpdateStatus(uint(id), statusUpdate.Status); err != nil {
-		h.logger.Error("Failed to update order status", zap.Error(err))
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update order status")
+	return c.JSON(http.StatusOK, shipment)
+}
+
+// shipmentError maps errors from the shipment methods of Repository to HTTP
+// responses.
+func (h *Handler) shipmentError(c echo.Context, err error, message string) error {
+	switch {
+	case errors.Is(err, ErrShipmentNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Shipment not found")
+	case errors.Is(err, ErrInvalidShipment):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrNotShippable), errors.Is(err, ErrShipmentDelivered):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
 	}
+	return h.transitionError(c, err, message)
+}
 
-	return c.JSON(http.StatusOK, map[string]string{
-		"message": "Order status updated successfully",
-		"status":  string(statusUpdate.Status),
-	})
+// transitionError maps errors from Repository.Transition to HTTP responses.
+func (h *Handler) transitionError(c echo.Context, err error, message string) error {
+	var illegal *IllegalTransitionError
+	switch {
+	case errors.As(err, &illegal):
+		return c.JSON(http.StatusConflict, IllegalTransitionResponse{
+			Error:   illegal.Error(),
+			From:    illegal.From,
+			To:      illegal.To,
+			Allowed: illegal.Allowed,
+		})
+	case errors.Is(err, ErrOrderNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Order not found")
+	case errors.Is(err, ErrReservationExpired), errors.Is(err, inventory.ErrInsufficientStock):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	h.logger.Error(message, zap.Error(err))
+	return echo.NewHTTPError(http.StatusInternalServerError, message)
 }
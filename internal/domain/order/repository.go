@@ -1,45 +1,890 @@
 package order
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/nneji123/ecommerce-golang/internal/common/geo"
+	"github.com/nneji123/ecommerce-golang/internal/common/money"
+	"github.com/nneji123/ecommerce-golang/internal/domain/inventory"
+	"github.com/nneji123/ecommerce-golang/internal/domain/product"
+	"github.com/nneji123/ecommerce-golang/internal/domain/promotion"
+	"github.com/nneji123/ecommerce-golang/internal/domain/shipping"
+	"github.com/nneji123/ecommerce-golang/internal/domain/tax"
+	"github.com/nneji123/ecommerce-golang/internal/domain/user"
+	"github.com/nneji123/ecommerce-golang/internal/middleware"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrOrderNotFound   = errors.New("order not found")
+	ErrProductNotFound = errors.New("product not found")
+	ErrVariantNotFound = errors.New("variant not found")
+	ErrVariantRequired = errors.New("a variant must be chosen for products sold in variants")
+	ErrMixedCurrencies = errors.New("all items of an order must be priced in the same currency")
+	// ErrReservationExpired is returned when an order whose reservations
+	// lapsed is confirmed after its stock was sold to someone else.
+	ErrReservationExpired = errors.New("the order's stock reservation has expired")
+	// ErrNotPending is returned when a customer cancels an order that is no
+	// longer pending.
+	ErrNotPending = errors.New("only pending orders can be cancelled")
 )
 
+// OutOfStockError lists every requested item that could not be fulfilled.
+type OutOfStockError struct {
+	Items []StockConflict
+}
+
+func (e *OutOfStockError) Error() string {
+	return fmt.Sprintf("%d item(s) out of stock", len(e.Items))
+}
+
 type Repository struct {
-	db *gorm.DB
+	db             *gorm.DB
+	machine        *StateMachine
+	reservationTTL time.Duration
+	taxes          tax.Calculator
+	shipping       *shipping.Quoter
+	cursors        *middleware.CursorSigner
+}
+
+// NewRepository returns an order repository. Checkout reserves stock for
+// reservationTTL; the reservation becomes a stock decrement when the order is
+// confirmed, and is released when it is cancelled or expires. Orders are
+// taxed by taxes, or not at all when it is nil, and their shipping methods
+// priced by rates. Listing cursors are signed by cursors.
+func NewRepository(db *gorm.DB, reservationTTL time.Duration, taxes tax.Calculator, rates *shipping.Quoter, cursors *middleware.CursorSigner) *Repository {
+	if reservationTTL <= 0 {
+		reservationTTL = 15 * time.Minute
+	}
+
+	machine := NewStateMachine()
+	machine.OnTransition(StatusPending, StatusConfirmed, convertReservationsHook)
+	machine.OnTransition(StatusPending, StatusCancelled, releaseReservationsHook)
+	machine.OnTransition(StatusConfirmed, StatusCancelled, restockHook)
+	machine.OnTransition(StatusPending, StatusCancelled, releasePromotionsHook)
+	machine.OnTransition(StatusConfirmed, StatusCancelled, releasePromotionsHook)
+
+	return &Repository{db: db, machine: machine, reservationTTL: reservationTTL, taxes: taxes, shipping: rates, cursors: cursors}
+}
+
+// WithTx returns a repository that runs its queries in tx, sharing this
+// repository's state machine.
+func (r *Repository) WithTx(tx *gorm.DB) *Repository {
+	return &Repository{db: tx, machine: r.machine, reservationTTL: r.reservationTTL, taxes: r.taxes, shipping: r.shipping, cursors: r.cursors}
 }
 
-func NewRepository(db *gorm.DB) *Repository {
-	return &Repository{db: db}
+// StateMachine returns the lifecycle used for status changes so callers can
+// register additional hooks.
+func (r *Repository) StateMachine() *StateMachine {
+	return r.machine
 }
 
 func (r *Repository) Create(order *Order) error {
 	return r.db.Create(order).Error
 }
 
+// lineKey identifies a checkout line. VariantID is zero for products sold
+// without variants.
+type lineKey struct {
+	ProductID uint
+	VariantID uint
+}
+
+// Checkout places an order for the given items inside a single transaction.
+// Product and variant rows are locked, prices are snapshotted from the
+// catalog and the items are reserved against available-to-sell stock: stock
+// less the active reservations of other pending orders. The coupons entered
+// and the eligible automatic promotions are applied and redeemed with the
+// order, and recorded as its discount lines; the addresses are copied from
+// the customer's address book, the chosen shipping method is priced and what
+// remains of each item is then taxed.
+func (r *Repository) Checkout(ctx context.Context, userID uint, req CreateOrderRequest) (*Order, error) {
+	items := req.Items
+	quantities := make(map[lineKey]int)
+	keys := make([]lineKey, 0, len(items))
+	seenProducts := make(map[uint]bool)
+	productIDs := make([]uint, 0, len(items))
+	for _, item := range items {
+		key := lineKey{ProductID: item.ProductID}
+		if item.VariantID != nil {
+			key.VariantID = *item.VariantID
+		}
+		if _, ok := quantities[key]; !ok {
+			keys = append(keys, key)
+		}
+		quantities[key] += item.Quantity
+
+		if !seenProducts[item.ProductID] {
+			seenProducts[item.ProductID] = true
+			productIDs = append(productIDs, item.ProductID)
+		}
+	}
+
+	// Lock rows in a stable order so concurrent checkouts cannot deadlock.
+	sort.Slice(productIDs, func(i, j int) bool { return productIDs[i] < productIDs[j] })
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].ProductID != keys[j].ProductID {
+			return keys[i].ProductID < keys[j].ProductID
+		}
+		return keys[i].VariantID < keys[j].VariantID
+	})
+
+	order := &Order{
+		UserID: userID,
+		Status: StatusPending,
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var products []product.Product
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", productIDs).
+			Order("id").
+			Find(&products).Error; err != nil {
+			return err
+		}
+		if len(products) != len(productIDs) {
+			return ErrProductNotFound
+		}
+		productsByID := make(map[uint]product.Product, len(products))
+		for _, p := range products {
+			productsByID[p.ID] = p
+		}
+
+		var variants []product.ProductVariant
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("product_id IN ?", productIDs).
+			Order("id").
+			Find(&variants).Error; err != nil {
+			return err
+		}
+		variantsByID := make(map[uint]product.ProductVariant, len(variants))
+		hasVariants := make(map[uint]bool)
+		for _, v := range variants {
+			variantsByID[v.ID] = v
+			hasVariants[v.ProductID] = true
+		}
+
+		// The row locks above serialize checkouts of the same stock, so the
+		// reserved quantities cannot change until this transaction ends.
+		reserved, err := inventory.NewRepository(tx).Reserved(productIDs)
+		if err != nil {
+			return err
+		}
+
+		var conflicts []StockConflict
+		for _, key := range keys {
+			p := productsByID[key.ProductID]
+			requested := quantities[key]
+
+			if key.VariantID == 0 {
+				if hasVariants[p.ID] {
+					return ErrVariantRequired
+				}
+				available := p.Stock - reserved[inventory.Key{ProductID: p.ID}]
+				if available < requested {
+					conflicts = append(conflicts, StockConflict{
+						ProductID: p.ID,
+						Requested: requested,
+						Available: max(available, 0),
+					})
+				}
+				order.Items = append(order.Items, OrderItem{
+					ProductID: p.ID,
+					Product:   p,
+					Quantity:  requested,
+					Price:     p.Price,
+				})
+				continue
+			}
+
+			v, err := variantOf(variantsByID, p.ID, key.VariantID)
+			if err != nil {
+				return err
+			}
+			variantID := v.ID
+			available := v.Stock - reserved[inventory.Key{ProductID: p.ID, VariantID: v.ID}]
+			if available < requested {
+				conflicts = append(conflicts, StockConflict{
+					ProductID: p.ID,
+					VariantID: &variantID,
+					Requested: requested,
+					Available: max(available, 0),
+				})
+			}
+			order.Items = append(order.Items, OrderItem{
+				ProductID: p.ID,
+				Product:   p,
+				VariantID: &variantID,
+				Variant:   &v,
+				SKU:       v.SKU,
+				Quantity:  requested,
+				Price:     v.UnitPrice(p.Price),
+			})
+		}
+		if len(conflicts) > 0 {
+			return &OutOfStockError{Items: conflicts}
+		}
+
+		if err := ValidateOrder(order); err != nil {
+			return err
+		}
+		if order.Subtotal, err = CalculateOrderTotal(order.Items); err != nil {
+			return err
+		}
+		promotions := promotion.NewRepository(tx)
+		applied, err := promotions.Apply(promotion.Checkout{
+			UserID: userID,
+			Lines:  promotionLines(order.Items),
+			Codes:  req.CouponCodes,
+		})
+		if err != nil {
+			return err
+		}
+		if err := setAddresses(tx, order, &req); err != nil {
+			return err
+		}
+		if err := r.applyShipping(ctx, order, req, applied); err != nil {
+			return err
+		}
+		currency := order.Subtotal.Currency
+		order.DiscountTotal = money.Zero(currency)
+		for i := range order.Items {
+			order.Items[i].Discount = money.Zero(currency)
+		}
+		for _, d := range applied {
+			if order.DiscountTotal, err = order.DiscountTotal.Add(d.Amount); err != nil {
+				return err
+			}
+			for i, amount := range d.Lines {
+				order.Items[i].Discount.Amount += amount
+			}
+		}
+		if err := r.applyTax(ctx, order, req.Destination); err != nil {
+			return err
+		}
+		if order.TotalAmount, err = order.Subtotal.Sub(order.DiscountTotal); err != nil {
+			return err
+		}
+		if order.TotalAmount, err = order.TotalAmount.Add(order.ShippingCost); err != nil {
+			return err
+		}
+		if !order.PricesIncludeTax {
+			order.TotalAmount.Amount += order.TaxTotal.Amount
+		}
+		reservedUntil := time.Now().Add(r.reservationTTL)
+		order.ReservedUntil = &reservedUntil
+
+		if err := tx.Omit(clause.Associations).Create(order).Error; err != nil {
+			return err
+		}
+		reservations := make([]inventory.Reservation, 0, len(order.Items))
+		for i, item := range order.Items {
+			order.Items[i].OrderID = order.ID
+			reservations = append(reservations, inventory.Reservation{
+				ProductID: item.ProductID,
+				VariantID: item.VariantID,
+				Quantity:  item.Quantity,
+			})
+		}
+		if err := tx.Omit("Product", "Variant").Create(&order.Items).Error; err != nil {
+			return err
+		}
+		if err := inventory.NewRepository(tx).Reserve(order.ID, reservations, reservedUntil); err != nil {
+			return err
+		}
+
+		order.Discounts = make([]OrderDiscount, 0, len(applied))
+		for _, d := range applied {
+			promotionID := d.PromotionID
+			order.Discounts = append(order.Discounts, OrderDiscount{
+				OrderID:     order.ID,
+				PromotionID: &promotionID,
+				Code:        d.Code,
+				Name:        d.Name,
+				Type:        d.Type,
+				Amount:      d.Amount,
+			})
+		}
+		if len(order.Discounts) > 0 {
+			if err := tx.Create(&order.Discounts).Error; err != nil {
+				return err
+			}
+		}
+		if err := promotions.Redeem(order.ID, userID, applied); err != nil {
+			return err
+		}
+
+		for i := range order.TaxLines {
+			order.TaxLines[i].OrderID = order.ID
+		}
+		if len(order.TaxLines) > 0 {
+			if err := tx.Create(&order.TaxLines).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Create(&OrderStatusHistory{
+			OrderID:  order.ID,
+			ToStatus: StatusPending,
+			ActorID:  userID,
+			Reason:   "order placed",
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// variantOf looks up a variant chosen for a product at checkout. The variants
+// of every product in the cart are loaded together, so one of another product
+// is refused as not found.
+func variantOf(variants map[uint]product.ProductVariant, productID, variantID uint) (product.ProductVariant, error) {
+	v, ok := variants[variantID]
+	if !ok || v.ProductID != productID {
+		return product.ProductVariant{}, ErrVariantNotFound
+	}
+	return v, nil
+}
+
+// Transition moves an order to a new status through the state machine and
+// records the change in the status history, all in one transaction.
+func (r *Repository) Transition(orderID uint, to OrderStatus, actorID uint, reason string) (*Order, error) {
+	var order Order
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockOrder(tx, &order, orderID); err != nil {
+			return err
+		}
+
+		return r.apply(tx, &order, to, actorID, reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &order, nil
+}
+
+// CancelPending cancels an order on behalf of its customer. Only pending
+// orders can be: a confirmed order has been paid for, and cancelling it is
+// left to staff, who refund the payment.
+func (r *Repository) CancelPending(orderID, actorID uint, reason string) (*Order, error) {
+	var order Order
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockOrder(tx, &order, orderID); err != nil {
+			return err
+		}
+		if order.Status != StatusPending {
+			return ErrNotPending
+		}
+
+		return r.apply(tx, &order, StatusCancelled, actorID, reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &order, nil
+}
+
+// lockOrder locks an order and loads it with its items, discounts and tax
+// lines for a status change.
+func lockOrder(tx *gorm.DB, order *Order, orderID uint) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("Items").
+		Preload("Discounts").
+		Preload("TaxLines").
+		First(order, orderID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrOrderNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// apply runs a locked order through the state machine and persists the new
+// status and its history entry.
+func (r *Repository) apply(tx *gorm.DB, order *Order, to OrderStatus, actorID uint, reason string) error {
+	t, err := r.machine.Apply(tx, order, to, actorID, reason)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Model(order).Update("status", t.To).Error; err != nil {
+		return err
+	}
+	order.Status = t.To
+
+	return tx.Create(&OrderStatusHistory{
+		OrderID:    order.ID,
+		FromStatus: t.From,
+		ToStatus:   t.To,
+		ActorID:    t.ActorID,
+		Reason:     t.Reason,
+	}).Error
+}
+
+// ExpireReservations cancels a pending order whose stock reservations have
+// expired, releasing them. It reports whether the order was cancelled. Orders
+// that were confirmed or cancelled in the meantime are left alone, apart from
+// releasing any reservation they still hold.
+func (r *Repository) ExpireReservations(orderID uint) (bool, error) {
+	cancelled := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var order Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("Items").
+			First(&order, orderID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrOrderNotFound
+			}
+			return err
+		}
+
+		reservations := inventory.NewRepository(tx)
+		if order.Status != StatusPending {
+			return reservations.Release(order.ID)
+		}
+
+		held, err := reservations.ForOrder(order.ID)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		expired := false
+		for _, reservation := range held {
+			if reservation.Status != inventory.ReservationActive {
+				continue
+			}
+			if !reservation.Expired(now) {
+				// Not due yet.
+				return nil
+			}
+			expired = true
+		}
+		if !expired {
+			return nil
+		}
+
+		cancelled = true
+		return r.apply(tx, &order, StatusCancelled, SystemActorID, "reservation expired")
+	})
+	return cancelled, err
+}
+
+// History returns the status changes of an order, oldest first.
+func (r *Repository) History(orderID uint) ([]OrderStatusHistory, error) {
+	var history []OrderStatusHistory
+	err := r.db.Where("order_id = ?", orderID).Order("created_at, id").Find(&history).Error
+	return history, err
+}
+
+// convertReservationsHook turns the reservations of an order being confirmed
+// into sales, picking the stock from warehouses in fulfilment order. A
+// reservation that expired before the sweeper released it is still honoured
+// if the stock has not been sold in the meantime. Orders placed before
+// reservations existed had their stock decremented at checkout and are left
+// as they are.
+func convertReservationsHook(tx *gorm.DB, order *Order, t TransitionContext) error {
+	reservations := inventory.NewRepository(tx)
+	held, err := reservations.ForOrder(order.ID)
+	if err != nil || len(held) == 0 {
+		return err
+	}
+
+	lines := make([]inventory.Line, 0, len(held))
+	productIDs := make([]uint, 0, len(held))
+	for _, reservation := range held {
+		if reservation.Status != inventory.ReservationActive {
+			continue
+		}
+		lines = append(lines, inventory.Line{
+			ProductID: reservation.ProductID,
+			VariantID: reservation.VariantID,
+			Quantity:  reservation.Quantity,
+		})
+		productIDs = append(productIDs, reservation.ProductID)
+	}
+	if len(lines) == 0 {
+		// Every reservation was released, so the stock is no longer held.
+		return ErrReservationExpired
+	}
+
+	stock, err := lockStock(tx, lines)
+	if err != nil {
+		return err
+	}
+	// Active reservations of this order that have not expired are included
+	// in reserved; expired ones are not and must fit in what is left.
+	reserved, err := reservations.Reserved(productIDs)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, reservation := range held {
+		if reservation.Status != inventory.ReservationActive || !reservation.Expired(now) {
+			continue
+		}
+		key := reservation.Key()
+		if stock[key]-reserved[key] < reservation.Quantity {
+			return ErrReservationExpired
+		}
+		reserved[key] += reservation.Quantity
+	}
+
+	if err := reservations.Pick(order.ID, lines, t.ActorID); err != nil {
+		return err
+	}
+	return reservations.Convert(order.ID)
+}
+
+// releaseReservationsHook returns the stock held for a pending order that is
+// cancelled. Orders placed before reservations existed are restocked instead.
+func releaseReservationsHook(tx *gorm.DB, order *Order, t TransitionContext) error {
+	reservations := inventory.NewRepository(tx)
+	held, err := reservations.ForOrder(order.ID)
+	if err != nil {
+		return err
+	}
+	if len(held) == 0 {
+		return restock(tx, order, t.ActorID)
+	}
+	return reservations.Release(order.ID)
+}
+
+// setAddresses copies the shipping and billing addresses chosen for an order,
+// or the customer's defaults, from the address book onto the order. Without
+// a destination of its own the order is shipped and taxed at its shipping
+// address.
+func setAddresses(tx *gorm.DB, order *Order, req *CreateOrderRequest) error {
+	addresses := user.NewAddressRepository(tx)
+	shippingAddress, billingAddress, err := addresses.Defaults(order.UserID)
+	if err != nil {
+		return err
+	}
+	if req.ShippingAddressID != nil {
+		if shippingAddress, err = addresses.Get(order.UserID, *req.ShippingAddressID); err != nil {
+			return err
+		}
+	}
+	if req.BillingAddressID != nil {
+		if billingAddress, err = addresses.Get(order.UserID, *req.BillingAddressID); err != nil {
+			return err
+		}
+	}
+	if billingAddress == nil {
+		billingAddress = shippingAddress
+	}
+
+	if shippingAddress != nil {
+		snapshot := shippingAddress.Address
+		order.ShippingAddress = &snapshot
+		if req.Destination == nil {
+			destination := snapshot.Location()
+			req.Destination = &destination
+		}
+	}
+	if billingAddress != nil {
+		snapshot := billingAddress.Address
+		order.BillingAddress = &snapshot
+	}
+	return nil
+}
+
+// applyShipping prices the shipping method chosen for an order, if any, for
+// its items and destination. A free shipping promotion among the applied
+// discounts takes the shipping cost off again, as its discount amount.
+func (r *Repository) applyShipping(ctx context.Context, order *Order, req CreateOrderRequest, applied []promotion.Discount) error {
+	order.ShippingCost = money.Zero(order.Subtotal.Currency)
+	if req.ShippingMethodID == nil {
+		return nil
+	}
+	if r.shipping == nil {
+		return shipping.ErrMethodUnavailable
+	}
+
+	parcel := shipping.Parcel{Subtotal: order.Subtotal, Items: make([]shipping.Item, 0, len(order.Items))}
+	if req.Destination != nil {
+		parcel.Destination = *req.Destination
+	}
+	for _, item := range order.Items {
+		parcel.Items = append(parcel.Items, shipping.Item{
+			ProductID:   item.ProductID,
+			Quantity:    item.Quantity,
+			WeightGrams: item.Product.WeightGrams,
+			LengthMM:    item.Product.LengthMM,
+			WidthMM:     item.Product.WidthMM,
+			HeightMM:    item.Product.HeightMM,
+		})
+	}
+	quote, err := r.shipping.QuoteMethod(ctx, *req.ShippingMethodID, parcel)
+	if err != nil {
+		return err
+	}
+	order.ShippingMethodID = &quote.MethodID
+	order.ShippingMethod = quote.Name
+	order.ShippingCost = quote.Price
+
+	for i := range applied {
+		if applied[i].Type == promotion.TypeFreeShipping {
+			applied[i].Amount = quote.Price
+			break
+		}
+	}
+	return nil
+}
+
+// applyTax taxes the items of an order, after discounts, at destination and
+// sets the tax of each item, the order's tax lines and its tax total.
+func (r *Repository) applyTax(ctx context.Context, order *Order, destination *geo.Location) error {
+	currency := order.Subtotal.Currency
+	order.TaxTotal = money.Zero(currency)
+	order.TaxLines = []OrderTaxLine{}
+	for i := range order.Items {
+		order.Items[i].Tax = money.Zero(currency)
+	}
+	if r.taxes == nil {
+		return nil
+	}
+
+	req := tax.Request{At: time.Now(), Currency: currency, Lines: make([]tax.Line, 0, len(order.Items))}
+	if destination != nil {
+		req.Location = *destination
+	}
+	for _, item := range order.Items {
+		amount, err := item.Price.Mul(int64(item.Quantity)).Sub(item.Discount)
+		if err != nil {
+			return err
+		}
+		req.Lines = append(req.Lines, tax.Line{
+			ProductID: item.ProductID,
+			ClassID:   item.Product.TaxClassID,
+			Quantity:  item.Quantity,
+			Amount:    amount,
+		})
+	}
+	result, err := r.taxes.Calculate(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	order.PricesIncludeTax = result.PricesIncludeTax
+	order.TaxTotal = result.Total
+	for i, amount := range result.Lines {
+		order.Items[i].Tax = amount
+	}
+	for _, t := range result.Taxes {
+		order.TaxLines = append(order.TaxLines, OrderTaxLine{
+			TaxRateID: t.RateID,
+			Name:      t.Name,
+			Rate:      t.Rate,
+			Taxable:   t.Taxable,
+			Amount:    t.Amount,
+		})
+	}
+	return nil
+}
+
+// releasePromotionsHook gives back the promotions redeemed by an order that
+// is cancelled, so they count towards usage limits no more.
+func releasePromotionsHook(tx *gorm.DB, order *Order, t TransitionContext) error {
+	return promotion.NewRepository(tx).Release(order.ID)
+}
+
+// promotionLines describes the items of an order to the promotions engine.
+func promotionLines(items []OrderItem) []promotion.Line {
+	lines := make([]promotion.Line, 0, len(items))
+	for _, item := range items {
+		lines = append(lines, promotion.Line{
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Quantity:  item.Quantity,
+			UnitPrice: item.Price,
+		})
+	}
+	return lines
+}
+
+// restockHook returns a cancelled order's items to stock.
+func restockHook(tx *gorm.DB, order *Order, t TransitionContext) error {
+	return restock(tx, order, t.ActorID)
+}
+
+// restock locks the products and variants of an order and returns its items
+// to the warehouses they were picked from.
+func restock(tx *gorm.DB, order *Order, actorID uint) error {
+	lines := make([]inventory.Line, 0, len(order.Items))
+	for _, item := range order.Items {
+		lines = append(lines, inventory.Line{
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Quantity:  item.Quantity,
+		})
+	}
+	if _, err := lockStock(tx, lines); err != nil {
+		return err
+	}
+	return inventory.NewRepository(tx).Return(order.ID, lines, actorID)
+}
+
+// lockStock locks the products and variants referenced by lines and returns
+// their total stock.
+func lockStock(tx *gorm.DB, lines []inventory.Line) (map[inventory.Key]int, error) {
+	stock := make(map[inventory.Key]int)
+	seen := make(map[uint]bool)
+	productIDs := make([]uint, 0, len(lines))
+	variantIDs := make([]uint, 0, len(lines))
+	for _, line := range lines {
+		if !seen[line.ProductID] {
+			seen[line.ProductID] = true
+			productIDs = append(productIDs, line.ProductID)
+		}
+		if line.VariantID != nil {
+			variantIDs = append(variantIDs, *line.VariantID)
+		}
+	}
+	if len(productIDs) == 0 {
+		return stock, nil
+	}
+
+	// Lock in the same order as Checkout: products, then variants.
+	var products []product.Product
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN ?", productIDs).
+		Order("id").
+		Find(&products).Error; err != nil {
+		return nil, err
+	}
+	for _, p := range products {
+		stock[inventory.Key{ProductID: p.ID}] = p.Stock
+	}
+	if len(variantIDs) > 0 {
+		var variants []product.ProductVariant
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", variantIDs).
+			Order("id").
+			Find(&variants).Error; err != nil {
+			return nil, err
+		}
+		for _, v := range variants {
+			stock[inventory.Key{ProductID: v.ProductID, VariantID: v.ID}] = v.Stock
+		}
+	}
+	return stock, nil
+}
+
 func (r *Repository) GetByID(id uint) (*Order, error) {
 	var order Order
-	if err := r.db.Preload("Items.Product").First(&order, id).Error; err != nil {
+	if err := r.db.Preload("Items.Product").Preload("Items.Variant.OptionValues").Preload("Discounts").Preload("TaxLines").Preload("Shipments.Items").First(&order, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("order not found")
+			return nil, ErrOrderNotFound
 		}
 		return nil, err
 	}
 	return &order, nil
 }
 
-func (r *Repository) ListByUser(userID uint, page, limit int) ([]Order, int64, error) {
+// SortColumns are the columns a user's orders can be sorted and paged by.
+var SortColumns = map[string]bool{
+	"created_at": true, "total_amount": true,
+}
+
+// ListByUser returns a page of a user's orders. With a cursor the page is
+// selected by keyset on the cursor's sort order; otherwise query's page
+// number is used. A cursor that was not signed by this repository's signer is
+// rejected with middleware.ErrInvalidCursor.
+func (r *Repository) ListByUser(userID uint, query middleware.PaginationQuery) ([]Order, middleware.PaginatedResponse, error) {
 	var orders []Order
+	var page middleware.PaginatedResponse
+
+	var cursor *middleware.Cursor
+	if query.Cursor != "" {
+		var err error
+		if cursor, err = r.cursors.Decode(query.Cursor); err != nil {
+			return nil, page, err
+		}
+	}
+
+	sortBy, dir := query.SortBy, query.SortDir
+	if cursor != nil {
+		sortBy, dir = cursor.SortBy, cursor.SortDir
+	}
+	if sortBy == "" {
+		sortBy, dir = "created_at", "DESC"
+	} else if dir != "ASC" {
+		dir = "DESC"
+	}
+	if !SortColumns[sortBy] {
+		return nil, page, middleware.ErrInvalidCursor
+	}
+
+	db := r.db.Model(&Order{}).Where("orders.user_id = ?", userID)
+	withItems := func(db *gorm.DB) *gorm.DB {
+		return db.Preload("Items.Product").Preload("Items.Variant.OptionValues").Preload("Discounts").Preload("TaxLines").Preload("Shipments.Items")
+	}
+
+	if cursor != nil {
+		value, err := orderCursorValue(sortBy, cursor.Value)
+		if err != nil {
+			return nil, page, err
+		}
+		condition, order := middleware.Keyset("orders."+sortBy, "orders.id", dir, cursor)
+		if err := db.Scopes(withItems).Where(condition, value, cursor.ID).Order(order).Limit(query.Limit + 1).Find(&orders).Error; err != nil {
+			return nil, page, err
+		}
+		orders, page = middleware.KeysetPage(r.cursors, orders, query.Limit, sortBy, dir, cursor, orderKey(sortBy))
+		return orders, page, nil
+	}
+
 	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, page, err
+	}
 
-	query := r.db.Model(&Order{}).Where("user_id = ?", userID)
-	query.Count(&total)
+	_, order := middleware.Keyset("orders."+sortBy, "orders.id", dir, nil)
+	offset := (query.Page - 1) * query.Limit
+	if err := db.Scopes(withItems).Order(order).Offset(offset).Limit(query.Limit).Find(&orders).Error; err != nil {
+		return nil, page, err
+	}
 
-	offset := (page - 1) * limit
-	err := query.Preload("Items.Product").Offset(offset).Limit(limit).Find(&orders).Error
-	return orders, total, err
+	page = middleware.OffsetPage(query.Page, query.Limit, total)
+	if len(orders) > 0 && int64(offset+len(orders)) < total {
+		value, id := orderKey(sortBy)(orders[len(orders)-1])
+		page.NextCursor = r.cursors.Encode(middleware.Cursor{SortBy: sortBy, SortDir: dir, Value: value, ID: id})
+	}
+	return orders, page, nil
 }
 
-func (r *Repository) UpdateStatus(orderID uint, status OrderStatus) error {
-	return r.db.Model(&Order{}).Where("id = ?", orderID).Update("status", status).Error
+// orderKey returns the cursor value and ID of an order for a sort column.
+func orderKey(sortBy string) func(Order) (string, uint) {
+	return func(o Order) (string, uint) {
+		if sortBy == "total_amount" {
+			return strconv.FormatInt(o.TotalAmount.Amount, 10), o.ID
+		}
+		return o.CreatedAt.UTC().Format(time.RFC3339Nano), o.ID
+	}
+}
+
+// orderCursorValue converts a cursor's sort value back to the column's type.
+func orderCursorValue(sortBy, value string) (interface{}, error) {
+	if sortBy == "total_amount" {
+		amount, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, middleware.ErrInvalidCursor
+		}
+		return amount, nil
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil, middleware.ErrInvalidCursor
+	}
+	return createdAt, nil
 }
@@ -0,0 +1,41 @@
+package order
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nneji123/ecommerce-golang/internal/domain/product"
+)
+
+func TestVariantOf(t *testing.T) {
+	variants := map[uint]product.ProductVariant{
+		10: {ID: 10, ProductID: 1, SKU: "TEE-S"},
+		11: {ID: 11, ProductID: 1, SKU: "TEE-M"},
+		20: {ID: 20, ProductID: 2, SKU: "WATCH-GOLD"},
+	}
+
+	tests := []struct {
+		name      string
+		productID uint
+		variantID uint
+		wantSKU   string
+		wantErr   error
+	}{
+		{"variant of the product", 1, 11, "TEE-M", nil},
+		{"variant of another product in the cart", 1, 20, "", ErrVariantNotFound},
+		{"variant of another product without variants", 3, 20, "", ErrVariantNotFound},
+		{"unknown variant", 1, 99, "", ErrVariantNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := variantOf(variants, tt.productID, tt.variantID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("variantOf() error = %v, want %v", err, tt.wantErr)
+			}
+			if got.SKU != tt.wantSKU {
+				t.Errorf("variantOf() SKU = %q, want %q", got.SKU, tt.wantSKU)
+			}
+		})
+	}
+}
@@ -4,11 +4,13 @@ import (
 	"log"
 
 	"github.com/labstack/echo/v4"
+	"github.com/nneji123/ecommerce-golang/internal/common/revocation"
 	"github.com/nneji123/ecommerce-golang/internal/config"
+	"github.com/nneji123/ecommerce-golang/internal/domain/rbac"
 	"github.com/nneji123/ecommerce-golang/internal/middleware"
 )
 
-func RegisterRoutes(e *echo.Echo, h *Handler) {
+func RegisterRoutes(e *echo.Echo, h *Handler, revocations revocation.Store, permissions middleware.PermissionResolver) {
 	orders := e.Group("/orders")
 
 	cfg, err := config.LoadConfig()
@@ -16,14 +18,18 @@ func RegisterRoutes(e *echo.Echo, h *Handler) {
 		log.Fatalf("Error loading configuration: %s", err)
 	}
 
-	orders.Use(middleware.AuthMiddleware(cfg.JWTSecret))
+	orders.Use(middleware.AuthMiddleware(cfg.JWTSecret, revocations))
 
 	// User routes
 	orders.POST("", h.Create)
 	orders.GET("", h.ListUserOrders)
+	orders.GET("/:id", h.Get)
 	orders.POST("/:id/cancel", h.CancelOrder)
+	orders.GET("/:id/history", h.History)
+	orders.GET("/:id/shipments", h.ListShipments)
 
-	// Admin routes
-	admin := orders.Group("", middleware.AdminMiddleware())
-	admin.PUT("/:id/status", h.UpdateStatus)
+	// Fulfilment routes
+	orders.PUT("/:id/status", h.UpdateStatus, middleware.RequirePermission(permissions, rbac.PermissionOrdersWrite))
+	orders.POST("/:id/shipments", h.CreateShipment, middleware.RequirePermission(permissions, rbac.PermissionOrdersWrite))
+	orders.POST("/:id/shipments/:shipment_id/deliver", h.DeliverShipment, middleware.RequirePermission(permissions, rbac.PermissionOrdersWrite))
 }
@@ -0,0 +1,245 @@
+package order
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nneji123/ecommerce-golang/internal/domain/outbox"
+	"github.com/nneji123/ecommerce-golang/internal/domain/user"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrShipmentNotFound  = errors.New("shipment not found")
+	ErrInvalidShipment   = errors.New("invalid shipment")
+	ErrNotShippable      = errors.New("the order has nothing left to ship")
+	ErrShipmentDelivered = errors.New("shipment already delivered")
+)
+
+// Shipments returns the shipments of an order with their items, oldest
+// first.
+func (r *Repository) Shipments(orderID uint) ([]Shipment, error) {
+	shipments := []Shipment{}
+	err := r.db.Preload("Items").Where("order_id = ?", orderID).Order("shipped_at, id").Find(&shipments).Error
+	return shipments, err
+}
+
+// CreateShipment sends the requested quantities of a confirmed order's items,
+// or everything not shipped yet when the request lists no items, and moves
+// the order to partially_shipped or shipped accordingly. The customer is
+// emailed the tracking details in the same transaction.
+func (r *Repository) CreateShipment(orderID, actorID uint, req CreateShipmentRequest) (*Shipment, error) {
+	var shipment *Shipment
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		order, err := lockForShipping(tx, orderID)
+		if err != nil {
+			return err
+		}
+		if order.Status != StatusConfirmed && order.Status != StatusPartiallyShipped {
+			return fmt.Errorf("%w: the order is %s", ErrNotShippable, order.Status)
+		}
+
+		remaining := unshipped(order)
+		var items []ShipmentItem
+		if len(req.Items) == 0 {
+			for _, item := range order.Items {
+				if remaining[item.ID] > 0 {
+					items = append(items, ShipmentItem{OrderItemID: item.ID, Quantity: remaining[item.ID]})
+				}
+			}
+			if len(items) == 0 {
+				return ErrNotShippable
+			}
+		} else {
+			quantities := make(map[uint]int, len(req.Items))
+			for _, line := range req.Items {
+				left, ok := remaining[line.OrderItemID]
+				if !ok {
+					return fmt.Errorf("%w: item %d is not part of order %d", ErrInvalidShipment, line.OrderItemID, order.ID)
+				}
+				if _, seen := quantities[line.OrderItemID]; !seen {
+					items = append(items, ShipmentItem{OrderItemID: line.OrderItemID})
+				}
+				quantities[line.OrderItemID] += line.Quantity
+				if quantities[line.OrderItemID] > left {
+					return fmt.Errorf("%w: only %d of item %d left to ship", ErrInvalidShipment, left, line.OrderItemID)
+				}
+			}
+			for i := range items {
+				items[i].Quantity = quantities[items[i].OrderItemID]
+			}
+		}
+
+		shipment = &Shipment{
+			OrderID:        order.ID,
+			Carrier:        req.Carrier,
+			TrackingNumber: req.TrackingNumber,
+			TrackingURL:    req.TrackingURL,
+			Status:         ShipmentShipped,
+			Items:          items,
+			ActorID:        actorID,
+			ShippedAt:      time.Now(),
+		}
+		if err := tx.Create(shipment).Error; err != nil {
+			return err
+		}
+		order.Shipments = append(order.Shipments, *shipment)
+
+		if err := r.follow(tx, order, actorID, fmt.Sprintf("shipment %d sent", shipment.ID)); err != nil {
+			return err
+		}
+		return queueShipmentEmail(tx, order, shipment)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return shipment, nil
+}
+
+// DeliverShipment marks a shipment of an order delivered. The order is
+// delivered once all of its items have shipped and every shipment has
+// arrived.
+func (r *Repository) DeliverShipment(orderID, shipmentID, actorID uint) (*Shipment, error) {
+	var shipment *Shipment
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		order, err := lockForShipping(tx, orderID)
+		if err != nil {
+			return err
+		}
+		for i := range order.Shipments {
+			if order.Shipments[i].ID == shipmentID {
+				shipment = &order.Shipments[i]
+				break
+			}
+		}
+		if shipment == nil {
+			return ErrShipmentNotFound
+		}
+		if shipment.Status == ShipmentDelivered {
+			return ErrShipmentDelivered
+		}
+
+		now := time.Now()
+		if err := tx.Model(shipment).Updates(map[string]interface{}{
+			"status":       ShipmentDelivered,
+			"delivered_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		shipment.Status = ShipmentDelivered
+		shipment.DeliveredAt = &now
+
+		return r.follow(tx, order, actorID, fmt.Sprintf("shipment %d delivered", shipment.ID))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return shipment, nil
+}
+
+// lockForShipping locks an order and loads its items and shipments.
+func lockForShipping(tx *gorm.DB, orderID uint) (*Order, error) {
+	var order Order
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("Items.Product").
+		Preload("Shipments", func(db *gorm.DB) *gorm.DB {
+			return db.Order("shipped_at, id")
+		}).
+		Preload("Shipments.Items").
+		First(&order, orderID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOrderNotFound
+		}
+		return nil, err
+	}
+	return &order, nil
+}
+
+// unshipped returns the quantity of each item of an order that is in none of
+// its shipments, by order item ID.
+func unshipped(order *Order) map[uint]int {
+	remaining := make(map[uint]int, len(order.Items))
+	for _, item := range order.Items {
+		remaining[item.ID] = item.Quantity
+	}
+	for _, shipment := range order.Shipments {
+		for _, item := range shipment.Items {
+			remaining[item.OrderItemID] -= item.Quantity
+		}
+	}
+	return remaining
+}
+
+// shippingStatus derives the status of an order from its shipments:
+// partially_shipped while some items have not left, shipped once they all
+// have and delivered once every shipment has arrived.
+func shippingStatus(order *Order) OrderStatus {
+	if len(order.Shipments) == 0 {
+		return order.Status
+	}
+	for _, quantity := range unshipped(order) {
+		if quantity > 0 {
+			return StatusPartiallyShipped
+		}
+	}
+	for _, shipment := range order.Shipments {
+		if shipment.Status != ShipmentDelivered {
+			return StatusShipped
+		}
+	}
+	return StatusDelivered
+}
+
+// follow moves a locked order to the status its shipments call for. Orders
+// already past that point are left where they are.
+func (r *Repository) follow(tx *gorm.DB, order *Order, actorID uint, reason string) error {
+	to := shippingStatus(order)
+	if to == order.Status || !r.machine.CanTransition(order.Status, to) {
+		return nil
+	}
+	return r.apply(tx, order, to, actorID, reason)
+}
+
+// queueShipmentEmail tells the customer what a shipment contains and how to
+// track it. Customers whose account has been deleted are not emailed.
+func queueShipmentEmail(tx *gorm.DB, order *Order, shipment *Shipment) error {
+	var customer user.User
+	if err := tx.Select("id", "email", "name").First(&customer, order.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	lines := make(map[uint]OrderItem, len(order.Items))
+	for _, item := range order.Items {
+		lines[item.ID] = item
+	}
+	items := make([]map[string]interface{}, 0, len(shipment.Items))
+	for _, item := range shipment.Items {
+		line := lines[item.OrderItemID]
+		items = append(items, map[string]interface{}{
+			"Name":     line.Product.Name,
+			"SKU":      line.SKU,
+			"Quantity": item.Quantity,
+		})
+	}
+
+	return outbox.Enqueue(
+		tx,
+		fmt.Sprintf("Your Order #%d Has Shipped", order.ID),
+		"templates/order-shipped.mjml",
+		customer.Email,
+		outbox.TemplateData{
+			"Name":           customer.Name,
+			"OrderID":        order.ID,
+			"Carrier":        shipment.Carrier,
+			"TrackingNumber": shipment.TrackingNumber,
+			"TrackingURL":    shipment.TrackingURL,
+			"Items":          items,
+			"Partial":        order.Status == StatusPartiallyShipped,
+		},
+	)
+}
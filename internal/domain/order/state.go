@@ -0,0 +1,115 @@
+package order
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// SystemActorID is recorded as the actor of transitions made by the system
+// rather than by a user.
+const SystemActorID uint = 0
+
+// Transition identifies a move from one order status to another.
+type Transition struct {
+	From OrderStatus
+	To   OrderStatus
+}
+
+// TransitionContext carries the details of a transition being applied.
+type TransitionContext struct {
+	Transition
+	ActorID uint
+	Reason  string
+}
+
+// TransitionHook runs inside the transition's database transaction before the
+// new status is written. Returning an error aborts the transition.
+type TransitionHook func(tx *gorm.DB, order *Order, t TransitionContext) error
+
+// IllegalTransitionError is returned when a status change is not permitted
+// from the order's current status.
+type IllegalTransitionError struct {
+	From    OrderStatus
+	To      OrderStatus
+	Allowed []OrderStatus
+}
+
+func (e *IllegalTransitionError) Error() string {
+	return fmt.Sprintf("cannot move order from %s to %s", e.From, e.To)
+}
+
+// StateMachine defines the order lifecycle and the hooks guarding each move.
+type StateMachine struct {
+	transitions map[OrderStatus][]OrderStatus
+	hooks       map[Transition][]TransitionHook
+}
+
+// NewStateMachine returns a state machine with the default order lifecycle:
+// pending → confirmed → shipped → delivered, with cancellation allowed until
+// the order has shipped. An order whose items go out in several shipments is
+// partially_shipped until the last of them has left.
+func NewStateMachine() *StateMachine {
+	return &StateMachine{
+		transitions: map[OrderStatus][]OrderStatus{
+			StatusPending:          {StatusConfirmed, StatusCancelled},
+			StatusConfirmed:        {StatusPartiallyShipped, StatusShipped, StatusCancelled},
+			StatusPartiallyShipped: {StatusShipped},
+			StatusShipped:          {StatusDelivered},
+			StatusDelivered:        {},
+			StatusCancelled:        {},
+		},
+		hooks: make(map[Transition][]TransitionHook),
+	}
+}
+
+// Allowed returns the statuses an order in the given status may move to.
+func (m *StateMachine) Allowed(from OrderStatus) []OrderStatus {
+	allowed := m.transitions[from]
+	result := make([]OrderStatus, len(allowed))
+	copy(result, allowed)
+	return result
+}
+
+// CanTransition reports whether moving from one status to another is permitted.
+func (m *StateMachine) CanTransition(from, to OrderStatus) bool {
+	for _, status := range m.transitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// OnTransition registers a hook for a specific transition. Hooks run in the
+// order they were registered.
+func (m *StateMachine) OnTransition(from, to OrderStatus, hook TransitionHook) {
+	t := Transition{From: from, To: to}
+	m.hooks[t] = append(m.hooks[t], hook)
+}
+
+// Apply validates the move and runs its hooks. It does not persist the new
+// status; callers do that in the same transaction.
+func (m *StateMachine) Apply(tx *gorm.DB, order *Order, to OrderStatus, actorID uint, reason string) (TransitionContext, error) {
+	t := TransitionContext{
+		Transition: Transition{From: order.Status, To: to},
+		ActorID:    actorID,
+		Reason:     reason,
+	}
+
+	if !m.CanTransition(order.Status, to) {
+		return t, &IllegalTransitionError{
+			From:    order.Status,
+			To:      to,
+			Allowed: m.Allowed(order.Status),
+		}
+	}
+
+	for _, hook := range m.hooks[t.Transition] {
+		if err := hook(tx, order, t); err != nil {
+			return t, err
+		}
+	}
+
+	return t, nil
+}
@@ -0,0 +1,112 @@
+package order
+
+import (
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestStateMachineCanTransition(t *testing.T) {
+	machine := NewStateMachine()
+
+	tests := []struct {
+		from OrderStatus
+		to   OrderStatus
+		want bool
+	}{
+		{StatusPending, StatusConfirmed, true},
+		{StatusPending, StatusCancelled, true},
+		{StatusConfirmed, StatusPartiallyShipped, true},
+		{StatusConfirmed, StatusShipped, true},
+		{StatusConfirmed, StatusCancelled, true},
+		{StatusPartiallyShipped, StatusShipped, true},
+		{StatusShipped, StatusDelivered, true},
+
+		{StatusPending, StatusShipped, false},
+		{StatusPending, StatusDelivered, false},
+		{StatusConfirmed, StatusPending, false},
+		{StatusPartiallyShipped, StatusCancelled, false},
+		{StatusShipped, StatusCancelled, false},
+		{StatusShipped, StatusConfirmed, false},
+		{StatusDelivered, StatusCancelled, false},
+		{StatusCancelled, StatusPending, false},
+		{StatusCancelled, StatusConfirmed, false},
+		{StatusPending, StatusPending, false},
+	}
+
+	for _, tt := range tests {
+		if got := machine.CanTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransition(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestStateMachineApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    OrderStatus
+		to      OrderStatus
+		illegal bool
+	}{
+		{"confirm pending order", StatusPending, StatusConfirmed, false},
+		{"cancel confirmed order", StatusConfirmed, StatusCancelled, false},
+		{"deliver shipped order", StatusShipped, StatusDelivered, false},
+		{"ship pending order", StatusPending, StatusShipped, true},
+		{"reopen cancelled order", StatusCancelled, StatusPending, true},
+		{"cancel delivered order", StatusDelivered, StatusCancelled, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			machine := NewStateMachine()
+			ran := false
+			machine.OnTransition(tt.from, tt.to, func(tx *gorm.DB, order *Order, tc TransitionContext) error {
+				ran = true
+				return nil
+			})
+
+			order := &Order{Status: tt.from}
+			tc, err := machine.Apply(nil, order, tt.to, 7, "test")
+
+			var illegal *IllegalTransitionError
+			if tt.illegal {
+				if !errors.As(err, &illegal) {
+					t.Fatalf("Apply() error = %v, want IllegalTransitionError", err)
+				}
+				if illegal.From != tt.from || illegal.To != tt.to {
+					t.Errorf("IllegalTransitionError = %s → %s, want %s → %s", illegal.From, illegal.To, tt.from, tt.to)
+				}
+				if len(illegal.Allowed) != len(machine.Allowed(tt.from)) {
+					t.Errorf("Allowed = %v, want %v", illegal.Allowed, machine.Allowed(tt.from))
+				}
+				if ran {
+					t.Error("hook ran for an illegal transition")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if !ran {
+				t.Error("hook did not run")
+			}
+			if tc.From != tt.from || tc.To != tt.to || tc.ActorID != 7 || tc.Reason != "test" {
+				t.Errorf("TransitionContext = %+v", tc)
+			}
+		})
+	}
+}
+
+func TestStateMachineHookAbortsTransition(t *testing.T) {
+	machine := NewStateMachine()
+	errStop := errors.New("stop")
+	machine.OnTransition(StatusPending, StatusConfirmed, func(tx *gorm.DB, order *Order, tc TransitionContext) error {
+		return errStop
+	})
+
+	if _, err := machine.Apply(nil, &Order{Status: StatusPending}, StatusConfirmed, SystemActorID, ""); !errors.Is(err, errStop) {
+		t.Errorf("Apply() error = %v, want %v", err, errStop)
+	}
+}
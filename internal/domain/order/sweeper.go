@@ -0,0 +1,120 @@
+package order
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nneji123/ecommerce-golang/internal/domain/inventory"
+	"go.uber.org/zap"
+)
+
+// SweeperConfig tunes the release of expired stock reservations.
+type SweeperConfig struct {
+	Interval  time.Duration
+	BatchSize int
+}
+
+// ReservationSweeper periodically cancels pending orders whose stock
+// reservations have expired, returning the stock to sale. Several instances
+// may run at once; each order is handled under its row lock.
+type ReservationSweeper struct {
+	orders       *Repository
+	reservations *inventory.Repository
+	config       SweeperConfig
+	logger       *zap.Logger
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewReservationSweeper(orders *Repository, reservations *inventory.Repository, config SweeperConfig, logger *zap.Logger) *ReservationSweeper {
+	if config.Interval <= 0 {
+		config.Interval = time.Minute
+	}
+	if config.BatchSize < 1 {
+		config.BatchSize = 100
+	}
+
+	return &ReservationSweeper{
+		orders:       orders,
+		reservations: reservations,
+		config:       config,
+		logger:       logger,
+	}
+}
+
+// Start launches the sweeper. It runs until Stop is called.
+func (s *ReservationSweeper) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	s.wg.Add(1)
+	go s.run(ctx)
+}
+
+// Stop signals the sweeper to finish and waits for the current sweep to
+// complete, or for ctx to expire.
+func (s *ReservationSweeper) Stop(ctx context.Context) error {
+	if s.cancel == nil {
+		return nil
+	}
+	s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("reservation sweeper did not stop in time: %w", ctx.Err())
+	}
+}
+
+func (s *ReservationSweeper) run(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil && s.sweep() {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sweep expires one batch of orders and reports whether a full batch was
+// found, in which case more may be waiting.
+func (s *ReservationSweeper) sweep() bool {
+	orderIDs, err := s.reservations.ExpiredOrders(s.config.BatchSize)
+	if err != nil {
+		s.logger.Error("Failed to find expired reservations", zap.Error(err))
+		return false
+	}
+
+	failed := 0
+	for _, orderID := range orderIDs {
+		cancelled, err := s.orders.ExpireReservations(orderID)
+		if err != nil {
+			failed++
+			s.logger.Error("Failed to expire reservations", zap.Error(err), zap.Uint("order_id", orderID))
+			continue
+		}
+		if cancelled {
+			s.logger.Info("Cancelled order with expired reservation", zap.Uint("order_id", orderID))
+		}
+	}
+	// Stop early when every order failed so a persistent error does not spin.
+	return len(orderIDs) == s.config.BatchSize && failed < len(orderIDs)
+}
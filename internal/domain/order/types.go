@@ -1,7 +1,11 @@
 package order
 
 import (
+	"github.com/nneji123/ecommerce-golang/internal/common/geo"
+	"github.com/nneji123/ecommerce-golang/internal/common/money"
 	"github.com/nneji123/ecommerce-golang/internal/domain/product"
+	"github.com/nneji123/ecommerce-golang/internal/domain/promotion"
+	"github.com/nneji123/ecommerce-golang/internal/domain/tax"
 	"gorm.io/gorm"
 	"time"
 )
@@ -11,27 +15,203 @@ type OrderStatus string
 const (
 	StatusPending   OrderStatus = "pending"
 	StatusConfirmed OrderStatus = "confirmed"
-	StatusShipped   OrderStatus = "shipped"
-	StatusDelivered OrderStatus = "delivered"
-	StatusCancelled OrderStatus = "cancelled"
+	// StatusPartiallyShipped orders have some, but not all, of their items
+	// in shipments.
+	StatusPartiallyShipped OrderStatus = "partially_shipped"
+	StatusShipped          OrderStatus = "shipped"
+	StatusDelivered        OrderStatus = "delivered"
+	StatusCancelled        OrderStatus = "cancelled"
 )
 
+// Order is a customer's purchase. While it is pending its items are reserved
+// until ReservedUntil; a pending order still unpaid by then is cancelled.
+// TotalAmount is the Subtotal of the items less the DiscountTotal of the
+// promotions applied, which are listed in Discounts, plus the ShippingCost of
+// the chosen shipping method and the TaxTotal of the TaxLines unless
+// PricesIncludeTax, in which case the prices already included it. Shipping
+// is not taxed. ShippingMethod is the method's name when the order was
+// placed; ShippingMethodID is cleared if the method is deleted.
+// ShippingAddress and BillingAddress are copies of the customer's addresses
+// taken when the order was placed, so later edits to the address book leave
+// them alone. Shipments lists the parcels the items were sent in; once an
+// order is confirmed its status follows them.
 type Order struct {
+	ID               uint            `gorm:"primaryKey" json:"id"`
+	UserID           uint            `gorm:"not null" json:"user_id"`
+	Status           OrderStatus     `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
+	Subtotal         money.Money     `gorm:"embedded;embeddedPrefix:subtotal_" json:"subtotal"`
+	DiscountTotal    money.Money     `gorm:"embedded;embeddedPrefix:discount_total_" json:"discount_total"`
+	TaxTotal         money.Money     `gorm:"embedded;embeddedPrefix:tax_total_" json:"tax_total"`
+	PricesIncludeTax bool            `gorm:"not null;default:false" json:"prices_include_tax"`
+	ShippingMethodID *uint           `json:"shipping_method_id,omitempty"`
+	ShippingMethod   string          `gorm:"size:255;not null" json:"shipping_method,omitempty"`
+	ShippingCost     money.Money     `gorm:"embedded;embeddedPrefix:shipping_cost_" json:"shipping_cost"`
+	ShippingAddress  *geo.Address    `gorm:"embedded;embeddedPrefix:shipping_address_" json:"shipping_address,omitempty"`
+	BillingAddress   *geo.Address    `gorm:"embedded;embeddedPrefix:billing_address_" json:"billing_address,omitempty"`
+	TotalAmount      money.Money     `gorm:"embedded;embeddedPrefix:total_" json:"total_amount"`
+	Items            []OrderItem     `json:"items"`
+	Discounts        []OrderDiscount `json:"discounts"`
+	TaxLines         []OrderTaxLine  `json:"tax_lines"`
+	Shipments        []Shipment      `json:"shipments"`
+	ReservedUntil    *time.Time      `json:"reserved_until,omitempty"`
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt  `gorm:"index" json:"-"`
+}
+
+// OrderItem is a line of an order. VariantID and SKU are set when the
+// product is sold in variants; the SKU is a snapshot taken at checkout.
+// Discount is the part of the order's discounts taken off the line and Tax
+// the tax levied on what remains.
+type OrderItem struct {
+	ID        uint                    `gorm:"primaryKey" json:"id"`
+	OrderID   uint                    `gorm:"not null" json:"order_id"`
+	ProductID uint                    `gorm:"not null" json:"product_id"`
+	Product   product.Product         `json:"product"`
+	VariantID *uint                   `gorm:"index" json:"variant_id,omitempty"`
+	Variant   *product.ProductVariant `json:"variant,omitempty"`
+	SKU       string                  `gorm:"column:sku;size:100" json:"sku,omitempty"`
+	Quantity  int                     `gorm:"not null" json:"quantity"`
+	Price     money.Money             `gorm:"embedded;embeddedPrefix:price_" json:"price"`
+	Discount  money.Money             `gorm:"embedded;embeddedPrefix:discount_" json:"discount"`
+	Tax       money.Money             `gorm:"embedded;embeddedPrefix:tax_" json:"tax"`
+}
+
+// OrderDiscount is a promotion applied to an order, recorded as it was when
+// the order was placed. PromotionID is cleared if the promotion is deleted.
+// The Amount of a free shipping discount is the shipping cost it waives.
+type OrderDiscount struct {
 	ID          uint           `gorm:"primaryKey" json:"id"`
-	UserID      uint           `gorm:"not null" json:"user_id"`
-	Status      OrderStatus    `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
-	TotalAmount float64        `gorm:"not null" json:"total_amount"`
-	Items       []OrderItem    `json:"items"`
+	OrderID     uint           `gorm:"not null;index" json:"order_id"`
+	PromotionID *uint          `json:"promotion_id,omitempty"`
+	Code        string         `gorm:"size:50" json:"code,omitempty"`
+	Name        string         `gorm:"size:255;not null" json:"name"`
+	Type        promotion.Type `gorm:"type:varchar(20);not null" json:"type"`
+	Amount      money.Money    `gorm:"embedded" json:"amount"`
 	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
-type OrderItem struct {
-	ID        uint            `gorm:"primaryKey" json:"id"`
-	OrderID   uint            `gorm:"not null" json:"order_id"`
-	ProductID uint            `gorm:"not null" json:"product_id"`
-	Product   product.Product `json:"product"`
-	Quantity  int             `gorm:"not null" json:"quantity"`
-	Price     float64         `gorm:"not null" json:"price"`
+// OrderTaxLine is the tax levied on an order at one rate, recorded as it was
+// when the order was placed. TaxRateID is cleared if the rate is deleted.
+// Taxable is the amount the tax was levied on, excluding tax.
+type OrderTaxLine struct {
+	ID        uint        `gorm:"primaryKey" json:"id"`
+	OrderID   uint        `gorm:"not null;index" json:"order_id"`
+	TaxRateID *uint       `json:"tax_rate_id,omitempty"`
+	Name      string      `gorm:"size:100;not null" json:"name"`
+	Rate      tax.Percent `gorm:"not null" json:"rate"`
+	Taxable   money.Money `gorm:"embedded;embeddedPrefix:taxable_" json:"taxable"`
+	Amount    money.Money `gorm:"embedded" json:"amount"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+type ShipmentStatus string
+
+const (
+	ShipmentShipped   ShipmentStatus = "shipped"
+	ShipmentDelivered ShipmentStatus = "delivered"
+)
+
+// Shipment is a parcel sent with some of an order's items. Items may cover
+// only part of an order line; the rest follows in later shipments.
+// TrackingURL, when given, is where the customer can follow the parcel.
+type Shipment struct {
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	OrderID        uint           `gorm:"not null;index" json:"order_id"`
+	Carrier        string         `gorm:"size:100;not null" json:"carrier"`
+	TrackingNumber string         `gorm:"size:100" json:"tracking_number,omitempty"`
+	TrackingURL    string         `gorm:"column:tracking_url;size:500" json:"tracking_url,omitempty"`
+	Status         ShipmentStatus `gorm:"type:varchar(20);not null;default:'shipped'" json:"status"`
+	Items          []ShipmentItem `json:"items"`
+	ActorID        uint           `gorm:"not null" json:"actor_id"`
+	ShippedAt      time.Time      `gorm:"not null" json:"shipped_at"`
+	DeliveredAt    *time.Time     `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+}
+
+// ShipmentItem is the quantity of an order line sent in a shipment.
+type ShipmentItem struct {
+	ID          uint `gorm:"primaryKey" json:"id"`
+	ShipmentID  uint `gorm:"not null;index" json:"shipment_id"`
+	OrderItemID uint `gorm:"not null" json:"order_item_id"`
+	Quantity    int  `gorm:"not null" json:"quantity"`
+}
+
+type ShipmentItemRequest struct {
+	OrderItemID uint `json:"order_item_id" validate:"required"`
+	Quantity    int  `json:"quantity" validate:"required,gt=0"`
+}
+
+// CreateShipmentRequest sends Items of an order, or everything not shipped
+// yet when Items is empty.
+type CreateShipmentRequest struct {
+	Carrier        string                `json:"carrier" validate:"required,max=100"`
+	TrackingNumber string                `json:"tracking_number" validate:"max=100"`
+	TrackingURL    string                `json:"tracking_url" validate:"omitempty,url,max=500"`
+	Items          []ShipmentItemRequest `json:"items" validate:"dive"`
+}
+
+// CheckoutItem requests a quantity of a product. VariantID is required for
+// products that have variants and must be omitted otherwise.
+type CheckoutItem struct {
+	ProductID uint  `json:"product_id" validate:"required"`
+	VariantID *uint `json:"variant_id,omitempty"`
+	Quantity  int   `json:"quantity" validate:"required,gt=0"`
+}
+
+// CreateOrderRequest places an order for Items. CouponCodes are applied
+// together with the automatic promotions the order is eligible for.
+// ShippingAddressID and BillingAddressID pick addresses from the customer's
+// address book, defaulting to the customer's default addresses; billing
+// falls back to the shipping address. The order is shipped to Destination,
+// or else to the shipping address, with ShippingMethodID, if given, and
+// taxed there, or at the store's default tax location when neither is
+// known.
+type CreateOrderRequest struct {
+	Items             []CheckoutItem `json:"items" validate:"required,min=1,dive"`
+	CouponCodes       []string       `json:"coupon_codes" validate:"max=5,dive,required,max=50"`
+	ShippingAddressID *uint          `json:"shipping_address_id"`
+	BillingAddressID  *uint          `json:"billing_address_id"`
+	Destination       *geo.Location  `json:"destination"`
+	ShippingMethodID  *uint          `json:"shipping_method_id"`
+}
+
+type StockConflict struct {
+	ProductID uint  `json:"product_id"`
+	VariantID *uint `json:"variant_id,omitempty"`
+	Requested int   `json:"requested"`
+	Available int   `json:"available"`
+}
+
+type OutOfStockResponse struct {
+	Error string          `json:"error"`
+	Items []StockConflict `json:"items"`
+}
+
+// OrderStatusHistory records a single status change of an order.
+type OrderStatusHistory struct {
+	ID         uint        `gorm:"primaryKey" json:"id"`
+	OrderID    uint        `gorm:"not null;index" json:"order_id"`
+	FromStatus OrderStatus `gorm:"type:varchar(20)" json:"from_status"`
+	ToStatus   OrderStatus `gorm:"type:varchar(20);not null" json:"to_status"`
+	ActorID    uint        `gorm:"not null" json:"actor_id"`
+	Reason     string      `gorm:"type:text" json:"reason"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+type UpdateStatusRequest struct {
+	Status OrderStatus `json:"status"`
+	Reason string      `json:"reason"`
+}
+
+type CancelOrderRequest struct {
+	Reason string `json:"reason"`
+}
+
+type IllegalTransitionResponse struct {
+	Error   string        `json:"error"`
+	From    OrderStatus   `json:"from"`
+	To      OrderStatus   `json:"to"`
+	Allowed []OrderStatus `json:"allowed"`
 }
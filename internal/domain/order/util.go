@@ -1,24 +1,48 @@
 package order
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+
+	"github.com/nneji123/ecommerce-golang/internal/common/money"
+)
 
 func IsValidOrderStatus(status OrderStatus) bool {
 	validStatuses := map[OrderStatus]bool{
-		StatusPending:   true,
-		StatusConfirmed: true,
-		StatusShipped:   true,
-		StatusDelivered: true,
-		StatusCancelled: true,
+		StatusPending:          true,
+		StatusConfirmed:        true,
+		StatusPartiallyShipped: true,
+		StatusShipped:          true,
+		StatusDelivered:        true,
+		StatusCancelled:        true,
 	}
 	return validStatuses[status]
 }
 
-func CalculateOrderTotal(items []OrderItem) float64 {
-	var total float64
+// IsShipmentStatus reports whether a status is derived from the shipments of
+// an order rather than set by hand.
+func IsShipmentStatus(status OrderStatus) bool {
+	switch status {
+	case StatusPartiallyShipped, StatusShipped, StatusDelivered:
+		return true
+	}
+	return false
+}
+
+// CalculateOrderTotal sums the lines of an order in the currency of its
+// first item. Every item must be priced in that currency.
+func CalculateOrderTotal(items []OrderItem) (money.Money, error) {
+	if len(items) == 0 {
+		return money.Zero(money.DefaultCurrency()), nil
+	}
+	total := money.Zero(items[0].Price.Currency)
 	for _, item := range items {
-		total += item.Price * float64(item.Quantity)
+		var err error
+		if total, err = total.Add(item.Price.Mul(int64(item.Quantity))); err != nil {
+			return money.Money{}, fmt.Errorf("%w: %v", ErrMixedCurrencies, err)
+		}
 	}
-	return total
+	return total, nil
 }
 
 func ValidateOrder(order *Order) error {
@@ -30,7 +54,7 @@ func ValidateOrder(order *Order) error {
 		if item.Quantity <= 0 {
 			return errors.New("item quantity must be greater than 0")
 		}
-		if item.Price <= 0 {
+		if !item.Price.IsPositive() {
 			return errors.New("item price must be greater than 0")
 		}
 	}
@@ -0,0 +1,183 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nneji123/ecommerce-golang/internal/common/email"
+	"go.uber.org/zap"
+)
+
+// DispatcherConfig tunes the background delivery of outbox messages.
+type DispatcherConfig struct {
+	Workers      int
+	PollInterval time.Duration
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	StaleAfter   time.Duration
+}
+
+// Dispatcher delivers outbox messages with a pool of workers, retrying
+// failures with exponential backoff until MaxAttempts is reached.
+type Dispatcher struct {
+	repo   *Repository
+	sender email.EmailService
+	config DispatcherConfig
+	logger *zap.Logger
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewDispatcher(repo *Repository, sender email.EmailService, config DispatcherConfig, logger *zap.Logger) *Dispatcher {
+	if config.Workers < 1 {
+		config.Workers = 1
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = 2 * time.Second
+	}
+	if config.MaxAttempts < 1 {
+		config.MaxAttempts = 5
+	}
+	if config.BaseBackoff <= 0 {
+		config.BaseBackoff = 30 * time.Second
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = time.Hour
+	}
+	if config.StaleAfter <= 0 {
+		config.StaleAfter = 5 * time.Minute
+	}
+
+	return &Dispatcher{
+		repo:   repo,
+		sender: sender,
+		config: config,
+		logger: logger,
+	}
+}
+
+// Start launches the workers. They run until Stop is called.
+func (d *Dispatcher) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+
+	for i := 0; i < d.config.Workers; i++ {
+		d.wg.Add(1)
+		go d.work(ctx)
+	}
+}
+
+// Stop signals the workers to finish and waits for in-flight sends to
+// complete, or for ctx to expire.
+func (d *Dispatcher) Stop(ctx context.Context) error {
+	if d.cancel == nil {
+		return nil
+	}
+	d.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("email dispatcher did not stop in time: %w", ctx.Err())
+	}
+}
+
+func (d *Dispatcher) work(ctx context.Context) {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Drain everything that is due before waiting for the next tick.
+		for ctx.Err() == nil && d.processNext() {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// processNext delivers one due message and reports whether there was one.
+func (d *Dispatcher) processNext() bool {
+	message, err := d.repo.Claim(d.config.StaleAfter)
+	if err != nil {
+		d.logger.Error("Failed to claim outbox message", zap.Error(err))
+		return false
+	}
+	if message == nil {
+		return false
+	}
+
+	sendErr := d.sender.SendEmail(message.Subject, message.Template, message.Recipient, message.Data, nil)
+	if sendErr == nil {
+		if err := d.repo.MarkSent(message); err != nil {
+			d.logger.Error("Failed to mark outbox message sent", zap.Error(err), zap.Uint("message_id", message.ID))
+		}
+		return true
+	}
+
+	attempts := message.Attempts + 1
+	dead := attempts >= d.config.MaxAttempts || !isTemporary(sendErr)
+	delay := d.backoff(attempts)
+	var sgErr *email.SendGridError
+	if errors.As(sendErr, &sgErr) && sgErr.RetryAfter > delay {
+		delay = sgErr.RetryAfter
+	}
+	nextAttempt := time.Now().Add(delay)
+	if err := d.repo.MarkFailed(message, sendErr, nextAttempt, dead); err != nil {
+		d.logger.Error("Failed to record outbox failure", zap.Error(err), zap.Uint("message_id", message.ID))
+	}
+
+	if dead {
+		d.logger.Error("Outbox message moved to dead letter",
+			zap.Error(sendErr),
+			zap.Uint("message_id", message.ID),
+			zap.Int("attempts", attempts))
+	} else {
+		d.logger.Warn("Outbox message delivery failed; will retry",
+			zap.Error(sendErr),
+			zap.Uint("message_id", message.ID),
+			zap.Int("attempts", attempts),
+			zap.Time("next_attempt_at", nextAttempt))
+	}
+	return true
+}
+
+// isTemporary reports whether a delivery error is worth retrying. Errors that
+// do not say otherwise are assumed to be transient.
+func isTemporary(err error) bool {
+	var temporary interface{ Temporary() bool }
+	if errors.As(err, &temporary) {
+		return temporary.Temporary()
+	}
+	return true
+}
+
+// backoff returns BaseBackoff doubled for every previous attempt, capped at
+// MaxBackoff.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.config.BaseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= d.config.MaxBackoff {
+			return d.config.MaxBackoff
+		}
+	}
+	return delay
+}
@@ -0,0 +1,94 @@
+package outbox
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nneji123/ecommerce-golang/internal/middleware"
+	"go.uber.org/zap"
+)
+
+type Handler struct {
+	repo   *Repository
+	logger *zap.Logger
+}
+
+func NewHandler(repo *Repository, logger *zap.Logger) *Handler {
+	return &Handler{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// @Summary		List outbox emails
+// @Description	List queued, sent and failed emails (requires emails:manage). Filter by status=dead to see failed messages.
+// @Tags			emails
+// @Produce		json
+// @Param			status	query		string	false	"Status (pending, processing, sent, dead)"
+// @Param			page	query		int		false	"Page number"
+// @Param			limit	query		int		false	"Items per page"
+// @Success		200		{object}	map[string]interface{}
+// @Failure		400		{object}	middleware.ErrorResponse
+// @Router			/emails [get]
+func (h *Handler) List(c echo.Context) error {
+	status := MessageStatus(c.QueryParam("status"))
+	switch status {
+	case "", StatusPending, StatusProcessing, StatusSent, StatusDead:
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid status")
+	}
+
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	messages, total, err := h.repo.List(status, page, limit)
+	if err != nil {
+		h.logger.Error("Failed to list outbox messages", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list emails")
+	}
+
+	pagination := middleware.OffsetPage(page, limit, total)
+	middleware.SetLinkHeader(c, pagination)
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"emails":     messages,
+		"pagination": pagination,
+	})
+}
+
+// @Summary		Requeue email
+// @Description	Send a dead-lettered email again (requires emails:manage)
+// @Tags			emails
+// @Produce		json
+// @Param			id	path		int	true	"Message ID"
+// @Success		200	{object}	Message
+// @Failure		404	{object}	middleware.ErrorResponse
+// @Failure		409	{object}	middleware.ErrorResponse
+// @Router			/emails/{id}/requeue [post]
+func (h *Handler) Requeue(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid message ID")
+	}
+
+	message, err := h.repo.Requeue(uint(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrMessageNotFound):
+			return echo.NewHTTPError(http.StatusNotFound, "Message not found")
+		case errors.Is(err, ErrNotDead):
+			return echo.NewHTTPError(http.StatusConflict, "Only dead messages can be requeued")
+		}
+		h.logger.Error("Failed to requeue outbox message", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to requeue email")
+	}
+
+	return c.JSON(http.StatusOK, message)
+}
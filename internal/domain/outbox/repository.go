@@ -0,0 +1,141 @@
+package outbox
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrMessageNotFound = errors.New("message not found")
+	ErrNotDead         = errors.New("only dead messages can be requeued")
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// Enqueue stores an email for delivery. Pass the transaction of the business
+// change so the email is only sent if that change commits.
+func Enqueue(tx *gorm.DB, subject, template, recipient string, data TemplateData) error {
+	return tx.Create(&Message{
+		Subject:       subject,
+		Template:      template,
+		Recipient:     recipient,
+		Data:          data,
+		Status:        StatusPending,
+		NextAttemptAt: time.Now(),
+	}).Error
+}
+
+// Claim locks the next message that is due and marks it as processing.
+// Messages stuck in processing for longer than staleAfter, e.g. because a
+// worker crashed mid-send, are claimed again. It returns nil when nothing is
+// due.
+func (r *Repository) Claim(staleAfter time.Duration) (*Message, error) {
+	var message Message
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status = ? AND next_attempt_at <= ?) OR (status = ? AND locked_at < ?)",
+				StatusPending, now, StatusProcessing, now.Add(-staleAfter)).
+			Order("next_attempt_at").
+			First(&message).Error
+		if err != nil {
+			return err
+		}
+
+		message.Status = StatusProcessing
+		message.LockedAt = &now
+		return tx.Model(&message).Updates(map[string]interface{}{
+			"status":    StatusProcessing,
+			"locked_at": now,
+		}).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &message, nil
+}
+
+func (r *Repository) MarkSent(message *Message) error {
+	now := time.Now()
+	return r.db.Model(message).Updates(map[string]interface{}{
+		"status":     StatusSent,
+		"attempts":   message.Attempts + 1,
+		"sent_at":    now,
+		"locked_at":  nil,
+		"last_error": "",
+	}).Error
+}
+
+// MarkFailed records a failed attempt, scheduling a retry at nextAttempt or
+// moving the message to the dead-letter state when dead is true.
+func (r *Repository) MarkFailed(message *Message, sendErr error, nextAttempt time.Time, dead bool) error {
+	status := StatusPending
+	if dead {
+		status = StatusDead
+	}
+	return r.db.Model(message).Updates(map[string]interface{}{
+		"status":          status,
+		"attempts":        message.Attempts + 1,
+		"next_attempt_at": nextAttempt,
+		"locked_at":       nil,
+		"last_error":      sendErr.Error(),
+	}).Error
+}
+
+func (r *Repository) List(status MessageStatus, page, limit int) ([]Message, int64, error) {
+	var messages []Message
+	var total int64
+
+	query := r.db.Model(&Message{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	err := query.Order("updated_at DESC").Offset(offset).Limit(limit).Find(&messages).Error
+	return messages, total, err
+}
+
+// Requeue resets a dead message so the dispatcher tries it again.
+func (r *Repository) Requeue(id uint) (*Message, error) {
+	var message Message
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&message, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrMessageNotFound
+			}
+			return err
+		}
+		if message.Status != StatusDead {
+			return ErrNotDead
+		}
+
+		message.Status = StatusPending
+		message.Attempts = 0
+		message.NextAttemptAt = time.Now()
+		return tx.Model(&message).Updates(map[string]interface{}{
+			"status":          message.Status,
+			"attempts":        message.Attempts,
+			"next_attempt_at": message.NextAttemptAt,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
@@ -0,0 +1,26 @@
+package outbox
+
+import (
+	"log"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nneji123/ecommerce-golang/internal/common/revocation"
+	"github.com/nneji123/ecommerce-golang/internal/config"
+	"github.com/nneji123/ecommerce-golang/internal/domain/rbac"
+	"github.com/nneji123/ecommerce-golang/internal/middleware"
+)
+
+func RegisterRoutes(e *echo.Echo, h *Handler, revocations revocation.Store, permissions middleware.PermissionResolver) {
+	emails := e.Group("/emails")
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Error loading configuration: %s", err)
+	}
+
+	emails.Use(middleware.AuthMiddleware(cfg.JWTSecret, revocations))
+	emails.Use(middleware.RequirePermission(permissions, rbac.PermissionEmailsManage))
+
+	emails.GET("", h.List)
+	emails.POST("/:id/requeue", h.Requeue)
+}
@@ -0,0 +1,65 @@
+package outbox
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+type MessageStatus string
+
+const (
+	StatusPending    MessageStatus = "pending"
+	StatusProcessing MessageStatus = "processing"
+	StatusSent       MessageStatus = "sent"
+	StatusDead       MessageStatus = "dead"
+)
+
+// TemplateData is the context passed to email.RenderTemplate, stored as JSON.
+type TemplateData map[string]interface{}
+
+func (d TemplateData) Value() (driver.Value, error) {
+	if d == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(d)
+	return string(b), err
+}
+
+func (d *TemplateData) Scan(value interface{}) error {
+	var b []byte
+	switch v := value.(type) {
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	case nil:
+		*d = TemplateData{}
+		return nil
+	default:
+		return errors.New("unsupported type for TemplateData")
+	}
+	return json.Unmarshal(b, d)
+}
+
+// Message is an email waiting to be delivered by the background dispatcher.
+type Message struct {
+	ID            uint          `gorm:"primaryKey" json:"id"`
+	Subject       string        `gorm:"size:255;not null" json:"subject"`
+	Template      string        `gorm:"size:255;not null" json:"template"`
+	Recipient     string        `gorm:"size:255;not null" json:"recipient"`
+	Data          TemplateData  `gorm:"type:jsonb;not null" json:"-"`
+	Status        MessageStatus `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
+	Attempts      int           `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt time.Time     `gorm:"not null" json:"next_attempt_at"`
+	LockedAt      *time.Time    `json:"-"`
+	LastError     string        `gorm:"type:text" json:"last_error,omitempty"`
+	SentAt        *time.Time    `json:"sent_at,omitempty"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+}
+
+func (Message) TableName() string {
+	return "email_outbox"
+}
@@ -0,0 +1,243 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/nneji123/ecommerce-golang/internal/common/money"
+)
+
+// Test card numbers recognised by the fake provider. Any other card succeeds.
+const (
+	FakeCardDeclined       = "4000000000000002"
+	FakeCardRequires3DS    = "4000000000003220"
+	FakeCardFailsOnCapture = "4000000000000341"
+)
+
+const FakeProviderName = "fake"
+
+// FakeProvider simulates a payment gateway in memory so the payment flow can be
+// exercised locally without network access. Webhooks are signed with
+// HMAC-SHA256 over the raw payload using the configured secret.
+type FakeProvider struct {
+	secret   string
+	mu       sync.Mutex
+	payments map[string]*fakePayment
+}
+
+type fakePayment struct {
+	amount         money.Money
+	status         PaymentStatus
+	failOnCapture  bool
+	refundedAmount money.Money
+}
+
+type fakeWebhook struct {
+	ID          string      `json:"id"`
+	Type        string      `json:"type"`
+	ProviderRef string      `json:"provider_ref"`
+	Amount      money.Money `json:"amount"`
+	RefundRef   string      `json:"refund_ref,omitempty"`
+}
+
+// NewFakeProvider returns a fake provider signing its webhooks with secret.
+// An empty secret is refused: anyone could forge webhooks signed with it.
+func NewFakeProvider(secret string) (*FakeProvider, error) {
+	if secret == "" {
+		return nil, ErrMissingWebhookSecret
+	}
+	return &FakeProvider{
+		secret:   secret,
+		payments: make(map[string]*fakePayment),
+	}, nil
+}
+
+func (p *FakeProvider) Name() string {
+	return FakeProviderName
+}
+
+func (p *FakeProvider) Authorize(_ context.Context, req AuthorizeRequest) (*ProviderResult, error) {
+	reference, err := randomID("fake_pay_")
+	if err != nil {
+		return nil, err
+	}
+
+	payment := &fakePayment{
+		amount:         req.Amount,
+		refundedAmount: money.Zero(req.Amount.Currency),
+		status:         StatusAuthorized,
+		failOnCapture:  req.CardNumber == FakeCardFailsOnCapture,
+	}
+	result := &ProviderResult{Reference: reference, Status: StatusAuthorized}
+
+	switch req.CardNumber {
+	case FakeCardDeclined:
+		payment.status = StatusDeclined
+		result.Status = StatusDeclined
+		result.Message = "card declined"
+	case FakeCardRequires3DS:
+		payment.status = StatusRequiresAction
+		result.Status = StatusRequiresAction
+		result.NextActionURL = "https://fake-gateway.local/3ds/" + reference
+		result.Message = "3D Secure authentication required"
+	}
+
+	p.mu.Lock()
+	p.payments[reference] = payment
+	p.mu.Unlock()
+
+	return result, nil
+}
+
+func (p *FakeProvider) Capture(_ context.Context, reference string, amount money.Money) (*ProviderResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	payment, ok := p.payments[reference]
+	if !ok {
+		return nil, fmt.Errorf("fake provider: unknown payment %s", reference)
+	}
+	if payment.status != StatusAuthorized {
+		return nil, fmt.Errorf("fake provider: cannot capture payment in status %s", payment.status)
+	}
+	if cmp, err := amount.Cmp(payment.amount); err != nil || cmp > 0 {
+		return nil, fmt.Errorf("fake provider: capture amount exceeds authorized amount")
+	}
+	if payment.failOnCapture {
+		payment.status = StatusFailed
+		return &ProviderResult{Reference: reference, Status: StatusFailed, Message: "capture failed"}, nil
+	}
+
+	payment.status = StatusCaptured
+	return &ProviderResult{Reference: reference, Status: StatusCaptured}, nil
+}
+
+func (p *FakeProvider) Void(_ context.Context, reference string) (*ProviderResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	payment, ok := p.payments[reference]
+	if !ok {
+		return nil, fmt.Errorf("fake provider: unknown payment %s", reference)
+	}
+	if payment.status != StatusAuthorized && payment.status != StatusRequiresAction {
+		return nil, fmt.Errorf("fake provider: cannot void payment in status %s", payment.status)
+	}
+
+	payment.status = StatusVoided
+	return &ProviderResult{Reference: reference, Status: StatusVoided}, nil
+}
+
+func (p *FakeProvider) Refund(_ context.Context, reference string, amount money.Money) (*ProviderResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	payment, ok := p.payments[reference]
+	if !ok {
+		return nil, fmt.Errorf("fake provider: unknown payment %s", reference)
+	}
+	if payment.status != StatusCaptured {
+		return nil, fmt.Errorf("fake provider: cannot refund payment in status %s", payment.status)
+	}
+	refunded, err := payment.refundedAmount.Add(amount)
+	if err != nil {
+		return nil, fmt.Errorf("fake provider: %w", err)
+	}
+	if refunded.Amount > payment.amount.Amount {
+		return nil, fmt.Errorf("fake provider: refund exceeds captured amount")
+	}
+	refundRef, err := randomID("fake_re_")
+	if err != nil {
+		return nil, err
+	}
+
+	payment.refundedAmount = refunded
+	status := StatusCaptured
+	if payment.refundedAmount.Amount >= payment.amount.Amount {
+		status = StatusRefunded
+		payment.status = StatusRefunded
+	}
+	return &ProviderResult{Reference: reference, Status: status, RefundRef: refundRef}, nil
+}
+
+func (p *FakeProvider) ParseWebhook(payload []byte, signature string) (*ProviderEvent, error) {
+	expected, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, p.mac(payload)) {
+		return nil, ErrInvalidSignature
+	}
+
+	var webhook fakeWebhook
+	if err := json.Unmarshal(payload, &webhook); err != nil {
+		return nil, fmt.Errorf("fake provider: invalid webhook payload: %w", err)
+	}
+	if webhook.ID == "" || webhook.Type == "" || webhook.ProviderRef == "" {
+		return nil, fmt.Errorf("fake provider: webhook is missing id, type or provider_ref")
+	}
+	if webhook.Type == EventRefunded && webhook.RefundRef == "" {
+		return nil, fmt.Errorf("fake provider: refund webhook is missing refund_ref")
+	}
+
+	return &ProviderEvent{
+		ID:          webhook.ID,
+		Type:        webhook.Type,
+		ProviderRef: webhook.ProviderRef,
+		Amount:      webhook.Amount,
+		RefundRef:   webhook.RefundRef,
+	}, nil
+}
+
+// Complete3DS simulates the customer passing 3D Secure for a payment and
+// returns the signed payment.authorized webhook the gateway would send.
+func (p *FakeProvider) Complete3DS(reference string) (payload []byte, signature string, err error) {
+	p.mu.Lock()
+	payment, ok := p.payments[reference]
+	if ok && payment.status == StatusRequiresAction {
+		payment.status = StatusAuthorized
+	}
+	p.mu.Unlock()
+
+	if !ok {
+		return nil, "", fmt.Errorf("fake provider: unknown payment %s", reference)
+	}
+
+	eventID, err := randomID("fake_evt_")
+	if err != nil {
+		return nil, "", err
+	}
+	payload, err = json.Marshal(fakeWebhook{
+		ID:          eventID,
+		Type:        EventAuthorized,
+		ProviderRef: reference,
+		Amount:      payment.amount,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return payload, p.Sign(payload), nil
+}
+
+// Sign returns the hex-encoded signature the fake provider expects for
+// payload, so local tooling can craft webhooks.
+func (p *FakeProvider) Sign(payload []byte) string {
+	return hex.EncodeToString(p.mac(payload))
+}
+
+func (p *FakeProvider) mac(payload []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(p.secret))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func randomID(prefix string) (string, error) {
+	bytes := make([]byte, 12)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(bytes), nil
+}
@@ -0,0 +1,375 @@
+package payment
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/nneji123/ecommerce-golang/internal/common/models"
+	"github.com/nneji123/ecommerce-golang/internal/domain/order"
+	"github.com/nneji123/ecommerce-golang/internal/domain/rbac"
+	"github.com/nneji123/ecommerce-golang/internal/middleware"
+	"go.uber.org/zap"
+)
+
+// SignatureHeader carries the provider's HMAC signature of a webhook payload.
+const SignatureHeader = "X-Webhook-Signature"
+
+type Handler struct {
+	repo      *Repository
+	orders    *order.Repository
+	providers Providers
+	resolver  middleware.PermissionResolver
+	validator *validator.Validate
+	logger    *zap.Logger
+}
+
+func NewHandler(repo *Repository, orders *order.Repository, providers Providers, resolver middleware.PermissionResolver, validator *validator.Validate, logger *zap.Logger) *Handler {
+	return &Handler{
+		repo:      repo,
+		orders:    orders,
+		providers: providers,
+		resolver:  resolver,
+		validator: validator,
+		logger:    logger,
+	}
+}
+
+// @Summary		Create payment
+// @Description	Authorize a payment for a pending order. An order has at most one payment that has not failed, been declined or been voided.
+// @Tags			payments
+// @Accept			json
+// @Produce		json
+// @Param			request	body		CreatePaymentRequest	true	"Order and card details"
+// @Success		201		{object}	Payment
+// @Failure		400		{object}	middleware.ErrorResponse
+// @Failure		402		{object}	Payment
+// @Failure		404		{object}	middleware.ErrorResponse
+// @Failure		409		{object}	middleware.ErrorResponse
+// @Router			/payments [post]
+func (h *Handler) Create(c echo.Context) error {
+	var req CreatePaymentRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	provider, err := h.providers.Get(req.Provider)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Unknown payment provider")
+	}
+
+	o, err := h.orders.GetByID(req.OrderID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Order not found")
+	}
+
+	claims := c.Get("userClaims").(*models.Claims)
+	if o.UserID != claims.UserID {
+		return echo.NewHTTPError(http.StatusForbidden, "Not authorized to pay for this order")
+	}
+
+	payment, err := h.repo.Start(o.ID, provider.Name(), req.CardNumber[len(req.CardNumber)-4:])
+	if err != nil {
+		switch {
+		case errors.Is(err, order.ErrOrderNotFound):
+			return echo.NewHTTPError(http.StatusNotFound, "Order not found")
+		case errors.Is(err, ErrOrderNotPayable):
+			return echo.NewHTTPError(http.StatusConflict, "Only pending orders can be paid")
+		case errors.Is(err, ErrPaymentInProgress):
+			return echo.NewHTTPError(http.StatusConflict, "The order already has a payment in progress")
+		}
+		h.logger.Error("Failed to save payment", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create payment")
+	}
+
+	result, err := provider.Authorize(c.Request().Context(), AuthorizeRequest{
+		OrderID:    o.ID,
+		Amount:     payment.Amount,
+		CardNumber: req.CardNumber,
+	})
+	if err != nil {
+		h.logger.Error("Failed to authorize payment", zap.Error(err), zap.Uint("order_id", o.ID))
+		payment.Status = StatusFailed
+		payment.FailureReason = "payment provider error"
+		if err := h.repo.Update(payment); err != nil {
+			h.logger.Error("Failed to update payment", zap.Error(err), zap.Uint("payment_id", payment.ID))
+		}
+		return echo.NewHTTPError(http.StatusBadGateway, "Payment provider error")
+	}
+
+	payment.ProviderRef = result.Reference
+	payment.Status = result.Status
+	payment.NextActionURL = result.NextActionURL
+	if result.Status == StatusDeclined || result.Status == StatusFailed {
+		payment.FailureReason = result.Message
+	}
+
+	if err := h.repo.Update(payment); err != nil {
+		h.logger.Error("Failed to save payment", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create payment")
+	}
+
+	if payment.Status == StatusDeclined {
+		return c.JSON(http.StatusPaymentRequired, payment)
+	}
+	return c.JSON(http.StatusCreated, payment)
+}
+
+// @Summary		Get payment
+// @Description	Get a payment by ID
+// @Tags			payments
+// @Produce		json
+// @Param			id	path		int	true	"Payment ID"
+// @Success		200	{object}	Payment
+// @Failure		404	{object}	middleware.ErrorResponse
+// @Router			/payments/{id} [get]
+func (h *Handler) Get(c echo.Context) error {
+	payment, err := h.loadPayment(c)
+	if err != nil {
+		return err
+	}
+
+	o, err := h.orders.GetByID(payment.OrderID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Payment not found")
+	}
+
+	claims := c.Get("userClaims").(*models.Claims)
+	if o.UserID != claims.UserID && !middleware.HasPermission(c, h.resolver, rbac.PermissionPaymentsRead) {
+		return echo.NewHTTPError(http.StatusNotFound, "Payment not found")
+	}
+
+	return c.JSON(http.StatusOK, payment)
+}
+
+// @Summary		List payments to reconcile
+// @Description	List payments whose money no longer matches their order, such as captures for cancelled orders, oldest first (requires payments:read)
+// @Tags			payments
+// @Produce		json
+// @Success		200	{array}	Payment
+// @Router			/payments/reconciliation [get]
+func (h *Handler) ListReconciliation(c echo.Context) error {
+	payments, err := h.repo.ListForReconciliation()
+	if err != nil {
+		h.logger.Error("Failed to list payments to reconcile", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list payments")
+	}
+	return c.JSON(http.StatusOK, payments)
+}
+
+// @Summary		Capture payment
+// @Description	Capture an authorized payment and confirm its order (requires payments:write)
+// @Tags			payments
+// @Produce		json
+// @Param			id	path		int	true	"Payment ID"
+// @Success		200	{object}	Payment
+// @Failure		404	{object}	middleware.ErrorResponse
+// @Failure		409	{object}	middleware.ErrorResponse
+// @Router			/payments/{id}/capture [post]
+func (h *Handler) Capture(c echo.Context) error {
+	payment, provider, err := h.loadWithProvider(c)
+	if err != nil {
+		return err
+	}
+	if payment.Status != StatusAuthorized {
+		return echo.NewHTTPError(http.StatusConflict, "Only authorized payments can be captured")
+	}
+
+	result, err := provider.Capture(c.Request().Context(), payment.ProviderRef, payment.Amount)
+	if err != nil {
+		h.logger.Error("Failed to capture payment", zap.Error(err), zap.Uint("payment_id", payment.ID))
+		return echo.NewHTTPError(http.StatusBadGateway, "Payment provider error")
+	}
+
+	if result.Status == StatusCaptured {
+		if err := h.repo.MarkCaptured(payment.ID); err != nil {
+			h.logger.Error("Failed to record capture", zap.Error(err), zap.Uint("payment_id", payment.ID))
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to capture payment")
+		}
+	} else {
+		payment.Status = result.Status
+		payment.FailureReason = result.Message
+		if err := h.repo.Update(payment); err != nil {
+			h.logger.Error("Failed to update payment", zap.Error(err))
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to capture payment")
+		}
+	}
+
+	return h.respond(c, payment.ID)
+}
+
+// @Summary		Void payment
+// @Description	Release an authorization that has not been captured (requires payments:write)
+// @Tags			payments
+// @Produce		json
+// @Param			id	path		int	true	"Payment ID"
+// @Success		200	{object}	Payment
+// @Failure		404	{object}	middleware.ErrorResponse
+// @Failure		409	{object}	middleware.ErrorResponse
+// @Router			/payments/{id}/void [post]
+func (h *Handler) Void(c echo.Context) error {
+	payment, provider, err := h.loadWithProvider(c)
+	if err != nil {
+		return err
+	}
+	if payment.Status != StatusAuthorized && payment.Status != StatusRequiresAction {
+		return echo.NewHTTPError(http.StatusConflict, "Only uncaptured payments can be voided")
+	}
+
+	result, err := provider.Void(c.Request().Context(), payment.ProviderRef)
+	if err != nil {
+		h.logger.Error("Failed to void payment", zap.Error(err), zap.Uint("payment_id", payment.ID))
+		return echo.NewHTTPError(http.StatusBadGateway, "Payment provider error")
+	}
+
+	payment.Status = result.Status
+	if err := h.repo.Update(payment); err != nil {
+		h.logger.Error("Failed to update payment", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to void payment")
+	}
+
+	return c.JSON(http.StatusOK, payment)
+}
+
+// @Summary		Refund payment
+// @Description	Refund all or part of a captured payment (requires payments:write). Without an amount the remaining balance is refunded.
+// @Tags			payments
+// @Accept			json
+// @Produce		json
+// @Param			id		path		int				true	"Payment ID"
+// @Param			request	body		RefundRequest	false	"Amount to refund"
+// @Success		200		{object}	Payment
+// @Failure		400		{object}	middleware.ErrorResponse
+// @Failure		409		{object}	middleware.ErrorResponse
+// @Router			/payments/{id}/refund [post]
+func (h *Handler) Refund(c echo.Context) error {
+	var req RefundRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	payment, provider, err := h.loadWithProvider(c)
+	if err != nil {
+		return err
+	}
+
+	refunded, err := h.repo.Refund(c.Request().Context(), provider, payment.ID, req.Amount)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrPaymentNotFound):
+			return echo.NewHTTPError(http.StatusNotFound, "Payment not found")
+		case errors.Is(err, ErrNotRefundable):
+			return echo.NewHTTPError(http.StatusConflict, "Only captured payments can be refunded")
+		case errors.Is(err, ErrRefundCurrency):
+			return echo.NewHTTPError(http.StatusBadRequest, "Refunds must be in the currency of the payment")
+		case errors.Is(err, ErrRefundExceedsBalance):
+			return echo.NewHTTPError(http.StatusBadRequest, "Refund exceeds the remaining captured amount")
+		}
+		h.logger.Error("Failed to refund payment", zap.Error(err), zap.Uint("payment_id", payment.ID))
+		if errors.Is(err, ErrProvider) {
+			return echo.NewHTTPError(http.StatusBadGateway, "Payment provider error")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to refund payment")
+	}
+
+	return c.JSON(http.StatusOK, refunded)
+}
+
+// @Summary		Payment provider webhook
+// @Description	Receive a signed event from a payment provider. Replayed events are acknowledged without being applied again.
+// @Tags			payments
+// @Accept			json
+// @Produce		json
+// @Param			provider			path		string	true	"Provider name"
+// @Param			X-Webhook-Signature	header		string	true	"HMAC-SHA256 signature of the body"
+// @Success		200					{object}	map[string]interface{}
+// @Failure		400					{object}	middleware.ErrorResponse
+// @Failure		401					{object}	middleware.ErrorResponse
+// @Router			/payments/webhooks/{provider} [post]
+func (h *Handler) Webhook(c echo.Context) error {
+	provider, err := h.providers.Get(c.Param("provider"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Unknown payment provider")
+	}
+
+	payload, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Failed to read payload")
+	}
+
+	event, err := provider.ParseWebhook(payload, c.Request().Header.Get(SignatureHeader))
+	if err != nil {
+		if errors.Is(err, ErrInvalidSignature) {
+			return echo.NewHTTPError(http.StatusUnauthorized, "Invalid signature")
+		}
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	applied, err := h.repo.ApplyWebhook(provider.Name(), event)
+	if err != nil {
+		if errors.Is(err, ErrPaymentNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "Payment not found")
+		}
+		h.logger.Error("Failed to apply webhook",
+			zap.Error(err),
+			zap.String("provider", provider.Name()),
+			zap.String("event_id", event.ID))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to process webhook")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"received":  true,
+		"duplicate": !applied,
+	})
+}
+
+func (h *Handler) loadPayment(c echo.Context) (*Payment, error) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid payment ID")
+	}
+
+	payment, err := h.repo.GetByID(uint(id))
+	if err != nil {
+		if errors.Is(err, ErrPaymentNotFound) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Payment not found")
+		}
+		h.logger.Error("Failed to load payment", zap.Error(err))
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to load payment")
+	}
+	return payment, nil
+}
+
+func (h *Handler) loadWithProvider(c echo.Context) (*Payment, PaymentProvider, error) {
+	payment, err := h.loadPayment(c)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	provider, err := h.providers.Get(payment.Provider)
+	if err != nil {
+		h.logger.Error("Payment references an unconfigured provider", zap.String("provider", payment.Provider))
+		return nil, nil, echo.NewHTTPError(http.StatusInternalServerError, "Payment provider unavailable")
+	}
+	return payment, provider, nil
+}
+
+func (h *Handler) respond(c echo.Context, paymentID uint) error {
+	payment, err := h.repo.GetByID(paymentID)
+	if err != nil {
+		h.logger.Error("Failed to reload payment", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load payment")
+	}
+	return c.JSON(http.StatusOK, payment)
+}
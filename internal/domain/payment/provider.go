@@ -0,0 +1,83 @@
+package payment
+
+import (
+	"context"
+	"errors"
+
+	"github.com/nneji123/ecommerce-golang/internal/common/money"
+)
+
+var (
+	ErrUnknownProvider  = errors.New("unknown payment provider")
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	// ErrMissingWebhookSecret is returned when a provider that verifies
+	// webhook signatures is configured without a secret to verify them with.
+	ErrMissingWebhookSecret = errors.New("payment webhook secret is not set")
+)
+
+// Webhook event types understood by the payment subsystem.
+const (
+	EventAuthorized = "payment.authorized"
+	EventCaptured   = "payment.captured"
+	EventFailed     = "payment.failed"
+	EventVoided     = "payment.voided"
+	EventRefunded   = "payment.refunded"
+)
+
+type AuthorizeRequest struct {
+	OrderID    uint
+	Amount     money.Money
+	CardNumber string
+}
+
+// ProviderResult is the outcome of a call to a payment provider. RefundRef
+// identifies the refund made by a call to Refund.
+type ProviderResult struct {
+	Reference     string
+	Status        PaymentStatus
+	NextActionURL string
+	Message       string
+	RefundRef     string
+}
+
+// ProviderEvent is a verified webhook notification from a provider. Amount is
+// the money the event moved: the amount captured, or that of a single refund.
+// Refund events carry the provider's reference for the refund in RefundRef,
+// the same one Refund returned for refunds made through the API.
+type ProviderEvent struct {
+	ID          string
+	Type        string
+	ProviderRef string
+	Amount      money.Money
+	RefundRef   string
+}
+
+// PaymentProvider is implemented by every payment gateway integration.
+type PaymentProvider interface {
+	Name() string
+	Authorize(ctx context.Context, req AuthorizeRequest) (*ProviderResult, error)
+	Capture(ctx context.Context, reference string, amount money.Money) (*ProviderResult, error)
+	Void(ctx context.Context, reference string) (*ProviderResult, error)
+	Refund(ctx context.Context, reference string, amount money.Money) (*ProviderResult, error)
+	// ParseWebhook verifies the signature of a webhook payload and decodes it.
+	ParseWebhook(payload []byte, signature string) (*ProviderEvent, error)
+}
+
+// Providers holds the configured payment providers keyed by name.
+type Providers map[string]PaymentProvider
+
+func NewProviders(providers ...PaymentProvider) Providers {
+	registry := make(Providers, len(providers))
+	for _, p := range providers {
+		registry[p.Name()] = p
+	}
+	return registry
+}
+
+func (p Providers) Get(name string) (PaymentProvider, error) {
+	provider, ok := p[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return provider, nil
+}
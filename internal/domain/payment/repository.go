@@ -0,0 +1,332 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/nneji123/ecommerce-golang/internal/common/money"
+	"github.com/nneji123/ecommerce-golang/internal/domain/order"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrPaymentNotFound = errors.New("payment not found")
+	// ErrOrderNotPayable is returned when paying for an order that is no
+	// longer pending.
+	ErrOrderNotPayable = errors.New("only pending orders can be paid")
+	// ErrPaymentInProgress is returned when paying for an order that already
+	// has a payment which has not failed.
+	ErrPaymentInProgress = errors.New("the order already has a payment")
+	// ErrNotRefundable is returned when refunding a payment that has not been
+	// captured or has been refunded in full.
+	ErrNotRefundable = errors.New("only captured payments can be refunded")
+	// ErrRefundCurrency is returned for a refund in another currency than the
+	// payment.
+	ErrRefundCurrency = errors.New("refunds must be in the currency of the payment")
+	// ErrRefundExceedsBalance is returned for a refund of more than the
+	// captured amount not yet refunded.
+	ErrRefundExceedsBalance = errors.New("refund exceeds the remaining captured amount")
+	// ErrProvider wraps the errors of calls to a payment provider.
+	ErrProvider = errors.New("payment provider error")
+)
+
+// activeStatuses are the statuses of payments that hold or have taken the
+// customer's money. An order has at most one payment in any of them.
+var activeStatuses = []PaymentStatus{
+	StatusPending,
+	StatusRequiresAction,
+	StatusAuthorized,
+	StatusCaptured,
+	StatusRefunded,
+}
+
+type Repository struct {
+	db     *gorm.DB
+	orders *order.Repository
+}
+
+func NewRepository(db *gorm.DB, orders *order.Repository) *Repository {
+	return &Repository{db: db, orders: orders}
+}
+
+// Start records a pending payment for the total of a pending order before
+// the provider is asked to authorize it. The order is locked so concurrent
+// attempts are serialized, and refused while another payment of the order
+// has not failed.
+func (r *Repository) Start(orderID uint, provider, cardLast4 string) (*Payment, error) {
+	var payment *Payment
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var o order.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&o, orderID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return order.ErrOrderNotFound
+			}
+			return err
+		}
+		if o.Status != order.StatusPending {
+			return ErrOrderNotPayable
+		}
+
+		var active int64
+		if err := tx.Model(&Payment{}).
+			Where("order_id = ? AND status IN ?", orderID, activeStatuses).
+			Count(&active).Error; err != nil {
+			return err
+		}
+		if active > 0 {
+			return ErrPaymentInProgress
+		}
+
+		payment = &Payment{
+			OrderID:        o.ID,
+			Provider:       provider,
+			Status:         StatusPending,
+			Amount:         o.TotalAmount,
+			RefundedAmount: money.Zero(o.TotalAmount.Currency),
+			CardLast4:      cardLast4,
+		}
+		return tx.Create(payment).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+func (r *Repository) Update(payment *Payment) error {
+	return r.db.Save(payment).Error
+}
+
+func (r *Repository) GetByID(id uint) (*Payment, error) {
+	var payment Payment
+	if err := r.db.First(&payment, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPaymentNotFound
+		}
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// ListForReconciliation returns the payments flagged for reconciliation,
+// oldest first.
+func (r *Repository) ListForReconciliation() ([]Payment, error) {
+	payments := []Payment{}
+	err := r.db.Where("reconciliation_reason <> ''").Order("created_at").Find(&payments).Error
+	return payments, err
+}
+
+func (r *Repository) ListByOrder(orderID uint) ([]Payment, error) {
+	var payments []Payment
+	err := r.db.Where("order_id = ?", orderID).Order("created_at").Find(&payments).Error
+	return payments, err
+}
+
+// MarkCaptured records a successful capture and confirms the order in the
+// same transaction. It is a no-op for payments that are already captured.
+func (r *Repository) MarkCaptured(paymentID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return markCaptured(tx, r.orders, paymentID)
+	})
+}
+
+// Refund refunds amount of a captured payment through its provider, or the
+// remaining balance when amount is nil. The payment stays locked from the
+// balance check until the refund is recorded, so concurrent refunds cannot
+// return more than was captured.
+func (r *Repository) Refund(ctx context.Context, provider PaymentProvider, paymentID uint, amount *money.Money) (*Payment, error) {
+	var payment Payment
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, paymentID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrPaymentNotFound
+			}
+			return err
+		}
+		if payment.Status != StatusCaptured {
+			return ErrNotRefundable
+		}
+
+		remaining, err := payment.Amount.Sub(payment.RefundedAmount)
+		if err != nil {
+			return fmt.Errorf("payment %d: %w", payment.ID, err)
+		}
+		refund := remaining
+		if amount != nil {
+			refund = *amount
+		}
+		if refund.Currency != payment.Amount.Currency {
+			return ErrRefundCurrency
+		}
+		if refund.Amount > remaining.Amount {
+			return ErrRefundExceedsBalance
+		}
+
+		result, err := provider.Refund(ctx, payment.ProviderRef, refund)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrProvider, err)
+		}
+		if result.RefundRef == "" {
+			return fmt.Errorf("%w: no reference returned for the refund", ErrProvider)
+		}
+		_, err = recordRefund(tx, &payment, result.RefundRef, refund)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// ApplyWebhook records a provider event and applies it to the matching
+// payment. It returns false without changing anything if the event has been
+// processed before.
+func (r *Repository) ApplyWebhook(provider string, event *ProviderEvent) (bool, error) {
+	applied := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&WebhookEvent{
+			Provider:    provider,
+			EventID:     event.ID,
+			Type:        event.Type,
+			ProviderRef: event.ProviderRef,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		applied = true
+
+		var payment Payment
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("provider = ? AND provider_ref = ?", provider, event.ProviderRef).
+			First(&payment).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrPaymentNotFound
+			}
+			return err
+		}
+
+		switch event.Type {
+		case EventCaptured:
+			if payment.Status == StatusCaptured || payment.Status == StatusRefunded {
+				return nil
+			}
+			if cmp, err := event.Amount.Cmp(payment.Amount); err == nil && cmp == 0 {
+				return markCaptured(tx, r.orders, payment.ID)
+			}
+			// The money has been taken, so the capture is recorded, but an
+			// order is not confirmed on a payment of another amount.
+			payment.Status = StatusCaptured
+			payment.FailureReason = ""
+			payment.ReconciliationReason = fmt.Sprintf("provider captured %s of %s", event.Amount, payment.Amount)
+		case EventAuthorized:
+			if payment.Status == StatusPending || payment.Status == StatusRequiresAction {
+				payment.Status = StatusAuthorized
+				payment.NextActionURL = ""
+			}
+		case EventFailed:
+			if payment.Status != StatusCaptured && payment.Status != StatusRefunded {
+				payment.Status = StatusFailed
+			}
+		case EventVoided:
+			if payment.Status != StatusPending && payment.Status != StatusRequiresAction && payment.Status != StatusAuthorized {
+				return nil
+			}
+			payment.Status = StatusVoided
+		case EventRefunded:
+			if payment.Status != StatusCaptured || !event.Amount.IsPositive() || event.RefundRef == "" {
+				return nil
+			}
+			// Refunds made through the API are already recorded under the
+			// same reference and are not counted again.
+			_, err := recordRefund(tx, &payment, event.RefundRef, event.Amount)
+			return err
+		default:
+			return nil
+		}
+
+		return tx.Save(&payment).Error
+	})
+	return applied, err
+}
+
+// recordRefund adds a refund of a locked payment to its refunded amount,
+// marking the payment refunded once nothing is left. It returns false without
+// changing anything if a refund with the same reference has been recorded.
+func recordRefund(tx *gorm.DB, payment *Payment, reference string, amount money.Money) (bool, error) {
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&Refund{
+		PaymentID:   payment.ID,
+		ProviderRef: reference,
+		Amount:      amount,
+	})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	refunded, err := payment.RefundedAmount.Add(amount)
+	if err != nil {
+		return false, fmt.Errorf("refund of payment %d: %w", payment.ID, err)
+	}
+	if refunded.Amount >= payment.Amount.Amount {
+		refunded = payment.Amount
+		payment.Status = StatusRefunded
+		payment.ReconciliationReason = ""
+	}
+	payment.RefundedAmount = refunded
+
+	return true, tx.Model(payment).Updates(map[string]interface{}{
+		"refunded_amount":       payment.RefundedAmount.Amount,
+		"refunded_currency":     payment.RefundedAmount.Currency,
+		"status":                payment.Status,
+		"reconciliation_reason": payment.ReconciliationReason,
+	}).Error
+}
+
+func markCaptured(tx *gorm.DB, orders *order.Repository, paymentID uint) error {
+	var payment Payment
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, paymentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrPaymentNotFound
+		}
+		return err
+	}
+	if payment.Status == StatusCaptured || payment.Status == StatusRefunded {
+		return nil
+	}
+
+	payment.Status = StatusCaptured
+	payment.FailureReason = ""
+	if err := tx.Save(&payment).Error; err != nil {
+		return err
+	}
+
+	// An order that has already moved on, e.g. confirmed by hand, is left as
+	// is. One that was cancelled, or whose reservation lapsed and whose stock
+	// has since been sold, cannot be fulfilled: the capture is kept and the
+	// payment flagged so the customer is refunded.
+	_, err := orders.WithTx(tx).Transition(payment.OrderID, order.StatusConfirmed, order.SystemActorID, "payment captured")
+	var illegal *order.IllegalTransitionError
+	switch {
+	case errors.As(err, &illegal):
+		if illegal.From != order.StatusCancelled {
+			return nil
+		}
+		return flagForReconciliation(tx, &payment, "captured after the order was cancelled")
+	case errors.Is(err, order.ErrReservationExpired):
+		return flagForReconciliation(tx, &payment, "captured after the order's stock reservation expired")
+	}
+	return err
+}
+
+// flagForReconciliation records why a payment needs to be settled by hand.
+func flagForReconciliation(tx *gorm.DB, payment *Payment, reason string) error {
+	payment.ReconciliationReason = reason
+	return tx.Model(payment).Update("reconciliation_reason", reason).Error
+}
@@ -0,0 +1,37 @@
+package payment
+
+import (
+	"log"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nneji123/ecommerce-golang/internal/common/revocation"
+	"github.com/nneji123/ecommerce-golang/internal/config"
+	"github.com/nneji123/ecommerce-golang/internal/domain/rbac"
+	"github.com/nneji123/ecommerce-golang/internal/middleware"
+)
+
+func RegisterRoutes(e *echo.Echo, h *Handler, revocations revocation.Store, permissions middleware.PermissionResolver) {
+	payments := e.Group("/payments")
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Error loading configuration: %s", err)
+	}
+
+	// Provider callbacks are authenticated by their signature
+	payments.POST("/webhooks/:provider", h.Webhook)
+
+	// User routes
+	authenticated := payments.Group("", middleware.AuthMiddleware(cfg.JWTSecret, revocations))
+	authenticated.POST("", h.Create)
+	authenticated.GET("/:id", h.Get)
+
+	// Finance routes
+	audit := authenticated.Group("", middleware.RequirePermission(permissions, rbac.PermissionPaymentsRead))
+	audit.GET("/reconciliation", h.ListReconciliation)
+
+	finance := authenticated.Group("", middleware.RequirePermission(permissions, rbac.PermissionPaymentsWrite))
+	finance.POST("/:id/capture", h.Capture)
+	finance.POST("/:id/void", h.Void)
+	finance.POST("/:id/refund", h.Refund)
+}
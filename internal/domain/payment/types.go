@@ -0,0 +1,80 @@
+package payment
+
+import (
+	"time"
+
+	"github.com/nneji123/ecommerce-golang/internal/common/money"
+)
+
+type PaymentStatus string
+
+const (
+	StatusPending        PaymentStatus = "pending"
+	StatusRequiresAction PaymentStatus = "requires_action"
+	StatusAuthorized     PaymentStatus = "authorized"
+	StatusCaptured       PaymentStatus = "captured"
+	StatusVoided         PaymentStatus = "voided"
+	StatusRefunded       PaymentStatus = "refunded"
+	StatusDeclined       PaymentStatus = "declined"
+	StatusFailed         PaymentStatus = "failed"
+)
+
+// Payment is a single attempt to pay for an order through a provider. Amount
+// is the order total; RefundedAmount is in the same currency.
+type Payment struct {
+	ID             uint          `gorm:"primaryKey" json:"id"`
+	OrderID        uint          `gorm:"not null;index" json:"order_id"`
+	Provider       string        `gorm:"size:50;not null" json:"provider"`
+	ProviderRef    string        `gorm:"size:255;index" json:"provider_ref"`
+	Status         PaymentStatus `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
+	Amount         money.Money   `gorm:"embedded" json:"amount"`
+	RefundedAmount money.Money   `gorm:"embedded;embeddedPrefix:refunded_" json:"refunded_amount"`
+	CardLast4      string        `gorm:"size:4" json:"card_last4,omitempty"`
+	NextActionURL  string        `gorm:"type:text" json:"next_action_url,omitempty"`
+	FailureReason  string        `gorm:"type:text" json:"failure_reason,omitempty"`
+	// ReconciliationReason is set when the money taken no longer matches the
+	// order, e.g. a capture for an order that has been cancelled, and staff
+	// have to settle it with the customer.
+	ReconciliationReason string    `gorm:"type:text" json:"reconciliation_reason,omitempty"`
+	CreatedAt            time.Time `json:"created_at"`
+	UpdatedAt            time.Time `json:"updated_at"`
+}
+
+// Refund is a single refund of a payment, keyed by the provider's reference
+// for it so it is counted once whether the API or a webhook reports it first.
+type Refund struct {
+	ID          uint        `gorm:"primaryKey" json:"id"`
+	PaymentID   uint        `gorm:"not null;uniqueIndex:idx_payment_refund" json:"payment_id"`
+	ProviderRef string      `gorm:"size:255;not null;uniqueIndex:idx_payment_refund" json:"provider_ref"`
+	Amount      money.Money `gorm:"embedded" json:"amount"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+func (Refund) TableName() string {
+	return "payment_refunds"
+}
+
+// WebhookEvent records a processed provider webhook so replays are ignored.
+type WebhookEvent struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Provider    string    `gorm:"size:50;not null;uniqueIndex:idx_provider_event" json:"provider"`
+	EventID     string    `gorm:"size:255;not null;uniqueIndex:idx_provider_event" json:"event_id"`
+	Type        string    `gorm:"size:100;not null" json:"type"`
+	ProviderRef string    `gorm:"size:255" json:"provider_ref"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (WebhookEvent) TableName() string {
+	return "payment_webhook_events"
+}
+
+type CreatePaymentRequest struct {
+	OrderID    uint   `json:"order_id" validate:"required"`
+	Provider   string `json:"provider" validate:"required"`
+	CardNumber string `json:"card_number" validate:"required,numeric,min=12,max=19"`
+}
+
+// RefundRequest refunds Amount, or the remaining balance when it is omitted.
+type RefundRequest struct {
+	Amount *money.Money `json:"amount" validate:"omitempty,gt=0"`
+}
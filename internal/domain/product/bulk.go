@@ -0,0 +1,496 @@
+package product
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql/driver"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nneji123/ecommerce-golang/internal/common/money"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BulkFormat is a file format products are imported from and exported to.
+type BulkFormat string
+
+const (
+	FormatCSV    BulkFormat = "csv"
+	FormatNDJSON BulkFormat = "ndjson"
+)
+
+// ParseBulkFormat accepts a format name or a file extension.
+func ParseBulkFormat(name string) (BulkFormat, bool) {
+	switch strings.ToLower(strings.TrimPrefix(name, ".")) {
+	case "csv":
+		return FormatCSV, true
+	case "ndjson", "jsonl":
+		return FormatNDJSON, true
+	}
+	return "", false
+}
+
+// ContentType returns the MIME type of files in the format.
+func (f BulkFormat) ContentType() string {
+	if f == FormatNDJSON {
+		return "application/x-ndjson"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+type ImportStatus string
+
+const (
+	ImportPending   ImportStatus = "pending"
+	ImportRunning   ImportStatus = "running"
+	ImportCompleted ImportStatus = "completed"
+	ImportFailed    ImportStatus = "failed"
+)
+
+var ErrImportJobNotFound = errors.New("import job not found")
+
+// RowError reports why a row of an import was skipped. Row is the line
+// number in the uploaded file.
+type RowError struct {
+	Row        int      `json:"row"`
+	ExternalID string   `json:"external_id,omitempty"`
+	Errors     []string `json:"errors"`
+}
+
+// RowErrors is the per-row error report of an import, stored as JSON.
+type RowErrors []RowError
+
+func (e RowErrors) Value() (driver.Value, error) {
+	if e == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(e)
+	return string(b), err
+}
+
+func (e *RowErrors) Scan(value interface{}) error {
+	var b []byte
+	switch v := value.(type) {
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	case nil:
+		*e = RowErrors{}
+		return nil
+	default:
+		return errors.New("unsupported type for RowErrors")
+	}
+	return json.Unmarshal(b, e)
+}
+
+// ImportJob tracks a bulk product import. The uploaded file is kept until
+// the job finishes so any instance can process it.
+type ImportJob struct {
+	ID            uint         `gorm:"primaryKey" json:"id"`
+	Status        ImportStatus `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
+	Format        BulkFormat   `gorm:"type:varchar(20);not null" json:"format"`
+	Filename      string       `gorm:"size:255" json:"filename,omitempty"`
+	Payload       []byte       `json:"-"`
+	TotalRows     int          `gorm:"not null;default:0" json:"total_rows"`
+	ProcessedRows int          `gorm:"not null;default:0" json:"processed_rows"`
+	CreatedCount  int          `gorm:"not null;default:0" json:"created"`
+	UpdatedCount  int          `gorm:"not null;default:0" json:"updated"`
+	FailedCount   int          `gorm:"not null;default:0" json:"failed"`
+	RowErrors     RowErrors    `gorm:"type:jsonb;not null" json:"errors"`
+	Error         string       `gorm:"type:text" json:"error,omitempty"`
+	CreatedBy     uint         `json:"created_by"`
+	LockedAt      *time.Time   `json:"-"`
+	StartedAt     *time.Time   `json:"started_at,omitempty"`
+	FinishedAt    *time.Time   `json:"finished_at,omitempty"`
+	CreatedAt     time.Time    `json:"created_at"`
+	UpdatedAt     time.Time    `json:"updated_at"`
+}
+
+func (ImportJob) TableName() string {
+	return "product_import_jobs"
+}
+
+// Record is a product as one row of an import or export file. Imports
+// ignore ID and match products on ExternalID. In CSV files the price is a
+// decimal amount in the currency column, or in the default currency when the
+// column is missing or empty.
+type Record struct {
+	ID          uint        `json:"id,omitempty"`
+	ExternalID  string      `json:"external_id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Price       money.Money `json:"price"`
+	Stock       int         `json:"stock"`
+}
+
+// recordColumns is the CSV header of an export.
+var recordColumns = []string{"id", "external_id", "name", "description", "price", "currency", "stock"}
+
+// requiredColumns must be present in the header of a CSV import.
+var requiredColumns = []string{"external_id", "name", "price", "stock"}
+
+func recordOf(p *Product) Record {
+	record := Record{
+		ID:          p.ID,
+		Name:        p.Name,
+		Description: p.Description,
+		Price:       p.Price,
+		Stock:       p.Stock,
+	}
+	if p.ExternalID != nil {
+		record.ExternalID = *p.ExternalID
+	}
+	return record
+}
+
+func (r Record) product() Product {
+	externalID := strings.TrimSpace(r.ExternalID)
+	return Product{
+		ExternalID:  &externalID,
+		Name:        r.Name,
+		Description: r.Description,
+		Price:       r.Price,
+		Stock:       r.Stock,
+	}
+}
+
+// parsedRecord is a record read from an import file, with the errors found
+// while parsing its fields.
+type parsedRecord struct {
+	Record
+	Row    int
+	Errors []string
+}
+
+// readRecords parses an import file. Malformed rows are returned with their
+// errors; an error is only returned when the file as a whole is unreadable.
+func readRecords(format BulkFormat, data []byte) ([]parsedRecord, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if format == FormatNDJSON {
+		return readNDJSON(data)
+	}
+	return readCSV(data)
+}
+
+func readCSV(data []byte) ([]parsedRecord, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("file is empty")
+		}
+		return nil, fmt.Errorf("invalid CSV header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range requiredColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing column %q", name)
+		}
+	}
+	field := func(row []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	var records []parsedRecord
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) && errors.Is(parseErr.Err, csv.ErrFieldCount) {
+			records = append(records, parsedRecord{
+				Row:    parseErr.StartLine,
+				Record: Record{ExternalID: field(row, "external_id")},
+				Errors: []string{fmt.Sprintf("expected %d fields, got %d", len(header), len(row))},
+			})
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		record := parsedRecord{
+			Row: line,
+			Record: Record{
+				ExternalID:  field(row, "external_id"),
+				Name:        field(row, "name"),
+				Description: field(row, "description"),
+			},
+		}
+		if value := field(row, "price"); value != "" {
+			currency := field(row, "currency")
+			if currency == "" {
+				currency = money.DefaultCurrency()
+			}
+			if record.Price, err = money.Parse(value, currency); err != nil {
+				record.Errors = append(record.Errors, "price: "+err.Error())
+			}
+		}
+		if value := field(row, "stock"); value != "" {
+			if record.Stock, err = strconv.Atoi(value); err != nil {
+				record.Errors = append(record.Errors, "stock must be a whole number")
+			}
+		}
+		records = append(records, record)
+	}
+}
+
+// maxNDJSONLine bounds the length of a single NDJSON record.
+const maxNDJSONLine = 1 << 20
+
+func readNDJSON(data []byte) ([]parsedRecord, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxNDJSONLine)
+
+	var records []parsedRecord
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		record := parsedRecord{Row: line}
+		if err := json.Unmarshal(text, &record.Record); err != nil {
+			record.Errors = []string{"invalid JSON: " + err.Error()}
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("invalid NDJSON: %w", err)
+	}
+	return records, nil
+}
+
+// RecordWriter writes export rows in a bulk format.
+type RecordWriter interface {
+	Write(record Record) error
+	Flush() error
+}
+
+// NewRecordWriter returns a writer for format. CSV output starts with a
+// header row.
+func NewRecordWriter(format BulkFormat, w io.Writer) (RecordWriter, error) {
+	if format == FormatNDJSON {
+		return &ndjsonWriter{w: bufio.NewWriter(w)}, nil
+	}
+	writer := csv.NewWriter(w)
+	if err := writer.Write(recordColumns); err != nil {
+		return nil, err
+	}
+	return &csvWriter{w: writer}, nil
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (w *csvWriter) Write(r Record) error {
+	return w.w.Write([]string{
+		strconv.FormatUint(uint64(r.ID), 10),
+		r.ExternalID,
+		r.Name,
+		r.Description,
+		r.Price.Decimal(),
+		r.Price.Currency,
+		strconv.Itoa(r.Stock),
+	})
+}
+
+func (w *csvWriter) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+type ndjsonWriter struct {
+	w *bufio.Writer
+}
+
+func (w *ndjsonWriter) Write(r Record) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if _, err := w.w.Write(b); err != nil {
+		return err
+	}
+	return w.w.WriteByte('\n')
+}
+
+func (w *ndjsonWriter) Flush() error {
+	return w.w.Flush()
+}
+
+// Export streams every product matching the query's filters to fn in
+// batches ordered by ID. Paging and sorting options are ignored.
+func (r *Repository) Export(query *ListProductsQuery, batchSize int, fn func([]Product) error) error {
+	filters, err := query.filters()
+	if err != nil {
+		return err
+	}
+
+	var batch []Product
+	return applyFilters(r.db.Model(&Product{}), filters, "").
+		FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+			return fn(batch)
+		}).Error
+}
+
+// UpsertByExternalID inserts products, or updates the name, description and
+// price of those whose external ID already exists. Deleted products are
+// restored. The stock of new products is received into the default
+// warehouse; that of existing ones only changes through the inventory
+// ledger. It returns how many products were created.
+func (r *Repository) UpsertByExternalID(products []Product, actorID uint) (int, error) {
+	created := len(products)
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		ids := make([]string, len(products))
+		for i, p := range products {
+			ids[i] = *p.ExternalID
+		}
+		var existing []string
+		if err := tx.Unscoped().Model(&Product{}).Where("external_id IN ?", ids).Pluck("external_id", &existing).Error; err != nil {
+			return err
+		}
+		created = len(products) - len(existing)
+		exists := make(map[string]bool, len(existing))
+		for _, id := range existing {
+			exists[id] = true
+		}
+
+		stock := make([]int, len(products))
+		for i := range products {
+			stock[i], products[i].Stock = products[i].Stock, 0
+		}
+		if err := tx.Omit(clause.Associations).Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "external_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"name", "description", "price_amount", "price_currency", "updated_at", "deleted_at"}),
+		}).Create(&products).Error; err != nil {
+			return err
+		}
+
+		for i, p := range products {
+			if exists[*p.ExternalID] {
+				continue
+			}
+			if err := receiveInitialStock(tx, p.ID, nil, stock[i], actorID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return created, err
+}
+
+func (r *Repository) CreateImportJob(job *ImportJob) error {
+	return r.db.Create(job).Error
+}
+
+// GetImportJob returns a job without its uploaded file.
+func (r *Repository) GetImportJob(id uint) (*ImportJob, error) {
+	var job ImportJob
+	if err := r.db.Omit("payload").First(&job, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrImportJobNotFound
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ClaimImportJob locks the oldest pending import and marks it as running.
+// Jobs whose worker stopped reporting progress for longer than staleAfter
+// are claimed again and restarted from the first row. It returns nil when
+// nothing is pending.
+func (r *Repository) ClaimImportJob(staleAfter time.Duration) (*ImportJob, error) {
+	var job ImportJob
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? OR (status = ? AND locked_at < ?)",
+				ImportPending, ImportRunning, now.Add(-staleAfter)).
+			Order("created_at").
+			First(&job).Error
+		if err != nil {
+			return err
+		}
+
+		job.Status = ImportRunning
+		job.LockedAt = &now
+		job.StartedAt = &now
+		job.TotalRows, job.ProcessedRows = 0, 0
+		job.CreatedCount, job.UpdatedCount, job.FailedCount = 0, 0, 0
+		job.RowErrors = RowErrors{}
+		return tx.Model(&job).Updates(map[string]interface{}{
+			"status":         ImportRunning,
+			"locked_at":      now,
+			"started_at":     now,
+			"total_rows":     0,
+			"processed_rows": 0,
+			"created_count":  0,
+			"updated_count":  0,
+			"failed_count":   0,
+			"row_errors":     RowErrors{},
+		}).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// SaveImportProgress records the counters and error report of a running
+// job and renews its lock.
+func (r *Repository) SaveImportProgress(job *ImportJob) error {
+	now := time.Now()
+	job.LockedAt = &now
+	return r.db.Model(job).Updates(map[string]interface{}{
+		"total_rows":     job.TotalRows,
+		"processed_rows": job.ProcessedRows,
+		"created_count":  job.CreatedCount,
+		"updated_count":  job.UpdatedCount,
+		"failed_count":   job.FailedCount,
+		"row_errors":     job.RowErrors,
+		"locked_at":      now,
+	}).Error
+}
+
+// FinishImportJob stores the final state of a job and discards its
+// uploaded file.
+func (r *Repository) FinishImportJob(job *ImportJob) error {
+	now := time.Now()
+	job.FinishedAt = &now
+	job.LockedAt = nil
+	job.Payload = nil
+	return r.db.Model(job).Updates(map[string]interface{}{
+		"status":         job.Status,
+		"error":          job.Error,
+		"total_rows":     job.TotalRows,
+		"processed_rows": job.ProcessedRows,
+		"created_count":  job.CreatedCount,
+		"updated_count":  job.UpdatedCount,
+		"failed_count":   job.FailedCount,
+		"row_errors":     job.RowErrors,
+		"finished_at":    now,
+		"locked_at":      nil,
+		"payload":        nil,
+	}).Error
+}
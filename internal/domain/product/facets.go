@@ -0,0 +1,266 @@
+package product
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nneji123/ecommerce-golang/internal/common/money"
+	"gorm.io/gorm"
+)
+
+// Facet names. Option facets are named FacetOptionPrefix followed by the
+// option type name, e.g. "option:size".
+const (
+	FacetPrice        = "price"
+	FacetAvailability = "availability"
+	FacetCategory     = "category"
+	FacetOptionPrefix = "option:"
+)
+
+// PriceBucket is a price range [Min, Max) in the default currency; a nil
+// Max is unbounded. Products priced in other currencies fall in no bucket.
+type PriceBucket struct {
+	Min money.Money
+	Max *money.Money
+}
+
+func (b PriceBucket) value() string {
+	if b.Max == nil {
+		return b.Min.Decimal() + "-"
+	}
+	return b.Min.Decimal() + "-" + b.Max.Decimal()
+}
+
+func (b PriceBucket) label() string {
+	if b.Max == nil {
+		return b.Min.Decimal() + "+"
+	}
+	return b.Min.Decimal() + " - " + b.Max.Decimal()
+}
+
+// FacetConfig selects the facets computed for product listings.
+type FacetConfig struct {
+	PriceBuckets []PriceBucket
+	Availability bool
+	Categories   bool
+	OptionTypes  []string
+}
+
+// ParseFacetConfig reads a comma-separated facet list such as
+// "price,availability,category,option:size" and price buckets written as
+// "0-25,25-50,50-" where a missing upper bound is unbounded. Buckets are in
+// the default currency, which must be set first.
+func ParseFacetConfig(facets, priceBuckets string) (FacetConfig, error) {
+	var config FacetConfig
+	for _, name := range strings.Split(facets, ",") {
+		name = strings.TrimSpace(name)
+		switch {
+		case name == "":
+		case name == FacetPrice:
+			buckets, err := parsePriceBuckets(priceBuckets)
+			if err != nil {
+				return config, err
+			}
+			config.PriceBuckets = buckets
+		case name == FacetAvailability:
+			config.Availability = true
+		case name == FacetCategory:
+			config.Categories = true
+		case strings.HasPrefix(name, FacetOptionPrefix) && len(name) > len(FacetOptionPrefix):
+			config.OptionTypes = append(config.OptionTypes, strings.TrimPrefix(name, FacetOptionPrefix))
+		default:
+			return config, fmt.Errorf("unknown product facet %q", name)
+		}
+	}
+	return config, nil
+}
+
+func parsePriceBuckets(spec string) ([]PriceBucket, error) {
+	var buckets []PriceBucket
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lower, upper, ok := strings.Cut(part, "-")
+		if !ok {
+			return nil, fmt.Errorf("invalid price bucket %q", part)
+		}
+		min, err := money.Parse(lower, money.DefaultCurrency())
+		if err != nil {
+			return nil, fmt.Errorf("invalid price bucket %q", part)
+		}
+		bucket := PriceBucket{Min: min}
+		if upper = strings.TrimSpace(upper); upper != "" {
+			max, err := money.Parse(upper, money.DefaultCurrency())
+			if err != nil || max.Amount <= min.Amount {
+				return nil, fmt.Errorf("invalid price bucket %q", part)
+			}
+			bucket.Max = &max
+		}
+		buckets = append(buckets, bucket)
+	}
+	return buckets, nil
+}
+
+// FacetValue is one entry of a facet with the number of matching products.
+// Min and Max are set for price buckets.
+type FacetValue struct {
+	Value string       `json:"value"`
+	Label string       `json:"label"`
+	Min   *money.Money `json:"min,omitempty"`
+	Max   *money.Money `json:"max,omitempty"`
+	Count int64        `json:"count"`
+}
+
+type facetRow struct {
+	Facet    string
+	Value    string
+	Label    string
+	Position int
+	Count    int64
+}
+
+// Facets computes the configured facets for a listing in a single query.
+// Each facet counts products matching every filter of the query except the
+// facet's own, so a selected value does not hide its alternatives.
+func (r *Repository) Facets(query *ListProductsQuery, config FacetConfig) (map[string][]FacetValue, error) {
+	filters, err := query.filters()
+	if err != nil {
+		return nil, err
+	}
+
+	base := func(facet string) *gorm.DB {
+		return applyFilters(r.db.Model(&Product{}), filters, facet)
+	}
+
+	var parts []string
+	var subqueries []interface{}
+
+	if len(config.PriceBuckets) > 0 {
+		cases := []string{}
+		args := []interface{}{}
+		for i, b := range config.PriceBuckets {
+			position := strconv.Itoa(i)
+			if b.Max == nil {
+				cases = append(cases, "WHEN products.price_currency = ? AND products.price_amount >= ? THEN "+position)
+				args = append(args, b.Min.Currency, b.Min.Amount)
+			} else {
+				cases = append(cases, "WHEN products.price_currency = ? AND products.price_amount >= ? AND products.price_amount < ? THEN "+position)
+				args = append(args, b.Min.Currency, b.Min.Amount, b.Max.Amount)
+			}
+		}
+		// Products outside every bucket fall into position -1, which is
+		// not reported.
+		bucket := "CASE " + strings.Join(cases, " ") + " ELSE -1 END"
+		parts = append(parts, "(?)")
+		subqueries = append(subqueries, base(FacetPrice).
+			Select("'"+FacetPrice+"' AS facet, '' AS value, '' AS label, "+bucket+" AS position, COUNT(*) AS count", args...).
+			Group("position"))
+	}
+
+	if config.Availability {
+		parts = append(parts, "(?)")
+		subqueries = append(subqueries, base(FacetAvailability).
+			Select("'"+FacetAvailability+"' AS facet, CASE WHEN "+inStockCondition+" THEN '"+AvailabilityInStock+"' ELSE '"+AvailabilityOutOfStock+"' END AS value, '' AS label, 0 AS position, COUNT(*) AS count").
+			Group("value"))
+	}
+
+	if config.Categories {
+		parts = append(parts, "(?)")
+		subqueries = append(subqueries, base(FacetCategory).
+			Joins("JOIN product_categories pc ON pc.product_id = products.id").
+			Joins("JOIN categories c ON c.id = pc.category_id").
+			Select("'"+FacetCategory+"' AS facet, CAST(c.id AS TEXT) AS value, c.name AS label, 0 AS position, COUNT(DISTINCT products.id) AS count").
+			Group("c.id, c.name"))
+	}
+
+	for _, optionType := range config.OptionTypes {
+		facet := FacetOptionPrefix + optionType
+		parts = append(parts, "(?)")
+		subqueries = append(subqueries, base(facet).
+			Joins("JOIN product_variants v ON v.product_id = products.id AND v.deleted_at IS NULL").
+			Joins("JOIN variant_option_values vov ON vov.product_variant_id = v.id").
+			Joins("JOIN option_values ov ON ov.id = vov.option_value_id").
+			Joins("JOIN option_types ot ON ot.id = ov.option_type_id AND ot.name = ?", optionType).
+			Select("CAST(? AS TEXT) AS facet, ov.name AS value, ov.presentation AS label, ov.position AS position, COUNT(DISTINCT products.id) AS count", facet).
+			Group("ov.name, ov.presentation, ov.position"))
+	}
+
+	facets := make(map[string][]FacetValue)
+	if len(parts) == 0 {
+		return facets, nil
+	}
+
+	var rows []facetRow
+	if err := r.db.Raw(strings.Join(parts, " UNION ALL "), subqueries...).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	if len(config.PriceBuckets) > 0 {
+		counts := make(map[int]int64)
+		for _, row := range rows {
+			if row.Facet == FacetPrice {
+				counts[row.Position] = row.Count
+			}
+		}
+		values := make([]FacetValue, 0, len(config.PriceBuckets))
+		for i, b := range config.PriceBuckets {
+			min := b.Min
+			values = append(values, FacetValue{
+				Value: b.value(),
+				Label: b.label(),
+				Min:   &min,
+				Max:   b.Max,
+				Count: counts[i],
+			})
+		}
+		facets[FacetPrice] = values
+	}
+
+	if config.Availability {
+		counts := make(map[string]int64)
+		for _, row := range rows {
+			if row.Facet == FacetAvailability {
+				counts[row.Value] = row.Count
+			}
+		}
+		facets[FacetAvailability] = []FacetValue{
+			{Value: AvailabilityInStock, Label: "In stock", Count: counts[AvailabilityInStock]},
+			{Value: AvailabilityOutOfStock, Label: "Out of stock", Count: counts[AvailabilityOutOfStock]},
+		}
+	}
+
+	if config.Categories {
+		facets[FacetCategory] = []FacetValue{}
+	}
+	for _, optionType := range config.OptionTypes {
+		facets[FacetOptionPrefix+optionType] = []FacetValue{}
+	}
+
+	// Category values are ordered by count and option values by the option
+	// type's own ordering.
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Position != rows[j].Position {
+			return rows[i].Position < rows[j].Position
+		}
+		if rows[i].Count != rows[j].Count {
+			return rows[i].Count > rows[j].Count
+		}
+		return rows[i].Label < rows[j].Label
+	})
+	for _, row := range rows {
+		if row.Facet == FacetPrice || row.Facet == FacetAvailability {
+			continue
+		}
+		facets[row.Facet] = append(facets[row.Facet], FacetValue{
+			Value: row.Value,
+			Label: row.Label,
+			Count: row.Count,
+		})
+	}
+
+	return facets, nil
+}
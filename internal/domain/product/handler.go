@@ -1,29 +1,50 @@
 package product
 
 import (
-	"math"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"path"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
+	"github.com/nneji123/ecommerce-golang/internal/common/models"
+	"github.com/nneji123/ecommerce-golang/internal/common/storage"
+	"github.com/nneji123/ecommerce-golang/internal/domain/inventory"
+	"github.com/nneji123/ecommerce-golang/internal/middleware"
 	"go.uber.org/zap"
 )
 
 type Handler struct {
-	repo   *Repository
-	logger *zap.Logger
+	repo      *Repository
+	importer  *Importer
+	storage   storage.Storage
+	media     MediaConfig
+	facets    FacetConfig
+	validator *validator.Validate
+	logger    *zap.Logger
 }
 
-func NewHandler(repo *Repository, logger *zap.Logger) *Handler {
+func NewHandler(repo *Repository, importer *Importer, storage storage.Storage, media MediaConfig, facets FacetConfig, validator *validator.Validate, logger *zap.Logger) *Handler {
 	return &Handler{
-		repo:   repo,
-		logger: logger,
+		repo:      repo,
+		importer:  importer,
+		storage:   storage,
+		media:     media,
+		facets:    facets,
+		validator: validator,
+		logger:    logger,
 	}
 }
 
 // @Summary		Create product
-// @Description	Create a new product (Admin only)
+// @Description	Create a new product (requires products:write). Its stock is received into the first warehouse in fulfilment order.
 // @Tags			products
 // @Accept			json
 // @Produce		json
@@ -37,7 +58,14 @@ func (h *Handler) Create(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
-	if err := h.repo.Create(&product); err != nil {
+	claims := c.Get("userClaims").(*models.Claims)
+	if err := h.repo.Create(&product, claims.UserID); err != nil {
+		if errors.Is(err, inventory.ErrNoWarehouse) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if errors.Is(err, ErrTaxClassNotFound) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Tax class not found")
+		}
 		h.logger.Error("Failed to create product", zap.Error(err))
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create product")
 	}
@@ -46,7 +74,7 @@ func (h *Handler) Create(c echo.Context) error {
 }
 
 // @Summary		Get product
-// @Description	Get product by ID
+// @Description	Get product by ID with its categories and variants, including each variant's effective price and availability
 // @Tags			products
 // @Produce		json
 // @Param			id	path		int	true	"Product ID"
@@ -64,11 +92,12 @@ func (h *Handler) Get(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Product not found")
 	}
 
+	h.fillMediaURLs(product.Media)
 	return c.JSON(http.StatusOK, product)
 }
 
 // @Summary		Update product
-// @Description	Update product by ID (Admin only)
+// @Description	Update product by ID (requires products:write). Stock is not changed; use the inventory endpoints.
 // @Tags			products
 // @Accept			json
 // @Produce		json
@@ -88,20 +117,29 @@ func (h *Handler) Update(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Product not found")
 	}
 
+	stock := product.Stock
 	if err := c.Bind(product); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
+	product.Stock = stock
 
 	if err := h.repo.Update(product); err != nil {
+		if errors.Is(err, ErrVariantCurrency) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if errors.Is(err, ErrTaxClassNotFound) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Tax class not found")
+		}
 		h.logger.Error("Failed to update product", zap.Error(err))
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update product")
 	}
 
+	h.fillMediaURLs(product.Media)
 	return c.JSON(http.StatusOK, product)
 }
 
 // @Summary		Delete product
-// @Description	Delete product by ID (Admin only)
+// @Description	Delete product by ID (requires products:write)
 // @Tags			products
 // @Param			id	path	int	true	"Product ID"
 // @Success		204	"No Content"
@@ -121,17 +159,64 @@ func (h *Handler) Delete(c echo.Context) error {
 	return c.NoContent(http.StatusNoContent)
 }
 
+// @Summary		Set product categories
+// @Description	Replace the categories a product is assigned to (requires products:write)
+// @Tags			products
+// @Accept			json
+// @Produce		json
+// @Param			id		path		int						true	"Product ID"
+// @Param			request	body		SetCategoriesRequest	true	"Category IDs"
+// @Success		200		{object}	Product
+// @Failure		400		{object}	middleware.ErrorResponse
+// @Failure		404		{object}	middleware.ErrorResponse
+// @Router			/products/{id}/categories [put]
+func (h *Handler) SetCategories(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
+	}
+
+	var req SetCategoriesRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	product, err := h.repo.GetByID(uint(id))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Product not found")
+	}
+
+	if err := h.repo.SetCategories(product, req.CategoryIDs); err != nil {
+		if errors.Is(err, ErrCategoryNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "Category not found")
+		}
+		h.logger.Error("Failed to set product categories", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to set product categories")
+	}
+
+	h.fillMediaURLs(product.Media)
+	return c.JSON(http.StatusOK, product)
+}
+
 // @Summary		List products
-// @Description	Get a paginated list of products with optional filters
+// @Description	Get a paginated list of products with optional filters. Pages are numbered, or selected by cursor for stable paging; neighbouring pages are also advertised in the Link header.
 // @Tags			products
 // @Produce		json
 // @Param			page		query		int		false	"Page number"
 // @Param			limit		query		int		false	"Items per page"
-// @Param			min_price	query		number	false	"Minimum price"
-// @Param			max_price	query		number	false	"Maximum price"
-// @Param			search		query		string	false	"Search term"
-// @Param			sort_by		query		string	false	"Sort by field (name, price, created_at)"
+// @Param			min_price	query		string	false	"Minimum price, e.g. 19.99"
+// @Param			max_price	query		string	false	"Maximum price"
+// @Param			currency	query		string	false	"ISO 4217 currency of the price filters; defaults to the store currency"
+// @Param			search		query		string	false	"Full-text search (web search syntax: quoted phrases, OR, -exclusion); results are ranked by relevance unless sort_by is given"
+// @Param			sort_by		query		string	false	"Sort by field (name, price, created_at, rating)"
 // @Param			sort_dir	query		string	false	"Sort direction (asc, desc)"
+// @Param			cursor		query		string	false	"Cursor from a previous page's next_cursor or prev_cursor; overrides page and sort"
+// @Param			category_id	query		int		false	"Only products in this category"
+// @Param			include_descendants	query	bool	false	"Also include products in descendant categories"
+// @Param			option		query		[]string	false	"Variant option filter as type:value, repeatable (e.g. size:m)"
+// @Param			availability	query	string	false	"Stock availability (in_stock, out_of_stock)"
+// @Param			min_rating	query		number	false	"Minimum average rating"
+// @Param			facets		query		bool	false	"Include facet counts computed against the other applied filters"
 // @Success		200			{object}	middleware.PaginatedResponse
 // @Router			/products [get]
 func (h *Handler) List(c echo.Context) error {
@@ -140,30 +225,20 @@ func (h *Handler) List(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
-	if query.Page < 1 {
-		query.Page = 1
-	}
-	if query.Limit < 1 || query.Limit > 100 {
-		query.Limit = 10
+	query.Normalize(10, 100, "")
+	if _, ok := sortColumns[query.SortBy]; query.SortBy != "" && !ok {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid sort column")
 	}
 
-	if query.SortBy != "" {
-		validColumns := map[string]bool{
-			"name": true, "price": true, "created_at": true,
-		}
-		if !validColumns[query.SortBy] {
-			return echo.NewHTTPError(http.StatusBadRequest, "Invalid sort column")
-		}
-	}
-	if query.SortDir != "" {
-		query.SortDir = strings.ToUpper(query.SortDir)
-		if query.SortDir != "ASC" && query.SortDir != "DESC" {
-			query.SortDir = "ASC"
-		}
+	if err := checkFilters(&query); err != nil {
+		return err
 	}
 
-	products, total, err := h.repo.List(&query)
+	products, page, err := h.repo.List(&query)
 	if err != nil {
+		if errors.Is(err, middleware.ErrInvalidCursor) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid cursor")
+		}
 		h.logger.Error("Failed to list products",
 			zap.Error(err),
 			zap.Int("page", query.Page),
@@ -171,24 +246,859 @@ func (h *Handler) List(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list products")
 	}
 
-	totalPages := int(math.Ceil(float64(total) / float64(query.Limit)))
+	for i := range products {
+		h.fillMediaURLs(products[i].Media)
+	}
 
 	response := map[string]interface{}{
-		"products": products,
-		"pagination": map[string]interface{}{
-			"current_page": query.Page,
-			"total_pages":  totalPages,
-			"total_items":  total,
-			"limit":        query.Limit,
-		},
+		"products":   products,
+		"pagination": page,
 		"filters": map[string]interface{}{
-			"min_price": query.MinPrice,
-			"max_price": query.MaxPrice,
-			"search":    query.Search,
-			"sort_by":   query.SortBy,
-			"sort_dir":  query.SortDir,
+			"min_price":           query.MinPrice,
+			"max_price":           query.MaxPrice,
+			"currency":            query.Currency,
+			"search":              query.Search,
+			"sort_by":             query.SortBy,
+			"sort_dir":            query.SortDir,
+			"category_id":         query.CategoryID,
+			"include_descendants": query.IncludeDescendants,
+			"options":             query.Options,
+			"availability":        query.Availability,
+			"min_rating":          query.MinRating,
 		},
 	}
 
+	if query.Facets {
+		facets, err := h.repo.Facets(&query, h.facets)
+		if err != nil {
+			h.logger.Error("Failed to compute product facets", zap.Error(err))
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list products")
+		}
+		response["facets"] = facets
+	}
+
+	middleware.SetLinkHeader(c, page)
 	return c.JSON(http.StatusOK, response)
 }
+
+// checkFilters rejects malformed list filters.
+func checkFilters(query *ListProductsQuery) error {
+	if _, err := ParseOptionFilters(query.Options); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Option filters must look like type:value")
+	}
+	if query.Availability != "" && query.Availability != AvailabilityInStock && query.Availability != AvailabilityOutOfStock {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid availability")
+	}
+	if _, _, err := query.PriceRange(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return nil
+}
+
+// @Summary		Suggest products
+// @Description	Autocomplete product names; the last word of q matches as a prefix
+// @Tags			products
+// @Produce		json
+// @Param			q		query		string	true	"Partial product name"
+// @Param			limit	query		int		false	"Maximum suggestions (default 10, max 25)"
+// @Success		200		{array}		Suggestion
+// @Router			/products/suggest [get]
+func (h *Handler) Suggest(c echo.Context) error {
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	if limit < 1 || limit > 25 {
+		limit = 10
+	}
+
+	suggestions, err := h.repo.Suggest(c.QueryParam("q"), limit)
+	if err != nil {
+		h.logger.Error("Failed to suggest products", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to suggest products")
+	}
+
+	return c.JSON(http.StatusOK, suggestions)
+}
+
+// maxImportSize bounds the size of an uploaded import file.
+const maxImportSize = 32 << 20
+
+// syncImportSize is the largest upload imported during the request; larger
+// files are queued as a background job.
+const syncImportSize = 256 << 10
+
+// @Summary		Import products
+// @Description	Create or update products from a CSV or NDJSON file, matched on external_id (requires products:write). CSV files need a header with external_id, name, price and stock, and optionally description. Every row is validated like a product; invalid rows are skipped and listed in the job's error report. Small files are imported immediately; larger ones are queued and their progress is polled at the Location returned.
+// @Tags			products
+// @Accept			multipart/form-data
+// @Accept			text/csv
+// @Accept			application/x-ndjson
+// @Produce		json
+// @Param			file	formData	file	false	"CSV or NDJSON file, when uploading a form"
+// @Param			format	query		string	false	"File format (csv, ndjson); detected from the file name or content type by default"
+// @Success		200		{object}	ImportJob
+// @Success		202		{object}	ImportJob
+// @Failure		400		{object}	middleware.ErrorResponse
+// @Failure		413		{object}	middleware.ErrorResponse
+// @Router			/products/import [post]
+func (h *Handler) Import(c echo.Context) error {
+	filename, data, err := readUpload(c)
+	if err != nil {
+		return err
+	}
+
+	format, ok := uploadFormat(c, filename)
+	if !ok {
+		return echo.NewHTTPError(http.StatusBadRequest, "Unsupported import format; use csv or ndjson")
+	}
+
+	claims := c.Get("userClaims").(*models.Claims)
+	job := &ImportJob{
+		Status:    ImportPending,
+		Format:    format,
+		Filename:  filename,
+		Payload:   data,
+		CreatedBy: claims.UserID,
+		RowErrors: RowErrors{},
+	}
+	inline := len(data) <= syncImportSize
+	if inline {
+		now := time.Now()
+		job.Status = ImportRunning
+		job.LockedAt = &now
+		job.StartedAt = &now
+	}
+
+	if err := h.repo.CreateImportJob(job); err != nil {
+		h.logger.Error("Failed to create product import", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to import products")
+	}
+
+	if inline {
+		if err := h.importer.Run(job); err != nil {
+			h.logger.Error("Failed to run product import", zap.Error(err), zap.Uint("job_id", job.ID))
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to import products")
+		}
+		return c.JSON(http.StatusOK, job)
+	}
+
+	h.importer.Notify()
+	c.Response().Header().Set(echo.HeaderLocation, fmt.Sprintf("/products/import/%d", job.ID))
+	return c.JSON(http.StatusAccepted, job)
+}
+
+// readUpload returns the name and content of the file in a multipart
+// "file" field, or the raw request body otherwise.
+func readUpload(c echo.Context) (string, []byte, error) {
+	tooLarge := echo.NewHTTPError(http.StatusRequestEntityTooLarge,
+		fmt.Sprintf("Import files are limited to %d MB", maxImportSize>>20))
+
+	var filename string
+	var body io.Reader = c.Request().Body
+	if strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		header, err := c.FormFile("file")
+		if err != nil {
+			return "", nil, echo.NewHTTPError(http.StatusBadRequest, "Missing file")
+		}
+		if header.Size > maxImportSize {
+			return "", nil, tooLarge
+		}
+		file, err := header.Open()
+		if err != nil {
+			return "", nil, echo.NewHTTPError(http.StatusBadRequest, "Unreadable file")
+		}
+		defer file.Close()
+		filename, body = header.Filename, file
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, maxImportSize+1))
+	if err != nil {
+		return "", nil, echo.NewHTTPError(http.StatusBadRequest, "Unreadable file")
+	}
+	if len(data) > maxImportSize {
+		return "", nil, tooLarge
+	}
+	if len(data) == 0 {
+		return "", nil, echo.NewHTTPError(http.StatusBadRequest, "File is empty")
+	}
+	return filename, data, nil
+}
+
+// uploadFormat picks the format of an upload from the format query
+// parameter, else the file extension, else the content type of the body.
+func uploadFormat(c echo.Context, filename string) (BulkFormat, bool) {
+	if name := c.QueryParam("format"); name != "" {
+		return ParseBulkFormat(name)
+	}
+	if format, ok := ParseBulkFormat(path.Ext(filename)); ok {
+		return format, true
+	}
+	mediaType, _, _ := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+	return ParseBulkFormat(strings.TrimPrefix(path.Base(mediaType), "x-"))
+}
+
+// @Summary		Get product import
+// @Description	Get the progress and per-row error report of an import (requires products:write)
+// @Tags			products
+// @Produce		json
+// @Param			id	path		int	true	"Import job ID"
+// @Success		200	{object}	ImportJob
+// @Failure		404	{object}	middleware.ErrorResponse
+// @Router			/products/import/{id} [get]
+func (h *Handler) GetImport(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid import ID")
+	}
+
+	job, err := h.repo.GetImportJob(uint(id))
+	if err != nil {
+		if errors.Is(err, ErrImportJobNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "Import not found")
+		}
+		h.logger.Error("Failed to get product import", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get product import")
+	}
+
+	return c.JSON(http.StatusOK, job)
+}
+
+// exportBatchSize is the number of products read and flushed to the client
+// at a time during an export.
+const exportBatchSize = 500
+
+// @Summary		Export products
+// @Description	Stream every product matching the list filters as CSV or NDJSON, in the format accepted by the import (requires products:write)
+// @Tags			products
+// @Produce		text/csv
+// @Produce		application/x-ndjson
+// @Param			format		query		string	false	"File format (csv, ndjson); defaults to csv"
+// @Param			min_price	query		string	false	"Minimum price, e.g. 19.99"
+// @Param			max_price	query		string	false	"Maximum price"
+// @Param			currency	query		string	false	"ISO 4217 currency of the price filters; defaults to the store currency"
+// @Param			search		query		string	false	"Full-text search"
+// @Param			category_id	query		int		false	"Only products in this category"
+// @Param			include_descendants	query	bool	false	"Also include products in descendant categories"
+// @Param			option		query		[]string	false	"Variant option filter as type:value, repeatable"
+// @Param			availability	query	string	false	"Stock availability (in_stock, out_of_stock)"
+// @Success		200
+// @Failure		400	{object}	middleware.ErrorResponse
+// @Router			/products/export [get]
+func (h *Handler) Export(c echo.Context) error {
+	var query ListProductsQuery
+	if err := c.Bind(&query); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := checkFilters(&query); err != nil {
+		return err
+	}
+
+	format := FormatCSV
+	if name := c.QueryParam("format"); name != "" {
+		var ok bool
+		if format, ok = ParseBulkFormat(name); !ok {
+			return echo.NewHTTPError(http.StatusBadRequest, "Unsupported export format; use csv or ndjson")
+		}
+	}
+
+	response := c.Response()
+	response.Header().Set(echo.HeaderContentType, format.ContentType())
+	response.Header().Set(echo.HeaderContentDisposition,
+		fmt.Sprintf(`attachment; filename="products-%s.%s"`, time.Now().Format("20060102"), format))
+	response.WriteHeader(http.StatusOK)
+
+	writer, err := NewRecordWriter(format, response)
+	if err == nil {
+		err = h.repo.Export(&query, exportBatchSize, func(products []Product) error {
+			for i := range products {
+				if err := writer.Write(recordOf(&products[i])); err != nil {
+					return err
+				}
+			}
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+			response.Flush()
+			return nil
+		})
+	}
+	if err != nil {
+		// The status line has been sent; the truncated body is all the
+		// client will see.
+		h.logger.Error("Failed to export products", zap.Error(err))
+	}
+	return nil
+}
+
+// @Summary		List option types
+// @Description	List the option types products can vary by, with their values
+// @Tags			products
+// @Produce		json
+// @Success		200	{array}	OptionType
+// @Router			/option-types [get]
+func (h *Handler) ListOptionTypes(c echo.Context) error {
+	optionTypes, err := h.repo.ListOptionTypes()
+	if err != nil {
+		h.logger.Error("Failed to list option types", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list option types")
+	}
+	return c.JSON(http.StatusOK, optionTypes)
+}
+
+// @Summary		Create option type
+// @Description	Create an option type such as size or colour with its values (requires products:write)
+// @Tags			products
+// @Accept			json
+// @Produce		json
+// @Param			request	body		CreateOptionTypeRequest	true	"Option type definition"
+// @Success		201		{object}	OptionType
+// @Failure		400		{object}	middleware.ErrorResponse
+// @Failure		409		{object}	middleware.ErrorResponse
+// @Router			/option-types [post]
+func (h *Handler) CreateOptionType(c echo.Context) error {
+	var req CreateOptionTypeRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	optionType := OptionType{Name: req.Name, Presentation: presentation(req.Presentation, req.Name)}
+	for _, v := range req.Values {
+		optionType.Values = append(optionType.Values, OptionValue{
+			Name:         v.Name,
+			Presentation: presentation(v.Presentation, v.Name),
+		})
+	}
+
+	if err := h.repo.CreateOptionType(&optionType); err != nil {
+		return h.variantError(err, "Failed to create option type")
+	}
+
+	return c.JSON(http.StatusCreated, optionType)
+}
+
+// @Summary		Add option value
+// @Description	Add a value to an option type (requires products:write)
+// @Tags			products
+// @Accept			json
+// @Produce		json
+// @Param			id		path		int					true	"Option type ID"
+// @Param			request	body		OptionValueRequest	true	"Option value"
+// @Success		201		{object}	OptionValue
+// @Failure		400		{object}	middleware.ErrorResponse
+// @Failure		404		{object}	middleware.ErrorResponse
+// @Failure		409		{object}	middleware.ErrorResponse
+// @Router			/option-types/{id}/values [post]
+func (h *Handler) AddOptionValue(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid option type ID")
+	}
+
+	var req OptionValueRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	value := OptionValue{Name: req.Name, Presentation: presentation(req.Presentation, req.Name)}
+	if err := h.repo.AddOptionValue(uint(id), &value); err != nil {
+		return h.variantError(err, "Failed to add option value")
+	}
+
+	return c.JSON(http.StatusCreated, value)
+}
+
+// @Summary		Create variant
+// @Description	Add a variant with its own SKU, price override and stock to a product (requires products:write)
+// @Tags			products
+// @Accept			json
+// @Produce		json
+// @Param			id		path		int				true	"Product ID"
+// @Param			request	body		VariantRequest	true	"Variant definition"
+// @Success		201		{object}	ProductVariant
+// @Failure		400		{object}	middleware.ErrorResponse
+// @Failure		404		{object}	middleware.ErrorResponse
+// @Failure		409		{object}	middleware.ErrorResponse
+// @Router			/products/{id}/variants [post]
+func (h *Handler) CreateVariant(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
+	}
+
+	var req VariantRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	product, err := h.repo.GetByID(uint(id))
+	if err != nil {
+		return h.variantError(err, "Failed to create variant")
+	}
+
+	variant := ProductVariant{
+		SKU:     req.SKU,
+		Barcode: req.Barcode,
+		Price:   req.Price,
+		Stock:   req.Stock,
+	}
+	claims := c.Get("userClaims").(*models.Claims)
+	if err := h.repo.CreateVariant(product, &variant, req.OptionValueIDs, claims.UserID); err != nil {
+		return h.variantError(err, "Failed to create variant")
+	}
+
+	return c.JSON(http.StatusCreated, variant)
+}
+
+// @Summary		Generate variants
+// @Description	Create a variant for every combination of the chosen option values that the product does not have yet (requires products:write)
+// @Tags			products
+// @Accept			json
+// @Produce		json
+// @Param			id		path		int						true	"Product ID"
+// @Param			request	body		GenerateVariantsRequest	true	"Option values and defaults"
+// @Success		201		{array}		ProductVariant
+// @Failure		400		{object}	middleware.ErrorResponse
+// @Failure		404		{object}	middleware.ErrorResponse
+// @Failure		409		{object}	middleware.ErrorResponse
+// @Router			/products/{id}/variants/generate [post]
+func (h *Handler) GenerateVariants(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
+	}
+
+	var req GenerateVariantsRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	product, err := h.repo.GetByID(uint(id))
+	if err != nil {
+		return h.variantError(err, "Failed to generate variants")
+	}
+
+	claims := c.Get("userClaims").(*models.Claims)
+	variants, err := h.repo.GenerateVariants(product, req, claims.UserID)
+	if err != nil {
+		return h.variantError(err, "Failed to generate variants")
+	}
+
+	return c.JSON(http.StatusCreated, variants)
+}
+
+// @Summary		Update variant
+// @Description	Update a variant's SKU, barcode and price override (requires products:write). Stock is changed through the inventory endpoints.
+// @Tags			products
+// @Accept			json
+// @Produce		json
+// @Param			id			path		int						true	"Product ID"
+// @Param			variant_id	path		int						true	"Variant ID"
+// @Param			request		body		UpdateVariantRequest	true	"Variant fields"
+// @Success		200			{object}	ProductVariant
+// @Failure		400			{object}	middleware.ErrorResponse
+// @Failure		404			{object}	middleware.ErrorResponse
+// @Failure		409			{object}	middleware.ErrorResponse
+// @Router			/products/{id}/variants/{variant_id} [put]
+func (h *Handler) UpdateVariant(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
+	}
+	variantID, err := strconv.ParseUint(c.Param("variant_id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid variant ID")
+	}
+
+	var req UpdateVariantRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	product, err := h.repo.GetByID(uint(id))
+	if err != nil {
+		return h.variantError(err, "Failed to update variant")
+	}
+	variant, err := h.repo.GetVariant(product.ID, uint(variantID))
+	if err != nil {
+		return h.variantError(err, "Failed to update variant")
+	}
+
+	variant.SKU = req.SKU
+	variant.Barcode = req.Barcode
+	variant.Price = req.Price
+	if err := h.repo.UpdateVariant(product, variant); err != nil {
+		return h.variantError(err, "Failed to update variant")
+	}
+
+	reserved, err := h.repo.Reserved(product.ID)
+	if err != nil {
+		return h.variantError(err, "Failed to update variant")
+	}
+	fillAvailability(variant, product.Price, reserved)
+	return c.JSON(http.StatusOK, variant)
+}
+
+// @Summary		Delete variant
+// @Description	Delete a variant of a product (requires products:write)
+// @Tags			products
+// @Param			id			path	int	true	"Product ID"
+// @Param			variant_id	path	int	true	"Variant ID"
+// @Success		204			"No Content"
+// @Failure		404			{object}	middleware.ErrorResponse
+// @Router			/products/{id}/variants/{variant_id} [delete]
+func (h *Handler) DeleteVariant(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
+	}
+	variantID, err := strconv.ParseUint(c.Param("variant_id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid variant ID")
+	}
+
+	variant, err := h.repo.GetVariant(uint(id), uint(variantID))
+	if err != nil {
+		return h.variantError(err, "Failed to delete variant")
+	}
+
+	if err := h.repo.DeleteVariant(variant); err != nil {
+		return h.variantError(err, "Failed to delete variant")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// @Summary		List product media
+// @Description	List the images of a product in display order, with thumbnail URLs
+// @Tags			products
+// @Produce		json
+// @Param			id	path		int	true	"Product ID"
+// @Success		200	{array}		ProductMedia
+// @Router			/products/{id}/media [get]
+func (h *Handler) ListMedia(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
+	}
+
+	media, err := h.repo.ListMedia(uint(id))
+	if err != nil {
+		h.logger.Error("Failed to list product media", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list product media")
+	}
+
+	h.fillMediaURLs(media)
+	return c.JSON(http.StatusOK, media)
+}
+
+// @Summary		Upload product media
+// @Description	Upload a JPEG, PNG, GIF or WebP image of a product and generate its thumbnails (requires products:write). The first image of a product becomes its primary image.
+// @Tags			products
+// @Accept			multipart/form-data
+// @Produce		json
+// @Param			id			path		int		true	"Product ID"
+// @Param			file		formData	file	true	"Image file"
+// @Param			alt_text	formData	string	false	"Alternative text"
+// @Param			is_primary	formData	bool	false	"Make this the primary image"
+// @Success		201			{object}	ProductMedia
+// @Failure		400			{object}	middleware.ErrorResponse
+// @Failure		404			{object}	middleware.ErrorResponse
+// @Failure		413			{object}	middleware.ErrorResponse
+// @Failure		415			{object}	middleware.ErrorResponse
+// @Router			/products/{id}/media [post]
+func (h *Handler) UploadMedia(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
+	}
+	if _, err := h.repo.GetByID(uint(id)); err != nil {
+		return h.mediaError(err, "Failed to upload product media")
+	}
+
+	tooLarge := echo.NewHTTPError(http.StatusRequestEntityTooLarge,
+		fmt.Sprintf("Images are limited to %d bytes", h.media.MaxBytes))
+	// Leave room for the multipart framing and form fields.
+	c.Request().Body = http.MaxBytesReader(c.Response(), c.Request().Body, h.media.MaxBytes+64<<10)
+	header, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return tooLarge
+		}
+		return echo.NewHTTPError(http.StatusBadRequest, "Missing file")
+	}
+	if header.Size > h.media.MaxBytes {
+		return tooLarge
+	}
+
+	altText := strings.TrimSpace(c.FormValue("alt_text"))
+	if len(altText) > 255 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Alt text is limited to 255 characters")
+	}
+	isPrimary := false
+	if value := c.FormValue("is_primary"); value != "" {
+		if isPrimary, err = strconv.ParseBool(value); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid is_primary")
+		}
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Unreadable file")
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, h.media.MaxBytes+1))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Unreadable file")
+	}
+	if int64(len(data)) > h.media.MaxBytes {
+		return tooLarge
+	}
+
+	processed, err := ProcessImage(uint(id), data, h.media.Thumbnails)
+	if err != nil {
+		return h.mediaError(err, "Failed to upload product media")
+	}
+
+	files := []MediaFile{processed.Original}
+	media := &ProductMedia{
+		ProductID:     uint(id),
+		StorageKey:    processed.Original.Key,
+		ContentType:   processed.Original.ContentType,
+		Size:          int64(len(data)),
+		Width:         processed.Width,
+		Height:        processed.Height,
+		AltText:       altText,
+		IsPrimary:     isPrimary,
+		ThumbnailKeys: MediaKeys{},
+	}
+	for name, thumbnail := range processed.Thumbnails {
+		files = append(files, thumbnail)
+		media.ThumbnailKeys[name] = thumbnail.Key
+	}
+
+	var stored []string
+	for _, f := range files {
+		if err := h.storage.Put(c.Request().Context(), f.Key, f.Data, f.ContentType); err != nil {
+			h.removeFiles(stored)
+			h.logger.Error("Failed to store product media", zap.Error(err), zap.String("key", f.Key))
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to upload product media")
+		}
+		stored = append(stored, f.Key)
+	}
+
+	if err := h.repo.CreateMedia(media); err != nil {
+		h.removeFiles(stored)
+		return h.mediaError(err, "Failed to upload product media")
+	}
+
+	media.URL = h.storage.URL(media.StorageKey)
+	media.Thumbnails = h.thumbnailURLs(media)
+	return c.JSON(http.StatusCreated, media)
+}
+
+// @Summary		Update product media
+// @Description	Change the alt text of an image or make it the primary image (requires products:write)
+// @Tags			products
+// @Accept			json
+// @Produce		json
+// @Param			id			path		int					true	"Product ID"
+// @Param			media_id	path		int					true	"Media ID"
+// @Param			request		body		UpdateMediaRequest	true	"Media changes"
+// @Success		200			{object}	ProductMedia
+// @Failure		400			{object}	middleware.ErrorResponse
+// @Failure		404			{object}	middleware.ErrorResponse
+// @Router			/products/{id}/media/{media_id} [put]
+func (h *Handler) UpdateMedia(c echo.Context) error {
+	media, err := h.findMedia(c)
+	if err != nil {
+		return err
+	}
+
+	var req UpdateMediaRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := h.validator.Struct(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.repo.UpdateMedia(media, req); err != nil {
+		return h.mediaError(err, "Failed to update product media")
+	}
+
+	media.URL = h.storage.URL(media.StorageKey)
+	media.Thumbnails = h.thumbnailURLs(media)
+	return c.JSON(http.StatusOK, media)
+}
+
+// @Summary		Reorder product media
+// @Description	Set the display order of a product's images; every image must be listed once (requires products:write)
+// @Tags			products
+// @Accept			json
+// @Produce		json
+// @Param			id		path		int					true	"Product ID"
+// @Param			request	body		ReorderMediaRequest	true	"Media IDs in display order"
+// @Success		200		{array}		ProductMedia
+// @Failure		400		{object}	middleware.ErrorResponse
+// @Failure		404		{object}	middleware.ErrorResponse
+// @Router			/products/{id}/media/reorder [post]
+func (h *Handler) ReorderMedia(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
+	}
+
+	var req ReorderMediaRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := h.validator.Struct(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	media, err := h.repo.ReorderMedia(uint(id), req.MediaIDs)
+	if err != nil {
+		return h.mediaError(err, "Failed to reorder product media")
+	}
+
+	h.fillMediaURLs(media)
+	return c.JSON(http.StatusOK, media)
+}
+
+// @Summary		Delete product media
+// @Description	Delete an image and its thumbnails; the next image becomes primary if it was the primary one (requires products:write)
+// @Tags			products
+// @Param			id			path	int	true	"Product ID"
+// @Param			media_id	path	int	true	"Media ID"
+// @Success		204			"No Content"
+// @Failure		404			{object}	middleware.ErrorResponse
+// @Router			/products/{id}/media/{media_id} [delete]
+func (h *Handler) DeleteMedia(c echo.Context) error {
+	media, err := h.findMedia(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.repo.DeleteMedia(media); err != nil {
+		return h.mediaError(err, "Failed to delete product media")
+	}
+	h.removeFiles(media.StorageKeys())
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// findMedia loads the media named by the id and media_id path parameters.
+func (h *Handler) findMedia(c echo.Context) (*ProductMedia, error) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
+	}
+	mediaID, err := strconv.ParseUint(c.Param("media_id"), 10, 32)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid media ID")
+	}
+
+	media, err := h.repo.GetMedia(uint(id), uint(mediaID))
+	if err != nil {
+		return nil, h.mediaError(err, "Failed to get product media")
+	}
+	return media, nil
+}
+
+// fillMediaURLs sets the public URLs of media from their storage keys.
+func (h *Handler) fillMediaURLs(media []ProductMedia) {
+	for i := range media {
+		media[i].URL = h.storage.URL(media[i].StorageKey)
+		media[i].Thumbnails = h.thumbnailURLs(&media[i])
+	}
+}
+
+func (h *Handler) thumbnailURLs(media *ProductMedia) map[string]string {
+	urls := make(map[string]string, len(media.ThumbnailKeys))
+	for name, key := range media.ThumbnailKeys {
+		urls[name] = h.storage.URL(key)
+	}
+	return urls
+}
+
+// removeFiles deletes stored files that are no longer referenced. Failures
+// only leave orphaned files behind, so they are logged and otherwise
+// ignored.
+func (h *Handler) removeFiles(keys []string) {
+	for _, key := range keys {
+		if err := h.storage.Delete(context.Background(), key); err != nil {
+			h.logger.Warn("Failed to delete stored file", zap.Error(err), zap.String("key", key))
+		}
+	}
+}
+
+func (h *Handler) mediaError(err error, message string) error {
+	switch {
+	case errors.Is(err, ErrProductNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Product not found")
+	case errors.Is(err, ErrMediaNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Media not found")
+	case errors.Is(err, ErrInvalidMediaOrder):
+		return echo.NewHTTPError(http.StatusBadRequest, "Media order must list every image of the product exactly once")
+	case errors.Is(err, ErrUnsupportedMedia):
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType, "Only JPEG, PNG, GIF and WebP images are supported")
+	}
+	h.logger.Error(message, zap.Error(err))
+	return echo.NewHTTPError(http.StatusInternalServerError, message)
+}
+
+func (h *Handler) variantError(err error, message string) error {
+	switch {
+	case errors.Is(err, ErrProductNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Product not found")
+	case errors.Is(err, ErrVariantNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Variant not found")
+	case errors.Is(err, ErrOptionTypeNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Option type not found")
+	case errors.Is(err, ErrOptionValueNotFound):
+		return echo.NewHTTPError(http.StatusBadRequest, "Unknown option value")
+	case errors.Is(err, ErrVariantCurrency):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrDuplicateOptionType):
+		return echo.NewHTTPError(http.StatusBadRequest, "A variant takes at most one value per option type")
+	case errors.Is(err, ErrSKUConflict):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	case errors.Is(err, ErrVariantExists):
+		return echo.NewHTTPError(http.StatusConflict, "A variant with these option values already exists")
+	case errors.Is(err, ErrOptionTypeConflict):
+		return echo.NewHTTPError(http.StatusConflict, "Option type already exists")
+	case errors.Is(err, ErrOptionValueConflict):
+		return echo.NewHTTPError(http.StatusConflict, "Option value already exists")
+	case errors.Is(err, inventory.ErrNoWarehouse):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	h.logger.Error(message, zap.Error(err))
+	return echo.NewHTTPError(http.StatusInternalServerError, message)
+}
+
+// presentation returns the display label, falling back to the name.
+func presentation(label, name string) string {
+	if label != "" {
+		return label
+	}
+	return name
+}
@@ -0,0 +1,259 @@
+package product
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+)
+
+// ImporterConfig tunes the processing of bulk product imports.
+type ImporterConfig struct {
+	Workers      int
+	PollInterval time.Duration
+	// BatchSize is the number of rows upserted per transaction; progress is
+	// reported after every batch.
+	BatchSize  int
+	StaleAfter time.Duration
+	// MaxRowErrors caps the rows listed in a job's error report. Every
+	// failed row is still counted.
+	MaxRowErrors int
+}
+
+// Importer validates and upserts the rows of import jobs. Jobs are run
+// directly with Run, or picked up from the queue by a pool of workers.
+type Importer struct {
+	repo      *Repository
+	validator *validator.Validate
+	config    ImporterConfig
+	logger    *zap.Logger
+
+	wake   chan struct{}
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewImporter(repo *Repository, validator *validator.Validate, config ImporterConfig, logger *zap.Logger) *Importer {
+	if config.Workers < 1 {
+		config.Workers = 1
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = 5 * time.Second
+	}
+	if config.BatchSize < 1 {
+		config.BatchSize = 500
+	}
+	if config.StaleAfter <= 0 {
+		config.StaleAfter = 5 * time.Minute
+	}
+	if config.MaxRowErrors < 1 {
+		config.MaxRowErrors = 1000
+	}
+
+	return &Importer{
+		repo:      repo,
+		validator: validator,
+		config:    config,
+		logger:    logger,
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+// Start launches the workers. They run until Stop is called.
+func (i *Importer) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	i.cancel = cancel
+
+	for n := 0; n < i.config.Workers; n++ {
+		i.wg.Add(1)
+		go i.work(ctx)
+	}
+}
+
+// Stop signals the workers to finish and waits for running imports to
+// complete, or for ctx to expire. Unfinished jobs are picked up again once
+// their lock goes stale.
+func (i *Importer) Stop(ctx context.Context) error {
+	if i.cancel == nil {
+		return nil
+	}
+	i.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		i.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("product importer did not stop in time: %w", ctx.Err())
+	}
+}
+
+// Notify wakes a worker to pick up a newly queued job without waiting for
+// the next poll.
+func (i *Importer) Notify() {
+	select {
+	case i.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (i *Importer) work(ctx context.Context) {
+	defer i.wg.Done()
+
+	ticker := time.NewTicker(i.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil && i.processNext() {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-i.wake:
+		}
+	}
+}
+
+// processNext runs one pending job and reports whether there was one.
+func (i *Importer) processNext() bool {
+	job, err := i.repo.ClaimImportJob(i.config.StaleAfter)
+	if err != nil {
+		i.logger.Error("Failed to claim product import", zap.Error(err))
+		return false
+	}
+	if job == nil {
+		return false
+	}
+
+	if err := i.Run(job); err != nil {
+		i.logger.Error("Failed to run product import", zap.Error(err), zap.Uint("job_id", job.ID))
+	}
+	return true
+}
+
+// Run imports every row of a claimed job, recording progress after each
+// batch. Rows that fail validation or cannot be saved are reported on the
+// job and skipped. The returned error only concerns saving the job itself.
+func (i *Importer) Run(job *ImportJob) error {
+	records, err := readRecords(job.Format, job.Payload)
+	if err != nil {
+		job.Status = ImportFailed
+		job.Error = err.Error()
+		return i.repo.FinishImportJob(job)
+	}
+
+	job.TotalRows = len(records)
+	if err := i.repo.SaveImportProgress(job); err != nil {
+		return err
+	}
+
+	seen := make(map[string]int)
+	batch := make([]Product, 0, i.config.BatchSize)
+	batchRecords := make([]parsedRecord, 0, i.config.BatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		created, err := i.repo.UpsertByExternalID(batch, job.CreatedBy)
+		if err != nil {
+			i.logger.Error("Failed to save product import batch",
+				zap.Error(err),
+				zap.Uint("job_id", job.ID),
+				zap.Int("first_row", batchRecords[0].Row))
+			for _, record := range batchRecords {
+				i.reject(job, record, []string{"row could not be saved"})
+			}
+		} else {
+			job.CreatedCount += created
+			job.UpdatedCount += len(batch) - created
+		}
+		job.ProcessedRows += len(batch)
+		batch, batchRecords = batch[:0], batchRecords[:0]
+		return i.repo.SaveImportProgress(job)
+	}
+
+	for _, record := range records {
+		product := record.product()
+		errs := record.Errors
+		if len(errs) == 0 {
+			errs = i.validate(&product)
+		}
+		if first, ok := seen[*product.ExternalID]; ok && len(errs) == 0 {
+			errs = []string{fmt.Sprintf("external_id repeats row %d", first)}
+		}
+		if len(errs) > 0 {
+			i.reject(job, record, errs)
+			job.ProcessedRows++
+			continue
+		}
+
+		seen[*product.ExternalID] = record.Row
+		batch = append(batch, product)
+		batchRecords = append(batchRecords, record)
+		if len(batch) == i.config.BatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	job.Status = ImportCompleted
+	return i.repo.FinishImportJob(job)
+}
+
+// validate checks a row with the validation rules of Product.
+func (i *Importer) validate(product *Product) []string {
+	var errs []string
+	if *product.ExternalID == "" {
+		errs = append(errs, "external_id is required")
+	}
+
+	err := i.validator.Struct(product)
+	var fieldErrs validator.ValidationErrors
+	if errors.As(err, &fieldErrs) {
+		for _, fe := range fieldErrs {
+			rule := fe.Tag()
+			if fe.Param() != "" {
+				rule += "=" + fe.Param()
+			}
+			errs = append(errs, fmt.Sprintf("%s failed on %s", fieldName(fe.Field()), rule))
+		}
+	} else if err != nil {
+		errs = append(errs, err.Error())
+	}
+	return errs
+}
+
+func (i *Importer) reject(job *ImportJob, record parsedRecord, errs []string) {
+	job.FailedCount++
+	if len(job.RowErrors) < i.config.MaxRowErrors {
+		job.RowErrors = append(job.RowErrors, RowError{
+			Row:        record.Row,
+			ExternalID: strings.TrimSpace(record.ExternalID),
+			Errors:     errs,
+		})
+	}
+}
+
+// fieldName converts a Product field name to its column name.
+func fieldName(field string) string {
+	if field == "ExternalID" {
+		return "external_id"
+	}
+	return strings.ToLower(field)
+}
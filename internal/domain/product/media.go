@@ -0,0 +1,393 @@
+package product
+
+import (
+	"bytes"
+	"crypto/rand"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrMediaNotFound     = errors.New("media not found")
+	ErrInvalidMediaOrder = errors.New("media order must list every media item of the product exactly once")
+	ErrUnsupportedMedia  = errors.New("unsupported media type")
+)
+
+// mediaTypes maps the image types accepted for upload to their file
+// extension.
+var mediaTypes = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+	"image/webp": "webp",
+}
+
+// maxMediaPixels bounds the dimensions of an uploaded image, so a small
+// file cannot decode into an enormous bitmap.
+const maxMediaPixels = 50_000_000
+
+// ThumbnailSize is a thumbnail generated for every upload, scaled to fit
+// within MaxSize pixels on its longest side.
+type ThumbnailSize struct {
+	Name    string
+	MaxSize int
+}
+
+// MediaConfig limits uploads and lists the thumbnails generated for them.
+type MediaConfig struct {
+	MaxBytes   int64
+	Thumbnails []ThumbnailSize
+}
+
+// ParseThumbnailSizes parses a comma-separated list of name=size pairs, such
+// as "small=160,large=1024".
+func ParseThumbnailSizes(spec string) ([]ThumbnailSize, error) {
+	var sizes []ThumbnailSize
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		name = strings.TrimSpace(name)
+		size, err := strconv.Atoi(strings.TrimSpace(value))
+		if !ok || name == "" || err != nil || size < 1 {
+			return nil, fmt.Errorf("invalid thumbnail size %q", part)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate thumbnail size %q", name)
+		}
+		seen[name] = true
+		sizes = append(sizes, ThumbnailSize{Name: name, MaxSize: size})
+	}
+	return sizes, nil
+}
+
+// MediaKeys maps thumbnail size names to storage keys, stored as JSON.
+type MediaKeys map[string]string
+
+func (k MediaKeys) Value() (driver.Value, error) {
+	if k == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(k)
+	return string(b), err
+}
+
+func (k *MediaKeys) Scan(value interface{}) error {
+	var b []byte
+	switch v := value.(type) {
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	case nil:
+		*k = MediaKeys{}
+		return nil
+	default:
+		return errors.New("unsupported type for MediaKeys")
+	}
+	return json.Unmarshal(b, k)
+}
+
+// ProductMedia is an image of a product. The original and its thumbnails
+// are kept in storage under StorageKey and ThumbnailKeys; URL and
+// Thumbnails are filled in from them when the media is returned.
+type ProductMedia struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	ProductID     uint      `gorm:"not null;index" json:"product_id"`
+	StorageKey    string    `gorm:"size:255;not null" json:"-"`
+	ContentType   string    `gorm:"size:100;not null" json:"content_type"`
+	Size          int64     `gorm:"not null" json:"size"`
+	Width         int       `gorm:"not null" json:"width"`
+	Height        int       `gorm:"not null" json:"height"`
+	AltText       string    `gorm:"size:255" json:"alt_text"`
+	Position      int       `gorm:"not null;default:0" json:"position"`
+	IsPrimary     bool      `gorm:"not null;default:false" json:"is_primary"`
+	ThumbnailKeys MediaKeys `gorm:"column:thumbnails;type:jsonb;not null" json:"-"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+
+	URL        string            `gorm:"-" json:"url"`
+	Thumbnails map[string]string `gorm:"-" json:"thumbnails"`
+}
+
+func (ProductMedia) TableName() string {
+	return "product_media"
+}
+
+// StorageKeys returns the keys of the original and every thumbnail.
+func (m *ProductMedia) StorageKeys() []string {
+	keys := []string{m.StorageKey}
+	for _, key := range m.ThumbnailKeys {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+type UpdateMediaRequest struct {
+	AltText *string `json:"alt_text" validate:"omitempty,max=255"`
+	// IsPrimary set to true makes this the product's primary image.
+	IsPrimary *bool `json:"is_primary"`
+}
+
+type ReorderMediaRequest struct {
+	MediaIDs []uint `json:"media_ids" validate:"required"`
+}
+
+// MediaFile is an encoded image ready to be stored.
+type MediaFile struct {
+	Key         string
+	Data        []byte
+	ContentType string
+}
+
+// ProcessedImage is an upload that passed validation, with its thumbnails.
+type ProcessedImage struct {
+	Original   MediaFile
+	Thumbnails map[string]MediaFile
+	Width      int
+	Height     int
+}
+
+// ProcessImage checks that data is an image of a supported type and
+// renders a thumbnail of every configured size. Files are keyed under a
+// random prefix per upload below the product's directory.
+func ProcessImage(productID uint, data []byte, sizes []ThumbnailSize) (*ProcessedImage, error) {
+	contentType := http.DetectContentType(data)
+	ext, ok := mediaTypes[contentType]
+	if !ok {
+		return nil, ErrUnsupportedMedia
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedMedia
+	}
+	if config.Width < 1 || config.Height < 1 || config.Width*config.Height > maxMediaPixels {
+		return nil, fmt.Errorf("%w: image dimensions %dx%d are out of range", ErrUnsupportedMedia, config.Width, config.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedMedia
+	}
+
+	token := make([]byte, 12)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	prefix := fmt.Sprintf("products/%d/%s/", productID, hex.EncodeToString(token))
+
+	processed := &ProcessedImage{
+		Original:   MediaFile{Key: prefix + "original." + ext, Data: data, ContentType: contentType},
+		Thumbnails: make(map[string]MediaFile, len(sizes)),
+		Width:      config.Width,
+		Height:     config.Height,
+	}
+	for _, size := range sizes {
+		thumbnail, err := renderThumbnail(img, contentType, size.MaxSize)
+		if err != nil {
+			return nil, err
+		}
+		thumbnail.Key = prefix + size.Name + thumbnailExt(thumbnail.ContentType)
+		processed.Thumbnails[size.Name] = thumbnail
+	}
+	return processed, nil
+}
+
+// renderThumbnail scales img to fit within maxSize, never enlarging it.
+// JPEG sources produce JPEG thumbnails; other formats may be transparent
+// and produce PNG.
+func renderThumbnail(img image.Image, contentType string, maxSize int) (MediaFile, error) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > maxSize || height > maxSize {
+		if width >= height {
+			width, height = maxSize, max(1, height*maxSize/width)
+		} else {
+			width, height = max(1, width*maxSize/height), maxSize
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Over, nil)
+
+	var buf bytes.Buffer
+	if contentType == "image/jpeg" {
+		if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85}); err != nil {
+			return MediaFile{}, err
+		}
+		return MediaFile{Data: buf.Bytes(), ContentType: "image/jpeg"}, nil
+	}
+	if err := png.Encode(&buf, dst); err != nil {
+		return MediaFile{}, err
+	}
+	return MediaFile{Data: buf.Bytes(), ContentType: "image/png"}, nil
+}
+
+func thumbnailExt(contentType string) string {
+	if contentType == "image/jpeg" {
+		return ".jpg"
+	}
+	return ".png"
+}
+
+func (r *Repository) ListMedia(productID uint) ([]ProductMedia, error) {
+	media := []ProductMedia{}
+	err := r.db.Where("product_id = ?", productID).Order("position, id").Find(&media).Error
+	return media, err
+}
+
+func (r *Repository) GetMedia(productID, mediaID uint) (*ProductMedia, error) {
+	var media ProductMedia
+	if err := r.db.Where("product_id = ?", productID).First(&media, mediaID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMediaNotFound
+		}
+		return nil, err
+	}
+	return &media, nil
+}
+
+// CreateMedia adds media after the product's existing media. The first
+// media of a product becomes its primary image, as does any media created
+// with IsPrimary set.
+func (r *Repository) CreateMedia(media *ProductMedia) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockProduct(tx, media.ProductID); err != nil {
+			return err
+		}
+
+		var stats struct {
+			Count       int64
+			MaxPosition int
+		}
+		if err := tx.Model(&ProductMedia{}).
+			Select("COUNT(*) AS count, COALESCE(MAX(position), -1) AS max_position").
+			Where("product_id = ?", media.ProductID).
+			Scan(&stats).Error; err != nil {
+			return err
+		}
+
+		media.Position = stats.MaxPosition + 1
+		if stats.Count == 0 {
+			media.IsPrimary = true
+		} else if media.IsPrimary {
+			if err := clearPrimary(tx, media.ProductID); err != nil {
+				return err
+			}
+		}
+		return tx.Create(media).Error
+	})
+}
+
+// UpdateMedia changes the alt text of media or makes it the primary image.
+func (r *Repository) UpdateMedia(media *ProductMedia, req UpdateMediaRequest) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockProduct(tx, media.ProductID); err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{}
+		if req.AltText != nil {
+			media.AltText = *req.AltText
+			updates["alt_text"] = media.AltText
+		}
+		if req.IsPrimary != nil && *req.IsPrimary && !media.IsPrimary {
+			if err := clearPrimary(tx, media.ProductID); err != nil {
+				return err
+			}
+			media.IsPrimary = true
+			updates["is_primary"] = true
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+		return tx.Model(media).Updates(updates).Error
+	})
+}
+
+// ReorderMedia sets the display order of a product's media to the order
+// of mediaIDs.
+func (r *Repository) ReorderMedia(productID uint, mediaIDs []uint) ([]ProductMedia, error) {
+	var media []ProductMedia
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockProduct(tx, productID); err != nil {
+			return err
+		}
+
+		var existing []uint
+		if err := tx.Model(&ProductMedia{}).Where("product_id = ?", productID).Pluck("id", &existing).Error; err != nil {
+			return err
+		}
+		if len(existing) != len(mediaIDs) {
+			return ErrInvalidMediaOrder
+		}
+		sorted := append([]uint(nil), mediaIDs...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		sort.Slice(existing, func(i, j int) bool { return existing[i] < existing[j] })
+		for i := range sorted {
+			if sorted[i] != existing[i] {
+				return ErrInvalidMediaOrder
+			}
+		}
+
+		for position, id := range mediaIDs {
+			if err := tx.Model(&ProductMedia{}).Where("id = ?", id).Update("position", position).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Where("product_id = ?", productID).Order("position, id").Find(&media).Error
+	})
+	return media, err
+}
+
+// DeleteMedia removes media, promoting the next media in order to primary
+// image when it was the primary one. Its files are left for the caller to
+// remove from storage.
+func (r *Repository) DeleteMedia(media *ProductMedia) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockProduct(tx, media.ProductID); err != nil {
+			return err
+		}
+		if err := tx.Delete(media).Error; err != nil {
+			return err
+		}
+		if !media.IsPrimary {
+			return nil
+		}
+
+		var next ProductMedia
+		err := tx.Where("product_id = ?", media.ProductID).Order("position, id").First(&next).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Model(&next).Update("is_primary", true).Error
+	})
+}
+
+func clearPrimary(tx *gorm.DB, productID uint) error {
+	return tx.Model(&ProductMedia{}).
+		Where("product_id = ? AND is_primary", productID).
+		Update("is_primary", false).Error
+}
@@ -2,36 +2,229 @@ package product
 
 import (
 	"errors"
+	"fmt"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
+	"github.com/nneji123/ecommerce-golang/internal/common/money"
+	"github.com/nneji123/ecommerce-golang/internal/domain/category"
+	"github.com/nneji123/ecommerce-golang/internal/domain/inventory"
+	"github.com/nneji123/ecommerce-golang/internal/middleware"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrProductNotFound     = errors.New("product not found")
+	ErrCategoryNotFound    = errors.New("category not found")
+	ErrVariantNotFound     = errors.New("variant not found")
+	ErrSKUConflict         = errors.New("sku already exists")
+	ErrVariantExists       = errors.New("a variant with these option values already exists")
+	ErrOptionTypeNotFound  = errors.New("option type not found")
+	ErrOptionTypeConflict  = errors.New("option type already exists")
+	ErrOptionValueNotFound = errors.New("option value not found")
+	ErrOptionValueConflict = errors.New("option value already exists")
+	ErrDuplicateOptionType = errors.New("a variant takes at most one value per option type")
+	ErrInvalidOptionFilter = errors.New("option filters must look like type:value")
+	ErrInvalidPriceFilter  = errors.New("price filters must be amounts in the given currency")
+	ErrVariantCurrency     = errors.New("variant prices must be in the product's currency")
+	ErrTaxClassNotFound    = errors.New("tax class not found")
 )
 
 type Repository struct {
-	db *gorm.DB
+	db        *gorm.DB
+	inventory *inventory.Repository
+	cursors   *middleware.CursorSigner
+}
+
+// NewRepository returns a product repository whose listing cursors are
+// signed by cursors.
+func NewRepository(db *gorm.DB, cursors *middleware.CursorSigner) *Repository {
+	return &Repository{db: db, inventory: inventory.NewRepository(db), cursors: cursors}
 }
 
-func NewRepository(db *gorm.DB) *Repository {
-	return &Repository{db: db}
+// Create inserts a product. Category assignments are managed separately
+// through SetCategories. The product's stock is recorded as a receipt into
+// the default warehouse.
+func (r *Repository) Create(product *Product, actorID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkTaxClass(tx, product.TaxClassID); err != nil {
+			return err
+		}
+		stock := product.Stock
+		product.Stock = 0
+		if err := tx.Omit(clause.Associations).Create(product).Error; err != nil {
+			return err
+		}
+		if err := receiveInitialStock(tx, product.ID, nil, stock, actorID); err != nil {
+			return err
+		}
+		product.Stock = stock
+		return nil
+	})
 }
 
-func (r *Repository) Create(product *Product) error {
-	return r.db.Create(product).Error
+// receiveInitialStock records the stock a product or variant is created with
+// as a receipt into the default warehouse. Stock changes after creation go
+// through the inventory ledger.
+func receiveInitialStock(tx *gorm.DB, productID uint, variantID *uint, quantity int, actorID uint) error {
+	if quantity <= 0 {
+		return nil
+	}
+	_, err := inventory.NewRepository(tx).Receive(inventory.ReceiveRequest{
+		ProductID: productID,
+		VariantID: variantID,
+		Quantity:  quantity,
+		Reason:    "initial stock",
+	}, actorID)
+	return err
 }
 
 func (r *Repository) GetByID(id uint) (*Product, error) {
 	var product Product
-	if err := r.db.First(&product, id).Error; err != nil {
+	err := r.db.Preload("Categories").
+		Scopes(withMedia).
+		Preload("Variants", func(db *gorm.DB) *gorm.DB {
+			return db.Order("id")
+		}).
+		Preload("Variants.OptionValues", func(db *gorm.DB) *gorm.DB {
+			return db.Order("option_type_id, position")
+		}).
+		First(&product, id).Error
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("product not found")
+			return nil, ErrProductNotFound
 		}
 		return nil, err
 	}
+	reserved, err := r.Reserved(product.ID)
+	if err != nil {
+		return nil, err
+	}
+	fillReserved(&product, reserved)
+	for i := range product.Variants {
+		fillAvailability(&product.Variants[i], product.Price, reserved)
+	}
 	return &product, nil
 }
 
+// Reserved returns the stock of a product and its variants held by pending
+// orders.
+func (r *Repository) Reserved(productID uint) (map[inventory.Key]int, error) {
+	return r.inventory.Reserved([]uint{productID})
+}
+
+// fillReserved sets the reserved and available-to-sell stock of a product.
+func fillReserved(product *Product, reserved map[inventory.Key]int) {
+	product.Reserved = reserved[inventory.Key{ProductID: product.ID}]
+	product.AvailableToSell = max(product.Stock-product.Reserved, 0)
+}
+
+func fillAvailability(variant *ProductVariant, basePrice money.Money, reserved map[inventory.Key]int) {
+	variant.EffectivePrice = variant.UnitPrice(basePrice)
+	variant.Reserved = reserved[inventory.Key{ProductID: variant.ProductID, VariantID: variant.ID}]
+	variant.AvailableToSell = max(variant.Stock-variant.Reserved, 0)
+	variant.Available = variant.AvailableToSell > 0
+}
+
+// fillReservedList sets the reserved and available-to-sell stock of a page
+// of products.
+func (r *Repository) fillReservedList(products []Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+	productIDs := make([]uint, len(products))
+	for i := range products {
+		productIDs[i] = products[i].ID
+	}
+	reserved, err := r.inventory.Reserved(productIDs)
+	if err != nil {
+		return err
+	}
+	for i := range products {
+		fillReserved(&products[i], reserved)
+	}
+	return nil
+}
+
+// withMedia preloads the media of products in display order.
+func withMedia(db *gorm.DB) *gorm.DB {
+	return db.Preload("Media", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position, id")
+	})
+}
+
+// lockProduct loads a product and locks it until the end of tx.
+func lockProduct(tx *gorm.DB, id uint) (*Product, error) {
+	var product Product
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrProductNotFound
+		}
+		return nil, err
+	}
+	return &product, nil
+}
+
+// Update saves a product. Its stock is left alone: stock only changes
+// through the inventory ledger.
 func (r *Repository) Update(product *Product) error {
-	return r.db.Save(product).Error
+	for i := range product.Variants {
+		if err := checkVariantPrice(product, product.Variants[i].Price); err != nil {
+			return err
+		}
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkTaxClass(tx, product.TaxClassID); err != nil {
+			return err
+		}
+		return tx.Omit(clause.Associations, "stock").Save(product).Error
+	})
+}
+
+// checkTaxClass ensures a product's tax class exists.
+func checkTaxClass(tx *gorm.DB, classID *uint) error {
+	if classID == nil {
+		return nil
+	}
+	var count int64
+	if err := tx.Table("tax_classes").Where("id = ?", *classID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrTaxClassNotFound
+	}
+	return nil
+}
+
+// SetCategories replaces the categories a product is assigned to.
+func (r *Repository) SetCategories(product *Product, categoryIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		categories := []category.Category{}
+		if len(categoryIDs) > 0 {
+			if err := tx.Where("id IN ?", categoryIDs).Find(&categories).Error; err != nil {
+				return err
+			}
+			found := make(map[uint]bool, len(categories))
+			for _, c := range categories {
+				found[c.ID] = true
+			}
+			for _, id := range categoryIDs {
+				if !found[id] {
+					return ErrCategoryNotFound
+				}
+			}
+		}
+
+		if err := tx.Model(product).Association("Categories").Replace(categories); err != nil {
+			return err
+		}
+		product.Categories = categories
+		return nil
+	})
 }
 
 func (r *Repository) Delete(id uint) error {
@@ -39,69 +232,702 @@ func (r *Repository) Delete(id uint) error {
 }
 
 type ListProductsQuery struct {
-	Page     int     `query:"page"`
-	Limit    int     `query:"limit"`
-	MinPrice float64 `query:"min_price"`
-	MaxPrice float64 `query:"max_price"`
-	Search   string  `query:"search"`
-	SortBy   string  `query:"sort_by"`
-	SortDir  string  `query:"sort_dir"`
+	middleware.PaginationQuery
+	// MinPrice and MaxPrice are decimal amounts in Currency, the default
+	// currency when empty. Products priced in other currencies do not match.
+	MinPrice string `query:"min_price"`
+	MaxPrice string `query:"max_price"`
+	Currency string `query:"currency"`
+	Search   string `query:"search"`
+	// CategoryID restricts the list to products in the category, and in
+	// every descendant category when IncludeDescendants is set.
+	CategoryID         uint `query:"category_id"`
+	IncludeDescendants bool `query:"include_descendants"`
+	// Options restricts the list to products with a variant carrying the
+	// given option values, each written as "type:value". Values of the same
+	// type are alternatives; different types must all match.
+	Options []string `query:"option"`
+	// Availability is AvailabilityInStock or AvailabilityOutOfStock.
+	Availability string `query:"availability"`
+	// MinRating restricts the list to products rated at least this high.
+	MinRating float64 `query:"min_rating"`
+	// Facets requests facet counts alongside the listing.
+	Facets bool `query:"facets"`
 }
 
-func (r *Repository) List(query *ListProductsQuery) ([]Product, int64, error) {
+// ParseOptionFilters groups "type:value" filters by option type name.
+func ParseOptionFilters(filters []string) (map[string][]string, error) {
+	grouped := make(map[string][]string)
+	for _, filter := range filters {
+		name, value, ok := strings.Cut(filter, ":")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || name == "" || value == "" {
+			return nil, ErrInvalidOptionFilter
+		}
+		grouped[name] = append(grouped[name], value)
+	}
+	return grouped, nil
+}
+
+// PriceRange parses the price filters; a bound is nil when not given.
+func (q *ListProductsQuery) PriceRange() (min, max *money.Money, err error) {
+	currency := q.Currency
+	if currency == "" {
+		currency = money.DefaultCurrency()
+	}
+	parse := func(value string) (*money.Money, error) {
+		if value == "" {
+			return nil, nil
+		}
+		price, err := money.Parse(value, currency)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPriceFilter, err)
+		}
+		return &price, nil
+	}
+	if min, err = parse(q.MinPrice); err != nil {
+		return nil, nil, err
+	}
+	if max, err = parse(q.MaxPrice); err != nil {
+		return nil, nil, err
+	}
+	return min, max, nil
+}
+
+// Availability filter values.
+const (
+	AvailabilityInStock    = "in_stock"
+	AvailabilityOutOfStock = "out_of_stock"
+)
+
+// inStockCondition matches products that can be bought: products without
+// variants that have stock available to sell, and products with at least one
+// such variant. Stock held by active reservations is not available.
+const inStockCondition = `((products.stock > COALESCE((
+		SELECT SUM(r.quantity) FROM inventory_reservations r
+		WHERE r.product_id = products.id AND r.variant_id IS NULL AND r.status = 'active' AND r.expires_at > now()), 0)
+	AND NOT EXISTS (
+		SELECT 1 FROM product_variants v WHERE v.product_id = products.id AND v.deleted_at IS NULL))
+	OR EXISTS (
+		SELECT 1 FROM product_variants v WHERE v.product_id = products.id AND v.deleted_at IS NULL AND v.stock > COALESCE((
+			SELECT SUM(r.quantity) FROM inventory_reservations r
+			WHERE r.product_id = v.product_id AND r.variant_id = v.id AND r.status = 'active' AND r.expires_at > now()), 0)))`
+
+// filter applies one condition of a product listing. Facets are computed
+// with every filter applied except their own, so a filter leaves the query
+// unchanged when except names the facet it belongs to.
+type filter func(db *gorm.DB, except string) *gorm.DB
+
+func (q *ListProductsQuery) filters() ([]filter, error) {
+	grouped, err := ParseOptionFilters(q.Options)
+	if err != nil {
+		return nil, err
+	}
+
+	min, max, err := q.PriceRange()
+	if err != nil {
+		return nil, err
+	}
+
+	var filters []filter
+	if min != nil || max != nil {
+		filters = append(filters, func(db *gorm.DB, except string) *gorm.DB {
+			if except == FacetPrice {
+				return db
+			}
+			if min != nil {
+				db = db.Where("products.price_currency = ? AND products.price_amount >= ?", min.Currency, min.Amount)
+			}
+			if max != nil {
+				db = db.Where("products.price_currency = ? AND products.price_amount <= ?", max.Currency, max.Amount)
+			}
+			return db
+		})
+	}
+	if search := strings.TrimSpace(q.Search); search != "" {
+		filters = append(filters, func(db *gorm.DB, _ string) *gorm.DB {
+			return db.Where("products.search_vector @@ websearch_to_tsquery('english', ?)", search)
+		})
+	}
+	if q.CategoryID > 0 {
+		filters = append(filters, func(db *gorm.DB, except string) *gorm.DB {
+			switch {
+			case except == FacetCategory:
+				return db
+			case q.IncludeDescendants:
+				return db.Where(`products.id IN (
+					SELECT pc.product_id FROM product_categories pc
+					JOIN categories c ON c.id = pc.category_id
+					WHERE c.path LIKE (SELECT path FROM categories WHERE id = ?) || '%')`, q.CategoryID)
+			default:
+				return db.Where("products.id IN (SELECT product_id FROM product_categories WHERE category_id = ?)", q.CategoryID)
+			}
+		})
+	}
+	if q.MinRating > 0 {
+		filters = append(filters, func(db *gorm.DB, _ string) *gorm.DB {
+			return db.Where("products.rating_average >= ?", q.MinRating)
+		})
+	}
+	if q.Availability == AvailabilityInStock || q.Availability == AvailabilityOutOfStock {
+		filters = append(filters, func(db *gorm.DB, except string) *gorm.DB {
+			switch {
+			case except == FacetAvailability:
+				return db
+			case q.Availability == AvailabilityInStock:
+				return db.Where(inStockCondition)
+			default:
+				return db.Where("NOT " + inStockCondition)
+			}
+		})
+	}
+	if len(grouped) > 0 {
+		filters = append(filters, func(db *gorm.DB, except string) *gorm.DB {
+			exceptType := ""
+			if strings.HasPrefix(except, FacetOptionPrefix) {
+				exceptType = strings.TrimPrefix(except, FacetOptionPrefix)
+			}
+			return optionFilter(db, grouped, exceptType)
+		})
+	}
+
+	return filters, nil
+}
+
+// optionFilter restricts db to products with a variant carrying a selected
+// value of every option type in grouped, ignoring the type named by except.
+func optionFilter(db *gorm.DB, grouped map[string][]string, except string) *gorm.DB {
+	names := make([]string, 0, len(grouped))
+	for name := range grouped {
+		if name != except {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return db
+	}
+	sort.Strings(names)
+
+	conditions := []string{"v.deleted_at IS NULL"}
+	args := []interface{}{}
+	for _, name := range names {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM variant_option_values vov
+			JOIN option_values ov ON ov.id = vov.option_value_id
+			JOIN option_types ot ON ot.id = ov.option_type_id
+			WHERE vov.product_variant_id = v.id AND ot.name = ? AND ov.name IN ?)`)
+		args = append(args, name, grouped[name])
+	}
+	return db.Where("products.id IN (SELECT v.product_id FROM product_variants v WHERE "+strings.Join(conditions, " AND ")+")", args...)
+}
+
+// applyFilters applies every filter, leaving out the named facet's own.
+func applyFilters(db *gorm.DB, filters []filter, except string) *gorm.DB {
+	for _, f := range filters {
+		db = f(db, except)
+	}
+	return db
+}
+
+// sortColumns maps the fields products can be sorted and paged by to their
+// column expressions. Unrated products sort as rated zero, and prices sort by
+// amount in minor units whatever their currency.
+var sortColumns = map[string]string{
+	"name":       "products.name",
+	"price":      "products.price_amount",
+	"created_at": "products.created_at",
+	"rating":     "COALESCE(products.rating_average, 0)",
+}
+
+// List returns a page of products matching the query. With a cursor the
+// page is selected by keyset on the cursor's sort order; otherwise query's
+// page number is used. Results sorted by search relevance are only paged by
+// number. A cursor that was not signed by this repository's signer is
+// rejected with middleware.ErrInvalidCursor.
+func (r *Repository) List(query *ListProductsQuery) ([]Product, middleware.PaginatedResponse, error) {
 	var products []Product
-	var total int64
+	var page middleware.PaginatedResponse
 
-	db := r.db.Model(&Product{})
+	var cursor *middleware.Cursor
+	if query.Cursor != "" {
+		var err error
+		if cursor, err = r.cursors.Decode(query.Cursor); err != nil {
+			return nil, page, err
+		}
+	}
 
-	// Apply filters
-	if query.MinPrice > 0 {
-		db = db.Where("price >= ?", query.MinPrice)
+	filters, err := query.filters()
+	if err != nil {
+		return nil, page, err
 	}
-	if query.MaxPrice > 0 {
-		db = db.Where("price <= ?", query.MaxPrice)
+	db := applyFilters(r.db.Model(&Product{}), filters, "")
+	search := strings.TrimSpace(query.Search)
+
+	sortBy, dir := query.SortBy, strings.ToUpper(query.SortDir)
+	if cursor != nil {
+		sortBy, dir = cursor.SortBy, cursor.SortDir
 	}
-	if query.Search != "" {
-		search := "%" + query.Search + "%"
-		db = db.Where("name LIKE ? OR description LIKE ?", search, search)
+	relevance := sortBy == "" && search != ""
+	if sortBy == "" {
+		sortBy, dir = "created_at", "DESC"
+	} else if dir != "DESC" {
+		dir = "ASC"
+	}
+	column, ok := sortColumns[sortBy]
+	if !ok {
+		return nil, page, middleware.ErrInvalidCursor
+	}
+
+	if search != "" {
+		db = db.Select(`products.*,
+			ts_rank(search_vector, websearch_to_tsquery('english', @search)) AS search_rank,
+			ts_headline('english', name, websearch_to_tsquery('english', @search), @nameOptions) AS name_highlight,
+			ts_headline('english', coalesce(description, ''), websearch_to_tsquery('english', @search), @snippetOptions) AS description_snippet`,
+			map[string]interface{}{
+				"search":         search,
+				"nameOptions":    highlightOptions + ", HighlightAll=true",
+				"snippetOptions": highlightOptions + ", MaxFragments=2, MaxWords=20, MinWords=5",
+			})
+	}
+
+	limit := query.Limit
+	if limit < 1 {
+		limit = 10
+	}
+
+	if cursor != nil {
+		value, err := cursorValue(sortBy, cursor.Value)
+		if err != nil {
+			return nil, page, err
+		}
+		condition, order := middleware.Keyset(column, "products.id", dir, cursor)
+		if err := db.Where(condition, value, cursor.ID).Order(order).Limit(limit + 1).Scopes(withMedia).Find(&products).Error; err != nil {
+			return nil, page, err
+		}
+		products, page = middleware.KeysetPage(r.cursors, products, limit, sortBy, dir, cursor, productKey(sortBy))
+		return products, page, r.fillReservedList(products)
 	}
 
 	// Count total before pagination
+	var total int64
 	if err := db.Count(&total).Error; err != nil {
-		return nil, 0, err
+		return nil, page, err
+	}
+
+	if relevance {
+		db = db.Order("search_rank DESC, products.created_at DESC, products.id DESC")
+	} else {
+		_, order := middleware.Keyset(column, "products.id", dir, nil)
+		db = db.Order(order)
 	}
 
-	// Apply sorting
-	if query.SortBy != "" {
-		direction := "ASC"
-		if strings.ToUpper(query.SortDir) == "DESC" {
-			direction = "DESC"
+	pageNumber := query.Page
+	if pageNumber < 1 {
+		pageNumber = 1
+	}
+
+	offset := (pageNumber - 1) * limit
+	if err := db.Offset(offset).Limit(limit).Scopes(withMedia).Find(&products).Error; err != nil {
+		return nil, page, err
+	}
+	if err := r.fillReservedList(products); err != nil {
+		return nil, page, err
+	}
+
+	page = middleware.OffsetPage(pageNumber, limit, total)
+	// Offer a cursor for the next page so clients can switch to keyset paging.
+	if !relevance && len(products) > 0 && int64(offset+len(products)) < total {
+		value, id := productKey(sortBy)(products[len(products)-1])
+		page.NextCursor = r.cursors.Encode(middleware.Cursor{SortBy: sortBy, SortDir: dir, Value: value, ID: id})
+	}
+	return products, page, nil
+}
+
+// productKey returns the cursor value and ID of a product for a sort column.
+func productKey(sortBy string) func(Product) (string, uint) {
+	return func(p Product) (string, uint) {
+		switch sortBy {
+		case "name":
+			return p.Name, p.ID
+		case "price":
+			return strconv.FormatInt(p.Price.Amount, 10), p.ID
+		case "rating":
+			rating := 0.0
+			if p.RatingAverage != nil {
+				rating = *p.RatingAverage
+			}
+			return strconv.FormatFloat(rating, 'f', -1, 64), p.ID
+		default:
+			return p.CreatedAt.UTC().Format(time.RFC3339Nano), p.ID
 		}
+	}
+}
 
-		validColumns := map[string]bool{
-			"name": true, "price": true, "created_at": true,
+// cursorValue converts a cursor's sort value back to the column's type.
+func cursorValue(sortBy, value string) (interface{}, error) {
+	switch sortBy {
+	case "name":
+		return value, nil
+	case "price":
+		amount, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, middleware.ErrInvalidCursor
 		}
-		if validColumns[query.SortBy] {
-			db = db.Order(query.SortBy + " " + direction)
+		return amount, nil
+	case "rating":
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, middleware.ErrInvalidCursor
 		}
-	} else {
-		db = db.Order("created_at DESC")
+		return number, nil
+	default:
+		createdAt, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return nil, middleware.ErrInvalidCursor
+		}
+		return createdAt, nil
 	}
+}
+
+// highlightOptions marks matched terms in search highlights.
+const highlightOptions = "StartSel=<mark>, StopSel=</mark>"
 
-	page := query.Page
-	if page < 1 {
-		page = 1
+// Suggestion is a product name matching an autocomplete prefix.
+type Suggestion struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// Suggest returns products whose names contain every word of term, the last
+// word matching as a prefix, best matches first.
+func (r *Repository) Suggest(term string, limit int) ([]Suggestion, error) {
+	words := strings.FieldsFunc(term, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	suggestions := []Suggestion{}
+	if len(words) == 0 {
+		return suggestions, nil
 	}
 
-	limit := query.Limit
-	if limit < 1 {
-		limit = 10
+	// Restrict matches to the name, which has weight A in search_vector.
+	for i, word := range words {
+		words[i] = word + ":A"
+	}
+	words[len(words)-1] = strings.TrimSuffix(words[len(words)-1], ":A") + ":*A"
+	tsquery := strings.Join(words, " & ")
+
+	err := r.db.Model(&Product{}).
+		Select("id, name").
+		Where("search_vector @@ to_tsquery('english', ?)", tsquery).
+		Order(clause.Expr{SQL: "ts_rank(search_vector, to_tsquery('english', ?)) DESC, name", Vars: []interface{}{tsquery}}).
+		Limit(limit).
+		Scan(&suggestions).Error
+	return suggestions, err
+}
+
+func (r *Repository) ListOptionTypes() ([]OptionType, error) {
+	var optionTypes []OptionType
+	err := r.db.Preload("Values", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position, id")
+	}).Order("name").Find(&optionTypes).Error
+	return optionTypes, err
+}
+
+func (r *Repository) CreateOptionType(optionType *OptionType) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&OptionType{}).Where("name = ?", optionType.Name).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrOptionTypeConflict
+		}
+
+		seen := make(map[string]bool, len(optionType.Values))
+		for i := range optionType.Values {
+			if seen[optionType.Values[i].Name] {
+				return ErrOptionValueConflict
+			}
+			seen[optionType.Values[i].Name] = true
+			optionType.Values[i].Position = i
+		}
+		return tx.Create(optionType).Error
+	})
+}
+
+// AddOptionValue appends a value to an option type.
+func (r *Repository) AddOptionValue(optionTypeID uint, value *OptionValue) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var optionType OptionType
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&optionType, optionTypeID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrOptionTypeNotFound
+			}
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&OptionValue{}).Where("option_type_id = ? AND name = ?", optionTypeID, value.Name).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrOptionValueConflict
+		}
+
+		var position int64
+		if err := tx.Model(&OptionValue{}).Where("option_type_id = ?", optionTypeID).Count(&position).Error; err != nil {
+			return err
+		}
+		value.OptionTypeID = optionTypeID
+		value.Position = int(position)
+		return tx.Create(value).Error
+	})
+}
+
+// GetVariant returns a variant of a product with its option values.
+func (r *Repository) GetVariant(productID, variantID uint) (*ProductVariant, error) {
+	var variant ProductVariant
+	err := r.db.Preload("OptionValues", func(db *gorm.DB) *gorm.DB {
+		return db.Order("option_type_id, position")
+	}).Where("product_id = ?", productID).First(&variant, variantID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrVariantNotFound
+		}
+		return nil, err
+	}
+	return &variant, nil
+}
+
+// CreateVariant adds a variant with the given option values to a product.
+func (r *Repository) CreateVariant(product *Product, variant *ProductVariant, optionValueIDs []uint, actorID uint) error {
+	if err := checkVariantPrice(product, variant.Price); err != nil {
+		return err
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkSKU(tx, variant.SKU, 0); err != nil {
+			return err
+		}
+
+		values, err := findOptionValues(tx, optionValueIDs)
+		if err != nil {
+			return err
+		}
+		types := make(map[uint]bool, len(values))
+		for _, v := range values {
+			if types[v.OptionTypeID] {
+				return ErrDuplicateOptionType
+			}
+			types[v.OptionTypeID] = true
+		}
+
+		existing, err := variantKeys(tx, product.ID)
+		if err != nil {
+			return err
+		}
+		if existing[combinationKey(values)] {
+			return ErrVariantExists
+		}
+
+		variant.ProductID = product.ID
+		variant.OptionValues = values
+		stock := variant.Stock
+		variant.Stock = 0
+		if err := tx.Omit("OptionValues.*").Create(variant).Error; err != nil {
+			return err
+		}
+		if err := receiveInitialStock(tx, product.ID, &variant.ID, stock, actorID); err != nil {
+			return err
+		}
+		variant.Stock = stock
+		fillAvailability(variant, product.Price, nil)
+		return nil
+	})
+}
+
+// GenerateVariants creates a variant for every combination of the given
+// option values that the product does not have yet and returns the new
+// variants.
+func (r *Repository) GenerateVariants(product *Product, req GenerateVariantsRequest, actorID uint) ([]ProductVariant, error) {
+	if err := checkVariantPrice(product, req.Price); err != nil {
+		return nil, err
+	}
+	created := []ProductVariant{}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		values, err := findOptionValues(tx, req.OptionValueIDs)
+		if err != nil {
+			return err
+		}
+
+		// Group values by option type; findOptionValues orders them by type
+		// and position so the matrix and SKUs are stable.
+		var groups [][]OptionValue
+		for i, v := range values {
+			if i == 0 || values[i-1].OptionTypeID != v.OptionTypeID {
+				groups = append(groups, nil)
+			}
+			groups[len(groups)-1] = append(groups[len(groups)-1], v)
+		}
+
+		existing, err := variantKeys(tx, product.ID)
+		if err != nil {
+			return err
+		}
+
+		prefix := req.SKUPrefix
+		if prefix == "" {
+			prefix = "P" + strconv.FormatUint(uint64(product.ID), 10)
+		}
+
+		for _, combination := range cartesian(groups) {
+			if existing[combinationKey(combination)] {
+				continue
+			}
+
+			parts := []string{prefix}
+			for _, v := range combination {
+				parts = append(parts, strings.ToUpper(v.Name))
+			}
+			variant := ProductVariant{
+				ProductID:    product.ID,
+				SKU:          strings.Join(parts, "-"),
+				Price:        req.Price,
+				OptionValues: combination,
+			}
+			if err := checkSKU(tx, variant.SKU, 0); err != nil {
+				return fmt.Errorf("%w: %s", err, variant.SKU)
+			}
+			if err := tx.Omit("OptionValues.*").Create(&variant).Error; err != nil {
+				return err
+			}
+			if err := receiveInitialStock(tx, product.ID, &variant.ID, req.Stock, actorID); err != nil {
+				return err
+			}
+			variant.Stock = req.Stock
+			fillAvailability(&variant, product.Price, nil)
+			created = append(created, variant)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// UpdateVariant changes a variant's SKU, barcode and price override.
+func (r *Repository) UpdateVariant(product *Product, variant *ProductVariant) error {
+	if err := checkVariantPrice(product, variant.Price); err != nil {
+		return err
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkSKU(tx, variant.SKU, variant.ID); err != nil {
+			return err
+		}
+		updates := map[string]interface{}{
+			"sku":            variant.SKU,
+			"barcode":        variant.Barcode,
+			"price_amount":   nil,
+			"price_currency": nil,
+		}
+		if variant.Price != nil {
+			updates["price_amount"] = variant.Price.Amount
+			updates["price_currency"] = variant.Price.Currency
+		}
+		return tx.Model(variant).Updates(updates).Error
+	})
+}
+
+// checkVariantPrice ensures a variant's price override is in the currency of
+// its product, so that a product's variants can be compared and summed.
+func checkVariantPrice(product *Product, price *money.Money) error {
+	if price != nil && price.Currency != product.Price.Currency {
+		return ErrVariantCurrency
+	}
+	return nil
+}
+
+func (r *Repository) DeleteVariant(variant *ProductVariant) error {
+	return r.db.Delete(variant).Error
+}
+
+func checkSKU(tx *gorm.DB, sku string, excludeID uint) error {
+	var count int64
+	if err := tx.Model(&ProductVariant{}).Where("sku = ? AND id <> ?", sku, excludeID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrSKUConflict
+	}
+	return nil
+}
+
+// findOptionValues loads the given option values ordered by option type and
+// position, failing if any is missing.
+func findOptionValues(tx *gorm.DB, ids []uint) ([]OptionValue, error) {
+	values := []OptionValue{}
+	if len(ids) == 0 {
+		return values, nil
+	}
+
+	if err := tx.Where("id IN ?", ids).Order("option_type_id, position, id").Find(&values).Error; err != nil {
+		return nil, err
 	}
 
-	offset := (page - 1) * limit
-	if err := db.Offset(offset).Limit(limit).Find(&products).Error; err != nil {
-		return nil, 0, err
+	found := make(map[uint]bool, len(values))
+	for _, v := range values {
+		found[v.ID] = true
 	}
+	for _, id := range ids {
+		if !found[id] {
+			return nil, ErrOptionValueNotFound
+		}
+	}
+	return values, nil
+}
 
-	return products, total, nil
+// variantKeys returns the combination keys of a product's live variants.
+func variantKeys(tx *gorm.DB, productID uint) (map[string]bool, error) {
+	var variants []ProductVariant
+	if err := tx.Preload("OptionValues").Where("product_id = ?", productID).Find(&variants).Error; err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]bool, len(variants))
+	for _, v := range variants {
+		keys[combinationKey(v.OptionValues)] = true
+	}
+	return keys, nil
+}
+
+// combinationKey identifies a set of option values independent of order.
+func combinationKey(values []OptionValue) string {
+	ids := make([]int, 0, len(values))
+	for _, v := range values {
+		ids = append(ids, int(v.ID))
+	}
+	sort.Ints(ids)
+
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, strconv.Itoa(id))
+	}
+	return strings.Join(parts, ",")
+}
+
+// cartesian returns every combination taking one value from each group.
+func cartesian(groups [][]OptionValue) [][]OptionValue {
+	combinations := [][]OptionValue{{}}
+	for _, group := range groups {
+		var next [][]OptionValue
+		for _, prefix := range combinations {
+			for _, v := range group {
+				combination := make([]OptionValue, len(prefix), len(prefix)+1)
+				copy(combination, prefix)
+				next = append(next, append(combination, v))
+			}
+		}
+		combinations = next
+	}
+	return combinations
 }
@@ -4,11 +4,13 @@ import (
 	"log"
 
 	"github.com/labstack/echo/v4"
+	"github.com/nneji123/ecommerce-golang/internal/common/revocation"
 	"github.com/nneji123/ecommerce-golang/internal/config"
+	"github.com/nneji123/ecommerce-golang/internal/domain/rbac"
 	"github.com/nneji123/ecommerce-golang/internal/middleware"
 )
 
-func RegisterRoutes(e *echo.Echo, h *Handler) {
+func RegisterRoutes(e *echo.Echo, h *Handler, revocations revocation.Store, permissions middleware.PermissionResolver) {
 	products := e.Group("/products")
 
 	cfg, err := config.LoadConfig()
@@ -16,15 +18,34 @@ func RegisterRoutes(e *echo.Echo, h *Handler) {
 		log.Fatalf("Error loading configuration: %s", err)
 	}
 
-	products.Use(middleware.AuthMiddleware(cfg.JWTSecret))
+	products.Use(middleware.AuthMiddleware(cfg.JWTSecret, revocations))
 
 	// Public routes (authenticated users)
 	products.GET("", h.List)
+	products.GET("/suggest", h.Suggest)
 	products.GET("/:id", h.Get)
+	products.GET("/:id/media", h.ListMedia)
 
-	// Admin routes
-	admin := products.Group("", middleware.AdminMiddleware())
-	admin.POST("", h.Create)
-	admin.PUT("/:id", h.Update)
-	admin.DELETE("/:id", h.Delete)
+	// Catalog management routes
+	catalog := products.Group("", middleware.RequirePermission(permissions, rbac.PermissionProductsWrite))
+	catalog.POST("", h.Create)
+	catalog.POST("/import", h.Import)
+	catalog.GET("/import/:id", h.GetImport)
+	catalog.GET("/export", h.Export)
+	catalog.PUT("/:id", h.Update)
+	catalog.DELETE("/:id", h.Delete)
+	catalog.PUT("/:id/categories", h.SetCategories)
+	catalog.POST("/:id/variants", h.CreateVariant)
+	catalog.POST("/:id/variants/generate", h.GenerateVariants)
+	catalog.PUT("/:id/variants/:variant_id", h.UpdateVariant)
+	catalog.DELETE("/:id/variants/:variant_id", h.DeleteVariant)
+	catalog.POST("/:id/media", h.UploadMedia)
+	catalog.POST("/:id/media/reorder", h.ReorderMedia)
+	catalog.PUT("/:id/media/:media_id", h.UpdateMedia)
+	catalog.DELETE("/:id/media/:media_id", h.DeleteMedia)
+
+	optionTypes := e.Group("/option-types", middleware.AuthMiddleware(cfg.JWTSecret, revocations))
+	optionTypes.GET("", h.ListOptionTypes)
+	optionTypes.POST("", h.CreateOptionType, middleware.RequirePermission(permissions, rbac.PermissionProductsWrite))
+	optionTypes.POST("/:id/values", h.AddOptionValue, middleware.RequirePermission(permissions, rbac.PermissionProductsWrite))
 }
@@ -1,17 +1,142 @@
 package product
 
 import (
-	"gorm.io/gorm"
 	"time"
+
+	"github.com/nneji123/ecommerce-golang/internal/common/money"
+	"github.com/nneji123/ecommerce-golang/internal/domain/category"
+	"gorm.io/gorm"
 )
 
+// Product is a catalog entry. Products sold in several sizes, colours and so
+// on have Variants; Stock is then unused and each variant tracks its own.
+// ExternalID identifies the product in the spreadsheet or system it is
+// maintained in; bulk imports match rows on it. Products without a TaxClassID
+// are taxed as the default tax class. The weight and dimensions of one unit,
+// in grams and millimetres, price shipping; variants share them.
 type Product struct {
-	ID          uint           `gorm:"primaryKey" json:"id"`
-	Name        string         `gorm:"size:255;not null" json:"name" validate:"required"`
-	Description string         `gorm:"type:text" json:"description"`
-	Price       float64        `gorm:"not null" json:"price" validate:"required,gt=0"`
-	Stock       int            `gorm:"not null" json:"stock" validate:"required,gte=0"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	ID          uint                `gorm:"primaryKey" json:"id"`
+	ExternalID  *string             `gorm:"size:100;uniqueIndex" json:"external_id,omitempty" validate:"omitempty,max=100"`
+	Name        string              `gorm:"size:255;not null" json:"name" validate:"required"`
+	Description string              `gorm:"type:text" json:"description"`
+	Price       money.Money         `gorm:"embedded;embeddedPrefix:price_" json:"price" validate:"required,gt=0"`
+	Stock       int                 `gorm:"not null" json:"stock" validate:"gte=0"`
+	TaxClassID  *uint               `gorm:"index" json:"tax_class_id,omitempty"`
+	WeightGrams int                 `gorm:"not null;default:0" json:"weight_grams" validate:"gte=0"`
+	LengthMM    int                 `gorm:"column:length_mm;not null;default:0" json:"length_mm" validate:"gte=0"`
+	WidthMM     int                 `gorm:"column:width_mm;not null;default:0" json:"width_mm" validate:"gte=0"`
+	HeightMM    int                 `gorm:"column:height_mm;not null;default:0" json:"height_mm" validate:"gte=0"`
+	Categories  []category.Category `gorm:"many2many:product_categories" json:"categories,omitempty"`
+	Variants    []ProductVariant    `json:"variants,omitempty"`
+	Media       []ProductMedia      `json:"media,omitempty"`
+	CreatedAt   time.Time           `json:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt      `gorm:"index" json:"-"`
+
+	// ReviewCount and RatingAverage summarize approved reviews. They are
+	// maintained by the review package and never written through Product.
+	ReviewCount   int      `gorm:"->" json:"review_count"`
+	RatingAverage *float64 `gorm:"->" json:"rating_average"`
+
+	// Reserved is the stock held by pending orders and AvailableToSell what
+	// remains of Stock, for products sold without variants. Set by GetByID
+	// and List.
+	Reserved        int `gorm:"-" json:"reserved"`
+	AvailableToSell int `gorm:"-" json:"available_to_sell"`
+
+	// Read-only search results, set by List when a search term is given.
+	SearchRank         float64 `gorm:"->;-:migration" json:"search_rank,omitempty"`
+	NameHighlight      string  `gorm:"->;-:migration" json:"name_highlight,omitempty"`
+	DescriptionSnippet string  `gorm:"->;-:migration" json:"description_snippet,omitempty"`
+}
+
+type SetCategoriesRequest struct {
+	CategoryIDs []uint `json:"category_ids"`
+}
+
+// OptionType is a dimension along which products vary, such as size or
+// colour. Option types and their values are shared by every product.
+type OptionType struct {
+	ID           uint          `gorm:"primaryKey" json:"id"`
+	Name         string        `gorm:"size:100;not null;uniqueIndex" json:"name"`
+	Presentation string        `gorm:"size:255;not null" json:"presentation"`
+	Values       []OptionValue `json:"values"`
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
+}
+
+type OptionValue struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	OptionTypeID uint      `gorm:"not null;uniqueIndex:idx_option_values_type_name" json:"option_type_id"`
+	Name         string    `gorm:"size:100;not null;uniqueIndex:idx_option_values_type_name" json:"name"`
+	Presentation string    `gorm:"size:255;not null" json:"presentation"`
+	Position     int       `gorm:"not null;default:0" json:"position"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// ProductVariant is a purchasable combination of option values of a product
+// with its own SKU and stock. A nil Price inherits the product's price; an
+// override is in the product's currency.
+type ProductVariant struct {
+	ID           uint           `gorm:"primaryKey" json:"id"`
+	ProductID    uint           `gorm:"not null;index" json:"product_id"`
+	SKU          string         `gorm:"column:sku;size:100;not null" json:"sku"`
+	Barcode      string         `gorm:"size:100" json:"barcode"`
+	Price        *money.Money   `gorm:"embedded;embeddedPrefix:price_" json:"price"`
+	Stock        int            `gorm:"not null" json:"stock"`
+	OptionValues []OptionValue  `gorm:"many2many:variant_option_values" json:"option_values"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// Set when the variant is loaded with its product. Reserved is the
+	// stock held by pending orders.
+	EffectivePrice  money.Money `gorm:"-" json:"effective_price"`
+	Reserved        int         `gorm:"-" json:"reserved"`
+	AvailableToSell int         `gorm:"-" json:"available_to_sell"`
+	Available       bool        `gorm:"-" json:"available"`
+}
+
+// UnitPrice returns the variant's price override, or base when it has none.
+func (v *ProductVariant) UnitPrice(base money.Money) money.Money {
+	if v.Price != nil {
+		return *v.Price
+	}
+	return base
+}
+
+type OptionValueRequest struct {
+	Name         string `json:"name" validate:"required,max=100"`
+	Presentation string `json:"presentation" validate:"max=255"`
+}
+
+type CreateOptionTypeRequest struct {
+	Name         string               `json:"name" validate:"required,max=100"`
+	Presentation string               `json:"presentation" validate:"max=255"`
+	Values       []OptionValueRequest `json:"values" validate:"dive"`
+}
+
+type VariantRequest struct {
+	SKU            string       `json:"sku" validate:"required,max=100"`
+	Barcode        string       `json:"barcode" validate:"max=100"`
+	Price          *money.Money `json:"price" validate:"omitempty,gt=0"`
+	Stock          int          `json:"stock" validate:"gte=0"`
+	OptionValueIDs []uint       `json:"option_value_ids"`
+}
+
+type UpdateVariantRequest struct {
+	SKU     string       `json:"sku" validate:"required,max=100"`
+	Barcode string       `json:"barcode" validate:"max=100"`
+	Price   *money.Money `json:"price" validate:"omitempty,gt=0"`
+}
+
+// GenerateVariantsRequest builds one variant for every combination of the
+// given option values, taking one value per option type. SKUs are the prefix
+// followed by the value names; the prefix defaults to "P<product id>".
+type GenerateVariantsRequest struct {
+	OptionValueIDs []uint       `json:"option_value_ids" validate:"required,min=1"`
+	SKUPrefix      string       `json:"sku_prefix" validate:"max=50"`
+	Price          *money.Money `json:"price" validate:"omitempty,gt=0"`
+	Stock          int          `json:"stock" validate:"gte=0"`
 }
@@ -0,0 +1,161 @@
+package promotion
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/nneji123/ecommerce-golang/internal/common/money"
+)
+
+// candidate is an eligible promotion with the indexes of the order lines it
+// applies to.
+type candidate struct {
+	promotion *Promotion
+	lines     []int
+}
+
+func (c candidate) coupon() bool {
+	return c.promotion.Code != nil
+}
+
+// notApplicable reports why a coupon cannot be used for an order.
+func notApplicable(p *Promotion, reason string) error {
+	return fmt.Errorf("%w: %s %s", ErrCouponNotApplicable, *p.Code, reason)
+}
+
+// discounts applies candidates to lines in descending priority and returns
+// the discounts granted. Each discount is taken from what is left of the
+// lines it applies to, so stacked discounts never exceed an item's price.
+// A coupon that cannot be applied is an error; an automatic promotion is
+// skipped.
+func discounts(candidates []candidate, lines []Line) ([]Discount, error) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i].promotion, candidates[j].promotion
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		return a.ID < b.ID
+	})
+
+	remaining := make([]int64, len(lines))
+	for i, line := range lines {
+		remaining[i] = line.UnitPrice.Mul(int64(line.Quantity)).Amount
+	}
+	currency := lines[0].UnitPrice.Currency
+
+	applied := []Discount{}
+	stackable := true
+	for _, c := range candidates {
+		p := c.promotion
+		if len(applied) > 0 && !(stackable && p.Stackable) {
+			if c.coupon() {
+				return nil, fmt.Errorf("%w: %s", ErrCouponNotCombinable, *p.Code)
+			}
+			continue
+		}
+
+		before := append([]int64(nil), remaining...)
+		amount, reason := discount(c, lines, remaining)
+		if reason != "" {
+			if c.coupon() {
+				return nil, notApplicable(p, reason)
+			}
+			continue
+		}
+
+		d := Discount{PromotionID: p.ID, Name: p.Name, Type: p.Type, Amount: money.New(amount, currency)}
+		d.Lines = make([]int64, len(lines))
+		for i := range lines {
+			d.Lines[i] = before[i] - remaining[i]
+		}
+		if p.Code != nil {
+			d.Code = *p.Code
+		}
+		applied = append(applied, d)
+		stackable = stackable && p.Stackable
+	}
+	return applied, nil
+}
+
+// discount computes the amount a promotion takes off the eligible lines and
+// deducts it from remaining. It returns a reason when the promotion grants
+// nothing.
+func discount(c candidate, lines []Line, remaining []int64) (int64, string) {
+	p := c.promotion
+	if p.Type == TypeFreeShipping {
+		return 0, ""
+	}
+
+	currency := lines[c.lines[0]].UnitPrice.Currency
+	var base int64
+	for _, i := range c.lines {
+		base += remaining[i]
+	}
+	if base <= 0 {
+		return 0, "has nothing left to discount"
+	}
+
+	if p.Type == TypeBuyXGetY {
+		return buyXGetY(c, lines, remaining)
+	}
+
+	var amount int64
+	switch p.Type {
+	case TypePercentage:
+		amount = money.New(base, currency).MulRat(int64(p.Percentage), 100, money.HalfUp).Amount
+	case TypeFixedAmount:
+		amount = min(p.AmountOff.Amount, base)
+	}
+	if amount <= 0 {
+		return 0, "has nothing left to discount"
+	}
+
+	// Spread the amount over the lines in proportion to what is left of
+	// them; lines already fully discounted take no share.
+	var open []int
+	var weights []int64
+	for _, i := range c.lines {
+		if remaining[i] > 0 {
+			open = append(open, i)
+			weights = append(weights, remaining[i])
+		}
+	}
+	for k, part := range money.New(amount, currency).Allocate(weights) {
+		remaining[open[k]] -= part.Amount
+	}
+	return amount, ""
+}
+
+// buyXGetY discounts GetQuantity units of every BuyQuantity+GetQuantity
+// eligible units, choosing the cheapest units.
+func buyXGetY(c candidate, lines []Line, remaining []int64) (int64, string) {
+	p := c.promotion
+
+	type unit struct {
+		line  int
+		price int64
+	}
+	var units []unit
+	for _, i := range c.lines {
+		for q := 0; q < lines[i].Quantity; q++ {
+			units = append(units, unit{line: i, price: lines[i].UnitPrice.Amount})
+		}
+	}
+	free := len(units) / (p.BuyQuantity + p.GetQuantity) * p.GetQuantity
+	if free == 0 {
+		return 0, fmt.Sprintf("requires %d eligible items", p.BuyQuantity+p.GetQuantity)
+	}
+	sort.SliceStable(units, func(i, j int) bool { return units[i].price < units[j].price })
+
+	var total int64
+	for _, u := range units[:free] {
+		amount := money.New(u.price, lines[u.line].UnitPrice.Currency).MulRat(int64(p.Percentage), 100, money.HalfUp).Amount
+		amount = min(amount, remaining[u.line])
+		remaining[u.line] -= amount
+		total += amount
+	}
+	if total <= 0 {
+		return 0, "has nothing left to discount"
+	}
+	return total, ""
+}
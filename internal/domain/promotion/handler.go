@@ -0,0 +1,190 @@
+package promotion
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/nneji123/ecommerce-golang/internal/middleware"
+	"go.uber.org/zap"
+)
+
+type Handler struct {
+	repo      *Repository
+	validator *validator.Validate
+	logger    *zap.Logger
+}
+
+func NewHandler(repo *Repository, validator *validator.Validate, logger *zap.Logger) *Handler {
+	return &Handler{
+		repo:      repo,
+		validator: validator,
+		logger:    logger,
+	}
+}
+
+// @Summary		List promotions
+// @Description	Get a paginated list of promotions, highest priority first (requires promotions:manage)
+// @Tags			promotions
+// @Produce		json
+// @Param			page	query		int		false	"Page number"
+// @Param			limit	query		int		false	"Items per page"
+// @Param			active	query		bool	false	"Only active or inactive promotions"
+// @Param			code	query		string	false	"Coupon code"
+// @Success		200		{object}	middleware.PaginatedResponse
+// @Router			/promotions [get]
+func (h *Handler) List(c echo.Context) error {
+	var query ListQuery
+	if err := c.Bind(&query); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	query.Normalize(10, 100, "")
+
+	promotions, page, err := h.repo.List(query)
+	if err != nil {
+		h.logger.Error("Failed to list promotions", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list promotions")
+	}
+
+	middleware.SetLinkHeader(c, page)
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"promotions": promotions,
+		"pagination": page,
+	})
+}
+
+// @Summary		Create promotion
+// @Description	Create a promotion (requires promotions:manage). With a code it is a coupon the customer enters at checkout; without one it applies to every eligible order.
+// @Tags			promotions
+// @Accept			json
+// @Produce		json
+// @Param			promotion	body		PromotionRequest	true	"Promotion"
+// @Success		201			{object}	Promotion
+// @Failure		400			{object}	middleware.ErrorResponse
+// @Failure		409			{object}	middleware.ErrorResponse
+// @Router			/promotions [post]
+func (h *Handler) Create(c echo.Context) error {
+	var req PromotionRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := h.validator.Struct(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	promotion, err := req.Promotion()
+	if err != nil {
+		return h.promotionError(err, "Failed to create promotion")
+	}
+	if err := h.repo.Create(promotion, req.ProductIDs, req.CategoryIDs); err != nil {
+		return h.promotionError(err, "Failed to create promotion")
+	}
+
+	return c.JSON(http.StatusCreated, promotion)
+}
+
+// @Summary		Get promotion
+// @Description	Get a promotion with the products and categories it is restricted to (requires promotions:manage)
+// @Tags			promotions
+// @Produce		json
+// @Param			id	path		int	true	"Promotion ID"
+// @Success		200	{object}	Promotion
+// @Failure		404	{object}	middleware.ErrorResponse
+// @Router			/promotions/{id} [get]
+func (h *Handler) Get(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid promotion ID")
+	}
+
+	promotion, err := h.repo.GetByID(uint(id))
+	if err != nil {
+		return h.promotionError(err, "Failed to get promotion")
+	}
+
+	return c.JSON(http.StatusOK, promotion)
+}
+
+// @Summary		Update promotion
+// @Description	Replace the rules of a promotion (requires promotions:manage). Its usage count is kept, and orders already placed keep their discounts.
+// @Tags			promotions
+// @Accept			json
+// @Produce		json
+// @Param			id			path		int					true	"Promotion ID"
+// @Param			promotion	body		PromotionRequest	true	"Promotion"
+// @Success		200			{object}	Promotion
+// @Failure		400			{object}	middleware.ErrorResponse
+// @Failure		404			{object}	middleware.ErrorResponse
+// @Failure		409			{object}	middleware.ErrorResponse
+// @Router			/promotions/{id} [put]
+func (h *Handler) Update(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid promotion ID")
+	}
+
+	var req PromotionRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := h.validator.Struct(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	existing, err := h.repo.GetByID(uint(id))
+	if err != nil {
+		return h.promotionError(err, "Failed to update promotion")
+	}
+	promotion, err := req.Promotion()
+	if err != nil {
+		return h.promotionError(err, "Failed to update promotion")
+	}
+	promotion.ID = existing.ID
+	promotion.UsageCount = existing.UsageCount
+	promotion.CreatedAt = existing.CreatedAt
+
+	if err := h.repo.Update(promotion, req.ProductIDs, req.CategoryIDs); err != nil {
+		return h.promotionError(err, "Failed to update promotion")
+	}
+
+	return c.JSON(http.StatusOK, promotion)
+}
+
+// @Summary		Delete promotion
+// @Description	Delete a promotion (requires promotions:manage). Orders keep the discounts they received; deactivate the promotion instead to keep its usage history.
+// @Tags			promotions
+// @Param			id	path	int	true	"Promotion ID"
+// @Success		204	"No Content"
+// @Failure		404	{object}	middleware.ErrorResponse
+// @Router			/promotions/{id} [delete]
+func (h *Handler) Delete(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid promotion ID")
+	}
+
+	if err := h.repo.Delete(uint(id)); err != nil {
+		return h.promotionError(err, "Failed to delete promotion")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Handler) promotionError(err error, message string) error {
+	switch {
+	case errors.Is(err, ErrPromotionNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Promotion not found")
+	case errors.Is(err, ErrCodeConflict):
+		return echo.NewHTTPError(http.StatusConflict, "Promotion code already exists")
+	case errors.Is(err, ErrInvalidPromotion):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrProductNotFound):
+		return echo.NewHTTPError(http.StatusBadRequest, "One or more products do not exist")
+	case errors.Is(err, ErrCategoryNotFound):
+		return echo.NewHTTPError(http.StatusBadRequest, "One or more categories do not exist")
+	}
+	h.logger.Error(message, zap.Error(err))
+	return echo.NewHTTPError(http.StatusInternalServerError, message)
+}
@@ -0,0 +1,247 @@
+package promotion
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/nneji123/ecommerce-golang/internal/common/money"
+)
+
+func usd(amount int64) money.Money {
+	return money.New(amount, "USD")
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestDiscountsStacking(t *testing.T) {
+	lines := []Line{
+		{ProductID: 1, Quantity: 1, UnitPrice: usd(6000)},
+		{ProductID: 2, Quantity: 2, UnitPrice: usd(2000)},
+	}
+	all := []int{0, 1}
+
+	percentage := func(id uint, percent, priority int, stackable bool) *Promotion {
+		return &Promotion{ID: id, Name: "percentage", Type: TypePercentage, Percentage: percent, Priority: priority, Stackable: stackable}
+	}
+	fixed := func(id uint, amount int64, priority int, stackable bool) *Promotion {
+		off := usd(amount)
+		return &Promotion{ID: id, Name: "fixed", Type: TypeFixedAmount, AmountOff: &off, Priority: priority, Stackable: stackable}
+	}
+	coupon := func(p *Promotion, code string) *Promotion {
+		p.Code = strPtr(code)
+		return p
+	}
+
+	tests := []struct {
+		name       string
+		candidates []candidate
+		want       map[uint]int64
+		order      []uint
+		wantErr    error
+	}{
+		{
+			name: "stackable promotions combine",
+			candidates: []candidate{
+				{promotion: percentage(1, 10, 2, true), lines: all},
+				{promotion: fixed(2, 1000, 1, true), lines: all},
+			},
+			want:  map[uint]int64{1: 1000, 2: 1000},
+			order: []uint{1, 2},
+		},
+		{
+			name: "higher priority applies first",
+			candidates: []candidate{
+				{promotion: percentage(1, 50, 1, true), lines: all},
+				{promotion: fixed(2, 3000, 5, true), lines: all},
+			},
+			want:  map[uint]int64{2: 3000, 1: 3500},
+			order: []uint{2, 1},
+		},
+		{
+			name: "equal priority by ID",
+			candidates: []candidate{
+				{promotion: fixed(7, 500, 0, true), lines: all},
+				{promotion: fixed(3, 500, 0, true), lines: all},
+			},
+			want:  map[uint]int64{3: 500, 7: 500},
+			order: []uint{3, 7},
+		},
+		{
+			name: "non-stackable promotion applies alone",
+			candidates: []candidate{
+				{promotion: percentage(1, 10, 5, false), lines: all},
+				{promotion: fixed(2, 500, 1, true), lines: all},
+			},
+			want:  map[uint]int64{1: 1000},
+			order: []uint{1},
+		},
+		{
+			name: "non-stackable automatic promotion is skipped after another",
+			candidates: []candidate{
+				{promotion: fixed(1, 500, 5, true), lines: all},
+				{promotion: percentage(2, 10, 1, false), lines: all},
+			},
+			want:  map[uint]int64{1: 500},
+			order: []uint{1},
+		},
+		{
+			name: "non-stackable coupon after another is refused",
+			candidates: []candidate{
+				{promotion: fixed(1, 500, 5, true), lines: all},
+				{promotion: coupon(percentage(2, 10, 1, false), "SAVE10"), lines: all},
+			},
+			wantErr: ErrCouponNotCombinable,
+		},
+		{
+			name: "stacked discounts never exceed the items",
+			candidates: []candidate{
+				{promotion: fixed(1, 8000, 2, true), lines: all},
+				{promotion: fixed(2, 5000, 1, true), lines: all},
+			},
+			want:  map[uint]int64{1: 8000, 2: 2000},
+			order: []uint{1, 2},
+		},
+		{
+			name: "automatic promotion with nothing left is skipped",
+			candidates: []candidate{
+				{promotion: fixed(1, 10000, 2, true), lines: all},
+				{promotion: fixed(2, 500, 1, true), lines: all},
+			},
+			want:  map[uint]int64{1: 10000},
+			order: []uint{1},
+		},
+		{
+			name: "coupon with nothing left is refused",
+			candidates: []candidate{
+				{promotion: fixed(1, 10000, 2, true), lines: all},
+				{promotion: coupon(fixed(2, 500, 1, true), "FIVE"), lines: all},
+			},
+			wantErr: ErrCouponNotApplicable,
+		},
+		{
+			name: "scoped promotion only discounts its lines",
+			candidates: []candidate{
+				{promotion: percentage(1, 50, 2, true), lines: []int{1}},
+				{promotion: percentage(2, 10, 1, true), lines: all},
+			},
+			want:  map[uint]int64{1: 2000, 2: 800},
+			order: []uint{1, 2},
+		},
+		{
+			name: "free shipping stacks without taking anything off the items",
+			candidates: []candidate{
+				{promotion: &Promotion{ID: 1, Type: TypeFreeShipping, Priority: 3, Stackable: true}, lines: all},
+				{promotion: fixed(2, 1000, 1, true), lines: all},
+			},
+			want:  map[uint]int64{1: 0, 2: 1000},
+			order: []uint{1, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := discounts(tt.candidates, lines)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("discounts() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			var order []uint
+			for _, d := range got {
+				order = append(order, d.PromotionID)
+				if d.Amount.Amount != tt.want[d.PromotionID] {
+					t.Errorf("promotion %d discount = %d, want %d", d.PromotionID, d.Amount.Amount, tt.want[d.PromotionID])
+				}
+				var spread int64
+				for _, amount := range d.Lines {
+					spread += amount
+				}
+				if spread != d.Amount.Amount {
+					t.Errorf("promotion %d lines sum to %d, want %d", d.PromotionID, spread, d.Amount.Amount)
+				}
+			}
+			if !reflect.DeepEqual(order, tt.order) {
+				t.Errorf("applied promotions %v, want %v", order, tt.order)
+			}
+		})
+	}
+}
+
+func TestCheckUsageLimits(t *testing.T) {
+	e := &eligibility{
+		lines:    []Line{{ProductID: 1, Quantity: 1, UnitPrice: usd(5000)}},
+		subtotal: 5000,
+		currency: "USD",
+		used:     map[uint]int{1: 2},
+	}
+
+	tests := []struct {
+		name      string
+		promotion Promotion
+		applies   bool
+		wantErr   error
+	}{
+		{"no limits", Promotion{ID: 1}, true, nil},
+		{"below usage limit", Promotion{ID: 1, UsageLimit: intPtr(5), UsageCount: 4}, true, nil},
+		{"usage limit reached", Promotion{ID: 1, UsageLimit: intPtr(5), UsageCount: 5}, false, nil},
+		{"coupon usage limit reached", Promotion{ID: 1, Code: strPtr("SAVE"), UsageLimit: intPtr(5), UsageCount: 5}, false, ErrUsageLimitReached},
+		{"below per-user limit", Promotion{ID: 1, PerUserLimit: intPtr(3)}, true, nil},
+		{"per-user limit reached", Promotion{ID: 1, PerUserLimit: intPtr(2)}, false, nil},
+		{"coupon per-user limit reached", Promotion{ID: 1, Code: strPtr("SAVE"), PerUserLimit: intPtr(2)}, false, ErrUsageLimitReached},
+		{"other customer's uses do not count", Promotion{ID: 2, PerUserLimit: intPtr(1)}, true, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines, reason, err := e.check(&tt.promotion)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("check() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if tt.applies && (reason != "" || len(lines) != 1) {
+				t.Errorf("check() = %v, %q, want the promotion to apply", lines, reason)
+			}
+			if !tt.applies && reason == "" {
+				t.Errorf("check() = %v, want a reason the promotion does not apply", lines)
+			}
+		})
+	}
+}
+
+func TestPerPromotion(t *testing.T) {
+	applied := []Discount{
+		{PromotionID: 5, Name: "shipping", Amount: usd(0), Lines: []int64{0}},
+		{PromotionID: 2, Name: "ten off", Amount: usd(1000), Lines: []int64{1000}},
+		{PromotionID: 5, Name: "shipping", Amount: usd(450)},
+	}
+
+	got, err := perPromotion(applied)
+	if err != nil {
+		t.Fatalf("perPromotion() error = %v", err)
+	}
+	want := []Discount{
+		{PromotionID: 2, Name: "ten off", Amount: usd(1000)},
+		{PromotionID: 5, Name: "shipping", Amount: usd(450)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("perPromotion() = %+v, want %+v", got, want)
+	}
+
+	if _, err := perPromotion([]Discount{
+		{PromotionID: 1, Amount: usd(100)},
+		{PromotionID: 1, Amount: money.New(100, "EUR")},
+	}); !errors.Is(err, money.ErrCurrencyMismatch) {
+		t.Errorf("perPromotion() in two currencies error = %v, want %v", err, money.ErrCurrencyMismatch)
+	}
+}
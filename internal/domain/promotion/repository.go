@@ -0,0 +1,520 @@
+package promotion
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/nneji123/ecommerce-golang/internal/domain/category"
+	"github.com/nneji123/ecommerce-golang/internal/domain/product"
+	"github.com/nneji123/ecommerce-golang/internal/middleware"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrPromotionNotFound = errors.New("promotion not found")
+	ErrCodeConflict      = errors.New("promotion code already exists")
+	ErrInvalidPromotion  = errors.New("invalid promotion")
+	ErrProductNotFound   = errors.New("product not found")
+	ErrCategoryNotFound  = errors.New("category not found")
+	// ErrCouponInvalid is returned for a code that matches no active
+	// promotion within its validity window.
+	ErrCouponInvalid = errors.New("coupon code is invalid or has expired")
+	// ErrCouponNotApplicable is returned for a valid coupon whose conditions
+	// the order does not meet.
+	ErrCouponNotApplicable = errors.New("coupon cannot be applied to this order")
+	// ErrCouponNotCombinable is returned for a coupon that cannot be stacked
+	// with the other promotions of the order.
+	ErrCouponNotCombinable = errors.New("coupon cannot be combined with the other promotions of this order")
+	// ErrUsageLimitReached is returned when a coupon has been used as many
+	// times as it may be, overall or by the customer.
+	ErrUsageLimitReached = errors.New("coupon usage limit reached")
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// List returns a page of promotions, highest priority first.
+func (r *Repository) List(query ListQuery) ([]Promotion, middleware.PaginatedResponse, error) {
+	promotions := []Promotion{}
+	db := r.db.Model(&Promotion{})
+	if query.Active != nil {
+		db = db.Where("active = ?", *query.Active)
+	}
+	if query.Code != "" {
+		db = db.Where("code = ?", NormalizeCode(query.Code))
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, middleware.PaginatedResponse{}, err
+	}
+
+	err := db.Order("priority DESC, id").
+		Offset((query.Page - 1) * query.Limit).
+		Limit(query.Limit).
+		Find(&promotions).Error
+	if err != nil {
+		return nil, middleware.PaginatedResponse{}, err
+	}
+	return promotions, middleware.OffsetPage(query.Page, query.Limit, total), nil
+}
+
+func (r *Repository) GetByID(id uint) (*Promotion, error) {
+	var promotion Promotion
+	if err := r.db.Preload("Products").Preload("Categories").First(&promotion, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPromotionNotFound
+		}
+		return nil, err
+	}
+	return &promotion, nil
+}
+
+// Create inserts a promotion restricted to the given products and
+// categories, if any.
+func (r *Repository) Create(promotion *Promotion, productIDs, categoryIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkCode(tx, promotion.Code, 0); err != nil {
+			return err
+		}
+		if err := tx.Omit(clause.Associations).Create(promotion).Error; err != nil {
+			return err
+		}
+		return setScope(tx, promotion, productIDs, categoryIDs)
+	})
+}
+
+// Update replaces the rules of a promotion. Its usage count is kept.
+func (r *Repository) Update(promotion *Promotion, productIDs, categoryIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkCode(tx, promotion.Code, promotion.ID); err != nil {
+			return err
+		}
+		err := tx.Model(promotion).Omit(clause.Associations, "UsageCount", "CreatedAt").Select("*").Updates(promotion).Error
+		if err != nil {
+			return err
+		}
+		return setScope(tx, promotion, productIDs, categoryIDs)
+	})
+}
+
+// Delete removes a promotion. Orders keep their discount lines.
+func (r *Repository) Delete(id uint) error {
+	result := r.db.Delete(&Promotion{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrPromotionNotFound
+	}
+	return nil
+}
+
+func checkCode(tx *gorm.DB, code *string, id uint) error {
+	if code == nil {
+		return nil
+	}
+	var count int64
+	if err := tx.Model(&Promotion{}).Where("code = ? AND id <> ?", *code, id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrCodeConflict
+	}
+	return nil
+}
+
+// setScope replaces the products and categories a promotion is restricted
+// to.
+func setScope(tx *gorm.DB, promotion *Promotion, productIDs, categoryIDs []uint) error {
+	products := []product.Product{}
+	if len(productIDs) > 0 {
+		if err := tx.Where("id IN ?", productIDs).Find(&products).Error; err != nil {
+			return err
+		}
+		if !allFound(productIDs, len(products), func(i int) uint { return products[i].ID }) {
+			return ErrProductNotFound
+		}
+	}
+	categories := []category.Category{}
+	if len(categoryIDs) > 0 {
+		if err := tx.Where("id IN ?", categoryIDs).Find(&categories).Error; err != nil {
+			return err
+		}
+		if !allFound(categoryIDs, len(categories), func(i int) uint { return categories[i].ID }) {
+			return ErrCategoryNotFound
+		}
+	}
+
+	if err := tx.Model(promotion).Association("Products").Replace(products); err != nil {
+		return err
+	}
+	if err := tx.Model(promotion).Association("Categories").Replace(categories); err != nil {
+		return err
+	}
+	promotion.Products = products
+	promotion.Categories = categories
+	return nil
+}
+
+// allFound reports whether every id is among the n records found.
+func allFound(ids []uint, n int, id func(int) uint) bool {
+	found := make(map[uint]bool, n)
+	for i := 0; i < n; i++ {
+		found[id(i)] = true
+	}
+	for _, id := range ids {
+		if !found[id] {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply returns the discounts an order placed by checkout.UserID receives:
+// those of the eligible automatic promotions and of the coupons entered.
+// Every coupon must be valid and applicable. Usage limits are checked but not
+// consumed; Redeem does that once the order exists. The lines must all be in
+// one currency.
+func (r *Repository) Apply(checkout Checkout) ([]Discount, error) {
+	if len(checkout.Lines) == 0 {
+		return []Discount{}, nil
+	}
+
+	codes := make([]string, 0, len(checkout.Codes))
+	seen := make(map[string]bool, len(checkout.Codes))
+	for _, code := range checkout.Codes {
+		code = NormalizeCode(code)
+		if code != "" && !seen[code] {
+			seen[code] = true
+			codes = append(codes, code)
+		}
+	}
+
+	now := time.Now()
+	db := r.db.Where("active AND (starts_at IS NULL OR starts_at <= ?) AND (ends_at IS NULL OR ends_at > ?)", now, now)
+	if len(codes) > 0 {
+		db = db.Where("code IS NULL OR code IN ?", codes)
+	} else {
+		db = db.Where("code IS NULL")
+	}
+	var promotions []Promotion
+	if err := db.Find(&promotions).Error; err != nil {
+		return nil, err
+	}
+
+	for _, code := range codes {
+		found := false
+		for _, p := range promotions {
+			if p.Code != nil && *p.Code == code {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: %s", ErrCouponInvalid, code)
+		}
+	}
+	if len(promotions) == 0 {
+		return []Discount{}, nil
+	}
+
+	e, err := r.eligibility(checkout, promotions)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]candidate, 0, len(promotions))
+	for i := range promotions {
+		p := &promotions[i]
+		lines, reason, err := e.check(p)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			if p.Code != nil {
+				return nil, notApplicable(p, reason)
+			}
+			continue
+		}
+		candidates = append(candidates, candidate{promotion: p, lines: lines})
+	}
+	return discounts(candidates, checkout.Lines)
+}
+
+// eligibility holds what is known about an order and its customer to decide
+// which promotions apply to it.
+type eligibility struct {
+	lines      []Line
+	subtotal   int64
+	currency   string
+	firstOrder bool
+	// used counts the customer's redemptions per promotion.
+	used map[uint]int
+	// products and categories are the scope of each promotion.
+	products   map[uint]map[uint]bool
+	categories map[uint]map[uint]bool
+	// productCategories holds the categories of each ordered product,
+	// including the ancestors of the categories it is assigned to.
+	productCategories map[uint][]uint
+}
+
+func (r *Repository) eligibility(checkout Checkout, promotions []Promotion) (*eligibility, error) {
+	e := &eligibility{
+		lines:             checkout.Lines,
+		currency:          checkout.Lines[0].UnitPrice.Currency,
+		used:              make(map[uint]int),
+		products:          make(map[uint]map[uint]bool),
+		categories:        make(map[uint]map[uint]bool),
+		productCategories: make(map[uint][]uint),
+	}
+	productIDs := make([]uint, 0, len(checkout.Lines))
+	for _, line := range checkout.Lines {
+		e.subtotal += line.UnitPrice.Mul(int64(line.Quantity)).Amount
+		productIDs = append(productIDs, line.ProductID)
+	}
+	promotionIDs := make([]uint, 0, len(promotions))
+	for _, p := range promotions {
+		promotionIDs = append(promotionIDs, p.ID)
+	}
+
+	var orders int64
+	err := r.db.Table("orders").
+		Where("user_id = ? AND status <> 'cancelled' AND deleted_at IS NULL", checkout.UserID).
+		Count(&orders).Error
+	if err != nil {
+		return nil, err
+	}
+	e.firstOrder = orders == 0
+
+	var used []struct {
+		PromotionID uint
+		Count       int
+	}
+	err = r.db.Model(&Redemption{}).
+		Select("promotion_id, COUNT(*) AS count").
+		Where("user_id = ? AND promotion_id IN ?", checkout.UserID, promotionIDs).
+		Group("promotion_id").
+		Scan(&used).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range used {
+		e.used[u.PromotionID] = u.Count
+	}
+
+	var scoped []struct {
+		PromotionID uint
+		ProductID   uint
+	}
+	if err := r.db.Table("promotion_products").Where("promotion_id IN ?", promotionIDs).Scan(&scoped).Error; err != nil {
+		return nil, err
+	}
+	for _, s := range scoped {
+		if e.products[s.PromotionID] == nil {
+			e.products[s.PromotionID] = make(map[uint]bool)
+		}
+		e.products[s.PromotionID][s.ProductID] = true
+	}
+
+	var scopedCategories []struct {
+		PromotionID uint
+		CategoryID  uint
+	}
+	if err := r.db.Table("promotion_categories").Where("promotion_id IN ?", promotionIDs).Scan(&scopedCategories).Error; err != nil {
+		return nil, err
+	}
+	for _, s := range scopedCategories {
+		if e.categories[s.PromotionID] == nil {
+			e.categories[s.PromotionID] = make(map[uint]bool)
+		}
+		e.categories[s.PromotionID][s.CategoryID] = true
+	}
+
+	if len(scopedCategories) > 0 {
+		var assigned []struct {
+			ProductID  uint
+			CategoryID uint
+		}
+		err := r.db.Table("product_categories pc").
+			Select("DISTINCT pc.product_id, a.id AS category_id").
+			Joins("JOIN categories c ON c.id = pc.category_id").
+			Joins("JOIN categories a ON c.path LIKE a.path || '%'").
+			Where("pc.product_id IN ?", productIDs).
+			Scan(&assigned).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range assigned {
+			e.productCategories[a.ProductID] = append(e.productCategories[a.ProductID], a.CategoryID)
+		}
+	}
+	return e, nil
+}
+
+// check returns the indexes of the lines a promotion applies to, or the
+// reason it does not apply to the order.
+func (e *eligibility) check(p *Promotion) ([]int, string, error) {
+	if p.AmountOff != nil && p.AmountOff.Currency != e.currency ||
+		p.MinSubtotal != nil && p.MinSubtotal.Currency != e.currency {
+		return nil, "is not available in " + e.currency, nil
+	}
+	if p.MinSubtotal != nil && e.subtotal < p.MinSubtotal.Amount {
+		return nil, "requires a subtotal of at least " + p.MinSubtotal.String(), nil
+	}
+	if p.UsageLimit != nil && p.UsageCount >= *p.UsageLimit ||
+		p.PerUserLimit != nil && e.used[p.ID] >= *p.PerUserLimit {
+		if p.Code != nil {
+			return nil, "", fmt.Errorf("%w: %s", ErrUsageLimitReached, *p.Code)
+		}
+		return nil, "has been used up", nil
+	}
+	if p.FirstOrderOnly && !e.firstOrder {
+		return nil, "is only available on a first order", nil
+	}
+
+	products, categories := e.products[p.ID], e.categories[p.ID]
+	var lines []int
+	for i, line := range e.lines {
+		if len(products) == 0 && len(categories) == 0 || products[line.ProductID] {
+			lines = append(lines, i)
+			continue
+		}
+		for _, id := range e.productCategories[line.ProductID] {
+			if categories[id] {
+				lines = append(lines, i)
+				break
+			}
+		}
+	}
+	if len(lines) == 0 {
+		return nil, "does not apply to any item in the order", nil
+	}
+	return lines, "", nil
+}
+
+// Redeem consumes the promotions applied to an order, recording a redemption
+// of each. The promotions are locked and their usage limits checked again,
+// so concurrent orders cannot exceed them. It must run in the transaction
+// that creates the order. A promotion granting several discounts is redeemed
+// once, for their total.
+func (r *Repository) Redeem(orderID, userID uint, applied []Discount) error {
+	if len(applied) == 0 {
+		return nil
+	}
+	applied, err := perPromotion(applied)
+	if err != nil {
+		return err
+	}
+	ids := make([]uint, 0, len(applied))
+	for _, d := range applied {
+		ids = append(ids, d.PromotionID)
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var promotions []Promotion
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", ids).
+			Order("id").
+			Find(&promotions).Error; err != nil {
+			return err
+		}
+		byID := make(map[uint]Promotion, len(promotions))
+		for _, p := range promotions {
+			byID[p.ID] = p
+		}
+
+		for _, d := range applied {
+			p, ok := byID[d.PromotionID]
+			if !ok {
+				return ErrPromotionNotFound
+			}
+			if p.UsageLimit != nil && p.UsageCount >= *p.UsageLimit {
+				return fmt.Errorf("%w: %s", ErrUsageLimitReached, name(p))
+			}
+			if p.PerUserLimit != nil {
+				var used int64
+				if err := tx.Model(&Redemption{}).
+					Where("promotion_id = ? AND user_id = ?", p.ID, userID).
+					Count(&used).Error; err != nil {
+					return err
+				}
+				if used >= int64(*p.PerUserLimit) {
+					return fmt.Errorf("%w: %s", ErrUsageLimitReached, name(p))
+				}
+			}
+
+			if err := tx.Model(&Promotion{}).
+				Where("id = ?", p.ID).
+				Update("usage_count", gorm.Expr("usage_count + 1")).Error; err != nil {
+				return err
+			}
+			if err := tx.Create(&Redemption{
+				PromotionID: p.ID,
+				OrderID:     orderID,
+				UserID:      userID,
+				Amount:      d.Amount,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// perPromotion totals discounts by promotion, in ascending promotion ID.
+func perPromotion(applied []Discount) ([]Discount, error) {
+	totals := make([]Discount, 0, len(applied))
+	index := make(map[uint]int, len(applied))
+	for _, d := range applied {
+		i, ok := index[d.PromotionID]
+		if !ok {
+			index[d.PromotionID] = len(totals)
+			d.Lines = nil
+			totals = append(totals, d)
+			continue
+		}
+		amount, err := totals[i].Amount.Add(d.Amount)
+		if err != nil {
+			return nil, err
+		}
+		totals[i].Amount = amount
+	}
+	sort.Slice(totals, func(i, j int) bool { return totals[i].PromotionID < totals[j].PromotionID })
+	return totals, nil
+}
+
+// Release gives back the promotions redeemed by an order that is cancelled.
+func (r *Repository) Release(orderID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var redemptions []Redemption
+		if err := tx.Where("order_id = ?", orderID).Order("promotion_id").Find(&redemptions).Error; err != nil {
+			return err
+		}
+		for _, redemption := range redemptions {
+			if err := tx.Model(&Promotion{}).
+				Where("id = ?", redemption.PromotionID).
+				Update("usage_count", gorm.Expr("GREATEST(usage_count - 1, 0)")).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Where("order_id = ?", orderID).Delete(&Redemption{}).Error
+	})
+}
+
+// name identifies a promotion in errors by its code, or its name for
+// automatic promotions.
+func name(p Promotion) string {
+	if p.Code != nil {
+		return *p.Code
+	}
+	return p.Name
+}
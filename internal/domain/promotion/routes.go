@@ -0,0 +1,28 @@
+package promotion
+
+import (
+	"log"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nneji123/ecommerce-golang/internal/common/revocation"
+	"github.com/nneji123/ecommerce-golang/internal/config"
+	"github.com/nneji123/ecommerce-golang/internal/domain/rbac"
+	"github.com/nneji123/ecommerce-golang/internal/middleware"
+)
+
+func RegisterRoutes(e *echo.Echo, h *Handler, revocations revocation.Store, permissions middleware.PermissionResolver) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Error loading configuration: %s", err)
+	}
+
+	promotions := e.Group("/promotions",
+		middleware.AuthMiddleware(cfg.JWTSecret, revocations),
+		middleware.RequirePermission(permissions, rbac.PermissionPromotionsManage),
+	)
+	promotions.GET("", h.List)
+	promotions.POST("", h.Create)
+	promotions.GET("/:id", h.Get)
+	promotions.PUT("/:id", h.Update)
+	promotions.DELETE("/:id", h.Delete)
+}
@@ -0,0 +1,201 @@
+package promotion
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nneji123/ecommerce-golang/internal/common/money"
+	"github.com/nneji123/ecommerce-golang/internal/domain/category"
+	"github.com/nneji123/ecommerce-golang/internal/domain/product"
+	"github.com/nneji123/ecommerce-golang/internal/middleware"
+)
+
+// Type is the kind of benefit a promotion grants.
+type Type string
+
+const (
+	// TypePercentage takes Percentage off the eligible items.
+	TypePercentage Type = "percentage"
+	// TypeFixedAmount takes AmountOff off the eligible items.
+	TypeFixedAmount Type = "fixed_amount"
+	// TypeFreeShipping waives the shipping cost of the order.
+	TypeFreeShipping Type = "free_shipping"
+	// TypeBuyXGetY discounts GetQuantity of every BuyQuantity+GetQuantity
+	// eligible units by Percentage, cheapest units first.
+	TypeBuyXGetY Type = "buy_x_get_y"
+)
+
+// Promotion is a discount rule. Promotions with a Code are coupons applied
+// when the customer enters the code at checkout; the others apply to every
+// eligible order automatically.
+//
+// A promotion is eligible when it is active and within its validity window,
+// its usage limits are not exhausted, the order subtotal reaches MinSubtotal,
+// and the order contains at least one eligible item: any item when Products
+// and Categories are empty, otherwise an item of one of the Products or in
+// one of the Categories or their descendants. FirstOrderOnly promotions are
+// only eligible for customers without another order that was not cancelled.
+//
+// Eligible promotions are applied in descending Priority. A promotion that is
+// not Stackable is only applied on its own; stackable ones combine.
+type Promotion struct {
+	ID             uint                `gorm:"primaryKey" json:"id"`
+	Name           string              `gorm:"size:255;not null" json:"name"`
+	Description    string              `gorm:"type:text" json:"description"`
+	Code           *string             `gorm:"size:50;uniqueIndex" json:"code,omitempty"`
+	Type           Type                `gorm:"type:varchar(20);not null" json:"type"`
+	Percentage     int                 `gorm:"not null;default:0" json:"percentage,omitempty"`
+	AmountOff      *money.Money        `gorm:"embedded;embeddedPrefix:amount_off_" json:"amount_off,omitempty"`
+	BuyQuantity    int                 `gorm:"not null;default:0" json:"buy_quantity,omitempty"`
+	GetQuantity    int                 `gorm:"not null;default:0" json:"get_quantity,omitempty"`
+	MinSubtotal    *money.Money        `gorm:"embedded;embeddedPrefix:min_subtotal_" json:"min_subtotal,omitempty"`
+	Products       []product.Product   `gorm:"many2many:promotion_products" json:"products,omitempty"`
+	Categories     []category.Category `gorm:"many2many:promotion_categories" json:"categories,omitempty"`
+	FirstOrderOnly bool                `gorm:"not null;default:false" json:"first_order_only"`
+	UsageLimit     *int                `json:"usage_limit,omitempty"`
+	PerUserLimit   *int                `json:"per_user_limit,omitempty"`
+	UsageCount     int                 `gorm:"not null;default:0" json:"usage_count"`
+	StartsAt       *time.Time          `json:"starts_at,omitempty"`
+	EndsAt         *time.Time          `json:"ends_at,omitempty"`
+	Stackable      bool                `gorm:"not null;default:false" json:"stackable"`
+	Priority       int                 `gorm:"not null;default:0" json:"priority"`
+	Active         bool                `gorm:"not null;default:true" json:"active"`
+	CreatedAt      time.Time           `json:"created_at"`
+	UpdatedAt      time.Time           `json:"updated_at"`
+}
+
+// Redemption records a promotion used by an order. Redemptions count
+// towards the per-user limit and are removed when the order is cancelled.
+type Redemption struct {
+	ID          uint        `gorm:"primaryKey" json:"id"`
+	PromotionID uint        `gorm:"not null;index" json:"promotion_id"`
+	OrderID     uint        `gorm:"not null;index" json:"order_id"`
+	UserID      uint        `gorm:"not null;index" json:"user_id"`
+	Amount      money.Money `gorm:"embedded" json:"amount"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+func (Redemption) TableName() string {
+	return "promotion_redemptions"
+}
+
+// Line is an order line offered for discount. VariantID is nil for products
+// sold without variants.
+type Line struct {
+	ProductID uint
+	VariantID *uint
+	Quantity  int
+	UnitPrice money.Money
+}
+
+// Checkout is an order being placed, with the coupon codes entered by the
+// customer.
+type Checkout struct {
+	UserID uint
+	Lines  []Line
+	Codes  []string
+}
+
+// Discount is a promotion applied to an order. Amount is zero for free
+// shipping until the order sets it to the shipping cost waived, which is
+// taken off no line. Lines holds the minor units of Amount taken off each
+// line, indexed like Checkout.Lines.
+type Discount struct {
+	PromotionID uint
+	Code        string
+	Name        string
+	Type        Type
+	Amount      money.Money
+	Lines       []int64
+}
+
+// PromotionRequest creates or replaces a promotion. An empty Code makes an
+// automatic promotion. Active defaults to true.
+type PromotionRequest struct {
+	Name           string       `json:"name" validate:"required,max=255"`
+	Description    string       `json:"description"`
+	Code           string       `json:"code" validate:"max=50"`
+	Type           Type         `json:"type" validate:"required,oneof=percentage fixed_amount free_shipping buy_x_get_y"`
+	Percentage     int          `json:"percentage" validate:"gte=0,lte=100"`
+	AmountOff      *money.Money `json:"amount_off" validate:"omitempty,gt=0"`
+	BuyQuantity    int          `json:"buy_quantity" validate:"gte=0"`
+	GetQuantity    int          `json:"get_quantity" validate:"gte=0"`
+	MinSubtotal    *money.Money `json:"min_subtotal" validate:"omitempty,gt=0"`
+	ProductIDs     []uint       `json:"product_ids"`
+	CategoryIDs    []uint       `json:"category_ids"`
+	FirstOrderOnly bool         `json:"first_order_only"`
+	UsageLimit     *int         `json:"usage_limit" validate:"omitempty,gt=0"`
+	PerUserLimit   *int         `json:"per_user_limit" validate:"omitempty,gt=0"`
+	StartsAt       *time.Time   `json:"starts_at"`
+	EndsAt         *time.Time   `json:"ends_at"`
+	Stackable      bool         `json:"stackable"`
+	Priority       int          `json:"priority"`
+	Active         *bool        `json:"active"`
+}
+
+// ListQuery selects a page of promotions, highest priority first.
+type ListQuery struct {
+	middleware.PaginationQuery
+	Active *bool  `query:"active"`
+	Code   string `query:"code"`
+}
+
+// NormalizeCode returns a coupon code as stored: trimmed and upper case.
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Promotion builds the promotion described by the request, checking the
+// fields the chosen type requires and clearing those it does not use.
+// Products and categories are assigned separately.
+func (req PromotionRequest) Promotion() (*Promotion, error) {
+	p := &Promotion{
+		Name:           req.Name,
+		Description:    req.Description,
+		Type:           req.Type,
+		MinSubtotal:    req.MinSubtotal,
+		FirstOrderOnly: req.FirstOrderOnly,
+		UsageLimit:     req.UsageLimit,
+		PerUserLimit:   req.PerUserLimit,
+		StartsAt:       req.StartsAt,
+		EndsAt:         req.EndsAt,
+		Stackable:      req.Stackable,
+		Priority:       req.Priority,
+		Active:         req.Active == nil || *req.Active,
+	}
+	if code := NormalizeCode(req.Code); code != "" {
+		p.Code = &code
+	}
+
+	switch req.Type {
+	case TypePercentage:
+		if req.Percentage < 1 {
+			return nil, fmt.Errorf("%w: percentage must be between 1 and 100", ErrInvalidPromotion)
+		}
+		p.Percentage = req.Percentage
+	case TypeFixedAmount:
+		if req.AmountOff == nil {
+			return nil, fmt.Errorf("%w: amount_off is required", ErrInvalidPromotion)
+		}
+		p.AmountOff = req.AmountOff
+	case TypeBuyXGetY:
+		if req.BuyQuantity < 1 || req.GetQuantity < 1 {
+			return nil, fmt.Errorf("%w: buy_quantity and get_quantity must be at least 1", ErrInvalidPromotion)
+		}
+		p.BuyQuantity, p.GetQuantity = req.BuyQuantity, req.GetQuantity
+		// The discounted units are free unless a percentage is given.
+		p.Percentage = req.Percentage
+		if p.Percentage == 0 {
+			p.Percentage = 100
+		}
+	}
+
+	if p.AmountOff != nil && p.MinSubtotal != nil && p.AmountOff.Currency != p.MinSubtotal.Currency {
+		return nil, fmt.Errorf("%w: amount_off and min_subtotal must be in the same currency", ErrInvalidPromotion)
+	}
+	if p.StartsAt != nil && p.EndsAt != nil && !p.EndsAt.After(*p.StartsAt) {
+		return nil, fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidPromotion)
+	}
+	return p, nil
+}
@@ -0,0 +1,218 @@
+package rbac
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/nneji123/ecommerce-golang/internal/common/revocation"
+	"go.uber.org/zap"
+)
+
+type Handler struct {
+	repo        *Repository
+	resolver    *Resolver
+	revocations revocation.Store
+	validator   *validator.Validate
+	logger      *zap.Logger
+}
+
+func NewHandler(repo *Repository, resolver *Resolver, revocations revocation.Store, validator *validator.Validate, logger *zap.Logger) *Handler {
+	return &Handler{
+		repo:        repo,
+		resolver:    resolver,
+		revocations: revocations,
+		validator:   validator,
+		logger:      logger,
+	}
+}
+
+// @Summary		List roles
+// @Description	List all roles with their permissions (requires roles:manage)
+// @Tags			roles
+// @Produce		json
+// @Success		200	{array}	Role
+// @Router			/roles [get]
+func (h *Handler) ListRoles(c echo.Context) error {
+	roles, err := h.repo.ListRoles()
+	if err != nil {
+		h.logger.Error("Failed to list roles", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list roles")
+	}
+	return c.JSON(http.StatusOK, roles)
+}
+
+// @Summary		List permissions
+// @Description	List every permission that can be granted to a role (requires roles:manage)
+// @Tags			roles
+// @Produce		json
+// @Success		200	{array}	Permission
+// @Router			/permissions [get]
+func (h *Handler) ListPermissions(c echo.Context) error {
+	permissions, err := h.repo.ListPermissions()
+	if err != nil {
+		h.logger.Error("Failed to list permissions", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list permissions")
+	}
+	return c.JSON(http.StatusOK, permissions)
+}
+
+// @Summary		Create role
+// @Description	Create a role with a set of permissions (requires roles:manage)
+// @Tags			roles
+// @Accept			json
+// @Produce		json
+// @Param			role	body		CreateRoleRequest	true	"Role definition"
+// @Success		201		{object}	Role
+// @Failure		400		{object}	middleware.ErrorResponse
+// @Failure		409		{object}	middleware.ErrorResponse
+// @Router			/roles [post]
+func (h *Handler) CreateRole(c echo.Context) error {
+	var req CreateRoleRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	role := Role{Name: req.Name, Description: req.Description}
+	if err := h.repo.CreateRole(&role, req.Permissions); err != nil {
+		return h.roleError(err, "Failed to create role")
+	}
+
+	h.resolver.Invalidate()
+	return c.JSON(http.StatusCreated, role)
+}
+
+// @Summary		Update role
+// @Description	Update a role's description and replace its permissions (requires roles:manage). The permissions of system roles cannot be changed.
+// @Tags			roles
+// @Accept			json
+// @Produce		json
+// @Param			id		path		int					true	"Role ID"
+// @Param			role	body		UpdateRoleRequest	true	"Role definition"
+// @Success		200		{object}	Role
+// @Failure		400		{object}	middleware.ErrorResponse
+// @Failure		404		{object}	middleware.ErrorResponse
+// @Failure		409		{object}	middleware.ErrorResponse
+// @Router			/roles/{id} [put]
+func (h *Handler) UpdateRole(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid role ID")
+	}
+
+	var req UpdateRoleRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	role, err := h.repo.GetRole(uint(id))
+	if err != nil {
+		return h.roleError(err, "Failed to update role")
+	}
+
+	role.Description = req.Description
+	if err := h.repo.UpdateRole(role, req.Permissions); err != nil {
+		return h.roleError(err, "Failed to update role")
+	}
+
+	h.resolver.Invalidate()
+	return c.JSON(http.StatusOK, role)
+}
+
+// @Summary		Delete role
+// @Description	Delete a role that is not assigned to any user (requires roles:manage)
+// @Tags			roles
+// @Param			id	path	int	true	"Role ID"
+// @Success		204	"No Content"
+// @Failure		404	{object}	middleware.ErrorResponse
+// @Failure		409	{object}	middleware.ErrorResponse
+// @Router			/roles/{id} [delete]
+func (h *Handler) DeleteRole(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid role ID")
+	}
+
+	role, err := h.repo.GetRole(uint(id))
+	if err != nil {
+		return h.roleError(err, "Failed to delete role")
+	}
+
+	if err := h.repo.DeleteRole(role); err != nil {
+		return h.roleError(err, "Failed to delete role")
+	}
+
+	h.resolver.Invalidate()
+	return c.NoContent(http.StatusNoContent)
+}
+
+// @Summary		Assign role
+// @Description	Assign a role to a user (requires roles:manage). The user's access tokens are revoked, so the new role takes effect when they next refresh.
+// @Tags			roles
+// @Accept			json
+// @Produce		json
+// @Param			id		path		int					true	"User ID"
+// @Param			request	body		AssignRoleRequest	true	"Role name"
+// @Success		200		{object}	map[string]string
+// @Failure		400		{object}	middleware.ErrorResponse
+// @Failure		404		{object}	middleware.ErrorResponse
+// @Router			/users/{id}/role [put]
+func (h *Handler) AssignRole(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid user ID")
+	}
+
+	var req AssignRoleRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.repo.AssignRole(uint(id), req.Role); err != nil {
+		return h.roleError(err, "Failed to assign role")
+	}
+	if err := h.revocations.RevokeUserTokens(uint(id)); err != nil {
+		h.logger.Error("Failed to revoke tokens after a role change", zap.Error(err), zap.Uint("user_id", uint(id)))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Role assigned, but the user's tokens could not be revoked")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Role assigned successfully",
+		"role":    req.Role,
+	})
+}
+
+func (h *Handler) roleError(err error, message string) error {
+	switch {
+	case errors.Is(err, ErrRoleNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Role not found")
+	case errors.Is(err, ErrUserNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "User not found")
+	case errors.Is(err, ErrUnknownPermission):
+		return echo.NewHTTPError(http.StatusBadRequest, "Unknown permission")
+	case errors.Is(err, ErrRoleNameConflict):
+		return echo.NewHTTPError(http.StatusConflict, "Role name already exists")
+	case errors.Is(err, ErrSystemRole):
+		return echo.NewHTTPError(http.StatusConflict, "System roles cannot be deleted")
+	case errors.Is(err, ErrSystemPermissions):
+		return echo.NewHTTPError(http.StatusConflict, "The permissions of system roles cannot be changed")
+	case errors.Is(err, ErrRoleInUse):
+		return echo.NewHTTPError(http.StatusConflict, "Role is assigned to users")
+	}
+	h.logger.Error(message, zap.Error(err))
+	return echo.NewHTTPError(http.StatusInternalServerError, message)
+}
@@ -0,0 +1,184 @@
+package rbac
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrRoleNotFound      = errors.New("role not found")
+	ErrUnknownPermission = errors.New("unknown permission")
+	ErrSystemRole        = errors.New("system roles cannot be deleted")
+	// ErrSystemPermissions is returned when changing the permissions of a
+	// system role, which could leave nobody able to manage roles.
+	ErrSystemPermissions = errors.New("the permissions of system roles cannot be changed")
+	ErrRoleInUse         = errors.New("role is assigned to users")
+	ErrUserNotFound      = errors.New("user not found")
+	ErrRoleNameConflict  = errors.New("role name already exists")
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) ListRoles() ([]Role, error) {
+	var roles []Role
+	err := r.db.Preload("Permissions").Order("name").Find(&roles).Error
+	return roles, err
+}
+
+func (r *Repository) ListPermissions() ([]Permission, error) {
+	var permissions []Permission
+	err := r.db.Order("name").Find(&permissions).Error
+	return permissions, err
+}
+
+func (r *Repository) GetRole(id uint) (*Role, error) {
+	var role Role
+	if err := r.db.Preload("Permissions").First(&role, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRoleNotFound
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
+// PermissionsForRole returns the names of the permissions granted to a role.
+func (r *Repository) PermissionsForRole(name string) ([]string, error) {
+	var names []string
+	err := r.db.Table("permissions").
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Joins("JOIN roles ON roles.id = role_permissions.role_id").
+		Where("roles.name = ?", name).
+		Pluck("permissions.name", &names).Error
+	return names, err
+}
+
+func (r *Repository) CreateRole(role *Role, permissions []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&Role{}).Where("name = ?", role.Name).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrRoleNameConflict
+		}
+
+		resolved, err := findPermissions(tx, permissions)
+		if err != nil {
+			return err
+		}
+		role.Permissions = resolved
+		return tx.Create(role).Error
+	})
+}
+
+// UpdateRole changes a role's description and replaces its permissions. The
+// permissions of system roles are fixed; only their description can change.
+func (r *Repository) UpdateRole(role *Role, permissions []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		resolved, err := findPermissions(tx, permissions)
+		if err != nil {
+			return err
+		}
+		if role.System && !samePermissions(role.Permissions, resolved) {
+			return ErrSystemPermissions
+		}
+		if err := tx.Model(role).Update("description", role.Description).Error; err != nil {
+			return err
+		}
+		role.Permissions = resolved
+		return tx.Model(role).Association("Permissions").Replace(resolved)
+	})
+}
+
+func (r *Repository) DeleteRole(role *Role) error {
+	if role.System {
+		return ErrSystemRole
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Table("users").Where("role = ? AND deleted_at IS NULL", role.Name).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrRoleInUse
+		}
+
+		if err := tx.Model(role).Association("Permissions").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(role).Error
+	})
+}
+
+// AssignRole sets the role of a user. The role must exist.
+func (r *Repository) AssignRole(userID uint, roleName string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&Role{}).Where("name = ?", roleName).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrRoleNotFound
+		}
+
+		result := tx.Table("users").Where("id = ? AND deleted_at IS NULL", userID).Updates(map[string]interface{}{
+			"role":       roleName,
+			"updated_at": time.Now(),
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrUserNotFound
+		}
+		return nil
+	})
+}
+
+// samePermissions reports whether two sets of permissions are equal.
+func samePermissions(a, b []Permission) bool {
+	ids := make(map[uint]bool, len(a))
+	for _, p := range a {
+		ids[p.ID] = true
+	}
+	matched := make(map[uint]bool, len(b))
+	for _, p := range b {
+		if !ids[p.ID] {
+			return false
+		}
+		matched[p.ID] = true
+	}
+	return len(matched) == len(ids)
+}
+
+func findPermissions(tx *gorm.DB, names []string) ([]Permission, error) {
+	permissions := []Permission{}
+	if len(names) == 0 {
+		return permissions, nil
+	}
+
+	if err := tx.Where("name IN ?", names).Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]bool, len(permissions))
+	for _, p := range permissions {
+		found[p.Name] = true
+	}
+	for _, name := range names {
+		if !found[name] {
+			return nil, ErrUnknownPermission
+		}
+	}
+	return permissions, nil
+}
@@ -0,0 +1,70 @@
+package rbac
+
+import (
+	"sync"
+	"time"
+)
+
+// Resolver answers permission checks for the auth middleware, caching each
+// role's permissions for a short time.
+type Resolver struct {
+	repo *Repository
+	ttl  time.Duration
+
+	mu    sync.RWMutex
+	cache map[string]cachedRole
+}
+
+type cachedRole struct {
+	permissions map[string]bool
+	loadedAt    time.Time
+}
+
+func NewResolver(repo *Repository, ttl time.Duration) *Resolver {
+	return &Resolver{
+		repo:  repo,
+		ttl:   ttl,
+		cache: make(map[string]cachedRole),
+	}
+}
+
+func (r *Resolver) HasPermission(role, permission string) (bool, error) {
+	permissions, err := r.permissions(role)
+	if err != nil {
+		return false, err
+	}
+	return permissions[PermissionAll] || permissions[permission], nil
+}
+
+// Invalidate drops every cached role so changes take effect immediately on
+// this instance.
+func (r *Resolver) Invalidate() {
+	r.mu.Lock()
+	r.cache = make(map[string]cachedRole)
+	r.mu.Unlock()
+}
+
+func (r *Resolver) permissions(role string) (map[string]bool, error) {
+	r.mu.RLock()
+	cached, ok := r.cache[role]
+	r.mu.RUnlock()
+	if ok && time.Since(cached.loadedAt) < r.ttl {
+		return cached.permissions, nil
+	}
+
+	names, err := r.repo.PermissionsForRole(role)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := make(map[string]bool, len(names))
+	for _, name := range names {
+		permissions[name] = true
+	}
+
+	r.mu.Lock()
+	r.cache[role] = cachedRole{permissions: permissions, loadedAt: time.Now()}
+	r.mu.Unlock()
+
+	return permissions, nil
+}
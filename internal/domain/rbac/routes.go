@@ -0,0 +1,31 @@
+package rbac
+
+import (
+	"log"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nneji123/ecommerce-golang/internal/common/revocation"
+	"github.com/nneji123/ecommerce-golang/internal/config"
+	"github.com/nneji123/ecommerce-golang/internal/middleware"
+)
+
+func RegisterRoutes(e *echo.Echo, h *Handler, revocations revocation.Store, permissions middleware.PermissionResolver) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Error loading configuration: %s", err)
+	}
+
+	guard := []echo.MiddlewareFunc{
+		middleware.AuthMiddleware(cfg.JWTSecret, revocations),
+		middleware.RequirePermission(permissions, PermissionRolesManage),
+	}
+
+	roles := e.Group("/roles", guard...)
+	roles.GET("", h.ListRoles)
+	roles.POST("", h.CreateRole)
+	roles.PUT("/:id", h.UpdateRole)
+	roles.DELETE("/:id", h.DeleteRole)
+
+	e.GET("/permissions", h.ListPermissions, guard...)
+	e.PUT("/users/:id/role", h.AssignRole, guard...)
+}
@@ -0,0 +1,66 @@
+package rbac
+
+import (
+	"time"
+)
+
+// Permissions checked by the API. The wildcard grants every permission. The
+// default roles and their permissions are seeded by the rbac migration.
+const (
+	PermissionAll              = "*"
+	PermissionProductsWrite    = "products:write"
+	PermissionOrdersRead       = "orders:read"
+	PermissionOrdersWrite      = "orders:write"
+	PermissionPaymentsRead     = "payments:read"
+	PermissionPaymentsWrite    = "payments:write"
+	PermissionRolesManage      = "roles:manage"
+	PermissionEmailsManage     = "emails:manage"
+	PermissionReviewsModerate  = "reviews:moderate"
+	PermissionInventoryManage  = "inventory:manage"
+	PermissionPromotionsManage = "promotions:manage"
+	PermissionTaxesManage      = "taxes:manage"
+	PermissionShippingManage   = "shipping:manage"
+)
+
+// Default role names. RoleAdmin and RoleUser match the values historically
+// stored in user.User.Role.
+const (
+	RoleAdmin          = "admin"
+	RoleUser           = "user"
+	RoleCatalogManager = "catalog-manager"
+	RoleFulfilment     = "fulfilment"
+	RoleSupport        = "support"
+	RoleFinance        = "finance"
+)
+
+type Permission struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Name        string    `gorm:"size:100;not null;uniqueIndex" json:"name"`
+	Description string    `gorm:"type:text" json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type Role struct {
+	ID          uint         `gorm:"primaryKey" json:"id"`
+	Name        string       `gorm:"size:100;not null;uniqueIndex" json:"name"`
+	Description string       `gorm:"type:text" json:"description"`
+	System      bool         `gorm:"not null;default:false" json:"system"`
+	Permissions []Permission `gorm:"many2many:role_permissions" json:"permissions"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+type CreateRoleRequest struct {
+	Name        string   `json:"name" validate:"required,max=100"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions" validate:"dive,required"`
+}
+
+type UpdateRoleRequest struct {
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions" validate:"dive,required"`
+}
+
+type AssignRoleRequest struct {
+	Role string `json:"role" validate:"required"`
+}
@@ -0,0 +1,283 @@
+package review
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/nneji123/ecommerce-golang/internal/common/models"
+	"github.com/nneji123/ecommerce-golang/internal/middleware"
+	"go.uber.org/zap"
+)
+
+type Handler struct {
+	repo      *Repository
+	validator *validator.Validate
+	logger    *zap.Logger
+}
+
+func NewHandler(repo *Repository, validator *validator.Validate, logger *zap.Logger) *Handler {
+	return &Handler{
+		repo:      repo,
+		validator: validator,
+		logger:    logger,
+	}
+}
+
+// @Summary		List product reviews
+// @Description	Get a paginated list of a product's approved reviews with a summary of its ratings
+// @Tags			reviews
+// @Produce		json
+// @Param			id			path		int		true	"Product ID"
+// @Param			page		query		int		false	"Page number"
+// @Param			limit		query		int		false	"Items per page"
+// @Param			sort_by		query		string	false	"Sort by field (created_at, rating)"
+// @Param			sort_dir	query		string	false	"Sort direction (asc, desc)"
+// @Param			rating		query		int		false	"Only reviews with this star rating"
+// @Success		200			{object}	middleware.PaginatedResponse
+// @Router			/products/{id}/reviews [get]
+func (h *Handler) ListForProduct(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
+	}
+
+	query, err := h.bindListQuery(c)
+	if err != nil {
+		return err
+	}
+
+	reviews, page, err := h.repo.List(uint(id), query)
+	if err != nil {
+		h.logger.Error("Failed to list reviews", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list reviews")
+	}
+	summary, err := h.repo.Summary(uint(id))
+	if err != nil {
+		h.logger.Error("Failed to summarize reviews", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list reviews")
+	}
+
+	middleware.SetLinkHeader(c, page)
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"reviews":    reviews,
+		"summary":    summary,
+		"pagination": page,
+	})
+}
+
+// @Summary		Review product
+// @Description	Post a review of a product. Each user reviews a product once; the review is published once approved by a moderator and is marked as a verified purchase when the user has a delivered order containing the product.
+// @Tags			reviews
+// @Accept			json
+// @Produce		json
+// @Param			id		path		int				true	"Product ID"
+// @Param			review	body		ReviewRequest	true	"Review"
+// @Success		201		{object}	Review
+// @Failure		400		{object}	middleware.ErrorResponse
+// @Failure		404		{object}	middleware.ErrorResponse
+// @Failure		409		{object}	middleware.ErrorResponse
+// @Router			/products/{id}/reviews [post]
+func (h *Handler) Create(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
+	}
+
+	var req ReviewRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := h.validator.Struct(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	claims := c.Get("userClaims").(*models.Claims)
+	review := &Review{
+		ProductID: uint(id),
+		UserID:    claims.UserID,
+		Rating:    req.Rating,
+		Title:     req.Title,
+		Body:      req.Body,
+	}
+	if err := h.repo.Create(review); err != nil {
+		return h.reviewError(err, "Failed to create review")
+	}
+
+	return c.JSON(http.StatusCreated, review)
+}
+
+// @Summary		Update review
+// @Description	Edit your own review. The edited review is withdrawn until a moderator approves it again.
+// @Tags			reviews
+// @Accept			json
+// @Produce		json
+// @Param			id		path		int				true	"Review ID"
+// @Param			review	body		ReviewRequest	true	"Review"
+// @Success		200		{object}	Review
+// @Failure		400		{object}	middleware.ErrorResponse
+// @Failure		404		{object}	middleware.ErrorResponse
+// @Router			/reviews/{id} [put]
+func (h *Handler) Update(c echo.Context) error {
+	review, err := h.ownReview(c)
+	if err != nil {
+		return err
+	}
+
+	var req ReviewRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := h.validator.Struct(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.repo.Update(review, req); err != nil {
+		return h.reviewError(err, "Failed to update review")
+	}
+
+	return c.JSON(http.StatusOK, review)
+}
+
+// @Summary		Delete review
+// @Description	Delete your own review
+// @Tags			reviews
+// @Param			id	path	int	true	"Review ID"
+// @Success		204	"No Content"
+// @Failure		404	{object}	middleware.ErrorResponse
+// @Router			/reviews/{id} [delete]
+func (h *Handler) Delete(c echo.Context) error {
+	review, err := h.ownReview(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.repo.Delete(review); err != nil {
+		return h.reviewError(err, "Failed to delete review")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// @Summary		List reviews for moderation
+// @Description	Get a paginated list of reviews of every product, optionally by status (requires reviews:moderate)
+// @Tags			reviews
+// @Produce		json
+// @Param			status		query		string	false	"Review status (pending, approved, rejected, hidden)"
+// @Param			page		query		int		false	"Page number"
+// @Param			limit		query		int		false	"Items per page"
+// @Param			sort_by		query		string	false	"Sort by field (created_at, rating)"
+// @Param			sort_dir	query		string	false	"Sort direction (asc, desc)"
+// @Param			rating		query		int		false	"Only reviews with this star rating"
+// @Success		200			{object}	middleware.PaginatedResponse
+// @Router			/reviews [get]
+func (h *Handler) List(c echo.Context) error {
+	query, err := h.bindListQuery(c)
+	if err != nil {
+		return err
+	}
+	switch query.Status {
+	case "", StatusPending, StatusApproved, StatusRejected, StatusHidden:
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid status")
+	}
+
+	reviews, page, err := h.repo.List(0, query)
+	if err != nil {
+		h.logger.Error("Failed to list reviews", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list reviews")
+	}
+
+	middleware.SetLinkHeader(c, page)
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"reviews":    reviews,
+		"pagination": page,
+	})
+}
+
+// @Summary		Moderate review
+// @Description	Approve, reject or hide a review (requires reviews:moderate). Only approved reviews are published and counted in the product's rating.
+// @Tags			reviews
+// @Accept			json
+// @Produce		json
+// @Param			id		path		int				true	"Review ID"
+// @Param			request	body		ModerateRequest	true	"Moderation decision"
+// @Success		200		{object}	Review
+// @Failure		400		{object}	middleware.ErrorResponse
+// @Failure		404		{object}	middleware.ErrorResponse
+// @Router			/reviews/{id}/moderate [post]
+func (h *Handler) Moderate(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid review ID")
+	}
+
+	var req ModerateRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := h.validator.Struct(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	review, err := h.repo.GetByID(uint(id))
+	if err != nil {
+		return h.reviewError(err, "Failed to moderate review")
+	}
+
+	claims := c.Get("userClaims").(*models.Claims)
+	if err := h.repo.Moderate(review, req.Status, req.Note, claims.UserID); err != nil {
+		return h.reviewError(err, "Failed to moderate review")
+	}
+
+	return c.JSON(http.StatusOK, review)
+}
+
+func (h *Handler) bindListQuery(c echo.Context) (ListQuery, error) {
+	var query ListQuery
+	if err := c.Bind(&query); err != nil {
+		return query, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	query.Normalize(10, 100, "DESC")
+	if query.SortBy != "" && !sortColumns[query.SortBy] {
+		return query, echo.NewHTTPError(http.StatusBadRequest, "Invalid sort column")
+	}
+	if query.Rating < 0 || query.Rating > 5 {
+		return query, echo.NewHTTPError(http.StatusBadRequest, "Invalid rating")
+	}
+	return query, nil
+}
+
+// ownReview loads the review named by the id path parameter if it belongs
+// to the current user. Other users' reviews are reported as not found.
+func (h *Handler) ownReview(c echo.Context) (*Review, error) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid review ID")
+	}
+
+	review, err := h.repo.GetByID(uint(id))
+	if err != nil {
+		return nil, h.reviewError(err, "Failed to get review")
+	}
+	claims := c.Get("userClaims").(*models.Claims)
+	if review.UserID != claims.UserID {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "Review not found")
+	}
+	return review, nil
+}
+
+func (h *Handler) reviewError(err error, message string) error {
+	switch {
+	case errors.Is(err, ErrReviewNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Review not found")
+	case errors.Is(err, ErrProductNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Product not found")
+	case errors.Is(err, ErrAlreadyReviewed):
+		return echo.NewHTTPError(http.StatusConflict, "You have already reviewed this product")
+	}
+	h.logger.Error(message, zap.Error(err))
+	return echo.NewHTTPError(http.StatusInternalServerError, message)
+}
@@ -0,0 +1,278 @@
+package review
+
+import (
+	"errors"
+	"time"
+
+	"github.com/nneji123/ecommerce-golang/internal/domain/order"
+	"github.com/nneji123/ecommerce-golang/internal/domain/product"
+	"github.com/nneji123/ecommerce-golang/internal/middleware"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrReviewNotFound  = errors.New("review not found")
+	ErrProductNotFound = errors.New("product not found")
+	ErrAlreadyReviewed = errors.New("product already reviewed")
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// withAuthor selects reviews together with their author's name.
+func withAuthor(db *gorm.DB) *gorm.DB {
+	return db.Select("reviews.*, users.name AS author_name").
+		Joins("LEFT JOIN users ON users.id = reviews.user_id")
+}
+
+// sortColumns are the columns reviews can be sorted by.
+var sortColumns = map[string]bool{
+	"created_at": true, "rating": true,
+}
+
+// List returns a page of reviews. With productID set only that product's
+// approved reviews are listed; otherwise reviews of every product are
+// listed, filtered by query.Status.
+func (r *Repository) List(productID uint, query ListQuery) ([]Review, middleware.PaginatedResponse, error) {
+	reviews := []Review{}
+	db := r.db.Model(&Review{})
+	if productID > 0 {
+		db = db.Where("reviews.product_id = ? AND reviews.status = ?", productID, StatusApproved)
+	} else if query.Status != "" {
+		db = db.Where("reviews.status = ?", query.Status)
+	}
+	if query.Rating > 0 {
+		db = db.Where("reviews.rating = ?", query.Rating)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, middleware.PaginatedResponse{}, err
+	}
+
+	sortBy := query.SortBy
+	if !sortColumns[sortBy] {
+		sortBy = "created_at"
+	}
+	_, order := middleware.Keyset("reviews."+sortBy, "reviews.id", query.SortDir, nil)
+	err := db.Scopes(withAuthor).
+		Order(order).
+		Offset((query.Page - 1) * query.Limit).
+		Limit(query.Limit).
+		Find(&reviews).Error
+	if err != nil {
+		return nil, middleware.PaginatedResponse{}, err
+	}
+	return reviews, middleware.OffsetPage(query.Page, query.Limit, total), nil
+}
+
+// Summary returns the rating distribution of a product's approved reviews.
+func (r *Repository) Summary(productID uint) (*Summary, error) {
+	var rows []struct {
+		Rating int
+		Count  int
+	}
+	err := r.db.Model(&Review{}).
+		Select("rating, COUNT(*) AS count").
+		Where("product_id = ? AND status = ?", productID, StatusApproved).
+		Group("rating").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &Summary{Distribution: map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0}}
+	sum := 0
+	for _, row := range rows {
+		summary.Distribution[row.Rating] = row.Count
+		summary.Count += int64(row.Count)
+		sum += row.Rating * row.Count
+	}
+	if summary.Count > 0 {
+		average := float64(sum) / float64(summary.Count)
+		summary.Average = &average
+	}
+	return summary, nil
+}
+
+func (r *Repository) GetByID(id uint) (*Review, error) {
+	var review Review
+	if err := r.db.Scopes(withAuthor).First(&review, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrReviewNotFound
+		}
+		return nil, err
+	}
+	return &review, nil
+}
+
+// Create adds a pending review. A user reviews each product at most once.
+func (r *Repository) Create(review *Review) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var products int64
+		if err := tx.Model(&product.Product{}).Where("id = ?", review.ProductID).Count(&products).Error; err != nil {
+			return err
+		}
+		if products == 0 {
+			return ErrProductNotFound
+		}
+
+		var existing int64
+		if err := tx.Model(&Review{}).
+			Where("product_id = ? AND user_id = ?", review.ProductID, review.UserID).
+			Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return ErrAlreadyReviewed
+		}
+
+		verified, err := verifiedPurchase(tx, review.UserID, review.ProductID)
+		if err != nil {
+			return err
+		}
+		review.VerifiedPurchase = verified
+		review.Status = StatusPending
+
+		return tx.Create(review).Error
+	})
+}
+
+// Update replaces the rating and text of a review. The edited review goes
+// back to moderation, so it leaves the product's rating until approved
+// again.
+func (r *Repository) Update(review *Review, req ReviewRequest) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		locked, err := lockReview(tx, review.ID)
+		if err != nil {
+			return err
+		}
+
+		verified, err := verifiedPurchase(tx, locked.UserID, locked.ProductID)
+		if err != nil {
+			return err
+		}
+
+		updated := *locked
+		updated.Rating = req.Rating
+		updated.Title = req.Title
+		updated.Body = req.Body
+		updated.Status = StatusPending
+		updated.VerifiedPurchase = verified
+		updated.ModerationNote = ""
+		updated.ModeratedBy = nil
+		updated.ModeratedAt = nil
+		if err := adjustRating(tx, locked, &updated); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&updated).Updates(map[string]interface{}{
+			"rating":            updated.Rating,
+			"title":             updated.Title,
+			"body":              updated.Body,
+			"status":            updated.Status,
+			"verified_purchase": updated.VerifiedPurchase,
+			"moderation_note":   "",
+			"moderated_by":      nil,
+			"moderated_at":      nil,
+		}).Error; err != nil {
+			return err
+		}
+		updated.AuthorName = review.AuthorName
+		*review = updated
+		return nil
+	})
+}
+
+// Moderate sets the status of a review, adding it to or removing it from
+// its product's rating.
+func (r *Repository) Moderate(review *Review, status Status, note string, moderatorID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		locked, err := lockReview(tx, review.ID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		updated := *locked
+		updated.Status = status
+		updated.ModerationNote = note
+		updated.ModeratedBy = &moderatorID
+		updated.ModeratedAt = &now
+		if err := adjustRating(tx, locked, &updated); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&updated).Updates(map[string]interface{}{
+			"status":          status,
+			"moderation_note": note,
+			"moderated_by":    moderatorID,
+			"moderated_at":    now,
+		}).Error; err != nil {
+			return err
+		}
+		updated.AuthorName = review.AuthorName
+		*review = updated
+		return nil
+	})
+}
+
+func (r *Repository) Delete(review *Review) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		locked, err := lockReview(tx, review.ID)
+		if err != nil {
+			return err
+		}
+
+		// A deleted review contributes nothing to the rating.
+		if err := adjustRating(tx, locked, &Review{ProductID: locked.ProductID}); err != nil {
+			return err
+		}
+		return tx.Delete(locked).Error
+	})
+}
+
+func lockReview(tx *gorm.DB, id uint) (*Review, error) {
+	var review Review
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&review, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrReviewNotFound
+		}
+		return nil, err
+	}
+	return &review, nil
+}
+
+// adjustRating applies the change from before to after to the rating sum
+// and review count of the product.
+func adjustRating(tx *gorm.DB, before, after *Review) error {
+	oldSum, oldCount := before.contribution()
+	newSum, newCount := after.contribution()
+	if oldSum == newSum && oldCount == newCount {
+		return nil
+	}
+	return tx.Model(&product.Product{}).
+		Where("id = ?", before.ProductID).
+		UpdateColumns(map[string]interface{}{
+			"rating_sum":   gorm.Expr("rating_sum + ?", newSum-oldSum),
+			"review_count": gorm.Expr("review_count + ?", newCount-oldCount),
+		}).Error
+}
+
+// verifiedPurchase reports whether the user has a delivered order
+// containing the product.
+func verifiedPurchase(tx *gorm.DB, userID, productID uint) (bool, error) {
+	var count int64
+	err := tx.Model(&order.Order{}).
+		Joins("JOIN order_items ON order_items.order_id = orders.id").
+		Where("orders.user_id = ? AND orders.status = ? AND order_items.product_id = ?",
+			userID, order.StatusDelivered, productID).
+		Count(&count).Error
+	return count > 0, err
+}
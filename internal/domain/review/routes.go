@@ -0,0 +1,31 @@
+package review
+
+import (
+	"log"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nneji123/ecommerce-golang/internal/common/revocation"
+	"github.com/nneji123/ecommerce-golang/internal/config"
+	"github.com/nneji123/ecommerce-golang/internal/domain/rbac"
+	"github.com/nneji123/ecommerce-golang/internal/middleware"
+)
+
+func RegisterRoutes(e *echo.Echo, h *Handler, revocations revocation.Store, permissions middleware.PermissionResolver) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Error loading configuration: %s", err)
+	}
+
+	productReviews := e.Group("/products/:id/reviews", middleware.AuthMiddleware(cfg.JWTSecret, revocations))
+	productReviews.GET("", h.ListForProduct)
+	productReviews.POST("", h.Create)
+
+	reviews := e.Group("/reviews", middleware.AuthMiddleware(cfg.JWTSecret, revocations))
+	reviews.PUT("/:id", h.Update)
+	reviews.DELETE("/:id", h.Delete)
+
+	// Moderation routes
+	moderation := reviews.Group("", middleware.RequirePermission(permissions, rbac.PermissionReviewsModerate))
+	moderation.GET("", h.List)
+	moderation.POST("/:id/moderate", h.Moderate)
+}
@@ -0,0 +1,75 @@
+package review
+
+import (
+	"time"
+
+	"github.com/nneji123/ecommerce-golang/internal/middleware"
+)
+
+type Status string
+
+const (
+	StatusPending  Status = "pending"
+	StatusApproved Status = "approved"
+	StatusRejected Status = "rejected"
+	StatusHidden   Status = "hidden"
+)
+
+// Review is a user's rating of a product. Only approved reviews are shown
+// to shoppers and counted in the product's rating. VerifiedPurchase is set
+// when the author had a delivered order containing the product at the time
+// the review was written or last edited.
+type Review struct {
+	ID               uint       `gorm:"primaryKey" json:"id"`
+	ProductID        uint       `gorm:"not null;uniqueIndex:idx_reviews_product_user" json:"product_id"`
+	UserID           uint       `gorm:"not null;uniqueIndex:idx_reviews_product_user" json:"user_id"`
+	Rating           int        `gorm:"not null" json:"rating"`
+	Title            string     `gorm:"size:255;not null" json:"title"`
+	Body             string     `gorm:"type:text" json:"body"`
+	Status           Status     `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
+	VerifiedPurchase bool       `gorm:"not null;default:false" json:"verified_purchase"`
+	ModerationNote   string     `gorm:"type:text" json:"moderation_note,omitempty"`
+	ModeratedBy      *uint      `json:"moderated_by,omitempty"`
+	ModeratedAt      *time.Time `json:"moderated_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+
+	// AuthorName is the author's display name, read with the review.
+	AuthorName string `gorm:"->;-:migration" json:"author_name"`
+}
+
+// contribution returns what the review adds to its product's rating sum and
+// review count.
+func (r *Review) contribution() (sum, count int) {
+	if r.Status != StatusApproved {
+		return 0, 0
+	}
+	return r.Rating, 1
+}
+
+// Summary describes the approved reviews of a product. Distribution maps
+// each star rating to its number of reviews.
+type Summary struct {
+	Average      *float64    `json:"average"`
+	Count        int64       `json:"count"`
+	Distribution map[int]int `json:"distribution"`
+}
+
+type ReviewRequest struct {
+	Rating int    `json:"rating" validate:"required,min=1,max=5"`
+	Title  string `json:"title" validate:"required,max=255"`
+	Body   string `json:"body" validate:"max=5000"`
+}
+
+type ModerateRequest struct {
+	Status Status `json:"status" validate:"required,oneof=approved rejected hidden"`
+	Note   string `json:"note"`
+}
+
+// ListQuery selects a page of reviews, optionally with one star rating or,
+// for moderators, one status. Reviews are paged by number only.
+type ListQuery struct {
+	middleware.PaginationQuery
+	Rating int    `query:"rating"`
+	Status Status `query:"status"`
+}
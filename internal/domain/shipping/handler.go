@@ -0,0 +1,291 @@
+package shipping
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type Handler struct {
+	repo      *Repository
+	providers Providers
+	validator *validator.Validate
+	logger    *zap.Logger
+}
+
+func NewHandler(repo *Repository, providers Providers, validator *validator.Validate, logger *zap.Logger) *Handler {
+	return &Handler{
+		repo:      repo,
+		providers: providers,
+		validator: validator,
+		logger:    logger,
+	}
+}
+
+// @Summary		List shipping zones
+// @Description	List every shipping zone with the countries, regions and postcode patterns it covers (requires shipping:manage)
+// @Tags			shipping
+// @Produce		json
+// @Success		200	{array}	Zone
+// @Router			/shipping/zones [get]
+func (h *Handler) ListZones(c echo.Context) error {
+	zones, err := h.repo.Zones()
+	if err != nil {
+		h.logger.Error("Failed to list shipping zones", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list shipping zones")
+	}
+	return c.JSON(http.StatusOK, zones)
+}
+
+// @Summary		Get shipping zone
+// @Description	Get a shipping zone with its locations (requires shipping:manage)
+// @Tags			shipping
+// @Produce		json
+// @Param			id	path		int	true	"Zone ID"
+// @Success		200	{object}	Zone
+// @Failure		404	{object}	middleware.ErrorResponse
+// @Router			/shipping/zones/{id} [get]
+func (h *Handler) GetZone(c echo.Context) error {
+	id, err := parseID(c, "Invalid zone ID")
+	if err != nil {
+		return err
+	}
+	zone, err := h.repo.GetZone(id)
+	if err != nil {
+		return h.shippingError(err, "Failed to get shipping zone")
+	}
+	return c.JSON(http.StatusOK, zone)
+}
+
+// @Summary		Create shipping zone
+// @Description	Create a shipping zone (requires shipping:manage). A destination is in the zone with its most specific matching location; priority breaks ties. Postcode patterns may use * and ?.
+// @Tags			shipping
+// @Accept			json
+// @Produce		json
+// @Param			zone	body		ZoneRequest	true	"Zone"
+// @Success		201		{object}	Zone
+// @Failure		400		{object}	middleware.ErrorResponse
+// @Router			/shipping/zones [post]
+func (h *Handler) CreateZone(c echo.Context) error {
+	zone, err := h.bindZone(c)
+	if err != nil {
+		return err
+	}
+	if err := h.repo.SaveZone(zone); err != nil {
+		return h.shippingError(err, "Failed to create shipping zone")
+	}
+	return c.JSON(http.StatusCreated, zone)
+}
+
+// @Summary		Update shipping zone
+// @Description	Replace the name, priority and locations of a shipping zone (requires shipping:manage)
+// @Tags			shipping
+// @Accept			json
+// @Produce		json
+// @Param			id		path		int			true	"Zone ID"
+// @Param			zone	body		ZoneRequest	true	"Zone"
+// @Success		200		{object}	Zone
+// @Failure		400		{object}	middleware.ErrorResponse
+// @Failure		404		{object}	middleware.ErrorResponse
+// @Router			/shipping/zones/{id} [put]
+func (h *Handler) UpdateZone(c echo.Context) error {
+	id, err := parseID(c, "Invalid zone ID")
+	if err != nil {
+		return err
+	}
+	zone, err := h.bindZone(c)
+	if err != nil {
+		return err
+	}
+	zone.ID = id
+	if err := h.repo.SaveZone(zone); err != nil {
+		return h.shippingError(err, "Failed to update shipping zone")
+	}
+	return c.JSON(http.StatusOK, zone)
+}
+
+// @Summary		Delete shipping zone
+// @Description	Delete a shipping zone that has no methods (requires shipping:manage)
+// @Tags			shipping
+// @Param			id	path	int	true	"Zone ID"
+// @Success		204	"No Content"
+// @Failure		404	{object}	middleware.ErrorResponse
+// @Failure		409	{object}	middleware.ErrorResponse
+// @Router			/shipping/zones/{id} [delete]
+func (h *Handler) DeleteZone(c echo.Context) error {
+	id, err := parseID(c, "Invalid zone ID")
+	if err != nil {
+		return err
+	}
+	if err := h.repo.DeleteZone(id); err != nil {
+		return h.shippingError(err, "Failed to delete shipping zone")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// @Summary		List shipping methods
+// @Description	List every shipping method, active or not, in position order (requires shipping:manage)
+// @Tags			shipping
+// @Produce		json
+// @Success		200	{array}	Method
+// @Router			/shipping/methods [get]
+func (h *Handler) ListMethods(c echo.Context) error {
+	methods, err := h.repo.Methods()
+	if err != nil {
+		h.logger.Error("Failed to list shipping methods", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list shipping methods")
+	}
+	return c.JSON(http.StatusOK, methods)
+}
+
+// @Summary		Get shipping method
+// @Description	Get a shipping method with its weight rates (requires shipping:manage)
+// @Tags			shipping
+// @Produce		json
+// @Param			id	path		int	true	"Method ID"
+// @Success		200	{object}	Method
+// @Failure		404	{object}	middleware.ErrorResponse
+// @Router			/shipping/methods/{id} [get]
+func (h *Handler) GetMethod(c echo.Context) error {
+	id, err := parseID(c, "Invalid method ID")
+	if err != nil {
+		return err
+	}
+	method, err := h.repo.GetMethod(id)
+	if err != nil {
+		return h.shippingError(err, "Failed to get shipping method")
+	}
+	return c.JSON(http.StatusOK, method)
+}
+
+// @Summary		Create shipping method
+// @Description	Create a shipping method (requires shipping:manage). flat_rate methods need a price, weight_based ones weight rates, free_over_threshold ones a threshold and local_pickup ones a pickup location. Methods without a zone are offered everywhere.
+// @Tags			shipping
+// @Accept			json
+// @Produce		json
+// @Param			method	body		MethodRequest	true	"Method"
+// @Success		201		{object}	Method
+// @Failure		400		{object}	middleware.ErrorResponse
+// @Router			/shipping/methods [post]
+func (h *Handler) CreateMethod(c echo.Context) error {
+	method, err := h.bindMethod(c)
+	if err != nil {
+		return err
+	}
+	if err := h.repo.SaveMethod(method); err != nil {
+		return h.shippingError(err, "Failed to create shipping method")
+	}
+	return c.JSON(http.StatusCreated, method)
+}
+
+// @Summary		Update shipping method
+// @Description	Replace a shipping method and its weight rates (requires shipping:manage). Orders already placed keep their shipping cost.
+// @Tags			shipping
+// @Accept			json
+// @Produce		json
+// @Param			id		path		int				true	"Method ID"
+// @Param			method	body		MethodRequest	true	"Method"
+// @Success		200		{object}	Method
+// @Failure		400		{object}	middleware.ErrorResponse
+// @Failure		404		{object}	middleware.ErrorResponse
+// @Router			/shipping/methods/{id} [put]
+func (h *Handler) UpdateMethod(c echo.Context) error {
+	id, err := parseID(c, "Invalid method ID")
+	if err != nil {
+		return err
+	}
+	method, err := h.bindMethod(c)
+	if err != nil {
+		return err
+	}
+	method.ID = id
+	if err := h.repo.SaveMethod(method); err != nil {
+		return h.shippingError(err, "Failed to update shipping method")
+	}
+	if method, err = h.repo.GetMethod(id); err != nil {
+		return h.shippingError(err, "Failed to update shipping method")
+	}
+	return c.JSON(http.StatusOK, method)
+}
+
+// @Summary		Delete shipping method
+// @Description	Delete a shipping method (requires shipping:manage). Orders shipped with it keep its name and cost.
+// @Tags			shipping
+// @Param			id	path	int	true	"Method ID"
+// @Success		204	"No Content"
+// @Failure		404	{object}	middleware.ErrorResponse
+// @Router			/shipping/methods/{id} [delete]
+func (h *Handler) DeleteMethod(c echo.Context) error {
+	id, err := parseID(c, "Invalid method ID")
+	if err != nil {
+		return err
+	}
+	if err := h.repo.DeleteMethod(id); err != nil {
+		return h.shippingError(err, "Failed to delete shipping method")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Handler) bindZone(c echo.Context) (*Zone, error) {
+	var req ZoneRequest
+	if err := c.Bind(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := h.validator.Struct(req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	zone := &Zone{Name: req.Name, Priority: req.Priority}
+	for _, location := range req.Locations {
+		zone.Locations = append(zone.Locations, ZoneLocation{
+			Country:  location.Country,
+			Region:   location.Region,
+			Postcode: location.Postcode,
+		})
+	}
+	return zone, nil
+}
+
+func (h *Handler) bindMethod(c echo.Context) (*Method, error) {
+	var req MethodRequest
+	if err := c.Bind(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := h.validator.Struct(req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	method, err := req.Method()
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if _, err := h.providers.Get(method.Provider); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Unknown shipping rate provider")
+	}
+	return method, nil
+}
+
+func parseID(c echo.Context, message string) (uint, error) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, message)
+	}
+	return uint(id), nil
+}
+
+func (h *Handler) shippingError(err error, message string) error {
+	switch {
+	case errors.Is(err, ErrZoneNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Shipping zone not found")
+	case errors.Is(err, ErrMethodNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Shipping method not found")
+	case errors.Is(err, ErrInvalidZone), errors.Is(err, ErrInvalidMethod):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrZoneInUse):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	h.logger.Error(message, zap.Error(err))
+	return echo.NewHTTPError(http.StatusInternalServerError, message)
+}
@@ -0,0 +1,90 @@
+package shipping
+
+import (
+	"context"
+	"errors"
+
+	"github.com/nneji123/ecommerce-golang/internal/common/money"
+)
+
+var ErrUnknownProvider = errors.New("unknown shipping rate provider")
+
+// ShippingRateProvider prices shipping methods. The local provider prices a
+// method from its own settings; carrier integrations implement this
+// interface to price methods whose Provider names them with the carrier's
+// live rates for Method.Service.
+type ShippingRateProvider interface {
+	Name() string
+	// Rate returns the price of shipping parcel with method, and false when
+	// the method cannot carry the parcel.
+	Rate(ctx context.Context, method Method, parcel Parcel) (money.Money, bool, error)
+}
+
+// Providers holds the configured rate providers keyed by name.
+type Providers map[string]ShippingRateProvider
+
+func NewProviders(providers ...ShippingRateProvider) Providers {
+	registry := make(Providers, len(providers))
+	for _, p := range providers {
+		registry[p.Name()] = p
+	}
+	return registry
+}
+
+func (p Providers) Get(name string) (ShippingRateProvider, error) {
+	provider, ok := p[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return provider, nil
+}
+
+// LocalProvider prices methods from their type, price, threshold and weight
+// rates, without calling a carrier.
+type LocalProvider struct{}
+
+func NewLocalProvider() *LocalProvider {
+	return &LocalProvider{}
+}
+
+func (p *LocalProvider) Name() string {
+	return LocalProviderName
+}
+
+func (p *LocalProvider) Rate(_ context.Context, method Method, parcel Parcel) (money.Money, bool, error) {
+	currency := parcel.Subtotal.Currency
+	if c := method.Currency(); c != "" && c != currency {
+		return money.Money{}, false, nil
+	}
+
+	switch method.Type {
+	case TypeFlatRate:
+		if method.Price == nil {
+			return money.Money{}, false, nil
+		}
+		return *method.Price, true, nil
+	case TypeWeightBased:
+		weight := parcel.WeightGrams()
+		for _, rate := range method.WeightRates {
+			if weight <= rate.MaxWeightGrams {
+				return rate.Price, true, nil
+			}
+		}
+		return money.Money{}, false, nil
+	case TypeFreeOverThreshold:
+		if method.Threshold == nil {
+			return money.Money{}, false, nil
+		}
+		reached, err := parcel.Subtotal.Cmp(*method.Threshold)
+		if err != nil || reached < 0 {
+			return money.Money{}, false, nil
+		}
+		return money.Zero(currency), true, nil
+	case TypeLocalPickup:
+		if method.Price != nil {
+			return *method.Price, true, nil
+		}
+		return money.Zero(currency), true, nil
+	}
+	return money.Money{}, false, nil
+}
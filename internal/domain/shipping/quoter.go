@@ -0,0 +1,101 @@
+package shipping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var ErrMethodUnavailable = errors.New("shipping method is not available for this order")
+
+// Quoter prices the shipping methods available for a parcel.
+type Quoter struct {
+	repo      *Repository
+	providers Providers
+}
+
+func NewQuoter(repo *Repository, providers Providers) *Quoter {
+	return &Quoter{repo: repo, providers: providers}
+}
+
+// Quote returns the price of every active method that can ship parcel to its
+// destination, in method position order.
+func (q *Quoter) Quote(ctx context.Context, parcel Parcel) ([]Quote, error) {
+	methods, err := q.available(parcel)
+	if err != nil {
+		return nil, err
+	}
+	quotes := []Quote{}
+	for _, method := range methods {
+		quote, err := q.rate(ctx, method, parcel)
+		if err != nil {
+			return nil, err
+		}
+		if quote != nil {
+			quotes = append(quotes, *quote)
+		}
+	}
+	return quotes, nil
+}
+
+// QuoteMethod returns the price of shipping parcel with one method. It fails
+// with ErrMethodUnavailable when the method does not exist, is inactive, is
+// not offered at the destination or cannot carry the parcel.
+func (q *Quoter) QuoteMethod(ctx context.Context, methodID uint, parcel Parcel) (*Quote, error) {
+	methods, err := q.available(parcel)
+	if err != nil {
+		return nil, err
+	}
+	for _, method := range methods {
+		if method.ID != methodID {
+			continue
+		}
+		quote, err := q.rate(ctx, method, parcel)
+		if err != nil {
+			return nil, err
+		}
+		if quote == nil {
+			break
+		}
+		return quote, nil
+	}
+	return nil, fmt.Errorf("%w: method %d", ErrMethodUnavailable, methodID)
+}
+
+// available returns the active methods offered at the parcel's destination.
+func (q *Quoter) available(parcel Parcel) ([]Method, error) {
+	var zoneID *uint
+	if parcel.Destination.Country != "" {
+		zone, err := q.repo.Match(parcel.Destination)
+		if err != nil {
+			return nil, err
+		}
+		if zone != nil {
+			zoneID = &zone.ID
+		}
+	}
+	return q.repo.ActiveMethods(zoneID)
+}
+
+// rate prices one method, returning nil when it cannot carry the parcel.
+func (q *Quoter) rate(ctx context.Context, method Method, parcel Parcel) (*Quote, error) {
+	if method.MaxWeightGrams != nil && parcel.WeightGrams() > *method.MaxWeightGrams {
+		return nil, nil
+	}
+	provider, err := q.providers.Get(method.Provider)
+	if err != nil {
+		return nil, fmt.Errorf("method %d: %w %q", method.ID, err, method.Provider)
+	}
+	price, ok, err := provider.Rate(ctx, method, parcel)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &Quote{
+		MethodID:       method.ID,
+		Name:           method.Name,
+		Description:    method.Description,
+		Type:           method.Type,
+		PickupLocation: method.PickupLocation,
+		Price:          price,
+	}, nil
+}
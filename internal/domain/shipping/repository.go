@@ -0,0 +1,249 @@
+package shipping
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/nneji123/ecommerce-golang/internal/common/geo"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrZoneNotFound   = errors.New("shipping zone not found")
+	ErrMethodNotFound = errors.New("shipping method not found")
+	ErrInvalidZone    = errors.New("invalid shipping zone")
+	ErrInvalidMethod  = errors.New("invalid shipping method")
+	ErrZoneInUse      = errors.New("shipping zone still has methods")
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// Zones returns every zone with its locations, by name.
+func (r *Repository) Zones() ([]Zone, error) {
+	zones := []Zone{}
+	err := r.db.Preload("Locations", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Order("name, id").Find(&zones).Error
+	return zones, err
+}
+
+func (r *Repository) GetZone(id uint) (*Zone, error) {
+	var zone Zone
+	err := r.db.Preload("Locations", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).First(&zone, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrZoneNotFound
+		}
+		return nil, err
+	}
+	return &zone, nil
+}
+
+// SaveZone creates a zone, or replaces the name, priority and locations of
+// an existing one.
+func (r *Repository) SaveZone(zone *Zone) error {
+	for i, location := range zone.Locations {
+		area := geo.Location{Country: location.Country, Region: location.Region, Postcode: location.Postcode}
+		if !geo.ValidPattern(area) {
+			return fmt.Errorf("%w: bad postcode pattern %q", ErrInvalidZone, location.Postcode)
+		}
+		area = area.Normalize()
+		zone.Locations[i].Country = area.Country
+		zone.Locations[i].Region = area.Region
+		zone.Locations[i].Postcode = area.Postcode
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		locations := zone.Locations
+		if zone.ID == 0 {
+			if err := tx.Omit("Locations").Create(zone).Error; err != nil {
+				return err
+			}
+		} else {
+			result := tx.Model(zone).Updates(map[string]interface{}{
+				"name":     zone.Name,
+				"priority": zone.Priority,
+			})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrZoneNotFound
+			}
+			if err := tx.Where("zone_id = ?", zone.ID).Delete(&ZoneLocation{}).Error; err != nil {
+				return err
+			}
+		}
+		for i := range locations {
+			locations[i].ID = 0
+			locations[i].ZoneID = zone.ID
+		}
+		if err := tx.Create(&locations).Error; err != nil {
+			return err
+		}
+		zone.Locations = locations
+		return nil
+	})
+}
+
+// DeleteZone removes a zone that has no methods.
+func (r *Repository) DeleteZone(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var methods int64
+		if err := tx.Model(&Method{}).Where("zone_id = ?", id).Count(&methods).Error; err != nil {
+			return err
+		}
+		if methods > 0 {
+			return ErrZoneInUse
+		}
+		if err := tx.Where("zone_id = ?", id).Delete(&ZoneLocation{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&Zone{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrZoneNotFound
+		}
+		return nil
+	})
+}
+
+// Match returns the zone of a destination, chosen as tax zones are: the one
+// with the most specific matching location, then the highest priority. It
+// returns nil when the destination is in no zone.
+func (r *Repository) Match(destination geo.Location) (*Zone, error) {
+	destination = destination.Normalize()
+	var candidates []struct {
+		ZoneLocation
+		Priority int
+	}
+	err := r.db.Table("shipping_zone_locations zl").
+		Select("zl.*, z.priority").
+		Joins("JOIN shipping_zones z ON z.id = zl.zone_id").
+		Where("zl.country = ?", destination.Country).
+		Scan(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	best, bestScore, bestPriority := uint(0), 0, 0
+	for _, c := range candidates {
+		score := geo.Specificity(geo.Location{Country: c.Country, Region: c.Region, Postcode: c.Postcode}, destination)
+		if score == 0 {
+			continue
+		}
+		if score > bestScore ||
+			score == bestScore && (c.Priority > bestPriority || c.Priority == bestPriority && c.ZoneID < best) {
+			best, bestScore, bestPriority = c.ZoneID, score, c.Priority
+		}
+	}
+	if best == 0 {
+		return nil, nil
+	}
+	return r.GetZone(best)
+}
+
+func preloadWeightRates(db *gorm.DB) *gorm.DB {
+	return db.Order("max_weight_grams")
+}
+
+// Methods returns every method, active or not, in position order.
+func (r *Repository) Methods() ([]Method, error) {
+	methods := []Method{}
+	err := r.db.Preload("WeightRates", preloadWeightRates).Order("position, id").Find(&methods).Error
+	return methods, err
+}
+
+// ActiveMethods returns the active methods offered in a zone, or only those
+// offered everywhere when zoneID is nil, in position order.
+func (r *Repository) ActiveMethods(zoneID *uint) ([]Method, error) {
+	methods := []Method{}
+	db := r.db.Preload("WeightRates", preloadWeightRates).Where("active")
+	if zoneID != nil {
+		db = db.Where("zone_id IS NULL OR zone_id = ?", *zoneID)
+	} else {
+		db = db.Where("zone_id IS NULL")
+	}
+	err := db.Order("position, id").Find(&methods).Error
+	return methods, err
+}
+
+func (r *Repository) GetMethod(id uint) (*Method, error) {
+	var method Method
+	if err := r.db.Preload("WeightRates", preloadWeightRates).First(&method, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMethodNotFound
+		}
+		return nil, err
+	}
+	return &method, nil
+}
+
+// SaveMethod creates a method, or replaces an existing one and its weight
+// rates. Orders already placed keep the shipping cost they were charged.
+func (r *Repository) SaveMethod(method *Method) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if method.ZoneID != nil {
+			var zones int64
+			if err := tx.Model(&Zone{}).Where("id = ?", *method.ZoneID).Count(&zones).Error; err != nil {
+				return err
+			}
+			if zones == 0 {
+				return fmt.Errorf("%w: zone %d does not exist", ErrInvalidMethod, *method.ZoneID)
+			}
+		}
+
+		rates := method.WeightRates
+		if method.ID == 0 {
+			if err := tx.Omit(clause.Associations).Create(method).Error; err != nil {
+				return err
+			}
+		} else {
+			result := tx.Model(method).Omit(clause.Associations, "CreatedAt").Select("*").Updates(method)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrMethodNotFound
+			}
+			if err := tx.Where("method_id = ?", method.ID).Delete(&WeightRate{}).Error; err != nil {
+				return err
+			}
+		}
+		for i := range rates {
+			rates[i].ID = 0
+			rates[i].MethodID = method.ID
+		}
+		if len(rates) > 0 {
+			if err := tx.Create(&rates).Error; err != nil {
+				return err
+			}
+		}
+		method.WeightRates = rates
+		return nil
+	})
+}
+
+// DeleteMethod removes a method. Orders shipped with it keep its name and
+// cost.
+func (r *Repository) DeleteMethod(id uint) error {
+	result := r.db.Delete(&Method{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrMethodNotFound
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package shipping
+
+import (
+	"log"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nneji123/ecommerce-golang/internal/common/revocation"
+	"github.com/nneji123/ecommerce-golang/internal/config"
+	"github.com/nneji123/ecommerce-golang/internal/domain/rbac"
+	"github.com/nneji123/ecommerce-golang/internal/middleware"
+)
+
+func RegisterRoutes(e *echo.Echo, h *Handler, revocations revocation.Store, permissions middleware.PermissionResolver) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Error loading configuration: %s", err)
+	}
+
+	manage := e.Group("/shipping",
+		middleware.AuthMiddleware(cfg.JWTSecret, revocations),
+		middleware.RequirePermission(permissions, rbac.PermissionShippingManage),
+	)
+	manage.GET("/zones", h.ListZones)
+	manage.POST("/zones", h.CreateZone)
+	manage.GET("/zones/:id", h.GetZone)
+	manage.PUT("/zones/:id", h.UpdateZone)
+	manage.DELETE("/zones/:id", h.DeleteZone)
+
+	manage.GET("/methods", h.ListMethods)
+	manage.POST("/methods", h.CreateMethod)
+	manage.GET("/methods/:id", h.GetMethod)
+	manage.PUT("/methods/:id", h.UpdateMethod)
+	manage.DELETE("/methods/:id", h.DeleteMethod)
+}
@@ -0,0 +1,257 @@
+package shipping
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nneji123/ecommerce-golang/internal/common/geo"
+	"github.com/nneji123/ecommerce-golang/internal/common/money"
+)
+
+// Zone is an area shipped to alike: the places listed in its Locations. A
+// destination belongs to the zone with its most specific matching location;
+// Priority breaks ties between zones matching equally well.
+type Zone struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	Name      string         `gorm:"size:255;not null" json:"name"`
+	Priority  int            `gorm:"not null;default:0" json:"priority"`
+	Locations []ZoneLocation `json:"locations"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+func (Zone) TableName() string {
+	return "shipping_zones"
+}
+
+// ZoneLocation is a place covered by a zone: a country, optionally narrowed
+// to a region and to postcodes matching Postcode, a pattern in which *
+// matches any characters and ? a single one.
+type ZoneLocation struct {
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	ZoneID   uint   `gorm:"not null;index" json:"zone_id"`
+	Country  string `gorm:"size:2;not null" json:"country"`
+	Region   string `gorm:"size:100" json:"region,omitempty"`
+	Postcode string `gorm:"size:50" json:"postcode,omitempty"`
+}
+
+func (ZoneLocation) TableName() string {
+	return "shipping_zone_locations"
+}
+
+// Type is how a shipping method is priced.
+type Type string
+
+const (
+	// TypeFlatRate costs Price whatever is shipped.
+	TypeFlatRate Type = "flat_rate"
+	// TypeWeightBased costs the price of the lightest of WeightRates the
+	// parcel fits in; heavier parcels cannot use the method.
+	TypeWeightBased Type = "weight_based"
+	// TypeFreeOverThreshold is free, and only offered for orders whose
+	// subtotal reaches Threshold.
+	TypeFreeOverThreshold Type = "free_over_threshold"
+	// TypeLocalPickup is collected by the customer at PickupLocation. It
+	// costs Price, if set, and is otherwise free.
+	TypeLocalPickup Type = "local_pickup"
+)
+
+// LocalProviderName is the provider of methods priced from their own
+// settings rather than by a carrier.
+const LocalProviderName = "local"
+
+// Method is a way of shipping an order. Methods without a ZoneID are offered
+// at every destination, the others in their zone only; parcels heavier than
+// MaxWeightGrams, when set, cannot use the method. Methods are priced by the
+// rate provider named by Provider, with Service identifying the carrier
+// service for providers other than the local one.
+//
+// Thresholds are compared with the order subtotal before discounts. Every
+// amount of a method is in one currency and the method is only offered for
+// orders in that currency.
+type Method struct {
+	ID             uint         `gorm:"primaryKey" json:"id"`
+	ZoneID         *uint        `gorm:"index" json:"zone_id,omitempty"`
+	Name           string       `gorm:"size:255;not null" json:"name"`
+	Description    string       `gorm:"type:text" json:"description"`
+	Type           Type         `gorm:"type:varchar(30);not null" json:"type"`
+	Provider       string       `gorm:"size:50;not null;default:local" json:"provider"`
+	Service        string       `gorm:"size:100;not null" json:"service,omitempty"`
+	Price          *money.Money `gorm:"embedded;embeddedPrefix:price_" json:"price,omitempty"`
+	Threshold      *money.Money `gorm:"embedded;embeddedPrefix:threshold_" json:"threshold,omitempty"`
+	WeightRates    []WeightRate `gorm:"constraint:OnDelete:CASCADE" json:"weight_rates,omitempty"`
+	MaxWeightGrams *int         `json:"max_weight_grams,omitempty"`
+	PickupLocation string       `gorm:"type:text" json:"pickup_location,omitempty"`
+	Position       int          `gorm:"not null;default:0" json:"position"`
+	Active         bool         `gorm:"not null;default:true" json:"active"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
+
+func (Method) TableName() string {
+	return "shipping_methods"
+}
+
+// Currency returns the currency of the method's amounts, or "" for a free
+// method without any.
+func (m Method) Currency() string {
+	switch {
+	case m.Price != nil:
+		return m.Price.Currency
+	case m.Threshold != nil:
+		return m.Threshold.Currency
+	case len(m.WeightRates) > 0:
+		return m.WeightRates[0].Price.Currency
+	}
+	return ""
+}
+
+// WeightRate is a bracket of a weight-based method: parcels of up to
+// MaxWeightGrams cost Price.
+type WeightRate struct {
+	ID             uint        `gorm:"primaryKey" json:"id"`
+	MethodID       uint        `gorm:"not null;index" json:"method_id"`
+	MaxWeightGrams int         `gorm:"not null" json:"max_weight_grams"`
+	Price          money.Money `gorm:"embedded;embeddedPrefix:price_" json:"price"`
+}
+
+func (WeightRate) TableName() string {
+	return "shipping_weight_rates"
+}
+
+// Parcel is what an order ships: its items, where to and the subtotal of
+// the order. Weights are in grams and dimensions in millimetres; zero means
+// unknown.
+type Parcel struct {
+	Destination geo.Location
+	Subtotal    money.Money
+	Items       []Item
+}
+
+// Item is a quantity of one product in a parcel, with the weight and
+// dimensions of a single unit.
+type Item struct {
+	ProductID   uint
+	Quantity    int
+	WeightGrams int
+	LengthMM    int
+	WidthMM     int
+	HeightMM    int
+}
+
+// WeightGrams returns the weight of every unit in the parcel.
+func (p Parcel) WeightGrams() int {
+	weight := 0
+	for _, item := range p.Items {
+		weight += item.WeightGrams * item.Quantity
+	}
+	return weight
+}
+
+// Quote is the price of shipping a parcel with one method.
+type Quote struct {
+	MethodID       uint        `json:"method_id"`
+	Name           string      `json:"name"`
+	Description    string      `json:"description,omitempty"`
+	Type           Type        `json:"type"`
+	PickupLocation string      `json:"pickup_location,omitempty"`
+	Price          money.Money `json:"price"`
+}
+
+type ZoneLocationRequest struct {
+	Country  string `json:"country" validate:"required,iso3166_1_alpha2"`
+	Region   string `json:"region" validate:"max=100"`
+	Postcode string `json:"postcode" validate:"max=50"`
+}
+
+type ZoneRequest struct {
+	Name      string                `json:"name" validate:"required,max=255"`
+	Priority  int                   `json:"priority"`
+	Locations []ZoneLocationRequest `json:"locations" validate:"required,min=1,dive"`
+}
+
+type WeightRateRequest struct {
+	MaxWeightGrams int         `json:"max_weight_grams" validate:"gt=0"`
+	Price          money.Money `json:"price" validate:"gte=0"`
+}
+
+// MethodRequest creates or replaces a shipping method. Provider defaults to
+// the local provider and Active to true.
+type MethodRequest struct {
+	ZoneID         *uint               `json:"zone_id"`
+	Name           string              `json:"name" validate:"required,max=255"`
+	Description    string              `json:"description"`
+	Type           Type                `json:"type" validate:"required,oneof=flat_rate weight_based free_over_threshold local_pickup"`
+	Provider       string              `json:"provider" validate:"max=50"`
+	Service        string              `json:"service" validate:"max=100"`
+	Price          *money.Money        `json:"price" validate:"omitempty,gte=0"`
+	Threshold      *money.Money        `json:"threshold" validate:"omitempty,gt=0"`
+	WeightRates    []WeightRateRequest `json:"weight_rates" validate:"dive"`
+	MaxWeightGrams *int                `json:"max_weight_grams" validate:"omitempty,gt=0"`
+	PickupLocation string              `json:"pickup_location"`
+	Position       int                 `json:"position"`
+	Active         *bool               `json:"active"`
+}
+
+// Method builds the method described by the request, checking the fields
+// the chosen type requires and clearing those it does not use.
+func (req MethodRequest) Method() (*Method, error) {
+	m := &Method{
+		ZoneID:         req.ZoneID,
+		Name:           req.Name,
+		Description:    req.Description,
+		Type:           req.Type,
+		Provider:       strings.TrimSpace(req.Provider),
+		Service:        strings.TrimSpace(req.Service),
+		MaxWeightGrams: req.MaxWeightGrams,
+		Position:       req.Position,
+		Active:         req.Active == nil || *req.Active,
+	}
+	if m.Provider == "" {
+		m.Provider = LocalProviderName
+	}
+
+	switch req.Type {
+	case TypeFlatRate:
+		if req.Price == nil {
+			return nil, fmt.Errorf("%w: price is required", ErrInvalidMethod)
+		}
+		m.Price = req.Price
+	case TypeWeightBased:
+		if len(req.WeightRates) == 0 {
+			return nil, fmt.Errorf("%w: weight_rates are required", ErrInvalidMethod)
+		}
+		seen := make(map[int]bool)
+		for _, rate := range req.WeightRates {
+			if seen[rate.MaxWeightGrams] {
+				return nil, fmt.Errorf("%w: duplicate weight bracket %d", ErrInvalidMethod, rate.MaxWeightGrams)
+			}
+			seen[rate.MaxWeightGrams] = true
+			m.WeightRates = append(m.WeightRates, WeightRate{MaxWeightGrams: rate.MaxWeightGrams, Price: rate.Price})
+		}
+		sort.Slice(m.WeightRates, func(i, j int) bool {
+			return m.WeightRates[i].MaxWeightGrams < m.WeightRates[j].MaxWeightGrams
+		})
+	case TypeFreeOverThreshold:
+		if req.Threshold == nil {
+			return nil, fmt.Errorf("%w: threshold is required", ErrInvalidMethod)
+		}
+		m.Threshold = req.Threshold
+	case TypeLocalPickup:
+		m.Price = req.Price
+		m.PickupLocation = strings.TrimSpace(req.PickupLocation)
+		if m.PickupLocation == "" {
+			return nil, fmt.Errorf("%w: pickup_location is required", ErrInvalidMethod)
+		}
+	}
+
+	currency := m.Currency()
+	for _, rate := range m.WeightRates {
+		if rate.Price.Currency != currency {
+			return nil, fmt.Errorf("%w: every weight rate must be in the same currency", ErrInvalidMethod)
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,256 @@
+package tax
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/nneji123/ecommerce-golang/internal/common/geo"
+	"github.com/nneji123/ecommerce-golang/internal/common/money"
+)
+
+var ErrInvalidConfig = errors.New("invalid tax configuration")
+
+// Calculator computes the tax of an order. The local implementation uses the
+// zones, classes and rates stored in the database; an external tax service
+// can be used instead by implementing this interface.
+type Calculator interface {
+	Calculate(ctx context.Context, req Request) (*Result, error)
+}
+
+// Request describes an order to tax. Amount is the price of a line after
+// discounts; it includes tax when prices are tax inclusive.
+type Request struct {
+	Location geo.Location
+	At       time.Time
+	Currency string
+	Lines    []Line
+}
+
+// Line is an order line to tax. A nil ClassID is the default class.
+type Line struct {
+	ProductID uint
+	ClassID   *uint
+	Quantity  int
+	Amount    money.Money
+}
+
+// Result is the tax of an order. Lines holds the tax of each request line, in
+// order, and Taxes the tax levied at each rate over the whole order; both sum
+// to Total.
+type Result struct {
+	PricesIncludeTax bool
+	Lines            []money.Money
+	Taxes            []Tax
+	Total            money.Money
+}
+
+// Tax is the tax levied at one rate. Taxable is the amount it was levied on,
+// excluding tax.
+type Tax struct {
+	RateID  *uint
+	Name    string
+	Rate    Percent
+	Taxable money.Money
+	Amount  money.Money
+}
+
+// Rounding selects where tax is rounded to a whole minor unit.
+type Rounding string
+
+const (
+	// RoundPerLine rounds the tax of each line at each rate.
+	RoundPerLine Rounding = "line"
+	// RoundPerOrder rounds the tax at each rate over the whole order once, and
+	// spreads it over the lines.
+	RoundPerOrder Rounding = "order"
+)
+
+// Config sets how the local calculator taxes orders. With PricesIncludeTax,
+// catalog prices are gross and tax is extracted from them; otherwise tax is
+// added on top. Orders without a location are taxed at DefaultLocation.
+type Config struct {
+	PricesIncludeTax bool
+	Rounding         Rounding
+	DefaultLocation  geo.Location
+}
+
+// LocalCalculator taxes orders with the rates stored in the database.
+type LocalCalculator struct {
+	repo *Repository
+	cfg  Config
+}
+
+func NewLocalCalculator(repo *Repository, cfg Config) (*LocalCalculator, error) {
+	switch cfg.Rounding {
+	case "":
+		cfg.Rounding = RoundPerLine
+	case RoundPerLine, RoundPerOrder:
+	default:
+		return nil, fmt.Errorf("%w: unknown rounding %q", ErrInvalidConfig, cfg.Rounding)
+	}
+	return &LocalCalculator{repo: repo, cfg: cfg}, nil
+}
+
+// Calculate taxes the lines at the rates in force at req.At for their class
+// in the zone of req.Location. Orders outside every zone are not taxed.
+func (c *LocalCalculator) Calculate(ctx context.Context, req Request) (*Result, error) {
+	location := req.Location.Normalize()
+	if location.Country == "" {
+		location = c.cfg.DefaultLocation
+	}
+	at := req.At
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	var rates map[uint][]Rate
+	if location.Country != "" {
+		zone, err := c.repo.Match(location)
+		if err != nil {
+			return nil, err
+		}
+		if zone != nil {
+			if rates, err = c.repo.RatesInForce(zone.ID, at); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	defaultClass := uint(0)
+	if len(rates) > 0 {
+		class, err := c.repo.DefaultClass()
+		if err != nil {
+			return nil, err
+		}
+		if class != nil {
+			defaultClass = class.ID
+		}
+	}
+
+	lines := make([][]Rate, len(req.Lines))
+	for i, line := range req.Lines {
+		class := defaultClass
+		if line.ClassID != nil {
+			class = *line.ClassID
+		}
+		lines[i] = rates[class]
+	}
+	return compute(req, lines, c.cfg), nil
+}
+
+// share is the exact tax of a line at one rate.
+type share struct {
+	line int
+	tax  *big.Rat
+}
+
+// compute taxes each request line at its rates.
+func compute(req Request, rates [][]Rate, cfg Config) *Result {
+	result := &Result{
+		PricesIncludeTax: cfg.PricesIncludeTax,
+		Lines:            make([]money.Money, len(req.Lines)),
+		Taxes:            []Tax{},
+		Total:            money.Zero(req.Currency),
+	}
+	for i := range result.Lines {
+		result.Lines[i] = money.Zero(req.Currency)
+	}
+
+	// Collect the exact tax of every line at every rate, grouped by rate.
+	type levy struct {
+		rate    Rate
+		taxable int64
+		shares  []share
+	}
+	levies := make(map[uint]*levy)
+	order := []uint{}
+	for i, line := range req.Lines {
+		if line.Amount.Amount <= 0 || len(rates[i]) == 0 {
+			continue
+		}
+		var combined Percent
+		for _, rate := range rates[i] {
+			combined += rate.Rate
+		}
+		for _, rate := range rates[i] {
+			// Exclusive: amount × rate. Inclusive: the part of the gross
+			// amount that is this rate's tax, amount × rate / (1 + rates).
+			den := int64(Hundred)
+			if cfg.PricesIncludeTax {
+				den += int64(combined)
+			}
+			tax := new(big.Rat).SetFrac(
+				new(big.Int).Mul(big.NewInt(line.Amount.Amount), big.NewInt(int64(rate.Rate))),
+				big.NewInt(den),
+			)
+			l, ok := levies[rate.ID]
+			if !ok {
+				l = &levy{rate: rate}
+				levies[rate.ID] = l
+				order = append(order, rate.ID)
+			}
+			l.shares = append(l.shares, share{line: i, tax: tax})
+			l.taxable += line.Amount.Amount
+		}
+	}
+	sort.Slice(order, func(i, j int) bool { return order[i] < order[j] })
+
+	for _, id := range order {
+		l := levies[id]
+		var amount int64
+		switch cfg.Rounding {
+		case RoundPerOrder:
+			exact := new(big.Rat)
+			weights := make([]int64, len(l.shares))
+			for k, s := range l.shares {
+				exact.Add(exact, s.tax)
+				weights[k] = req.Lines[s.line].Amount.Amount
+			}
+			amount = round(exact)
+			for k, part := range money.New(amount, req.Currency).Allocate(weights) {
+				result.Lines[l.shares[k].line].Amount += part.Amount
+			}
+		default:
+			for _, s := range l.shares {
+				tax := round(s.tax)
+				result.Lines[s.line].Amount += tax
+				amount += tax
+			}
+		}
+
+		rateID := l.rate.ID
+		result.Taxes = append(result.Taxes, Tax{
+			RateID:  &rateID,
+			Name:    l.rate.Name,
+			Rate:    l.rate.Rate,
+			Taxable: money.New(l.taxable, req.Currency),
+			Amount:  money.New(amount, req.Currency),
+		})
+		result.Total.Amount += amount
+	}
+
+	// Inclusive amounts contain the tax; report what it was levied on.
+	if cfg.PricesIncludeTax {
+		for k := range result.Taxes {
+			var net int64
+			for _, s := range levies[*result.Taxes[k].RateID].shares {
+				net += req.Lines[s.line].Amount.Amount - result.Lines[s.line].Amount
+			}
+			result.Taxes[k].Taxable.Amount = net
+		}
+	}
+	return result
+}
+
+// round rounds r to the nearest whole number, halves away from zero.
+func round(r *big.Rat) int64 {
+	q, m := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	if new(big.Int).Lsh(m.Abs(m), 1).Cmp(r.Denom()) >= 0 {
+		q.Add(q, big.NewInt(int64(r.Num().Sign())))
+	}
+	return q.Int64()
+}
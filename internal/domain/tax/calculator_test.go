@@ -0,0 +1,192 @@
+package tax
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nneji123/ecommerce-golang/internal/common/money"
+)
+
+func TestComputeRounding(t *testing.T) {
+	vat := Rate{ID: 1, Name: "VAT", Rate: 200_000}
+	state := Rate{ID: 2, Name: "State", Rate: 100_000}
+	county := Rate{ID: 3, Name: "County", Rate: 50_000}
+	half := Rate{ID: 4, Name: "Half", Rate: 50_000}
+
+	type levied struct {
+		rate    uint
+		taxable int64
+		amount  int64
+	}
+	tests := []struct {
+		name      string
+		amounts   []int64
+		rates     [][]Rate
+		inclusive bool
+		rounding  Rounding
+		lines     []int64
+		taxes     []levied
+		total     int64
+	}{
+		{
+			name:     "exclusive per line",
+			amounts:  []int64{1000, 333},
+			rates:    [][]Rate{{vat}, {vat}},
+			rounding: RoundPerLine,
+			lines:    []int64{200, 67},
+			taxes:    []levied{{1, 1333, 267}},
+			total:    267,
+		},
+		{
+			name:     "exclusive per line rounds every line",
+			amounts:  []int64{333, 333, 333},
+			rates:    [][]Rate{{vat}, {vat}, {vat}},
+			rounding: RoundPerLine,
+			lines:    []int64{67, 67, 67},
+			taxes:    []levied{{1, 999, 201}},
+			total:    201,
+		},
+		{
+			name:     "exclusive per order rounds once",
+			amounts:  []int64{333, 333, 333},
+			rates:    [][]Rate{{vat}, {vat}, {vat}},
+			rounding: RoundPerOrder,
+			lines:    []int64{67, 67, 66},
+			taxes:    []levied{{1, 999, 200}},
+			total:    200,
+		},
+		{
+			name:      "inclusive per line",
+			amounts:   []int64{1200, 1000},
+			rates:     [][]Rate{{vat}, {vat}},
+			inclusive: true,
+			rounding:  RoundPerLine,
+			lines:     []int64{200, 167},
+			taxes:     []levied{{1, 1833, 367}},
+			total:     367,
+		},
+		{
+			name:      "inclusive per line rounds every line",
+			amounts:   []int64{1000, 1000, 1000},
+			rates:     [][]Rate{{vat}, {vat}, {vat}},
+			inclusive: true,
+			rounding:  RoundPerLine,
+			lines:     []int64{167, 167, 167},
+			taxes:     []levied{{1, 2499, 501}},
+			total:     501,
+		},
+		{
+			name:      "inclusive per order rounds once",
+			amounts:   []int64{1000, 1000, 1000},
+			rates:     [][]Rate{{vat}, {vat}, {vat}},
+			inclusive: true,
+			rounding:  RoundPerOrder,
+			lines:     []int64{167, 167, 166},
+			taxes:     []levied{{1, 2500, 500}},
+			total:     500,
+		},
+		{
+			name:     "exclusive compound rates",
+			amounts:  []int64{1000},
+			rates:    [][]Rate{{state, county}},
+			rounding: RoundPerLine,
+			lines:    []int64{150},
+			taxes:    []levied{{2, 1000, 100}, {3, 1000, 50}},
+			total:    150,
+		},
+		{
+			name:      "inclusive compound rates extract from the combined rate",
+			amounts:   []int64{1150},
+			rates:     [][]Rate{{state, county}},
+			inclusive: true,
+			rounding:  RoundPerLine,
+			lines:     []int64{150},
+			taxes:     []levied{{2, 1000, 100}, {3, 1000, 50}},
+			total:     150,
+		},
+		{
+			name:     "halves round away from zero",
+			amounts:  []int64{10, 30},
+			rates:    [][]Rate{{half}, {half}},
+			rounding: RoundPerLine,
+			lines:    []int64{1, 2},
+			taxes:    []levied{{4, 40, 3}},
+			total:    3,
+		},
+		{
+			name:     "halves round once per order",
+			amounts:  []int64{10, 30},
+			rates:    [][]Rate{{half}, {half}},
+			rounding: RoundPerOrder,
+			lines:    []int64{1, 1},
+			taxes:    []levied{{4, 40, 2}},
+			total:    2,
+		},
+		{
+			name:     "untaxed and free lines",
+			amounts:  []int64{1000, 0, 500},
+			rates:    [][]Rate{{vat}, {vat}, nil},
+			rounding: RoundPerLine,
+			lines:    []int64{200, 0, 0},
+			taxes:    []levied{{1, 1000, 200}},
+			total:    200,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := Request{Currency: "USD"}
+			for i, amount := range tt.amounts {
+				req.Lines = append(req.Lines, Line{ProductID: uint(i + 1), Quantity: 1, Amount: money.New(amount, "USD")})
+			}
+			result := compute(req, tt.rates, Config{PricesIncludeTax: tt.inclusive, Rounding: tt.rounding})
+
+			if result.PricesIncludeTax != tt.inclusive {
+				t.Errorf("PricesIncludeTax = %v, want %v", result.PricesIncludeTax, tt.inclusive)
+			}
+			lines := make([]int64, len(result.Lines))
+			for i, line := range result.Lines {
+				lines[i] = line.Amount
+			}
+			if !reflect.DeepEqual(lines, tt.lines) {
+				t.Errorf("line taxes = %v, want %v", lines, tt.lines)
+			}
+
+			taxes := make([]levied, len(result.Taxes))
+			for i, tax := range result.Taxes {
+				taxes[i] = levied{rate: *tax.RateID, taxable: tax.Taxable.Amount, amount: tax.Amount.Amount}
+			}
+			if !reflect.DeepEqual(taxes, tt.taxes) {
+				t.Errorf("taxes = %+v, want %+v", taxes, tt.taxes)
+			}
+
+			if result.Total.Amount != tt.total || result.Total.Currency != "USD" {
+				t.Errorf("total = %+v, want %d USD", result.Total, tt.total)
+			}
+		})
+	}
+}
+
+func TestNewLocalCalculatorRounding(t *testing.T) {
+	tests := []struct {
+		rounding Rounding
+		want     Rounding
+		wantErr  bool
+	}{
+		{"", RoundPerLine, false},
+		{RoundPerLine, RoundPerLine, false},
+		{RoundPerOrder, RoundPerOrder, false},
+		{"invoice", "", true},
+	}
+
+	for _, tt := range tests {
+		calculator, err := NewLocalCalculator(nil, Config{Rounding: tt.rounding})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NewLocalCalculator(%q) error = %v, want error %v", tt.rounding, err, tt.wantErr)
+			continue
+		}
+		if err == nil && calculator.cfg.Rounding != tt.want {
+			t.Errorf("NewLocalCalculator(%q) rounding = %q, want %q", tt.rounding, calculator.cfg.Rounding, tt.want)
+		}
+	}
+}
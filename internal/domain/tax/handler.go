@@ -0,0 +1,398 @@
+package tax
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/nneji123/ecommerce-golang/internal/middleware"
+	"go.uber.org/zap"
+)
+
+type Handler struct {
+	repo      *Repository
+	validator *validator.Validate
+	logger    *zap.Logger
+}
+
+func NewHandler(repo *Repository, validator *validator.Validate, logger *zap.Logger) *Handler {
+	return &Handler{
+		repo:      repo,
+		validator: validator,
+		logger:    logger,
+	}
+}
+
+// @Summary		List tax zones
+// @Description	List every tax zone with the countries, regions and postcode patterns it covers (requires taxes:manage)
+// @Tags			taxes
+// @Produce		json
+// @Success		200	{array}	Zone
+// @Router			/taxes/zones [get]
+func (h *Handler) ListZones(c echo.Context) error {
+	zones, err := h.repo.Zones()
+	if err != nil {
+		h.logger.Error("Failed to list tax zones", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list tax zones")
+	}
+	return c.JSON(http.StatusOK, zones)
+}
+
+// @Summary		Get tax zone
+// @Description	Get a tax zone with its locations (requires taxes:manage)
+// @Tags			taxes
+// @Produce		json
+// @Param			id	path		int	true	"Zone ID"
+// @Success		200	{object}	Zone
+// @Failure		404	{object}	middleware.ErrorResponse
+// @Router			/taxes/zones/{id} [get]
+func (h *Handler) GetZone(c echo.Context) error {
+	id, err := parseID(c, "Invalid zone ID")
+	if err != nil {
+		return err
+	}
+	zone, err := h.repo.GetZone(id)
+	if err != nil {
+		return h.taxError(err, "Failed to get tax zone")
+	}
+	return c.JSON(http.StatusOK, zone)
+}
+
+// @Summary		Create tax zone
+// @Description	Create a tax zone (requires taxes:manage). An address is in the zone with its most specific matching location; priority breaks ties. Postcode patterns may use * and ?.
+// @Tags			taxes
+// @Accept			json
+// @Produce		json
+// @Param			zone	body		ZoneRequest	true	"Zone"
+// @Success		201		{object}	Zone
+// @Failure		400		{object}	middleware.ErrorResponse
+// @Router			/taxes/zones [post]
+func (h *Handler) CreateZone(c echo.Context) error {
+	zone, err := h.bindZone(c)
+	if err != nil {
+		return err
+	}
+	if err := h.repo.SaveZone(zone); err != nil {
+		return h.taxError(err, "Failed to create tax zone")
+	}
+	return c.JSON(http.StatusCreated, zone)
+}
+
+// @Summary		Update tax zone
+// @Description	Replace the name, priority and locations of a tax zone (requires taxes:manage)
+// @Tags			taxes
+// @Accept			json
+// @Produce		json
+// @Param			id		path		int			true	"Zone ID"
+// @Param			zone	body		ZoneRequest	true	"Zone"
+// @Success		200		{object}	Zone
+// @Failure		400		{object}	middleware.ErrorResponse
+// @Failure		404		{object}	middleware.ErrorResponse
+// @Router			/taxes/zones/{id} [put]
+func (h *Handler) UpdateZone(c echo.Context) error {
+	id, err := parseID(c, "Invalid zone ID")
+	if err != nil {
+		return err
+	}
+	zone, err := h.bindZone(c)
+	if err != nil {
+		return err
+	}
+	zone.ID = id
+	if err := h.repo.SaveZone(zone); err != nil {
+		return h.taxError(err, "Failed to update tax zone")
+	}
+	return c.JSON(http.StatusOK, zone)
+}
+
+// @Summary		Delete tax zone
+// @Description	Delete a tax zone that has no rates (requires taxes:manage)
+// @Tags			taxes
+// @Param			id	path	int	true	"Zone ID"
+// @Success		204	"No Content"
+// @Failure		404	{object}	middleware.ErrorResponse
+// @Failure		409	{object}	middleware.ErrorResponse
+// @Router			/taxes/zones/{id} [delete]
+func (h *Handler) DeleteZone(c echo.Context) error {
+	id, err := parseID(c, "Invalid zone ID")
+	if err != nil {
+		return err
+	}
+	if err := h.repo.DeleteZone(id); err != nil {
+		return h.taxError(err, "Failed to delete tax zone")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// @Summary		List tax classes
+// @Description	List the tax classes products can be assigned to
+// @Tags			taxes
+// @Produce		json
+// @Success		200	{array}	Class
+// @Router			/taxes/classes [get]
+func (h *Handler) ListClasses(c echo.Context) error {
+	classes, err := h.repo.Classes()
+	if err != nil {
+		h.logger.Error("Failed to list tax classes", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list tax classes")
+	}
+	return c.JSON(http.StatusOK, classes)
+}
+
+// @Summary		Create tax class
+// @Description	Create a tax class (requires taxes:manage). Products without a class are taxed as the default class.
+// @Tags			taxes
+// @Accept			json
+// @Produce		json
+// @Param			class	body		ClassRequest	true	"Class"
+// @Success		201		{object}	Class
+// @Failure		400		{object}	middleware.ErrorResponse
+// @Failure		409		{object}	middleware.ErrorResponse
+// @Router			/taxes/classes [post]
+func (h *Handler) CreateClass(c echo.Context) error {
+	class, err := h.bindClass(c)
+	if err != nil {
+		return err
+	}
+	if err := h.repo.SaveClass(class); err != nil {
+		return h.taxError(err, "Failed to create tax class")
+	}
+	return c.JSON(http.StatusCreated, class)
+}
+
+// @Summary		Update tax class
+// @Description	Update a tax class (requires taxes:manage)
+// @Tags			taxes
+// @Accept			json
+// @Produce		json
+// @Param			id		path		int				true	"Class ID"
+// @Param			class	body		ClassRequest	true	"Class"
+// @Success		200		{object}	Class
+// @Failure		400		{object}	middleware.ErrorResponse
+// @Failure		404		{object}	middleware.ErrorResponse
+// @Failure		409		{object}	middleware.ErrorResponse
+// @Router			/taxes/classes/{id} [put]
+func (h *Handler) UpdateClass(c echo.Context) error {
+	id, err := parseID(c, "Invalid class ID")
+	if err != nil {
+		return err
+	}
+	class, err := h.bindClass(c)
+	if err != nil {
+		return err
+	}
+	class.ID = id
+	if err := h.repo.SaveClass(class); err != nil {
+		return h.taxError(err, "Failed to update tax class")
+	}
+	if class, err = h.repo.GetClass(id); err != nil {
+		return h.taxError(err, "Failed to update tax class")
+	}
+	return c.JSON(http.StatusOK, class)
+}
+
+// @Summary		Delete tax class
+// @Description	Delete a tax class that has no rates and no products (requires taxes:manage)
+// @Tags			taxes
+// @Param			id	path	int	true	"Class ID"
+// @Success		204	"No Content"
+// @Failure		404	{object}	middleware.ErrorResponse
+// @Failure		409	{object}	middleware.ErrorResponse
+// @Router			/taxes/classes/{id} [delete]
+func (h *Handler) DeleteClass(c echo.Context) error {
+	id, err := parseID(c, "Invalid class ID")
+	if err != nil {
+		return err
+	}
+	if err := h.repo.DeleteClass(id); err != nil {
+		return h.taxError(err, "Failed to delete tax class")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// @Summary		List tax rates
+// @Description	Get a paginated list of tax rates, latest first (requires taxes:manage)
+// @Tags			taxes
+// @Produce		json
+// @Param			zone_id		query		int		false	"Zone ID"
+// @Param			class_id	query		int		false	"Class ID"
+// @Param			at			query		string	false	"Only rates in force at this RFC 3339 time"
+// @Param			page		query		int		false	"Page number"
+// @Param			limit		query		int		false	"Items per page"
+// @Success		200			{object}	middleware.PaginatedResponse
+// @Failure		400			{object}	middleware.ErrorResponse
+// @Router			/taxes/rates [get]
+func (h *Handler) ListRates(c echo.Context) error {
+	var query RateQuery
+	if err := c.Bind(&query); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	query.Normalize(20, 100, "")
+
+	var at *time.Time
+	if query.At != "" {
+		t, err := time.Parse(time.RFC3339, query.At)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid time")
+		}
+		at = &t
+	}
+
+	rates, page, err := h.repo.Rates(query, at)
+	if err != nil {
+		h.logger.Error("Failed to list tax rates", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list tax rates")
+	}
+
+	middleware.SetLinkHeader(c, page)
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"rates":      rates,
+		"pagination": page,
+	})
+}
+
+// @Summary		Create tax rate
+// @Description	Add a tax rate for a class of products in a zone, in force from effective_from until effective_to if given (requires taxes:manage). Rates are percentages such as "8.875".
+// @Tags			taxes
+// @Accept			json
+// @Produce		json
+// @Param			rate	body		RateRequest	true	"Rate"
+// @Success		201		{object}	Rate
+// @Failure		400		{object}	middleware.ErrorResponse
+// @Router			/taxes/rates [post]
+func (h *Handler) CreateRate(c echo.Context) error {
+	rate, err := h.bindRate(c)
+	if err != nil {
+		return err
+	}
+	if err := h.repo.SaveRate(rate); err != nil {
+		return h.taxError(err, "Failed to create tax rate")
+	}
+	return c.JSON(http.StatusCreated, rate)
+}
+
+// @Summary		Update tax rate
+// @Description	Update a tax rate (requires taxes:manage). Orders already placed keep the tax they were charged; to change a rate from a date, end it there and add a new one.
+// @Tags			taxes
+// @Accept			json
+// @Produce		json
+// @Param			id		path		int			true	"Rate ID"
+// @Param			rate	body		RateRequest	true	"Rate"
+// @Success		200		{object}	Rate
+// @Failure		400		{object}	middleware.ErrorResponse
+// @Failure		404		{object}	middleware.ErrorResponse
+// @Router			/taxes/rates/{id} [put]
+func (h *Handler) UpdateRate(c echo.Context) error {
+	id, err := parseID(c, "Invalid rate ID")
+	if err != nil {
+		return err
+	}
+	rate, err := h.bindRate(c)
+	if err != nil {
+		return err
+	}
+	rate.ID = id
+	if err := h.repo.SaveRate(rate); err != nil {
+		return h.taxError(err, "Failed to update tax rate")
+	}
+	if rate, err = h.repo.GetRate(id); err != nil {
+		return h.taxError(err, "Failed to update tax rate")
+	}
+	return c.JSON(http.StatusOK, rate)
+}
+
+// @Summary		Delete tax rate
+// @Description	Delete a tax rate (requires taxes:manage)
+// @Tags			taxes
+// @Param			id	path	int	true	"Rate ID"
+// @Success		204	"No Content"
+// @Failure		404	{object}	middleware.ErrorResponse
+// @Router			/taxes/rates/{id} [delete]
+func (h *Handler) DeleteRate(c echo.Context) error {
+	id, err := parseID(c, "Invalid rate ID")
+	if err != nil {
+		return err
+	}
+	if err := h.repo.DeleteRate(id); err != nil {
+		return h.taxError(err, "Failed to delete tax rate")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Handler) bindZone(c echo.Context) (*Zone, error) {
+	var req ZoneRequest
+	if err := c.Bind(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := h.validator.Struct(req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	zone := &Zone{Name: req.Name, Priority: req.Priority}
+	for _, location := range req.Locations {
+		zone.Locations = append(zone.Locations, ZoneLocation{
+			Country:  location.Country,
+			Region:   location.Region,
+			Postcode: location.Postcode,
+		})
+	}
+	return zone, nil
+}
+
+func (h *Handler) bindClass(c echo.Context) (*Class, error) {
+	var req ClassRequest
+	if err := c.Bind(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := h.validator.Struct(req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return &Class{Name: req.Name, Description: req.Description, Default: req.Default}, nil
+}
+
+func (h *Handler) bindRate(c echo.Context) (*Rate, error) {
+	var req RateRequest
+	if err := c.Bind(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := h.validator.Struct(req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return &Rate{
+		ZoneID:        req.ZoneID,
+		ClassID:       req.ClassID,
+		Name:          req.Name,
+		Rate:          req.Rate,
+		EffectiveFrom: req.EffectiveFrom,
+		EffectiveTo:   req.EffectiveTo,
+	}, nil
+}
+
+func parseID(c echo.Context, message string) (uint, error) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, message)
+	}
+	return uint(id), nil
+}
+
+func (h *Handler) taxError(err error, message string) error {
+	switch {
+	case errors.Is(err, ErrZoneNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Tax zone not found")
+	case errors.Is(err, ErrClassNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Tax class not found")
+	case errors.Is(err, ErrRateNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Tax rate not found")
+	case errors.Is(err, ErrInvalidZone), errors.Is(err, ErrInvalidRate):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrClassConflict):
+		return echo.NewHTTPError(http.StatusConflict, "Tax class name already exists")
+	case errors.Is(err, ErrInUse), errors.Is(err, ErrClassInUse):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	h.logger.Error(message, zap.Error(err))
+	return echo.NewHTTPError(http.StatusInternalServerError, message)
+}
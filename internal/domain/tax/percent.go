@@ -0,0 +1,74 @@
+package tax
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidPercent = errors.New("invalid percentage")
+
+// percentDigits is the number of decimal places of a Percent.
+const percentDigits = 4
+
+// Percent is a tax rate in millionths of the taxed amount, so that rates such
+// as 8.875% are exact: 8.875% is Percent(88750). It is written to JSON as a
+// percentage string, "8.875".
+type Percent int64
+
+// Hundred is 100%.
+const Hundred Percent = 1_000_000
+
+// ParsePercent reads a percentage such as "20" or "8.875" with at most four
+// decimal places.
+func ParsePercent(s string) (Percent, error) {
+	s = strings.TrimSpace(s)
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" || len(frac) > percentDigits || strings.HasPrefix(whole, "-") {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidPercent, s)
+	}
+	frac += strings.Repeat("0", percentDigits-len(frac))
+	value, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidPercent, s)
+	}
+	return Percent(value), nil
+}
+
+// String formats p as a percentage without trailing zeros, e.g. "8.875".
+func (p Percent) String() string {
+	s := strconv.FormatInt(int64(p), 10)
+	if len(s) <= percentDigits {
+		s = strings.Repeat("0", percentDigits-len(s)+1) + s
+	}
+	whole, frac := s[:len(s)-percentDigits], strings.TrimRight(s[len(s)-percentDigits:], "0")
+	if frac == "" {
+		return whole
+	}
+	return whole + "." + frac
+}
+
+func (p Percent) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.String())
+}
+
+// UnmarshalJSON reads a percentage given as a string or a number.
+func (p *Percent) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if strings.HasPrefix(s, `"`) {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	}
+	parsed, err := ParsePercent(s)
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
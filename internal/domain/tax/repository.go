@@ -0,0 +1,357 @@
+package tax
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nneji123/ecommerce-golang/internal/common/geo"
+	"github.com/nneji123/ecommerce-golang/internal/middleware"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrZoneNotFound  = errors.New("tax zone not found")
+	ErrClassNotFound = errors.New("tax class not found")
+	ErrRateNotFound  = errors.New("tax rate not found")
+	ErrInvalidZone   = errors.New("invalid tax zone")
+	ErrInvalidRate   = errors.New("invalid tax rate")
+	ErrClassConflict = errors.New("tax class name already exists")
+	ErrInUse         = errors.New("tax zone or class still has rates")
+	ErrClassInUse    = errors.New("tax class is assigned to products")
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// Zones returns every zone with its locations, by name.
+func (r *Repository) Zones() ([]Zone, error) {
+	zones := []Zone{}
+	err := r.db.Preload("Locations", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Order("name, id").Find(&zones).Error
+	return zones, err
+}
+
+func (r *Repository) GetZone(id uint) (*Zone, error) {
+	var zone Zone
+	err := r.db.Preload("Locations", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).First(&zone, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrZoneNotFound
+		}
+		return nil, err
+	}
+	return &zone, nil
+}
+
+// SaveZone creates a zone, or replaces the name, priority and locations of
+// an existing one.
+func (r *Repository) SaveZone(zone *Zone) error {
+	for i, location := range zone.Locations {
+		area := geo.Location{Country: location.Country, Region: location.Region, Postcode: location.Postcode}
+		if !geo.ValidPattern(area) {
+			return fmt.Errorf("%w: bad postcode pattern %q", ErrInvalidZone, location.Postcode)
+		}
+		area = area.Normalize()
+		zone.Locations[i].Country = area.Country
+		zone.Locations[i].Region = area.Region
+		zone.Locations[i].Postcode = area.Postcode
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		locations := zone.Locations
+		if zone.ID == 0 {
+			if err := tx.Omit("Locations").Create(zone).Error; err != nil {
+				return err
+			}
+		} else {
+			result := tx.Model(zone).Updates(map[string]interface{}{
+				"name":     zone.Name,
+				"priority": zone.Priority,
+			})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrZoneNotFound
+			}
+			if err := tx.Where("zone_id = ?", zone.ID).Delete(&ZoneLocation{}).Error; err != nil {
+				return err
+			}
+		}
+		for i := range locations {
+			locations[i].ID = 0
+			locations[i].ZoneID = zone.ID
+		}
+		if err := tx.Create(&locations).Error; err != nil {
+			return err
+		}
+		zone.Locations = locations
+		return nil
+	})
+}
+
+// DeleteZone removes a zone that has no rates.
+func (r *Repository) DeleteZone(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var rates int64
+		if err := tx.Model(&Rate{}).Where("zone_id = ?", id).Count(&rates).Error; err != nil {
+			return err
+		}
+		if rates > 0 {
+			return ErrInUse
+		}
+		if err := tx.Where("zone_id = ?", id).Delete(&ZoneLocation{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&Zone{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrZoneNotFound
+		}
+		return nil
+	})
+}
+
+// Match returns the zone of a location: the one with the most specific
+// matching location, a postcode pattern being more specific than a region
+// and a region more specific than a whole country. It returns nil when the
+// location is in no zone.
+func (r *Repository) Match(location geo.Location) (*Zone, error) {
+	location = location.Normalize()
+	var candidates []struct {
+		ZoneLocation
+		Priority int
+	}
+	err := r.db.Table("tax_zone_locations zl").
+		Select("zl.*, z.priority").
+		Joins("JOIN tax_zones z ON z.id = zl.zone_id").
+		Where("zl.country = ?", location.Country).
+		Scan(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	best, bestScore, bestPriority := uint(0), 0, 0
+	for _, c := range candidates {
+		score := geo.Specificity(geo.Location{Country: c.Country, Region: c.Region, Postcode: c.Postcode}, location)
+		if score == 0 {
+			continue
+		}
+		if score > bestScore ||
+			score == bestScore && (c.Priority > bestPriority || c.Priority == bestPriority && c.ZoneID < best) {
+			best, bestScore, bestPriority = c.ZoneID, score, c.Priority
+		}
+	}
+	if best == 0 {
+		return nil, nil
+	}
+	return r.GetZone(best)
+}
+
+// Classes returns every tax class by name.
+func (r *Repository) Classes() ([]Class, error) {
+	classes := []Class{}
+	err := r.db.Order("name, id").Find(&classes).Error
+	return classes, err
+}
+
+func (r *Repository) GetClass(id uint) (*Class, error) {
+	var class Class
+	if err := r.db.First(&class, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrClassNotFound
+		}
+		return nil, err
+	}
+	return &class, nil
+}
+
+// DefaultClass returns the class of products without one, or nil if no class
+// is the default.
+func (r *Repository) DefaultClass() (*Class, error) {
+	var class Class
+	if err := r.db.Where("is_default").First(&class).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &class, nil
+}
+
+// SaveClass creates or updates a class. Making a class the default clears
+// the flag on the previous default.
+func (r *Repository) SaveClass(class *Class) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&Class{}).Where("LOWER(name) = LOWER(?) AND id <> ?", class.Name, class.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrClassConflict
+		}
+		if class.Default {
+			if err := tx.Model(&Class{}).Where("is_default AND id <> ?", class.ID).Update("is_default", false).Error; err != nil {
+				return err
+			}
+		}
+		if class.ID == 0 {
+			return tx.Create(class).Error
+		}
+		result := tx.Model(class).Select("name", "description", "is_default").Updates(class)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrClassNotFound
+		}
+		return nil
+	})
+}
+
+// DeleteClass removes a class that has no rates and no products.
+func (r *Repository) DeleteClass(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var rates int64
+		if err := tx.Model(&Rate{}).Where("class_id = ?", id).Count(&rates).Error; err != nil {
+			return err
+		}
+		if rates > 0 {
+			return ErrInUse
+		}
+		var products int64
+		if err := tx.Table("products").Where("tax_class_id = ? AND deleted_at IS NULL", id).Count(&products).Error; err != nil {
+			return err
+		}
+		if products > 0 {
+			return ErrClassInUse
+		}
+		result := tx.Delete(&Class{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrClassNotFound
+		}
+		return nil
+	})
+}
+
+// Rates returns a page of rates, latest first.
+func (r *Repository) Rates(query RateQuery, at *time.Time) ([]Rate, middleware.PaginatedResponse, error) {
+	rates := []Rate{}
+	db := r.db.Model(&Rate{})
+	if query.ZoneID > 0 {
+		db = db.Where("zone_id = ?", query.ZoneID)
+	}
+	if query.ClassID > 0 {
+		db = db.Where("class_id = ?", query.ClassID)
+	}
+	if at != nil {
+		db = db.Where("effective_from <= ? AND (effective_to IS NULL OR effective_to > ?)", *at, *at)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, middleware.PaginatedResponse{}, err
+	}
+	err := db.Order("effective_from DESC, id DESC").
+		Offset((query.Page - 1) * query.Limit).
+		Limit(query.Limit).
+		Find(&rates).Error
+	if err != nil {
+		return nil, middleware.PaginatedResponse{}, err
+	}
+	return rates, middleware.OffsetPage(query.Page, query.Limit, total), nil
+}
+
+// RatesInForce returns the rates of a zone in force at t, by class.
+func (r *Repository) RatesInForce(zoneID uint, t time.Time) (map[uint][]Rate, error) {
+	var rates []Rate
+	err := r.db.Where("zone_id = ? AND effective_from <= ? AND (effective_to IS NULL OR effective_to > ?)", zoneID, t, t).
+		Order("id").
+		Find(&rates).Error
+	if err != nil {
+		return nil, err
+	}
+	byClass := make(map[uint][]Rate)
+	for _, rate := range rates {
+		byClass[rate.ClassID] = append(byClass[rate.ClassID], rate)
+	}
+	return byClass, nil
+}
+
+func (r *Repository) GetRate(id uint) (*Rate, error) {
+	var rate Rate
+	if err := r.db.First(&rate, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRateNotFound
+		}
+		return nil, err
+	}
+	return &rate, nil
+}
+
+// SaveRate creates or updates a rate. Orders already placed keep the tax they
+// were charged; to change a rate from a date, end the current rate there and
+// add a new one.
+func (r *Repository) SaveRate(rate *Rate) error {
+	if rate.EffectiveTo != nil && !rate.EffectiveTo.After(rate.EffectiveFrom) {
+		return fmt.Errorf("%w: effective_to must be after effective_from", ErrInvalidRate)
+	}
+	rate.Name = strings.TrimSpace(rate.Name)
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var zones int64
+		if err := tx.Model(&Zone{}).Where("id = ?", rate.ZoneID).Count(&zones).Error; err != nil {
+			return err
+		}
+		if zones == 0 {
+			return fmt.Errorf("%w: zone %d does not exist", ErrInvalidRate, rate.ZoneID)
+		}
+		var classes int64
+		if err := tx.Model(&Class{}).Where("id = ?", rate.ClassID).Count(&classes).Error; err != nil {
+			return err
+		}
+		if classes == 0 {
+			return fmt.Errorf("%w: class %d does not exist", ErrInvalidRate, rate.ClassID)
+		}
+
+		if rate.ID == 0 {
+			return tx.Create(rate).Error
+		}
+		result := tx.Model(rate).
+			Select("zone_id", "class_id", "name", "rate", "effective_from", "effective_to").
+			Updates(rate)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRateNotFound
+		}
+		return nil
+	})
+}
+
+func (r *Repository) DeleteRate(id uint) error {
+	result := r.db.Delete(&Rate{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRateNotFound
+	}
+	return nil
+}
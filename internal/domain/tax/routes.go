@@ -0,0 +1,37 @@
+package tax
+
+import (
+	"log"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nneji123/ecommerce-golang/internal/common/revocation"
+	"github.com/nneji123/ecommerce-golang/internal/config"
+	"github.com/nneji123/ecommerce-golang/internal/domain/rbac"
+	"github.com/nneji123/ecommerce-golang/internal/middleware"
+)
+
+func RegisterRoutes(e *echo.Echo, h *Handler, revocations revocation.Store, permissions middleware.PermissionResolver) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Error loading configuration: %s", err)
+	}
+
+	taxes := e.Group("/taxes", middleware.AuthMiddleware(cfg.JWTSecret, revocations))
+	taxes.GET("/classes", h.ListClasses)
+
+	manage := taxes.Group("", middleware.RequirePermission(permissions, rbac.PermissionTaxesManage))
+	manage.GET("/zones", h.ListZones)
+	manage.POST("/zones", h.CreateZone)
+	manage.GET("/zones/:id", h.GetZone)
+	manage.PUT("/zones/:id", h.UpdateZone)
+	manage.DELETE("/zones/:id", h.DeleteZone)
+
+	manage.POST("/classes", h.CreateClass)
+	manage.PUT("/classes/:id", h.UpdateClass)
+	manage.DELETE("/classes/:id", h.DeleteClass)
+
+	manage.GET("/rates", h.ListRates)
+	manage.POST("/rates", h.CreateRate)
+	manage.PUT("/rates/:id", h.UpdateRate)
+	manage.DELETE("/rates/:id", h.DeleteRate)
+}
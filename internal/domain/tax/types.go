@@ -0,0 +1,114 @@
+package tax
+
+import (
+	"time"
+
+	"github.com/nneji123/ecommerce-golang/internal/middleware"
+)
+
+// Zone is a tax jurisdiction: the places listed in its Locations. An address
+// belongs to the zone with its most specific matching location; Priority
+// breaks ties between zones matching equally well.
+type Zone struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	Name      string         `gorm:"size:255;not null" json:"name"`
+	Priority  int            `gorm:"not null;default:0" json:"priority"`
+	Locations []ZoneLocation `json:"locations"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+func (Zone) TableName() string {
+	return "tax_zones"
+}
+
+// ZoneLocation is a place covered by a zone: a country, optionally narrowed
+// to a region (state, province) and to postcodes matching Postcode, a pattern
+// in which * matches any characters and ? a single one.
+type ZoneLocation struct {
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	ZoneID   uint   `gorm:"not null;index" json:"zone_id"`
+	Country  string `gorm:"size:2;not null" json:"country"`
+	Region   string `gorm:"size:100" json:"region,omitempty"`
+	Postcode string `gorm:"size:50" json:"postcode,omitempty"`
+}
+
+func (ZoneLocation) TableName() string {
+	return "tax_zone_locations"
+}
+
+// Class groups products taxed alike, such as standard, reduced or exempt
+// goods. Products without a class are taxed as the Default class.
+type Class struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Name        string    `gorm:"size:100;not null;uniqueIndex" json:"name"`
+	Description string    `gorm:"type:text" json:"description"`
+	Default     bool      `gorm:"column:is_default;not null;default:false" json:"default"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (Class) TableName() string {
+	return "tax_classes"
+}
+
+// Rate is a tax levied on a class of products in a zone from EffectiveFrom
+// until EffectiveTo, if set. Several rates in force for the same zone and
+// class are all levied, each as its own tax line, e.g. a state and a county
+// sales tax.
+type Rate struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	ZoneID        uint       `gorm:"not null;index" json:"zone_id"`
+	ClassID       uint       `gorm:"not null;index" json:"class_id"`
+	Name          string     `gorm:"size:100;not null" json:"name"`
+	Rate          Percent    `gorm:"not null" json:"rate"`
+	EffectiveFrom time.Time  `gorm:"not null" json:"effective_from"`
+	EffectiveTo   *time.Time `json:"effective_to,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+func (Rate) TableName() string {
+	return "tax_rates"
+}
+
+// InForce reports whether the rate applies at t.
+func (r Rate) InForce(t time.Time) bool {
+	return !t.Before(r.EffectiveFrom) && (r.EffectiveTo == nil || t.Before(*r.EffectiveTo))
+}
+
+type ZoneLocationRequest struct {
+	Country  string `json:"country" validate:"required,iso3166_1_alpha2"`
+	Region   string `json:"region" validate:"max=100"`
+	Postcode string `json:"postcode" validate:"max=50"`
+}
+
+type ZoneRequest struct {
+	Name      string                `json:"name" validate:"required,max=255"`
+	Priority  int                   `json:"priority"`
+	Locations []ZoneLocationRequest `json:"locations" validate:"required,min=1,dive"`
+}
+
+type ClassRequest struct {
+	Name        string `json:"name" validate:"required,max=100"`
+	Description string `json:"description"`
+	Default     bool   `json:"default"`
+}
+
+type RateRequest struct {
+	ZoneID        uint       `json:"zone_id" validate:"required"`
+	ClassID       uint       `json:"class_id" validate:"required"`
+	Name          string     `json:"name" validate:"required,max=100"`
+	Rate          Percent    `json:"rate" validate:"lte=1000000"`
+	EffectiveFrom time.Time  `json:"effective_from" validate:"required"`
+	EffectiveTo   *time.Time `json:"effective_to"`
+}
+
+// RateQuery selects rates by zone and class. At, an RFC 3339 time, selects
+// the rates in force at that time.
+type RateQuery struct {
+	middleware.PaginationQuery
+	ZoneID  uint   `query:"zone_id"`
+	ClassID uint   `query:"class_id"`
+	At      string `query:"at"`
+}
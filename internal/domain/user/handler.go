@@ -3,29 +3,41 @@ package user
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
+	"github.com/nneji123/ecommerce-golang/internal/common/geo"
 	"github.com/nneji123/ecommerce-golang/internal/common/models"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// cartTokenHeader carries the anonymous cart token to merge on login.
+const cartTokenHeader = "X-Cart-Token"
+
 type Handler struct {
 	repo         Repository
 	validator    *validator.Validate
-	auth         AuthService
+	sessions     SessionService
 	emailService EmailService
+	carts        CartMerger
+	addresses    AddressRepository
 	logger       *zap.Logger
 }
 
-func NewHandler(repo Repository, validator *validator.Validate, auth AuthService, emailService EmailService, logger *zap.Logger) *Handler {
+func NewHandler(repo Repository, validator *validator.Validate, sessions SessionService, emailService EmailService, carts CartMerger, addresses AddressRepository, logger *zap.Logger) *Handler {
 	return &Handler{
 		repo:         repo,
 		validator:    validator,
-		auth:         auth,
+		sessions:     sessions,
 		emailService: emailService,
+		carts:        carts,
+		addresses:    addresses,
 		logger:       logger,
 	}
 }
@@ -87,28 +99,31 @@ func (h *Handler) Register(c echo.Context) error {
 		PasswordResetExpiry:     nil,
 	}
 
-	if err := h.repo.Create(user); err != nil {
+	err = h.repo.Transaction(func(tx *gorm.DB) error {
+		if err := h.repo.WithTx(tx).Create(user); err != nil {
+			return err
+		}
+		return h.emailService.QueueVerificationEmail(tx, user.Email, token)
+	})
+	if err != nil {
 		return err
 	}
 
-	if err := h.emailService.SendVerificationEmail(user.Email, token); err != nil {
-		h.logger.Error("Failed to send verification email", zap.Error(err))
-	}
-
 	return c.JSON(http.StatusCreated, user)
 }
 
 // Login godoc
 //
 //	@Summary		User login
-//	@Description	Authenticate user and return JWT token
+//	@Description	Authenticate user and return JWT token. An anonymous cart named by X-Cart-Token is merged into the user's cart.
 //	@Tags			auth
 //	@Accept			json
 //	@Produce		json
-//	@Param			request	body		LoginRequest	true	"Login credentials"
-//	@Success		200		{object}	LoginResponse
-//	@Failure		400		{object}	middleware.ErrorResponse
-//	@Failure		401		{object}	middleware.ErrorResponse
+//	@Param			request			body		LoginRequest	true	"Login credentials"
+//	@Param			X-Cart-Token	header		string			false	"Anonymous cart token"
+//	@Success		200				{object}	LoginResponse
+//	@Failure		400				{object}	middleware.ErrorResponse
+//	@Failure		401				{object}	middleware.ErrorResponse
 //	@Router			/auth/login [post]
 func (h *Handler) Login(c echo.Context) error {
 	var req LoginRequest
@@ -136,14 +151,90 @@ func (h *Handler) Login(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusUnauthorized, "email not verified")
 	}
 
-	token, err := h.auth.GenerateToken(user.ID, user.Email, user.Role)
+	tokens, err := h.sessions.Issue(user)
 	if err != nil {
 		return err
 	}
 
+	if cartToken := c.Request().Header.Get(cartTokenHeader); cartToken != "" && h.carts != nil {
+		if err := h.carts.MergeGuestCart(cartToken, user.ID); err != nil {
+			h.logger.Error("Failed to merge guest cart", zap.Error(err), zap.Uint("user_id", user.ID))
+		}
+	}
+
 	return c.JSON(http.StatusOK, LoginResponse{
-		Token: token,
-		User:  *user,
+		Token:        tokens.Token,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		User:         *user,
+	})
+}
+
+// Refresh godoc
+//
+//	@Summary		Refresh access token
+//	@Description	Exchange a refresh token for a new access token and a rotated refresh token. Reusing a rotated refresh token revokes the whole session.
+//	@Tags			auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		RefreshRequest	true	"Refresh token"
+//	@Success		200		{object}	TokenResponse
+//	@Failure		400		{object}	middleware.ErrorResponse
+//	@Failure		401		{object}	middleware.ErrorResponse
+//	@Router			/auth/refresh [post]
+func (h *Handler) Refresh(c echo.Context) error {
+	var req RefreshRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	tokens, err := h.sessions.Refresh(req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, ErrRefreshTokenReused) {
+			h.logger.Warn("Refresh token reuse detected; session revoked")
+			return echo.NewHTTPError(http.StatusUnauthorized, "refresh token reuse detected")
+		}
+		if errors.Is(err, ErrInvalidRefreshToken) {
+			return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired refresh token")
+		}
+		return err
+	}
+
+	return c.JSON(http.StatusOK, tokens)
+}
+
+// Logout godoc
+//
+//	@Summary		Logout
+//	@Description	Revoke the current access token and the session of the given refresh token
+//	@Tags			auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		LogoutRequest	false	"Refresh token to revoke"
+//	@Success		200		{object}	map[string]string
+//	@Failure		401		{object}	middleware.ErrorResponse
+//	@Router			/auth/logout [post]
+func (h *Handler) Logout(c echo.Context) error {
+	var req LogoutRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	claims, ok := c.Get("userClaims").(*models.Claims)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or missing user claims")
+	}
+
+	if err := h.sessions.Logout(req.RefreshToken, claims); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "logged out successfully",
 	})
 }
 
@@ -230,14 +321,16 @@ func (h *Handler) RequestPasswordReset(c echo.Context) error {
 	user.PasswordResetToken = &token
 	user.PasswordResetExpiry = &expiryTime
 
-	if err := h.repo.Update(user); err != nil {
+	err = h.repo.Transaction(func(tx *gorm.DB) error {
+		if err := h.repo.WithTx(tx).Update(user); err != nil {
+			return err
+		}
+		return h.emailService.QueuePasswordResetEmail(tx, user.Email, token)
+	})
+	if err != nil {
 		return err
 	}
 
-	if err := h.emailService.SendPasswordResetEmail(user.Email, token); err != nil {
-		h.logger.Error("Failed to send password reset email", zap.Error(err))
-	}
-
 	return c.JSON(http.StatusOK, map[string]string{
 		"message": "if your email is registered, you will receive a password reset link",
 	})
@@ -246,7 +339,7 @@ func (h *Handler) RequestPasswordReset(c echo.Context) error {
 // ConfirmPasswordReset godoc
 //
 //	@Summary		Confirm password reset
-//	@Description	Reset user's password using reset token
+//	@Description	Reset user's password using reset token and sign out all existing sessions
 //	@Tags			auth
 //	@Accept			json
 //	@Produce		json
@@ -285,6 +378,10 @@ func (h *Handler) ConfirmPasswordReset(c echo.Context) error {
 		return err
 	}
 
+	if err := h.sessions.RevokeAll(user.ID); err != nil {
+		return err
+	}
+
 	return c.JSON(http.StatusOK, map[string]string{
 		"message": "password reset successfully",
 	})
@@ -322,3 +419,166 @@ func (h *Handler) UserDetail(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, response)
 }
+
+// ListAddresses godoc
+//
+//	@Summary		List addresses
+//	@Description	List the logged-in user's address book, default addresses first
+//	@Tags			user
+//	@Produce		json
+//	@Success		200	{array}		Address
+//	@Failure		401	{object}	middleware.ErrorResponse
+//	@Router			/user/addresses [get]
+func (h *Handler) ListAddresses(c echo.Context) error {
+	claims := c.Get("userClaims").(*models.Claims)
+
+	addresses, err := h.addresses.List(claims.UserID)
+	if err != nil {
+		h.logger.Error("Failed to list addresses", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list addresses")
+	}
+
+	return c.JSON(http.StatusOK, addresses)
+}
+
+// GetAddress godoc
+//
+//	@Summary		Get address
+//	@Description	Get an address from the logged-in user's address book
+//	@Tags			user
+//	@Produce		json
+//	@Param			id	path		int	true	"Address ID"
+//	@Success		200	{object}	Address
+//	@Failure		404	{object}	middleware.ErrorResponse
+//	@Router			/user/addresses/{id} [get]
+func (h *Handler) GetAddress(c echo.Context) error {
+	claims := c.Get("userClaims").(*models.Claims)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid address ID")
+	}
+
+	address, err := h.addresses.Get(claims.UserID, uint(id))
+	if err != nil {
+		return h.addressError(err, "Failed to get address")
+	}
+
+	return c.JSON(http.StatusOK, address)
+}
+
+// CreateAddress godoc
+//
+//	@Summary		Create address
+//	@Description	Add an address to the logged-in user's address book. Which of region and postcode are required, and the postcode format, depend on the country. The first address becomes the default shipping and billing address.
+//	@Tags			user
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		AddressRequest	true	"Address"
+//	@Success		201		{object}	Address
+//	@Failure		400		{object}	middleware.ErrorResponse
+//	@Router			/user/addresses [post]
+func (h *Handler) CreateAddress(c echo.Context) error {
+	claims := c.Get("userClaims").(*models.Claims)
+
+	address, err := h.bindAddress(c)
+	if err != nil {
+		return err
+	}
+	address.UserID = claims.UserID
+
+	if err := h.addresses.Save(address); err != nil {
+		return h.addressError(err, "Failed to create address")
+	}
+
+	return c.JSON(http.StatusCreated, address)
+}
+
+// UpdateAddress godoc
+//
+//	@Summary		Update address
+//	@Description	Replace an address in the logged-in user's address book. Orders already placed keep the address they were placed with.
+//	@Tags			user
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int				true	"Address ID"
+//	@Param			request	body		AddressRequest	true	"Address"
+//	@Success		200		{object}	Address
+//	@Failure		400		{object}	middleware.ErrorResponse
+//	@Failure		404		{object}	middleware.ErrorResponse
+//	@Router			/user/addresses/{id} [put]
+func (h *Handler) UpdateAddress(c echo.Context) error {
+	claims := c.Get("userClaims").(*models.Claims)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid address ID")
+	}
+
+	address, err := h.bindAddress(c)
+	if err != nil {
+		return err
+	}
+	address.ID = uint(id)
+	address.UserID = claims.UserID
+
+	if err := h.addresses.Save(address); err != nil {
+		return h.addressError(err, "Failed to update address")
+	}
+	if address, err = h.addresses.Get(claims.UserID, uint(id)); err != nil {
+		return h.addressError(err, "Failed to update address")
+	}
+
+	return c.JSON(http.StatusOK, address)
+}
+
+// DeleteAddress godoc
+//
+//	@Summary		Delete address
+//	@Description	Remove an address from the logged-in user's address book. Orders placed with it keep their copy.
+//	@Tags			user
+//	@Param			id	path	int	true	"Address ID"
+//	@Success		204	"No Content"
+//	@Failure		404	{object}	middleware.ErrorResponse
+//	@Router			/user/addresses/{id} [delete]
+func (h *Handler) DeleteAddress(c echo.Context) error {
+	claims := c.Get("userClaims").(*models.Claims)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid address ID")
+	}
+
+	if err := h.addresses.Delete(claims.UserID, uint(id)); err != nil {
+		return h.addressError(err, "Failed to delete address")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Handler) bindAddress(c echo.Context) (*Address, error) {
+	var req AddressRequest
+	if err := c.Bind(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := h.validator.Struct(req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return &Address{
+		Label:           strings.TrimSpace(req.Label),
+		Address:         req.Address,
+		DefaultShipping: req.DefaultShipping,
+		DefaultBilling:  req.DefaultBilling,
+	}, nil
+}
+
+func (h *Handler) addressError(err error, message string) error {
+	switch {
+	case errors.Is(err, ErrAddressNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Address not found")
+	case errors.Is(err, geo.ErrInvalidAddress):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	h.logger.Error(message, zap.Error(err))
+	return echo.NewHTTPError(http.StatusInternalServerError, message)
+}
@@ -1,53 +1,59 @@
 package user
 
 import (
-	"github.com/nneji123/ecommerce-golang/internal/common/email"
 	"github.com/nneji123/ecommerce-golang/internal/config"
+	"github.com/nneji123/ecommerce-golang/internal/domain/outbox"
+	"gorm.io/gorm"
 )
 
+// EmailService queues account emails in the outbox. Pass the transaction of
+// the change that triggers the email so both commit together.
 type EmailService interface {
-	SendVerificationEmail(email, token string) error
-	SendPasswordResetEmail(email, token string) error
+	QueueVerificationEmail(tx *gorm.DB, email, token string) error
+	QueuePasswordResetEmail(tx *gorm.DB, email, token string) error
+}
+
+// CartMerger merges an anonymous shopping cart into a user's cart.
+type CartMerger interface {
+	MergeGuestCart(token string, userID uint) error
 }
 
 type emailService struct {
-	service *email.EmailNotificationService
-	config  *config.Config
+	config *config.Config
 }
 
-func NewEmailService(service *email.EmailNotificationService, cfg *config.Config) EmailService { // Changed parameter type to pointer
+func NewEmailService(cfg *config.Config) EmailService {
 	return &emailService{
-		service: service,
-		config:  cfg,
+		config: cfg,
 	}
 }
 
-func (s *emailService) SendVerificationEmail(email, token string) error {
-	context := map[string]interface{}{
+func (s *emailService) QueueVerificationEmail(tx *gorm.DB, email, token string) error {
+	context := outbox.TemplateData{
 		"VerificationLink": s.config.AppURL + "/verify-email?token=" + token,
 		"Token":            token,
 	}
 
-	return s.service.SendEmail(
+	return outbox.Enqueue(
+		tx,
 		"Verify Your Email",
 		"templates/verify-email.mjml",
 		email,
 		context,
-		nil,
 	)
 }
 
-func (s *emailService) SendPasswordResetEmail(email, token string) error {
-	context := map[string]interface{}{
+func (s *emailService) QueuePasswordResetEmail(tx *gorm.DB, email, token string) error {
+	context := outbox.TemplateData{
 		"ResetLink": s.config.AppURL + "/reset-password?token=" + token,
 		"Token":     token,
 	}
 
-	return s.service.SendEmail(
+	return outbox.Enqueue(
+		tx,
 		"Reset Your Password",
 		"templates/reset-password.mjml",
 		email,
 		context,
-		nil,
 	)
 }
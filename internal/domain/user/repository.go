@@ -5,6 +5,7 @@ import (
 
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"time"
 )
 
@@ -15,6 +16,8 @@ type Repository interface {
 	FindByEmailVerificationToken(token string) (*User, error)
 	FindByPasswordResetToken(token string) (*User, error)
 	Update(user *User) error
+	Transaction(fn func(tx *gorm.DB) error) error
+	WithTx(tx *gorm.DB) Repository
 }
 
 func (r *repository) FindByPasswordResetToken(token string) (*User, error) {
@@ -47,6 +50,16 @@ func (r *repository) Update(user *User) error {
 	return r.db.Save(user).Error
 }
 
+// Transaction runs fn in a database transaction. Use WithTx to make repository
+// calls inside it.
+func (r *repository) Transaction(fn func(tx *gorm.DB) error) error {
+	return r.db.Transaction(fn)
+}
+
+func (r *repository) WithTx(tx *gorm.DB) Repository {
+	return &repository{db: tx}
+}
+
 type repository struct {
 	db *gorm.DB
 }
@@ -87,3 +100,205 @@ func (r *repository) FindByID(id uint) (*User, error) {
 	}
 	return &user, nil
 }
+
+var ErrRefreshTokenReused = errors.New("refresh token has already been used")
+
+type RefreshTokenRepository interface {
+	Create(token *RefreshToken) error
+	FindByHash(hash string) (*RefreshToken, error)
+	Rotate(current *RefreshToken, next *RefreshToken) error
+	RevokeFamily(familyID string) error
+	RevokeUser(userID uint) error
+}
+
+type refreshTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) RefreshTokenRepository {
+	return &refreshTokenRepository{db: db}
+}
+
+func (r *refreshTokenRepository) Create(token *RefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *refreshTokenRepository) FindByHash(hash string) (*RefreshToken, error) {
+	var token RefreshToken
+	err := r.db.Where("token_hash = ?", hash).First(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Rotate revokes current and stores next in its place. If current was revoked
+// concurrently, ErrRefreshTokenReused is returned and nothing is written.
+func (r *refreshTokenRepository) Rotate(current *RefreshToken, next *RefreshToken) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var locked RefreshToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, current.ID).Error; err != nil {
+			return err
+		}
+		if locked.RevokedAt != nil {
+			return ErrRefreshTokenReused
+		}
+
+		if err := tx.Create(next).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		return tx.Model(&locked).Updates(map[string]interface{}{
+			"revoked_at":     now,
+			"replaced_by_id": next.ID,
+		}).Error
+	})
+}
+
+func (r *refreshTokenRepository) RevokeFamily(familyID string) error {
+	return r.db.Model(&RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *refreshTokenRepository) RevokeUser(userID uint) error {
+	return r.db.Model(&RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+var ErrAddressNotFound = errors.New("address not found")
+
+type AddressRepository interface {
+	List(userID uint) ([]Address, error)
+	Get(userID, id uint) (*Address, error)
+	Defaults(userID uint) (shipping *Address, billing *Address, err error)
+	Save(address *Address) error
+	Delete(userID, id uint) error
+}
+
+type addressRepository struct {
+	db *gorm.DB
+}
+
+func NewAddressRepository(db *gorm.DB) AddressRepository {
+	return &addressRepository{db: db}
+}
+
+// List returns a user's addresses, the defaults first.
+func (r *addressRepository) List(userID uint) ([]Address, error) {
+	addresses := []Address{}
+	err := r.db.Where("user_id = ?", userID).
+		Order("is_default_shipping DESC, is_default_billing DESC, id").
+		Find(&addresses).Error
+	return addresses, err
+}
+
+// Get returns one of a user's addresses, or ErrAddressNotFound if the user
+// has no address with that ID.
+func (r *addressRepository) Get(userID, id uint) (*Address, error) {
+	var address Address
+	err := r.db.Where("user_id = ?", userID).First(&address, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAddressNotFound
+		}
+		return nil, err
+	}
+	return &address, nil
+}
+
+// Defaults returns a user's default shipping and billing addresses, either
+// of which is nil when the user has none.
+func (r *addressRepository) Defaults(userID uint) (*Address, *Address, error) {
+	var addresses []Address
+	err := r.db.Where("user_id = ? AND (is_default_shipping OR is_default_billing)", userID).
+		Find(&addresses).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	var shipping, billing *Address
+	for i := range addresses {
+		if addresses[i].DefaultShipping {
+			shipping = &addresses[i]
+		}
+		if addresses[i].DefaultBilling {
+			billing = &addresses[i]
+		}
+	}
+	return shipping, billing, nil
+}
+
+// Save creates an address, or replaces one of the user's addresses. Making
+// it a default clears the flag on the user's previous default.
+func (r *addressRepository) Save(address *Address) error {
+	address.Address = address.Address.Normalize()
+	if err := address.Address.Validate(); err != nil {
+		return err
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Serialize address book changes of a user so defaults stay unique.
+		var owner User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&owner, address.UserID).Error; err != nil {
+			return err
+		}
+
+		if address.ID == 0 {
+			var count int64
+			if err := tx.Model(&Address{}).Where("user_id = ?", address.UserID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				address.DefaultShipping, address.DefaultBilling = true, true
+			}
+		}
+		if address.DefaultShipping {
+			if err := tx.Model(&Address{}).
+				Where("user_id = ? AND is_default_shipping AND id <> ?", address.UserID, address.ID).
+				Update("is_default_shipping", false).Error; err != nil {
+				return err
+			}
+		}
+		if address.DefaultBilling {
+			if err := tx.Model(&Address{}).
+				Where("user_id = ? AND is_default_billing AND id <> ?", address.UserID, address.ID).
+				Update("is_default_billing", false).Error; err != nil {
+				return err
+			}
+		}
+
+		if address.ID == 0 {
+			return tx.Create(address).Error
+		}
+		result := tx.Model(address).
+			Where("user_id = ?", address.UserID).
+			Omit("UserID", "CreatedAt").
+			Select("*").
+			Updates(address)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrAddressNotFound
+		}
+		return nil
+	})
+}
+
+// Delete removes one of a user's addresses. Orders placed with it keep their
+// copy.
+func (r *addressRepository) Delete(userID, id uint) error {
+	result := r.db.Where("user_id = ?", userID).Delete(&Address{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAddressNotFound
+	}
+	return nil
+}
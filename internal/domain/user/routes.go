@@ -2,12 +2,13 @@ package user
 
 import (
 	"github.com/labstack/echo/v4"
+	"github.com/nneji123/ecommerce-golang/internal/common/revocation"
 	"github.com/nneji123/ecommerce-golang/internal/config"
 	"github.com/nneji123/ecommerce-golang/internal/middleware"
 	"log"
 )
 
-func RegisterRoutes(e *echo.Echo, h *Handler) {
+func RegisterRoutes(e *echo.Echo, h *Handler, revocations revocation.Store) {
 	// Public routes group
 	auth := e.Group("/auth")
 	{
@@ -16,6 +17,7 @@ func RegisterRoutes(e *echo.Echo, h *Handler) {
 		auth.POST("/confirm-registration", h.ConfirmRegistration)
 		auth.POST("/password-reset-request", h.RequestPasswordReset)
 		auth.POST("/confirm-password-reset", h.ConfirmPasswordReset)
+		auth.POST("/refresh", h.Refresh)
 	}
 
 	cfg, err := config.LoadConfig()
@@ -23,10 +25,17 @@ func RegisterRoutes(e *echo.Echo, h *Handler) {
 		log.Fatalf("Error loading configuration: %s", err)
 	}
 
+	auth.POST("/logout", h.Logout, middleware.AuthMiddleware(cfg.JWTSecret, revocations))
+
 	// Define protected route group
 	protected := e.Group("/user")
-	protected.Use(middleware.AuthMiddleware(cfg.JWTSecret))
+	protected.Use(middleware.AuthMiddleware(cfg.JWTSecret, revocations))
 
 	// Register protected routes
 	protected.GET("/detail", h.UserDetail)
+	protected.GET("/addresses", h.ListAddresses)
+	protected.POST("/addresses", h.CreateAddress)
+	protected.GET("/addresses/:id", h.GetAddress)
+	protected.PUT("/addresses/:id", h.UpdateAddress)
+	protected.DELETE("/addresses/:id", h.DeleteAddress)
 }
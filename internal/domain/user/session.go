@@ -0,0 +1,161 @@
+package user
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nneji123/ecommerce-golang/internal/common/models"
+	"github.com/nneji123/ecommerce-golang/internal/common/revocation"
+)
+
+var ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+
+// SessionService issues access/refresh token pairs and revokes them.
+type SessionService interface {
+	Issue(user *User) (*TokenResponse, error)
+	Refresh(refreshToken string) (*TokenResponse, error)
+	Logout(refreshToken string, claims *models.Claims) error
+	RevokeAll(userID uint) error
+}
+
+type sessionService struct {
+	auth        AuthService
+	users       Repository
+	tokens      RefreshTokenRepository
+	revocations revocation.Store
+	refreshTTL  time.Duration
+}
+
+func NewSessionService(auth AuthService, users Repository, tokens RefreshTokenRepository, revocations revocation.Store, refreshTTL time.Duration) SessionService {
+	return &sessionService{
+		auth:        auth,
+		users:       users,
+		tokens:      tokens,
+		revocations: revocations,
+		refreshTTL:  refreshTTL,
+	}
+}
+
+// hashRefreshToken returns the stored form of a refresh token.
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Issue starts a new session for user with a fresh refresh token family.
+func (s *sessionService) Issue(user *User) (*TokenResponse, error) {
+	familyID, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+	return s.issue(user, familyID, nil)
+}
+
+// issue creates an access token and a refresh token in the given family. When
+// current is set, it is rotated out in favour of the new refresh token.
+func (s *sessionService) issue(user *User, familyID string, current *RefreshToken) (*TokenResponse, error) {
+	version, err := s.revocations.TokenVersion(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	accessToken, err := s.auth.GenerateToken(user.ID, user.Email, user.Role, version)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	next := &RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: hashRefreshToken(refreshToken),
+		ExpiresAt: time.Now().Add(s.refreshTTL),
+	}
+	if current == nil {
+		err = s.tokens.Create(next)
+	} else {
+		err = s.tokens.Rotate(current, next)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.auth.TokenTTL().Seconds()),
+	}, nil
+}
+
+// Refresh exchanges a refresh token for a new token pair. Presenting a token
+// that has already been rotated revokes its whole family, since it means the
+// token was stolen or replayed.
+func (s *sessionService) Refresh(refreshToken string) (*TokenResponse, error) {
+	current, err := s.tokens.FindByHash(hashRefreshToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if current == nil || current.ExpiresAt.Before(time.Now()) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if current.RevokedAt != nil {
+		return nil, s.reuseDetected(current)
+	}
+
+	user, err := s.users.FindByID(current.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	pair, err := s.issue(user, current.FamilyID, current)
+	if errors.Is(err, ErrRefreshTokenReused) {
+		return nil, s.reuseDetected(current)
+	}
+	return pair, err
+}
+
+func (s *sessionService) reuseDetected(token *RefreshToken) error {
+	if err := s.tokens.RevokeFamily(token.FamilyID); err != nil {
+		return fmt.Errorf("failed to revoke token family: %w", err)
+	}
+	return ErrRefreshTokenReused
+}
+
+// Logout revokes the presented access token and, if given, the refresh token
+// family it belongs to.
+func (s *sessionService) Logout(refreshToken string, claims *models.Claims) error {
+	if refreshToken != "" {
+		current, err := s.tokens.FindByHash(hashRefreshToken(refreshToken))
+		if err != nil {
+			return err
+		}
+		if current != nil && current.UserID == claims.UserID {
+			if err := s.tokens.RevokeFamily(current.FamilyID); err != nil {
+				return err
+			}
+		}
+	}
+
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return nil
+	}
+	return s.revocations.RevokeToken(claims.ID, claims.ExpiresAt.Time)
+}
+
+// RevokeAll ends every session of a user: refresh tokens are revoked and all
+// access tokens issued so far are rejected.
+func (s *sessionService) RevokeAll(userID uint) error {
+	if err := s.tokens.RevokeUser(userID); err != nil {
+		return err
+	}
+	return s.revocations.RevokeUserTokens(userID)
+}
@@ -3,6 +3,7 @@ package user
 import (
 	"time"
 
+	"github.com/nneji123/ecommerce-golang/internal/common/geo"
 	"gorm.io/gorm"
 )
 
@@ -48,6 +49,58 @@ type LoginRequest struct {
 }
 
 type LoginResponse struct {
-	Token string `json:"token"`
-	User  User   `json:"user"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	User         User   `json:"user"`
+}
+
+// RefreshToken is an opaque, single-use token exchanged for a new access
+// token. Only its SHA-256 hash is stored. Tokens rotated from the same login
+// share a FamilyID so the whole chain can be revoked when reuse is detected.
+type RefreshToken struct {
+	ID           uint       `gorm:"primaryKey"`
+	UserID       uint       `gorm:"not null;index"`
+	FamilyID     string     `gorm:"size:64;not null;index"`
+	TokenHash    string     `gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt    time.Time  `gorm:"not null"`
+	RevokedAt    *time.Time `gorm:"default:null"`
+	ReplacedByID *uint      `gorm:"default:null"`
+	CreatedAt    time.Time
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type TokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// Address is an entry of a user's address book. At most one address of a
+// user is the default shipping address and one the default billing address;
+// checkout uses them when the customer chooses none. A user's first address
+// becomes both defaults.
+type Address struct {
+	ID              uint   `json:"id" gorm:"primaryKey"`
+	UserID          uint   `json:"user_id" gorm:"not null;index"`
+	Label           string `json:"label,omitempty" gorm:"size:100"`
+	geo.Address     `gorm:"embedded"`
+	DefaultShipping bool      `json:"default_shipping" gorm:"column:is_default_shipping;not null;default:false"`
+	DefaultBilling  bool      `json:"default_billing" gorm:"column:is_default_billing;not null;default:false"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+type AddressRequest struct {
+	Label string `json:"label" validate:"max=100"`
+	geo.Address
+	DefaultShipping bool `json:"default_shipping"`
+	DefaultBilling  bool `json:"default_billing"`
 }
@@ -1,6 +1,8 @@
 package user
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"time"
 
@@ -10,31 +12,49 @@ import (
 
 // AuthService defines methods for token generation and validation.
 type AuthService interface {
-	GenerateToken(userID uint, email, role string) (string, error)
+	GenerateToken(userID uint, email, role string, version uint) (string, error)
 	ValidateToken(token string) (*models.Claims, error)
+	TokenTTL() time.Duration
 }
 
 // jwtService implements the AuthService interface.
 type jwtService struct {
 	secretKey string
+	ttl       time.Duration
 }
 
-// NewJWTService creates a new instance of jwtService.
-func NewJWTService(secretKey string) AuthService {
-	return &jwtService{secretKey: secretKey}
+// NewJWTService creates a new instance of jwtService issuing access tokens
+// that are valid for ttl.
+func NewJWTService(secretKey string, ttl time.Duration) AuthService {
+	return &jwtService{secretKey: secretKey, ttl: ttl}
 }
 
-// GenerateToken creates a new JWT for a user.
-func (s *jwtService) GenerateToken(userID uint, email, role string) (string, error) {
+// TokenTTL returns how long issued access tokens remain valid.
+func (s *jwtService) TokenTTL() time.Duration {
+	return s.ttl
+}
+
+// GenerateToken creates a new short-lived JWT for a user. Each token carries a
+// unique jti so it can be revoked individually, and the user's token version
+// so all of a user's tokens can be revoked at once.
+func (s *jwtService) GenerateToken(userID uint, email, role string, version uint) (string, error) {
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", fmt.Errorf("failed to generate token id: %w", err)
+	}
+
+	now := time.Now()
 	claims := &models.Claims{
 		UserID: userID,
 		Email:  email,
 		Role:   role,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)), // Token expires in 24 hours
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			ID:        hex.EncodeToString(jti),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 		},
+		TokenVersion: version,
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -9,10 +9,12 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
 	"github.com/nneji123/ecommerce-golang/internal/common/models"
+	"github.com/nneji123/ecommerce-golang/internal/common/revocation"
 )
 
-// AuthMiddleware validates JWT tokens and extracts user claims.
-func AuthMiddleware(secretKey string) echo.MiddlewareFunc {
+// AuthMiddleware validates JWT tokens and extracts user claims. Tokens found
+// in revocations are rejected; a nil store skips the check.
+func AuthMiddleware(secretKey string, revocations revocation.Store) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			authHeader := c.Request().Header.Get("Authorization")
@@ -20,22 +22,9 @@ func AuthMiddleware(secretKey string) echo.MiddlewareFunc {
 				return echo.NewHTTPError(http.StatusUnauthorized, "missing or invalid Authorization header")
 			}
 
-			tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-
-			claims := &models.Claims{}
-			token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-					return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-				}
-				return []byte(secretKey), nil
-			})
-
-			if err != nil || !token.Valid {
-				return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired token")
-			}
-
-			if claims.ExpiresAt != nil && claims.ExpiresAt.Before(time.Now()) {
-				return echo.NewHTTPError(http.StatusUnauthorized, "token has expired")
+			claims, err := parseClaims(strings.TrimPrefix(authHeader, "Bearer "), secretKey, revocations)
+			if err != nil {
+				return err
 			}
 
 			c.Set("userClaims", claims)
@@ -45,19 +34,58 @@ func AuthMiddleware(secretKey string) echo.MiddlewareFunc {
 	}
 }
 
-func AdminMiddleware() echo.MiddlewareFunc {
+// OptionalAuthMiddleware extracts user claims when a bearer token is present
+// and lets anonymous requests through untouched. An invalid token is still
+// rejected, as is a revoked one.
+func OptionalAuthMiddleware(secretKey string, revocations revocation.Store) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			claims, ok := c.Get("userClaims").(*models.Claims)
-			if !ok {
-				return echo.NewHTTPError(http.StatusUnauthorized, "invalid or missing user claims")
+			authHeader := c.Request().Header.Get("Authorization")
+			if authHeader == "" {
+				return next(c)
+			}
+			if !strings.HasPrefix(authHeader, "Bearer ") {
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid Authorization header")
 			}
 
-			if claims.Role != "admin" {
-				return echo.NewHTTPError(http.StatusForbidden, "admin access required")
+			claims, err := parseClaims(strings.TrimPrefix(authHeader, "Bearer "), secretKey, revocations)
+			if err != nil {
+				return err
 			}
 
+			c.Set("userClaims", claims)
+
 			return next(c)
 		}
 	}
 }
+
+func parseClaims(tokenString, secretKey string, revocations revocation.Store) (*models.Claims, error) {
+	claims := &models.Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(secretKey), nil
+	})
+
+	if err != nil || !token.Valid {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired token")
+	}
+
+	if claims.ExpiresAt != nil && claims.ExpiresAt.Before(time.Now()) {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "token has expired")
+	}
+
+	if revocations != nil {
+		revoked, err := revocations.IsRevoked(claims)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "failed to verify token")
+		}
+		if revoked {
+			return nil, echo.NewHTTPError(http.StatusUnauthorized, "token has been revoked")
+		}
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,215 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	ErrInvalidCursor       = errors.New("invalid cursor")
+	ErrMissingCursorSecret = errors.New("cursor secret is not set")
+)
+
+// Cursor is the position of a keyset page: the sort key and ID of the row at
+// its boundary. A Backward cursor selects the rows before the boundary.
+type Cursor struct {
+	SortBy   string `json:"s"`
+	SortDir  string `json:"d"`
+	Value    string `json:"v"`
+	ID       uint   `json:"id"`
+	Backward bool   `json:"b,omitempty"`
+}
+
+// CursorSigner signs pagination cursors so clients cannot forge positions.
+type CursorSigner struct {
+	secret []byte
+}
+
+// NewCursorSigner returns a signer keyed with secret, which must not be empty.
+func NewCursorSigner(secret string) (*CursorSigner, error) {
+	if secret == "" {
+		return nil, ErrMissingCursorSecret
+	}
+	return &CursorSigner{secret: []byte(secret)}, nil
+}
+
+// Encode returns the opaque, signed form of a cursor.
+func (s *CursorSigner) Encode(cursor Cursor) string {
+	payload, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(s.sign(payload))
+}
+
+// Decode verifies and decodes a cursor produced by Encode.
+func (s *CursorSigner) Decode(token string) (*Cursor, error) {
+	encodedPayload, encodedMAC, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedMAC)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	if !hmac.Equal(signature, s.sign(payload)) {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(payload, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.SortDir != "ASC" && cursor.SortDir != "DESC" {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+func (s *CursorSigner) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// Normalize clamps Page and Limit and upper-cases SortDir, defaulting it to
+// defaultDir.
+func (q *PaginationQuery) Normalize(defaultLimit, maxLimit int, defaultDir string) {
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.Limit < 1 || q.Limit > maxLimit {
+		q.Limit = defaultLimit
+	}
+	q.SortDir = strings.ToUpper(q.SortDir)
+	if q.SortDir != "ASC" && q.SortDir != "DESC" {
+		q.SortDir = defaultDir
+	}
+}
+
+// OffsetPage describes an offset page of a listing with total items.
+func OffsetPage(page, limit int, total int64) PaginatedResponse {
+	totalPages := int(math.Ceil(float64(total) / float64(limit)))
+	return PaginatedResponse{
+		CurrentPage: page,
+		TotalPages:  &totalPages,
+		TotalItems:  &total,
+		Limit:       limit,
+	}
+}
+
+// Keyset returns the condition and ordering that select the page following
+// cursor for a listing sorted by column and then idColumn in dir. The
+// condition takes the cursor's sort value and ID as arguments and is empty
+// when cursor is nil. Backward cursors reverse the ordering; KeysetPage
+// restores it.
+func Keyset(column, idColumn, dir string, cursor *Cursor) (condition, order string) {
+	if cursor != nil && cursor.Backward {
+		if dir == "ASC" {
+			dir = "DESC"
+		} else {
+			dir = "ASC"
+		}
+	}
+
+	order = fmt.Sprintf("%s %s, %s %s", column, dir, idColumn, dir)
+	if cursor == nil {
+		return "", order
+	}
+
+	operator := ">"
+	if dir == "DESC" {
+		operator = "<"
+	}
+	return fmt.Sprintf("(%s, %s) %s (?, ?)", column, idColumn, operator), order
+}
+
+// KeysetPage trims rows fetched with Keyset and a limit of limit+1 to the
+// page, puts backward pages back in sort order and sets the cursors of the
+// neighbouring pages, signed by cursors. key returns the sort value and ID of
+// a row.
+func KeysetPage[T any](cursors *CursorSigner, rows []T, limit int, sortBy, dir string, cursor *Cursor, key func(T) (string, uint)) ([]T, PaginatedResponse) {
+	page := PaginatedResponse{Limit: limit}
+
+	hasMore := len(rows) > limit
+	if hasMore {
+		rows = rows[:limit]
+	}
+	backward := cursor != nil && cursor.Backward
+	if backward {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+	if len(rows) == 0 {
+		return rows, page
+	}
+
+	// A backward page always has rows after it: the page it came from.
+	if hasMore || backward {
+		value, id := key(rows[len(rows)-1])
+		page.NextCursor = cursors.Encode(Cursor{SortBy: sortBy, SortDir: dir, Value: value, ID: id})
+	}
+	if (cursor != nil && !backward) || (backward && hasMore) {
+		value, id := key(rows[0])
+		page.PrevCursor = cursors.Encode(Cursor{SortBy: sortBy, SortDir: dir, Value: value, ID: id, Backward: true})
+	}
+	return rows, page
+}
+
+// SetLinkHeader adds RFC 8288 Link headers pointing at the neighbouring
+// pages: cursor links for cursor pages, numbered links for offset pages.
+func SetLinkHeader(c echo.Context, page PaginatedResponse) {
+	link := func(rel string, set map[string]string) string {
+		u := *c.Request().URL
+		u.Scheme = c.Scheme()
+		u.Host = c.Request().Host
+		query := u.Query()
+		query.Del("page")
+		query.Del("cursor")
+		for k, v := range set {
+			query.Set(k, v)
+		}
+		u.RawQuery = query.Encode()
+		return fmt.Sprintf("<%s>; rel=\"%s\"", u.String(), rel)
+	}
+
+	var links []string
+	if page.CurrentPage > 0 && page.TotalPages != nil {
+		pageLink := func(rel string, n int) string {
+			return link(rel, map[string]string{"page": strconv.Itoa(n)})
+		}
+		links = append(links, pageLink("first", 1))
+		if page.CurrentPage > 1 {
+			links = append(links, pageLink("prev", page.CurrentPage-1))
+		}
+		if page.CurrentPage < *page.TotalPages {
+			links = append(links, pageLink("next", page.CurrentPage+1))
+		}
+		if *page.TotalPages > 0 {
+			links = append(links, pageLink("last", *page.TotalPages))
+		}
+	} else {
+		if page.PrevCursor != "" {
+			links = append(links, link("prev", map[string]string{"cursor": page.PrevCursor}))
+		}
+		if page.NextCursor != "" {
+			links = append(links, link("next", map[string]string{"cursor": page.NextCursor}))
+		}
+	}
+
+	if len(links) > 0 {
+		c.Response().Header().Set("Link", strings.Join(links, ", "))
+	}
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNewCursorSignerRequiresSecret(t *testing.T) {
+	if _, err := NewCursorSigner(""); !errors.Is(err, ErrMissingCursorSecret) {
+		t.Errorf("NewCursorSigner(\"\") error = %v, want %v", err, ErrMissingCursorSecret)
+	}
+}
+
+func TestCursorSignerRoundTrip(t *testing.T) {
+	signer, err := NewCursorSigner("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Cursor{SortBy: "created_at", SortDir: "DESC", Value: "2024-01-02T03:04:05Z", ID: 42, Backward: true}
+	got, err := signer.Decode(signer.Encode(want))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if *got != want {
+		t.Errorf("Decode() = %+v, want %+v", *got, want)
+	}
+}
+
+func TestCursorSignerRejectsTampering(t *testing.T) {
+	signer, err := NewCursorSigner("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewCursorSigner("other-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := signer.Encode(Cursor{SortBy: "price", SortDir: "ASC", Value: "1999", ID: 7})
+	payload, signature, _ := strings.Cut(token, ".")
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"s":"price","d":"ASC","v":"0","id":1}`))
+	flipped := []byte(signature)
+	if flipped[0] == 'A' {
+		flipped[0] = 'B'
+	} else {
+		flipped[0] = 'A'
+	}
+	unsortable := signer.Encode(Cursor{SortBy: "price", SortDir: "sideways", Value: "1999", ID: 7})
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"empty", ""},
+		{"no signature", payload},
+		{"forged payload", forged + "." + signature},
+		{"altered signature", payload + "." + string(flipped)},
+		{"signed with another secret", other.Encode(Cursor{SortBy: "price", SortDir: "ASC", Value: "1999", ID: 7})},
+		{"invalid base64", "!!!." + signature},
+		{"invalid sort direction", unsortable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := signer.Decode(tt.token); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("Decode(%q) error = %v, want %v", tt.token, err, ErrInvalidCursor)
+			}
+		})
+	}
+}
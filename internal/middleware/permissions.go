@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nneji123/ecommerce-golang/internal/common/models"
+)
+
+// PermissionResolver reports whether a role grants a permission.
+type PermissionResolver interface {
+	HasPermission(role, permission string) (bool, error)
+}
+
+// RequirePermission only lets through users whose role grants permission
+// according to permissions. It must run after AuthMiddleware.
+func RequirePermission(permissions PermissionResolver, permission string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			claims, ok := c.Get("userClaims").(*models.Claims)
+			if !ok {
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid or missing user claims")
+			}
+
+			allowed, err := permissions.HasPermission(claims.Role, permission)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, "failed to check permissions")
+			}
+			if !allowed {
+				return echo.NewHTTPError(http.StatusForbidden, "missing permission: "+permission)
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// HasPermission reports whether the authenticated user of c holds permission.
+// Errors resolving permissions are treated as a denial.
+func HasPermission(c echo.Context, permissions PermissionResolver, permission string) bool {
+	claims, ok := c.Get("userClaims").(*models.Claims)
+	if !ok {
+		return false
+	}
+	allowed, err := permissions.HasPermission(claims.Role, permission)
+	return err == nil && allowed
+}
@@ -4,16 +4,26 @@ type ErrorResponse struct {
 	Error string `json:"error"`
 }
 
+// PaginationQuery is the common paging contract of list endpoints. Page and
+// Limit select an offset page; a Cursor from a previous response selects the
+// page after (or before) it instead, using the sort order encoded in the
+// cursor.
 type PaginationQuery struct {
 	Page    int    `query:"page"`
 	Limit   int    `query:"limit"`
 	SortBy  string `query:"sort_by"`
 	SortDir string `query:"sort_dir"`
+	Cursor  string `query:"cursor"`
 }
 
+// PaginatedResponse describes the page returned by a list endpoint. Offset
+// pages report their position and totals; cursor pages only report the
+// cursors of the neighbouring pages, which are also sent as Link headers.
 type PaginatedResponse struct {
-	CurrentPage int   `json:"current_page"`
-	TotalPages  int   `json:"total_pages"`
-	TotalItems  int64 `json:"total_items"`
-	Limit       int   `json:"limit"`
+	CurrentPage int    `json:"current_page,omitempty"`
+	TotalPages  *int   `json:"total_pages,omitempty"`
+	TotalItems  *int64 `json:"total_items,omitempty"`
+	Limit       int    `json:"limit"`
+	NextCursor  string `json:"next_cursor,omitempty"`
+	PrevCursor  string `json:"prev_cursor,omitempty"`
 }